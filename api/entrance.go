@@ -29,6 +29,11 @@ type APIGroup struct {
 	WafLoadBalanceApi
 	WafSslConfigApi
 	WafBatchTaskApi
+	WafLoginProtectApi
+	WafOwaspRuleApi
+	WafRuleTemplateApi
+	WafGeoPolicyApi
+	WafThreatFeedApi
 }
 
 var APIGroupAPP = new(APIGroup)
@@ -66,4 +71,14 @@ var (
 	wafSslConfigService = waf_service.WafSslConfigServiceApp
 
 	wafBatchTaskService = waf_service.WafBatchServiceApp
+
+	wafLoginProtectService = waf_service.WafLoginProtectServiceApp
+
+	wafOwaspRuleService = waf_service.WafOwaspRuleServiceApp
+
+	wafRuleTemplateService = waf_service.WafRuleTemplateServiceApp
+
+	wafGeoPolicyService = waf_service.WafGeoPolicyServiceApp
+
+	wafThreatFeedService = waf_service.WafThreatFeedServiceApp
 )
@@ -6,6 +6,7 @@ import (
 	"SamWaf/model"
 	"SamWaf/model/common/response"
 	"SamWaf/model/request"
+	response2 "SamWaf/model/response"
 	"SamWaf/model/spec"
 	"errors"
 	"github.com/gin-gonic/gin"
@@ -54,11 +55,16 @@ func (w *WafAllowIpApi) GetListApi(c *gin.Context) {
 	err := c.ShouldBindJSON(&req)
 	if err == nil {
 		wafIpWhites, total, _ := wafIpAllowService.GetListApi(req)
-		response.OkWithDetailed(response.PageResult{
+		codes := make([]string, 0, len(wafIpWhites))
+		for _, bean := range wafIpWhites {
+			codes = append(codes, bean.Id)
+		}
+		response.OkWithDetailed(response2.WafRuleHitPageResult{
 			List:      wafIpWhites,
 			Total:     total,
 			PageIndex: req.PageIndex,
 			PageSize:  req.PageSize,
+			HitStats:  wafStatService.StatRuleHitApi(enums.RULE_STATS_TYPE_IP_ALLOW, codes),
 		}, "获取成功", c)
 	} else {
 		response.FailWithMessage("解析失败", c)
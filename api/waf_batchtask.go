@@ -2,6 +2,7 @@ package api
 
 import (
 	"SamWaf/enums"
+	"SamWaf/globalobj"
 	"SamWaf/model/common/response"
 	"SamWaf/model/request"
 	"SamWaf/waftask"
@@ -14,11 +15,17 @@ type WafBatchTaskApi struct{}
 
 // AddBatchTaskApi 添加自动任务
 func (s *WafBatchTaskApi) AddBatchTaskApi(c *gin.Context) {
-	var req request.BatchTaskAddReq
+	//未传启用状态时默认启用
+	req := request.BatchTaskAddReq{BatchEnable: 1}
 	err := c.ShouldBindJSON(&req)
 	if err == nil {
+		if msg := s.checkReq(req.BatchType, req.BatchSourceType, req.BatchExecuteMethod, req.BatchCron, req.BatchEnable); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
 		err = wafBatchTaskService.AddApi(req)
 		if err == nil {
+			waftask.ScheduleBatchTasks(globalobj.GWAF_RUNTIME_OBJ_WAF_CRON)
 			response.OkWithMessage("添加成功", c)
 		} else {
 			response.FailWithMessage("添加失败:"+err.Error(), c)
@@ -69,6 +76,7 @@ func (s *WafBatchTaskApi) DelBatchTaskApi(c *gin.Context) {
 		} else if err != nil {
 			response.FailWithMessage("发生错误", c)
 		} else {
+			waftask.ScheduleBatchTasks(globalobj.GWAF_RUNTIME_OBJ_WAF_CRON)
 			response.OkWithMessage("删除成功", c)
 		}
 	} else {
@@ -81,11 +89,15 @@ func (s *WafBatchTaskApi) ModifyBatchTaskApi(c *gin.Context) {
 	var req request.BatchTaskEditReq
 	err := c.ShouldBindJSON(&req)
 	if err == nil {
+		if msg := s.checkReq(req.BatchType, req.BatchSourceType, req.BatchExecuteMethod, req.BatchCron, req.BatchEnable); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
 		err = wafBatchTaskService.ModifyApi(req)
 		if err != nil {
 			response.FailWithMessage("编辑发生错误", c)
 		} else {
-
+			waftask.ScheduleBatchTasks(globalobj.GWAF_RUNTIME_OBJ_WAF_CRON)
 			response.OkWithMessage("编辑成功", c)
 		}
 	} else {
@@ -99,13 +111,60 @@ func (s *WafBatchTaskApi) ManualBatchTaskApi(c *gin.Context) {
 	err := c.ShouldBind(&req)
 	if err == nil {
 		bean := wafBatchTaskService.GetDetailByIdApi(req.Id)
-		switch bean.BatchType {
-		case enums.BATCHTASK_IPALLOW:
-			waftask.IPAllowBatch(bean)
-			break
+		if bean.Id == "" {
+			response.FailWithMessage("任务不存在", c)
+			return
 		}
-		response.OkWithMessage("手工执行任务成功", c)
+		//返回本次执行结果 新增、覆盖、跳过、无法识别的条数及错误信息
+		history, err := waftask.ExecuteBatchTask(bean, enums.BATCHTASK_TRIGGER_MANUAL)
+		if err != nil {
+			response.FailWithMessage("手工执行任务失败:"+err.Error(), c)
+			return
+		}
+		response.OkWithDetailed(history, "手工执行任务完成", c)
 	} else {
 		response.FailWithMessage("手工执行任务失败", c)
 	}
 }
+
+// GetBatchTaskHistoryListApi 获取任务执行记录
+func (s *WafBatchTaskApi) GetBatchTaskHistoryListApi(c *gin.Context) {
+	var req request.BatchTaskHistorySearchReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		list, total, _ := wafBatchTaskService.GetHistoryListApi(req)
+		response.OkWithDetailed(response.PageResult{
+			List:      list,
+			Total:     total,
+			PageIndex: req.PageIndex,
+			PageSize:  req.PageSize,
+		}, "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+// 校验任务类型、来源类型和执行方式
+func (s *WafBatchTaskApi) checkReq(batchType string, sourceType string, executeMethod string, cronExpr string, enable int) string {
+	switch batchType {
+	case enums.BATCHTASK_IPALLOW, enums.BATCHTASK_IPBLOCK, enums.BATCHTASK_URLALLOW, enums.BATCHTASK_URLBLOCK,
+		enums.BATCHTASK_LDPURL, enums.BATCHTASK_SENSITIVE, enums.BATCHTASK_RULE:
+	default:
+		return "任务类型不正确"
+	}
+	if sourceType != "local" && sourceType != "remote" {
+		return "来源类型不正确"
+	}
+	if executeMethod != enums.BATCHTASK_EXECUTEMETHODAPPEND && executeMethod != enums.BATCHTASK_EXECUTEMETHODOVERWRITE {
+		return "执行方式不正确"
+	}
+	if cronExpr != "" {
+		if err := waftask.CheckBatchTaskCron(cronExpr); err != nil {
+			return "cron表达式不正确:" + err.Error()
+		}
+	}
+	if enable != 0 && enable != 1 {
+		return "启用状态不正确"
+	}
+	return ""
+}
@@ -6,7 +6,9 @@ import (
 	"SamWaf/model"
 	"SamWaf/model/common/response"
 	"SamWaf/model/request"
+	response2 "SamWaf/model/response"
 	"SamWaf/model/spec"
+	"SamWaf/utils"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -19,6 +21,12 @@ func (w *WafBlockUrlApi) AddApi(c *gin.Context) {
 	var req request.WafBlockUrlAddReq
 	err := c.ShouldBindJSON(&req)
 	if err == nil {
+		if err = utils.CheckURLMatchRule(utils.URLMatchRule{CompareType: req.CompareType, Url: req.Url, IgnoreCase: req.IgnoreCase == 1,
+			Method: req.ReqMethod, Host: req.ReqHost, HeaderName: req.HeaderName, HeaderValue: req.HeaderValue,
+			QueryName: req.QueryName, QueryValue: req.QueryValue}); err != nil {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
 		err = wafUrlBlockService.CheckIsExistApi(req)
 		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
 			err = wafUrlBlockService.AddApi(req)
@@ -54,11 +62,16 @@ func (w *WafBlockUrlApi) GetListApi(c *gin.Context) {
 	err := c.ShouldBindJSON(&req)
 	if err == nil {
 		beans, total, _ := wafUrlBlockService.GetListApi(req)
-		response.OkWithDetailed(response.PageResult{
+		codes := make([]string, 0, len(beans))
+		for _, bean := range beans {
+			codes = append(codes, bean.Id)
+		}
+		response.OkWithDetailed(response2.WafRuleHitPageResult{
 			List:      beans,
 			Total:     total,
 			PageIndex: req.PageIndex,
 			PageSize:  req.PageSize,
+			HitStats:  wafStatService.StatRuleHitApi(enums.RULE_STATS_TYPE_URL_BLOCK, codes),
 		}, "获取成功", c)
 	} else {
 		response.FailWithMessage("解析失败", c)
@@ -88,6 +101,12 @@ func (w *WafBlockUrlApi) ModifyBlockUrlApi(c *gin.Context) {
 	var req request.WafBlockUrlEditReq
 	err := c.ShouldBindJSON(&req)
 	if err == nil {
+		if err = utils.CheckURLMatchRule(utils.URLMatchRule{CompareType: req.CompareType, Url: req.Url, IgnoreCase: req.IgnoreCase == 1,
+			Method: req.ReqMethod, Host: req.ReqHost, HeaderName: req.HeaderName, HeaderValue: req.HeaderValue,
+			QueryName: req.QueryName, QueryValue: req.QueryValue}); err != nil {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
 		err = wafUrlBlockService.ModifyApi(req)
 		if err != nil {
 			response.FailWithMessage("编辑发生错误", c)
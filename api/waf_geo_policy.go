@@ -0,0 +1,144 @@
+package api
+
+import (
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/model/common/response"
+	"SamWaf/model/request"
+	"SamWaf/model/spec"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strings"
+)
+
+type WafGeoPolicyApi struct {
+}
+
+func (w *WafGeoPolicyApi) AddApi(c *gin.Context) {
+	var req request.WafGeoPolicyAddReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		if msg := w.checkReq(req.Mode, req.Regions, req.Action, req.Status); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
+		err = wafGeoPolicyService.CheckIsExistApi(req)
+		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+			err = wafGeoPolicyService.AddApi(req)
+			if err == nil {
+				w.NotifyWaf(req.HostCode)
+				response.OkWithMessage("添加成功", c)
+			} else {
+
+				response.FailWithMessage("添加失败", c)
+			}
+			return
+		} else {
+			response.FailWithMessage("当前网站已经存在地域限制", c)
+			return
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafGeoPolicyApi) GetDetailApi(c *gin.Context) {
+	var req request.WafGeoPolicyDetailReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		bean := wafGeoPolicyService.GetDetailApi(req)
+		response.OkWithDetailed(bean, "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafGeoPolicyApi) GetListApi(c *gin.Context) {
+	var req request.WafGeoPolicySearchReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		beans, total, _ := wafGeoPolicyService.GetListApi(req)
+		response.OkWithDetailed(response.PageResult{
+			List:      beans,
+			Total:     total,
+			PageIndex: req.PageIndex,
+			PageSize:  req.PageSize,
+		}, "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafGeoPolicyApi) DelGeoPolicyApi(c *gin.Context) {
+	var req request.WafGeoPolicyDelReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		bean := wafGeoPolicyService.GetDetailByIdApi(req.Id)
+		err = wafGeoPolicyService.DelApi(req)
+		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailWithMessage("请检测参数", c)
+		} else if err != nil {
+			response.FailWithMessage("发生错误", c)
+		} else {
+			w.NotifyWaf(bean.HostCode)
+			response.OkWithMessage("删除成功", c)
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+func (w *WafGeoPolicyApi) ModifyGeoPolicyApi(c *gin.Context) {
+	var req request.WafGeoPolicyEditReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		if msg := w.checkReq(req.Mode, req.Regions, req.Action, req.Status); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
+		bean := wafGeoPolicyService.GetDetailByIdApi(req.Id)
+		err = wafGeoPolicyService.ModifyApi(req)
+		if err != nil {
+			response.FailWithMessage("编辑发生错误:"+err.Error(), c)
+		} else {
+			w.NotifyWaf(req.HostCode)
+			if bean.HostCode != "" && bean.HostCode != req.HostCode {
+				w.NotifyWaf(bean.HostCode)
+			}
+			response.OkWithMessage("编辑成功", c)
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+// 校验地域限制配置
+func (w *WafGeoPolicyApi) checkReq(mode string, regions string, action string, status int) string {
+	if mode != enums.GEO_POLICY_MODE_ALLOW && mode != enums.GEO_POLICY_MODE_DENY {
+		return "限制方式不正确"
+	}
+	if action != enums.GEO_POLICY_ACTION_BLOCK && action != enums.GEO_POLICY_ACTION_CHALLENGE {
+		return "处置方式不正确"
+	}
+	if status != 0 && status != 1 {
+		return "状态不正确"
+	}
+	if strings.TrimSpace(regions) == "" {
+		return "地区不能为空"
+	}
+	return ""
+}
+
+/*
+*
+通知到waf引擎实时生效
+*/
+func (w *WafGeoPolicyApi) NotifyWaf(host_code string) {
+	var chanInfo = spec.ChanCommonHost{
+		HostCode: host_code,
+		Type:     enums.ChanTypeGeoPolicy,
+		Content:  wafGeoPolicyService.GetDetailByHostCodeApi(host_code),
+	}
+	global.GWAF_CHAN_MSG <- chanInfo
+}
@@ -25,6 +25,10 @@ func (w *WafHostAPi) AddApi(c *gin.Context) {
 	var req request.WafHostAddReq
 	err := c.ShouldBindJSON(&req)
 	if err == nil {
+		if _, err = model.ParseDetectExcludes(req.DETECT_EXCLUDE_JSON); err != nil {
+			response.FailWithMessage("检测排除项不正确:"+err.Error(), c)
+			return
+		}
 		//端口从未在本系统加过，检测端口是否被其他应用占用
 		_, svrOk := globalobj.GWAF_RUNTIME_OBJ_WAF_ENGINE.ServerOnline[req.Port]
 		if !svrOk && utils.PortCheck(req.Port) == false {
@@ -135,6 +139,10 @@ func (w *WafHostAPi) ModifyHostApi(c *gin.Context) {
 	var req request.WafHostEditReq
 	err := c.ShouldBindJSON(&req)
 	if err == nil {
+		if _, err = model.ParseDetectExcludes(req.DETECT_EXCLUDE_JSON); err != nil {
+			response.FailWithMessage("检测排除项不正确:"+err.Error(), c)
+			return
+		}
 		wafHostOld := wafHostService.GetDetailByCodeApi(req.CODE)
 		//端口从未在本系统加过，检测端口是否被其他应用占用
 
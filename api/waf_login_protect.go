@@ -0,0 +1,178 @@
+package api
+
+import (
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/common/response"
+	"SamWaf/model/request"
+	response2 "SamWaf/model/response"
+	"SamWaf/model/spec"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"regexp"
+	"strings"
+)
+
+type WafLoginProtectApi struct {
+}
+
+func (w *WafLoginProtectApi) AddApi(c *gin.Context) {
+	var req request.WafLoginProtectAddReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		if msg := w.checkReq(req.Action, req.FailBodyPattern); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
+		err = wafLoginProtectService.CheckIsExistApi(req)
+		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+			err = wafLoginProtectService.AddApi(req)
+			if err == nil {
+				w.NotifyWaf(req.HostCode)
+				response.OkWithMessage("添加成功", c)
+			} else {
+
+				response.FailWithMessage("添加失败", c)
+			}
+			return
+		} else {
+			response.FailWithMessage("当前网站的登录地址已经存在", c)
+			return
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafLoginProtectApi) GetDetailApi(c *gin.Context) {
+	var req request.WafLoginProtectDetailReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		bean := wafLoginProtectService.GetDetailApi(req)
+		response.OkWithDetailed(bean, "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafLoginProtectApi) GetListApi(c *gin.Context) {
+	var req request.WafLoginProtectSearchReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		beans, total, _ := wafLoginProtectService.GetListApi(req)
+		response.OkWithDetailed(response.PageResult{
+			List:      beans,
+			Total:     total,
+			PageIndex: req.PageIndex,
+			PageSize:  req.PageSize,
+		}, "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafLoginProtectApi) DelLoginProtectApi(c *gin.Context) {
+	var req request.WafLoginProtectDelReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		bean := wafLoginProtectService.GetDetailByIdApi(req.Id)
+		err = wafLoginProtectService.DelApi(req)
+		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailWithMessage("请检测参数", c)
+		} else if err != nil {
+			response.FailWithMessage("发生错误", c)
+		} else {
+			w.NotifyWaf(bean.HostCode)
+			response.OkWithMessage("删除成功", c)
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+func (w *WafLoginProtectApi) ModifyLoginProtectApi(c *gin.Context) {
+	var req request.WafLoginProtectEditReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		if msg := w.checkReq(req.Action, req.FailBodyPattern); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
+		err = wafLoginProtectService.ModifyApi(req)
+		if err != nil {
+			response.FailWithMessage("编辑发生错误", c)
+		} else {
+			w.NotifyWaf(req.HostCode)
+			response.OkWithMessage("编辑成功", c)
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+// GetLockListApi 获取当前被锁定的登录(IP、用户名、IP+用户名)
+func (w *WafLoginProtectApi) GetLockListApi(c *gin.Context) {
+	lockList := global.GCACHE_WAFCACHE.ListAvailableKeysWithPrefix(enums.CACHE_LOGIN_LOCK_PRE)
+	beans := make([]response2.LoginLockRep, 0, len(lockList))
+	for lockKey, duration := range lockList {
+		beans = append(beans, response2.LoginLockRep{
+			LockKey:    strings.TrimPrefix(lockKey, enums.CACHE_LOGIN_LOCK_PRE),
+			RemainTime: fmt.Sprintf("%02d时%02d分", int(duration.Hours()), int(duration.Minutes())%60),
+		})
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:      beans,
+		Total:     int64(len(beans)),
+		PageIndex: 1,
+		PageSize:  999999,
+	}, "获取成功", c)
+}
+
+// RemoveLockApi 移除登录锁定
+func (w *WafLoginProtectApi) RemoveLockApi(c *gin.Context) {
+	var req request.WafLoginProtectRemoveLockReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		cacheKey := enums.CACHE_LOGIN_LOCK_PRE + req.LockKey
+		if global.GCACHE_WAFCACHE.IsKeyExist(cacheKey) {
+			global.GCACHE_WAFCACHE.Remove(cacheKey)
+			response.OkWithMessage(req.LockKey+" 移除成功", c)
+		} else {
+			response.FailWithMessage("键值未找到或以过期", c)
+		}
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+// checkReq 校验处置方式和失败正则
+func (w *WafLoginProtectApi) checkReq(action string, failBodyPattern string) string {
+	if action != enums.LOGIN_PROTECT_ACTION_THROTTLE && action != enums.LOGIN_PROTECT_ACTION_CHALLENGE &&
+		action != enums.LOGIN_PROTECT_ACTION_BLOCK {
+		return "处置方式不正确"
+	}
+	if failBodyPattern != "" {
+		if _, err := regexp.Compile(failBodyPattern); err != nil {
+			return "失败返回内容正则不正确"
+		}
+	}
+	return ""
+}
+
+/*
+*
+通知到waf引擎实时生效
+*/
+func (w *WafLoginProtectApi) NotifyWaf(host_code string) {
+	var loginProtects []model.LoginProtect
+	global.GWAF_LOCAL_DB.Where("host_code = ? ", host_code).Find(&loginProtects)
+	var chanInfo = spec.ChanCommonHost{
+		HostCode: host_code,
+		Type:     enums.ChanTypeLoginProtect,
+		Content:  loginProtects,
+	}
+	global.GWAF_CHAN_MSG <- chanInfo
+}
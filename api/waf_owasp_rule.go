@@ -0,0 +1,166 @@
+package api
+
+import (
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/common/response"
+	"SamWaf/model/request"
+	"SamWaf/model/spec"
+	"SamWaf/utils"
+	"SamWaf/wafowasp"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strings"
+)
+
+type WafOwaspRuleApi struct {
+}
+
+func (w *WafOwaspRuleApi) AddApi(c *gin.Context) {
+	var req request.WafOwaspRuleAddReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		if msg := w.checkReq("", req.HostCode, req.Content, req.Position, req.Status); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
+		err = wafOwaspRuleService.CheckIsExistApi(req)
+		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+			err = wafOwaspRuleService.AddApi(req)
+			if err == nil {
+				w.NotifyWaf(req.HostCode)
+				response.OkWithMessage("添加成功", c)
+			} else {
+
+				response.FailWithMessage("添加失败", c)
+			}
+			return
+		} else {
+			response.FailWithMessage("当前规则名称已经存在", c)
+			return
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafOwaspRuleApi) GetDetailApi(c *gin.Context) {
+	var req request.WafOwaspRuleDetailReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		bean := wafOwaspRuleService.GetDetailApi(req)
+		response.OkWithDetailed(bean, "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafOwaspRuleApi) GetListApi(c *gin.Context) {
+	var req request.WafOwaspRuleSearchReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		beans, total, _ := wafOwaspRuleService.GetListApi(req)
+		response.OkWithDetailed(response.PageResult{
+			List:      beans,
+			Total:     total,
+			PageIndex: req.PageIndex,
+			PageSize:  req.PageSize,
+		}, "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafOwaspRuleApi) DelOwaspRuleApi(c *gin.Context) {
+	var req request.WafOwaspRuleDelReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		bean := wafOwaspRuleService.GetDetailByIdApi(req.Id)
+		err = wafOwaspRuleService.DelApi(req)
+		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailWithMessage("请检测参数", c)
+		} else if err != nil {
+			response.FailWithMessage("发生错误", c)
+		} else {
+			w.NotifyWaf(bean.HostCode)
+			response.OkWithMessage("删除成功", c)
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+func (w *WafOwaspRuleApi) ModifyOwaspRuleApi(c *gin.Context) {
+	var req request.WafOwaspRuleEditReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		if msg := w.checkReq(req.Id, req.HostCode, req.Content, req.Position, req.Status); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
+		bean := wafOwaspRuleService.GetDetailByIdApi(req.Id)
+		err = wafOwaspRuleService.ModifyApi(req)
+		if err != nil {
+			response.FailWithMessage("编辑发生错误", c)
+		} else {
+			w.NotifyWaf(req.HostCode)
+			//规则换了网站时原网站也需要更新
+			if bean.HostCode != "" && bean.HostCode != req.HostCode {
+				w.NotifyWaf(bean.HostCode)
+			}
+			response.OkWithMessage("编辑成功", c)
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+/*
+*
+校验规则 和同一作用域内已启用的规则一起用Coraza编译，编译失败时不允许保存
+*/
+func (w *WafOwaspRuleApi) checkReq(id string, hostCode string, content string, position int, status int) string {
+	if strings.TrimSpace(content) == "" {
+		return "规则内容不能为空"
+	}
+	if position != enums.OWASP_RULE_POSITION_AFTER && position != enums.OWASP_RULE_POSITION_BEFORE {
+		return "加载位置不正确"
+	}
+	if status != 0 && status != 1 {
+		return "状态不正确"
+	}
+	var scopeRules []model.OwaspRule
+	hostCodes := []string{hostCode}
+	if hostCode != global.GWAF_GLOBAL_HOST_CODE {
+		hostCodes = []string{global.GWAF_GLOBAL_HOST_CODE, hostCode}
+	}
+	for _, code := range hostCodes {
+		for _, rule := range wafOwaspRuleService.GetListByHostCodeApi(code) {
+			if rule.Status == 1 && rule.Id != id {
+				scopeRules = append(scopeRules, rule)
+			}
+		}
+	}
+	scopeRules = append(scopeRules, model.OwaspRule{Content: content, Position: position, Status: status})
+	if err := wafowasp.ValidateRules(utils.GetCurrentDir(), scopeRules); err != nil {
+		return "规则校验失败:" + err.Error()
+	}
+	return ""
+}
+
+/*
+*
+通知到waf引擎实时生效
+*/
+func (w *WafOwaspRuleApi) NotifyWaf(host_code string) {
+	var owaspRules []model.OwaspRule
+	global.GWAF_LOCAL_DB.Where("host_code = ? ", host_code).Order("create_time asc").Find(&owaspRules)
+	var chanInfo = spec.ChanCommonHost{
+		HostCode: host_code,
+		Type:     enums.ChanTypeOwaspRule,
+		Content:  owaspRules,
+	}
+	global.GWAF_CHAN_MSG <- chanInfo
+}
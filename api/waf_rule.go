@@ -6,6 +6,7 @@ import (
 	"SamWaf/model"
 	"SamWaf/model/common/response"
 	"SamWaf/model/request"
+	response2 "SamWaf/model/response"
 	"SamWaf/model/spec"
 	"SamWaf/utils"
 	"errors"
@@ -56,6 +57,16 @@ func (w *WafRuleAPi) AddApi(c *gin.Context) {
 				return
 			}
 		}
+		//执行测试用例
+		testResults, err := utils.RunRuleTestCases(model.Rules{RuleCode: ruleCode, RuleName: chsName, RuleContent: ruleContent, RuleTestCases: req.RuleTestCases}, ruleContent)
+		if err != nil {
+			response.FailWithMessage("规则测试失败:"+err.Error(), c)
+			return
+		}
+		if !utils.IsRuleTestPassed(testResults) {
+			response.FailWithDetailed(testResults, "规则测试未通过", c)
+			return
+		}
 
 		err = wafRuleService.AddApi(req, ruleCode, chsName, ruleInfo.RuleBase.RuleDomainCode, ruleContent)
 		if err == nil {
@@ -87,11 +98,16 @@ func (w *WafRuleAPi) GetListApi(c *gin.Context) {
 	err := c.ShouldBindJSON(&req)
 	if err == nil {
 		wafRules, total, _ := wafRuleService.GetListApi(req)
-		response.OkWithDetailed(response.PageResult{
+		codes := make([]string, 0, len(wafRules))
+		for _, bean := range wafRules {
+			codes = append(codes, bean.RuleCode)
+		}
+		response.OkWithDetailed(response2.WafRuleHitPageResult{
 			List:      wafRules,
 			Total:     total,
 			PageIndex: req.PageIndex,
 			PageSize:  req.PageSize,
+			HitStats:  wafStatService.StatRuleHitApi(enums.RULE_STATS_TYPE_RULE, codes),
 		}, "获取成功", c)
 	} else {
 		response.FailWithMessage("解析失败", c)
@@ -126,6 +142,10 @@ func (w *WafRuleAPi) DelRuleApi(c *gin.Context) {
 		} else if err != nil {
 			response.FailWithMessage("发生错误", c)
 		} else {
+			//模板生成的规则删除后即取消该网站的挂载
+			if wafRule.TemplateId != "" {
+				_ = wafRuleTemplateService.DelBindByRuleCodeApi(wafRule.RuleCode)
+			}
 			w.NotifyWaf(wafRule.HostCode)
 			response.OkWithMessage("删除成功", c)
 		}
@@ -147,6 +167,10 @@ func (w *WafRuleAPi) ModifyRuleApi(c *gin.Context) {
 			return
 		}
 		rule := wafRuleService.GetDetailByCodeApi(req.CODE)
+		if rule.TemplateId != "" {
+			response.FailWithMessage("该规则由规则模板生成，请在规则模板中修改", c)
+			return
+		}
 		var ruleName = ruleInfo.RuleBase.RuleName //中文名
 		ruleInfo.RuleBase.RuleName = strings.Replace(rule.RuleCode, "-", "", -1)
 		var ruleContent = ruleTool.GenRuleInfo(ruleInfo, ruleName)
@@ -159,6 +183,16 @@ func (w *WafRuleAPi) ModifyRuleApi(c *gin.Context) {
 				return
 			}
 		}
+		//执行测试用例
+		testResults, err := utils.RunRuleTestCases(model.Rules{RuleCode: rule.RuleCode, RuleName: ruleName, RuleContent: ruleContent, RuleTestCases: req.RuleTestCases}, ruleContent)
+		if err != nil {
+			response.FailWithMessage("规则测试失败:"+err.Error(), c)
+			return
+		}
+		if !utils.IsRuleTestPassed(testResults) {
+			response.FailWithDetailed(testResults, "规则测试未通过", c)
+			return
+		}
 
 		err = wafRuleService.ModifyApi(req, ruleName, ruleInfo.RuleBase.RuleDomainCode, ruleContent)
 		if err != nil {
@@ -173,6 +207,47 @@ func (w *WafRuleAPi) ModifyRuleApi(c *gin.Context) {
 	}
 }
 
+// TestRuleApi 执行规则的测试用例 和网站的其他规则一起匹配，返回每个样例命中的规则
+func (w *WafRuleAPi) TestRuleApi(c *gin.Context) {
+	var req request.WafRuleTestReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		hostCode := req.HostCode
+		if req.CODE != "" {
+			rule := wafRuleService.GetDetailByCodeApi(req.CODE)
+			if rule.RuleCode == "" {
+				response.FailWithMessage("规则不存在", c)
+				return
+			}
+			hostCode = rule.HostCode
+		}
+		rules := wafRuleService.GetEffectiveListByHostCodeApi(hostCode)
+		ruleText := ""
+		for _, rule := range rules {
+			ruleText = ruleText + rule.RuleContent + " \n"
+		}
+		testResults := []response2.RuleTestRep{}
+		for _, rule := range rules {
+			if req.CODE != "" && rule.RuleCode != req.CODE {
+				continue
+			}
+			ruleResults, err := utils.RunRuleTestCases(rule, ruleText)
+			if err != nil {
+				testResults = append(testResults, response2.RuleTestRep{
+					RuleCode: rule.RuleCode,
+					RuleName: rule.RuleName,
+					Error:    err.Error(),
+				})
+				continue
+			}
+			testResults = append(testResults, ruleResults...)
+		}
+		response.OkWithDetailed(testResults, "测试完成", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
 /*
 *
 通知到waf引擎实时生效
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"SamWaf/model"
+	"SamWaf/model/common/response"
+	"SamWaf/model/request"
+	"SamWaf/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"strings"
+)
+
+type WafRuleTemplateApi struct {
+}
+
+func (w *WafRuleTemplateApi) AddApi(c *gin.Context) {
+	var req request.WafRuleTemplateAddReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		if msg := w.checkReq(model.RuleTemplate{TemplateName: req.TemplateName, RuleContent: req.RuleContent, Params: req.Params}); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
+		err = wafRuleTemplateService.CheckIsExistApi(req)
+		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+			err = wafRuleTemplateService.AddApi(req)
+			if err == nil {
+				response.OkWithMessage("添加成功", c)
+			} else {
+
+				response.FailWithMessage("添加失败", c)
+			}
+			return
+		} else {
+			response.FailWithMessage("当前模板名称已经存在", c)
+			return
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafRuleTemplateApi) GetDetailApi(c *gin.Context) {
+	var req request.WafRuleTemplateDetailReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		bean := wafRuleTemplateService.GetDetailApi(req)
+		response.OkWithDetailed(bean, "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafRuleTemplateApi) GetListApi(c *gin.Context) {
+	var req request.WafRuleTemplateSearchReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		beans, total, _ := wafRuleTemplateService.GetListApi(req)
+		response.OkWithDetailed(response.PageResult{
+			List:      beans,
+			Total:     total,
+			PageIndex: req.PageIndex,
+			PageSize:  req.PageSize,
+		}, "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+// DelRuleTemplateApi 删除模板 已挂载的网站一并取消挂载
+func (w *WafRuleTemplateApi) DelRuleTemplateApi(c *gin.Context) {
+	var req request.WafRuleTemplateDelReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		binds := wafRuleTemplateService.GetBindListApi(req.Id)
+		err = wafRuleTemplateService.DelApi(req, binds)
+		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailWithMessage("请检测参数", c)
+		} else if err != nil {
+			response.FailWithMessage("发生错误", c)
+		} else {
+			for _, bind := range binds {
+				APIGroupAPP.WafRuleAPi.NotifyWaf(bind.HostCode)
+			}
+			response.OkWithMessage("删除成功", c)
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+// ModifyRuleTemplateApi 编辑模板 重新生成所有已挂载网站的规则并通知引擎重新加载
+func (w *WafRuleTemplateApi) ModifyRuleTemplateApi(c *gin.Context) {
+	var req request.WafRuleTemplateEditReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		template := model.RuleTemplate{TemplateName: req.TemplateName, RuleContent: req.RuleContent, Params: req.Params}
+		if msg := w.checkReq(template); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
+		template.Id = req.Id
+		binds := wafRuleTemplateService.GetBindListApi(req.Id)
+		ruleContents := make([]string, len(binds))
+		for i, bind := range binds {
+			ruleContent, msg := w.renderBind(template, bind)
+			if msg != "" {
+				response.FailWithMessage("网站"+bind.HostCode+" "+msg, c)
+				return
+			}
+			ruleContents[i] = ruleContent
+		}
+		err = wafRuleTemplateService.ModifyApi(req, template, binds, ruleContents)
+		if err != nil {
+			response.FailWithMessage("编辑发生错误:"+err.Error(), c)
+			return
+		}
+		for _, bind := range binds {
+			APIGroupAPP.WafRuleAPi.NotifyWaf(bind.HostCode)
+		}
+		response.OkWithMessage("编辑成功", c)
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+// AttachApi 挂载模板到网站 已挂载的网站更新参数值
+func (w *WafRuleTemplateApi) AttachApi(c *gin.Context) {
+	var req request.WafRuleTemplateAttachReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		template := wafRuleTemplateService.GetDetailByIdApi(req.Id)
+		if template.Id == "" {
+			response.FailWithMessage("模板不存在", c)
+			return
+		}
+		if len(req.HostCodes) == 0 {
+			response.FailWithMessage("请选择网站", c)
+			return
+		}
+		for _, hostCode := range req.HostCodes {
+			if wafHostService.GetDetailByCodeApi(hostCode).Code == "" {
+				response.FailWithMessage("网站"+hostCode+"不存在", c)
+				return
+			}
+		}
+		binds := make([]model.RuleTemplateBind, len(req.HostCodes))
+		ruleContents := make([]string, len(req.HostCodes))
+		for i, hostCode := range req.HostCodes {
+			bind := wafRuleTemplateService.GetBindApi(template.Id, hostCode)
+			if bind.Id == "" {
+				if wafRuleService.CheckIsExistApi(template.TemplateName, hostCode) > 0 {
+					response.FailWithMessage("网站"+hostCode+"已存在同名规则", c)
+					return
+				}
+				bind.HostCode = hostCode
+				bind.RuleCode = uuid.NewV4().String()
+			}
+			bind.ParamValues = req.ParamValues
+			ruleContent, msg := w.renderBind(template, bind)
+			if msg != "" {
+				response.FailWithMessage("网站"+hostCode+" "+msg, c)
+				return
+			}
+			binds[i] = bind
+			ruleContents[i] = ruleContent
+		}
+		if err = wafRuleTemplateService.SaveBindRulesApi(template, binds, ruleContents); err != nil {
+			response.FailWithMessage("挂载发生错误:"+err.Error(), c)
+			return
+		}
+		for _, bind := range binds {
+			APIGroupAPP.WafRuleAPi.NotifyWaf(bind.HostCode)
+		}
+		response.OkWithMessage("挂载成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+// DetachApi 取消模板在网站上的挂载
+func (w *WafRuleTemplateApi) DetachApi(c *gin.Context) {
+	var req request.WafRuleTemplateDetachReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		bind := wafRuleTemplateService.GetBindApi(req.Id, req.HostCode)
+		if bind.Id == "" {
+			response.FailWithMessage("当前网站未挂载该模板", c)
+			return
+		}
+		err = wafRuleTemplateService.DelBindApi(bind)
+		if err != nil {
+			response.FailWithMessage("发生错误", c)
+		} else {
+			APIGroupAPP.WafRuleAPi.NotifyWaf(bind.HostCode)
+			response.OkWithMessage("取消挂载成功", c)
+		}
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+// GetBindListApi 获取模板挂载的网站
+func (w *WafRuleTemplateApi) GetBindListApi(c *gin.Context) {
+	var req request.WafRuleTemplateBindListReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		response.OkWithDetailed(wafRuleTemplateService.GetBindListApi(req.Id), "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+/*
+*
+校验模板 使用参数默认值生成规则并检查是否合法
+*/
+func (w *WafRuleTemplateApi) checkReq(template model.RuleTemplate) string {
+	if strings.TrimSpace(template.TemplateName) == "" {
+		return "模板名称不能为空"
+	}
+	if !strings.Contains(template.RuleContent, "${rule_name}") {
+		return "规则名请使用${rule_name}，以免和网站的其他规则重名"
+	}
+	_, msg := w.renderBind(template, model.RuleTemplateBind{RuleCode: uuid.NewV4().String()})
+	return msg
+}
+
+// 生成挂载网站的规则内容并检查是否合法
+func (w *WafRuleTemplateApi) renderBind(template model.RuleTemplate, bind model.RuleTemplateBind) (string, string) {
+	ruleContent, err := template.Render(bind.RuleCode, bind.ParamValues)
+	if err != nil {
+		return "", err.Error()
+	}
+	ruleHelper := &utils.RuleHelper{}
+	if err = ruleHelper.CheckRuleAvailable(ruleContent); err != nil {
+		return "", "规则校验失败:" + err.Error()
+	}
+	return ruleContent, ""
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"SamWaf/enums"
+	"SamWaf/model/common/response"
+	"SamWaf/model/request"
+	"SamWaf/waftask"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+)
+
+type WafThreatFeedApi struct {
+}
+
+func (w *WafThreatFeedApi) AddApi(c *gin.Context) {
+	var req request.WafThreatFeedAddReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		if msg := w.checkReq(req.FeedName, req.SourceType, req.Source, req.Format, req.FormatArg, req.Action, req.RefreshInterval, req.ExpireMinutes); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
+		err = wafThreatFeedService.CheckIsExistApi(req)
+		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+			bean, err := wafThreatFeedService.AddApi(req)
+			if err == nil {
+				//启用的订阅添加后立即拉取一次
+				if bean.Status == 1 {
+					go waftask.RefreshThreatFeed(bean)
+				}
+				response.OkWithMessage("添加成功", c)
+			} else {
+
+				response.FailWithMessage("添加失败", c)
+			}
+			return
+		} else {
+			response.FailWithMessage("当前网站已经存在同名订阅", c)
+			return
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafThreatFeedApi) GetDetailApi(c *gin.Context) {
+	var req request.WafThreatFeedDetailReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		bean := wafThreatFeedService.GetDetailApi(req)
+		response.OkWithDetailed(bean, "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafThreatFeedApi) GetListApi(c *gin.Context) {
+	var req request.WafThreatFeedSearchReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		beans, total, _ := wafThreatFeedService.GetListApi(req)
+		response.OkWithDetailed(response.PageResult{
+			List:      beans,
+			Total:     total,
+			PageIndex: req.PageIndex,
+			PageSize:  req.PageSize,
+		}, "获取成功", c)
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+func (w *WafThreatFeedApi) DelThreatFeedApi(c *gin.Context) {
+	var req request.WafThreatFeedDelReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		bean := wafThreatFeedService.GetDetailByIdApi(req.Id)
+		err = wafThreatFeedService.DelApi(req)
+		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+			response.FailWithMessage("请检测参数", c)
+		} else if err != nil {
+			response.FailWithMessage("发生错误", c)
+		} else {
+			w.NotifyWaf(bean.HostCode)
+			response.OkWithMessage("删除成功", c)
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+func (w *WafThreatFeedApi) ModifyThreatFeedApi(c *gin.Context) {
+	var req request.WafThreatFeedEditReq
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		if msg := w.checkReq(req.FeedName, req.SourceType, req.Source, req.Format, req.FormatArg, req.Action, req.RefreshInterval, req.ExpireMinutes); msg != "" {
+			response.FailWithMessage(msg, c)
+			return
+		}
+		bean := wafThreatFeedService.GetDetailByIdApi(req.Id)
+		err = wafThreatFeedService.ModifyApi(req)
+		if err != nil {
+			response.FailWithMessage("编辑发生错误:"+err.Error(), c)
+		} else {
+			w.NotifyWaf(req.HostCode)
+			if bean.HostCode != "" && bean.HostCode != req.HostCode {
+				w.NotifyWaf(bean.HostCode)
+			}
+			response.OkWithMessage("编辑成功", c)
+		}
+
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+// RefreshThreatFeedApi 立即刷新订阅
+func (w *WafThreatFeedApi) RefreshThreatFeedApi(c *gin.Context) {
+	var req request.WafThreatFeedRefreshReq
+	err := c.ShouldBind(&req)
+	if err == nil {
+		bean := wafThreatFeedService.GetDetailByIdApi(req.Id)
+		if bean.Id == "" {
+			response.FailWithMessage("订阅不存在", c)
+			return
+		}
+		err = waftask.RefreshThreatFeed(bean)
+		if err != nil {
+			response.FailWithMessage("刷新失败:"+err.Error(), c)
+		} else {
+			response.OkWithDetailed(wafThreatFeedService.GetDetailByIdApi(req.Id), "刷新成功", c)
+		}
+	} else {
+		response.FailWithMessage("解析失败", c)
+	}
+}
+
+// 校验订阅配置
+func (w *WafThreatFeedApi) checkReq(feedName string, sourceType string, source string, format string, formatArg string, action string, refreshInterval int, expireMinutes int) string {
+	if strings.TrimSpace(feedName) == "" {
+		return "订阅名称不能为空"
+	}
+	if sourceType != enums.THREAT_FEED_SOURCE_LOCAL && sourceType != enums.THREAT_FEED_SOURCE_REMOTE {
+		return "来源类型不正确"
+	}
+	if strings.TrimSpace(source) == "" {
+		return "来源不能为空"
+	}
+	if sourceType == enums.THREAT_FEED_SOURCE_REMOTE && !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return "远程地址需以http://或https://开头"
+	}
+	switch format {
+	case enums.THREAT_FEED_FORMAT_PLAIN, enums.THREAT_FEED_FORMAT_CIDR, enums.THREAT_FEED_FORMAT_JSON,
+		enums.THREAT_FEED_FORMAT_SPAMHAUS, enums.THREAT_FEED_FORMAT_TOR:
+	case enums.THREAT_FEED_FORMAT_CSV:
+		if column, err := strconv.Atoi(strings.TrimSpace(formatArg)); err != nil || column < 1 {
+			return "CSV格式请填写列序号(从1开始)"
+		}
+	default:
+		return "格式不正确"
+	}
+	if action != enums.THREAT_FEED_ACTION_BLOCK && action != enums.THREAT_FEED_ACTION_CHALLENGE && action != enums.THREAT_FEED_ACTION_TAG {
+		return "处置方式不正确"
+	}
+	if refreshInterval < 0 || expireMinutes < 0 {
+		return "刷新间隔和有效期不能小于0"
+	}
+	return ""
+}
+
+/*
+*
+通知到waf引擎实时生效
+*/
+func (w *WafThreatFeedApi) NotifyWaf(host_code string) {
+	waftask.NotifyThreatFeed(host_code)
+}
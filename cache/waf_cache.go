@@ -46,6 +46,33 @@ func (wafCache *WafCache) SetWithTTl(key string, value interface{}, ttl time.Dur
 		ttl:        ttl,
 	}
 }
+
+/*
+*
+计数加一并返回加一后的值 不存在或已过期时从1开始计数并使用新的ttl，存在时保持原来的过期时间
+整个过程在锁内完成，并发计数不会丢失
+*/
+func (wafCache *WafCache) IncrWithTTl(key string, ttl time.Duration) int {
+	wafCache.mu.Lock()
+	defer wafCache.mu.Unlock()
+	now := time.Now()
+	item, found := wafCache.cache[key]
+	if found && now.Sub(item.createTime) <= item.ttl {
+		if counter, ok := item.value.(int); ok {
+			item.value = counter + 1
+			item.lastTime = now
+			wafCache.cache[key] = item
+			return counter + 1
+		}
+	}
+	wafCache.cache[key] = WafCacheItem{
+		value:      1,
+		createTime: now,
+		lastTime:   now,
+		ttl:        ttl,
+	}
+	return 1
+}
 func (wafCache *WafCache) GetString(key string) (string, error) {
 	key1Value := wafCache.Get(key)
 	if str, ok := key1Value.(string); ok {
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"sync"
 	"testing"
 	"time"
 )
@@ -42,3 +43,24 @@ func TestWafCache_IsKeyExist(t *testing.T) {
 		println("不存在")
 	}
 }
+
+func TestWafCache_IncrWithTTl(t *testing.T) {
+	wafcache := InitWafCache()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wafcache.IncrWithTTl("COUNTER", 5*time.Second)
+		}()
+	}
+	wg.Wait()
+	if counter, err := wafcache.GetInt("COUNTER"); err != nil || counter != 100 {
+		t.Errorf("counter got %d %v, want 100", counter, err)
+	}
+	//过期后重新计数
+	wafcache.SetWithTTl("EXPIRED", 10, -1)
+	if counter := wafcache.IncrWithTTl("EXPIRED", 5*time.Second); counter != 1 {
+		t.Errorf("expired counter got %d, want 1", counter)
+	}
+}
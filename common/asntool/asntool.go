@@ -0,0 +1,39 @@
+package asntool
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+*
+解析ASN 支持 AS13335 和 13335 两种写法
+不是ASN时返回false
+*/
+func ParseASN(value string) (int64, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) > 2 && strings.EqualFold(value[:2], "AS") {
+		value = value[2:]
+	}
+	asn, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || asn <= 0 {
+		return 0, false
+	}
+	return asn, true
+}
+
+/*
+*
+判断ASN是否在列表中 多个ASN用逗号隔开，如 AS13335,16509
+*/
+func InASNList(asn int64, list string) bool {
+	if asn <= 0 {
+		return false
+	}
+	for _, item := range strings.Split(list, ",") {
+		if itemAsn, ok := ParseASN(item); ok && itemAsn == asn {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package asntool
+
+import "testing"
+
+func TestParseASN(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		ok    bool
+	}{
+		{"AS13335", 13335, true},
+		{" as16509 ", 16509, true},
+		{"13335", 13335, true},
+		{"AS", 0, false},
+		{"AS0", 0, false},
+		{"AS-1", 0, false},
+		{"ASabc", 0, false},
+		{"1.1.1.1", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseASN(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseASN(%q) = %d %v, want %d %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestInASNList(t *testing.T) {
+	if !InASNList(13335, "AS16509, as13335") || InASNList(13335, "AS16509,abc") || InASNList(0, "0,AS0") {
+		t.Errorf("InASNList not correct")
+	}
+}
@@ -4,4 +4,13 @@ const (
 	CACHE_LOGIN_ERROR    = "CACHE_LOGIN_ERROR"     //登录密码错误
 	CACHE_NOTICE_PRE     = "CACHE_NOTICE_PRE"      //通知前缀
 	CACHE_CCVISITBAN_PRE = "CACHE_CCVISITBAN_PRE_" //CC封禁前缀
+	CACHE_SCAN_ERROR_PRE = "CACHE_SCAN_ERROR_PRE_" //扫描404/403计数前缀
+	CACHE_SCAN_HIT_PRE   = "CACHE_SCAN_HIT_PRE_"   //扫描特征命中计数前缀
+	CACHE_SCANBAN_PRE    = "CACHE_SCANBAN_PRE_"    //扫描封禁前缀
+
+	CACHE_LOGIN_FAIL_IP_PRE      = "CACHE_LOGIN_FAIL_IP_PRE_"      //登录防护 IP失败计数前缀
+	CACHE_LOGIN_FAIL_USER_PRE    = "CACHE_LOGIN_FAIL_USER_PRE_"    //登录防护 用户名失败计数前缀
+	CACHE_LOGIN_FAIL_IP_USER_PRE = "CACHE_LOGIN_FAIL_IP_USER_PRE_" //登录防护 IP+用户名失败计数前缀
+	CACHE_LOGIN_IP_USERS_PRE     = "CACHE_LOGIN_IP_USERS_PRE_"     //登录防护 IP尝试的用户名前缀
+	CACHE_LOGIN_LOCK_PRE         = "CACHE_LOGIN_LOCK_PRE_"         //登录防护 锁定前缀
 )
@@ -13,4 +13,8 @@ const (
 	ChanTypeSensitive
 	ChanTypeLoadBalance
 	ChanTypeSSL
+	ChanTypeLoginProtect
+	ChanTypeOwaspRule
+	ChanTypeGeoPolicy
+	ChanTypeThreatFeed
 )
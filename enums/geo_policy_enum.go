@@ -0,0 +1,13 @@
+package enums
+
+// 地域限制方式
+const (
+	GEO_POLICY_MODE_ALLOW = "allow" //只允许列表中的地区
+	GEO_POLICY_MODE_DENY  = "deny"  //禁止列表中的地区
+)
+
+// 地域限制处置方式
+const (
+	GEO_POLICY_ACTION_BLOCK     = "block"     //阻止
+	GEO_POLICY_ACTION_CHALLENGE = "challenge" //挑战
+)
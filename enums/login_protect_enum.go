@@ -0,0 +1,8 @@
+package enums
+
+// 登录防护处置方式
+const (
+	LOGIN_PROTECT_ACTION_THROTTLE  = "throttle"  //限流
+	LOGIN_PROTECT_ACTION_CHALLENGE = "challenge" //挑战
+	LOGIN_PROTECT_ACTION_BLOCK     = "block"     //阻止
+)
@@ -0,0 +1,7 @@
+package enums
+
+// 自定义SecLang规则加载位置
+const (
+	OWASP_RULE_POSITION_AFTER  = 0 //CRS规则之后
+	OWASP_RULE_POSITION_BEFORE = 1 //CRS规则之前
+)
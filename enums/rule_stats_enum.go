@@ -0,0 +1,11 @@
+package enums
+
+// 命中统计的对象类型
+const (
+	RULE_STATS_TYPE_RULE      = "rule"      //规则
+	RULE_STATS_TYPE_IP_ALLOW  = "ip_allow"  //IP白名单
+	RULE_STATS_TYPE_URL_ALLOW = "url_allow" //URL白名单
+	RULE_STATS_TYPE_IP_BLOCK  = "ip_block"  //IP黑名单
+	RULE_STATS_TYPE_URL_BLOCK = "url_block" //URL黑名单
+	RULE_STATS_TYPE_URL_LDP   = "url_ldp"   //隐私保护URL
+)
@@ -0,0 +1,15 @@
+package enums
+
+// 敏感词检测方向
+const (
+	SENSITIVE_DIRECTION_REQUEST  = 0 //检测请求
+	SENSITIVE_DIRECTION_RESPONSE = 1 //检测响应
+	SENSITIVE_DIRECTION_BOTH     = 2 //请求和响应都检测
+)
+
+// 敏感词命中后的处理方式
+const (
+	SENSITIVE_ACTION_BLOCK   = 0 //阻止
+	SENSITIVE_ACTION_REPLACE = 1 //替换成*号
+	SENSITIVE_ACTION_LOG     = 2 //仅记录
+)
@@ -2,6 +2,14 @@ package enums
 
 const (
 	BATCHTASK_IPALLOW                = "ipallow"
+	BATCHTASK_IPBLOCK                = "ipblock"
+	BATCHTASK_URLALLOW               = "urlallow"
+	BATCHTASK_URLBLOCK               = "urlblock"
+	BATCHTASK_LDPURL                 = "ldpurl"
+	BATCHTASK_SENSITIVE              = "sensitive"
+	BATCHTASK_RULE                   = "rule"
 	BATCHTASK_EXECUTEMETHODAPPEND    = "append"
 	BATCHTASK_EXECUTEMETHODOVERWRITE = "overwrite"
+	BATCHTASK_TRIGGER_CRON           = "cron"   //定时执行
+	BATCHTASK_TRIGGER_MANUAL         = "manual" //手工执行
 )
@@ -0,0 +1,24 @@
+package enums
+
+// 威胁情报来源类型
+const (
+	THREAT_FEED_SOURCE_LOCAL  = "local"  //本地文件
+	THREAT_FEED_SOURCE_REMOTE = "remote" //远程地址
+)
+
+// 威胁情报格式
+const (
+	THREAT_FEED_FORMAT_PLAIN    = "plain"         //纯文本 每行一个IP
+	THREAT_FEED_FORMAT_CIDR     = "cidr"          //每行一个网段
+	THREAT_FEED_FORMAT_CSV      = "csv"           //CSV 取指定列
+	THREAT_FEED_FORMAT_JSON     = "json"          //JSON 取指定路径
+	THREAT_FEED_FORMAT_SPAMHAUS = "spamhaus_drop" //Spamhaus DROP
+	THREAT_FEED_FORMAT_TOR      = "tor_exit"      //Tor出口节点列表
+)
+
+// 威胁情报命中后的处置方式
+const (
+	THREAT_FEED_ACTION_BLOCK     = "block"     //阻止
+	THREAT_FEED_ACTION_CHALLENGE = "challenge" //挑战
+	THREAT_FEED_ACTION_TAG       = "tag"       //仅在日志中标记
+)
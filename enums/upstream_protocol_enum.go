@@ -0,0 +1,8 @@
+package enums
+
+// 后端协议
+const (
+	UPSTREAM_PROTOCOL_HTTP1 = "http1" //HTTP/1.1 默认
+	UPSTREAM_PROTOCOL_H2    = "h2"    //HTTP/2 基于TLS
+	UPSTREAM_PROTOCOL_H2C   = "h2c"   //HTTP/2 明文
+)
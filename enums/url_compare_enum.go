@@ -0,0 +1,10 @@
+package enums
+
+// URL名单（白名单、黑名单、隐私保护）对比方式
+const (
+	URL_COMPARE_EQUAL    = "等于"
+	URL_COMPARE_PREFIX   = "前缀匹配"
+	URL_COMPARE_SUFFIX   = "后缀匹配"
+	URL_COMPARE_CONTAINS = "包含匹配"
+	URL_COMPARE_REGEX    = "正则匹配"
+)
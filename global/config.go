@@ -15,5 +15,12 @@ var (
 	GCONFIG_RECORD_LOGIN_MAX_ERROR_TIME int64 = 3 //登录周期里错误最大次数
 	GCONFIG_RECORD_LOGIN_LIMIT_MINTUTES int64 = 1 //登录错误记录周期 单位分钟最小1
 
-	GCONFIG_RECORD_ENABLE_OWASP int64 = 0 //启动OWASP数据检测
+	GCONFIG_RECORD_ENABLE_OWASP    int64 = 0 //启动OWASP数据检测
+	GCONFIG_RECORD_OWASP_RESPONSE  int64 = 0 //启动OWASP响应检测（数据泄露）
+	GCONFIG_RECORD_OWASP_AUDIT_LOG int64 = 0 //记录OWASP审计日志
+
+	GCONFIG_RECORD_SCAN_ERROR_WINDOW_SECONDS int64 = 60 //扫描检测404/403统计周期 单位秒
+	GCONFIG_RECORD_SCAN_ERROR_MAX_COUNT      int64 = 50 //扫描检测周期内404/403最大次数 0不检测
+	GCONFIG_RECORD_SCAN_LOCK_MINUTES         int64 = 10 //扫描IP自动封禁分钟数 0不封禁
+	GCONFIG_RECORD_SCAN_BAN_HIT_COUNT        int64 = 5  //扫描检测周期内命中扫描特征次数达到后封禁 0不封禁
 )
@@ -86,6 +86,8 @@ var (
 	GCACHE_IP_V6_COUNTRY_CBUFF []byte         // IPv6国家相关缓存
 	GCACHE_IPV4_SEARCHER       *xdb.Searcher  //IPV4得查询器
 	GCACHE_IPV6_SEARCHER       *geoip2.Reader // IPV6得查询器
+	GCACHE_IP_ASN_CBUFF        []byte         // ASN相关缓存 未放置ASN数据库时为空
+	GCACHE_ASN_SEARCHER        *geoip2.Reader // ASN得查询器
 
 	GDATA_DELETE_INTERVAL int64 = 180 // 删除180天前的数据
 
@@ -0,0 +1,384 @@
+package innerbean
+
+import (
+	"SamWaf/common/asntool"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 规则中使用的正则 编译后缓存
+var ruleRegexCache sync.Map
+
+// 规则处置方式
+const (
+	RULE_ACTION_ALLOW     = "allow"     //放行 只跳过规则之后的检测
+	RULE_ACTION_BLOCK     = "block"     //阻止
+	RULE_ACTION_CHALLENGE = "challenge" //挑战
+	RULE_ACTION_REDIRECT  = "redirect"  //跳转
+)
+
+// 规则的处置结果
+type RuleDecision struct {
+	Action      string //处置方式 为空表示没有规则做出处置
+	RuleName    string //做出处置的规则
+	StatusCode  int    //阻止时返回的状态码
+	Message     string //阻止时展示的内容
+	RedirectUrl string //跳转地址
+}
+
+/*
+*
+规则使用的对象 规则中以MF引用
+访问日志的字段可以直接使用（如 MF.URL），同时提供请求头、参数、计数等辅助函数
+*/
+type RuleFact struct {
+	*WebLog
+	request     *http.Request
+	header      http.Header
+	query       url.Values
+	cookies     map[string]string
+	jsonBody    interface{}
+	jsonParsed  bool
+	counter     func(key string, seconds int64) int64
+	botResolver func() (bool, bool, string)
+	botResolved bool
+	isBot       bool
+	isNormalBot bool
+	botName     string
+
+	firing          bool   //是否有规则在执行
+	firedRule       string //正在执行的规则
+	firedActed      bool   //正在执行的规则是否调用了动作
+	decision        RuleDecision
+	risk            int
+	tags            []string
+	upstreamHeaders map[string]string
+}
+
+/*
+*
+创建规则对象 r 为空时请求头、参数、cookie从访问日志中解析（规则测试用）
+*/
+func NewRuleFact(weblog *WebLog, r *http.Request) *RuleFact {
+	//规则使用日志的副本 旧规则then中的赋值不会修改实际记录的日志
+	weblogCopy := *weblog
+	return &RuleFact{WebLog: &weblogCopy, request: r, risk: -1}
+}
+
+// SetCounter 设置计数查询函数
+func (fact *RuleFact) SetCounter(counter func(key string, seconds int64) int64) {
+	fact.counter = counter
+}
+
+// SetBotResolver 设置爬虫识别函数 返回值同 wafbot.DetermineNormalSearch
+func (fact *RuleFact) SetBotResolver(resolver func() (bool, bool, string)) {
+	fact.botResolver = resolver
+}
+
+// CounterKeys 计数使用的键 依次为 IP、IP+路径
+func (fact *RuleFact) CounterKeys() []string {
+	ipKey := fact.HOST_CODE + "|" + fact.SRC_IP
+	return []string{ipKey, ipKey + "|" + fact.path()}
+}
+
+// Header 获取请求头
+func (fact *RuleFact) Header(name string) string {
+	if fact.request != nil {
+		return fact.request.Header.Get(name)
+	}
+	if fact.header == nil {
+		fact.header = http.Header{}
+		for _, line := range strings.Split(fact.HEADER, "\r\n") {
+			if key, value, ok := strings.Cut(line, ":"); ok {
+				fact.header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+			}
+		}
+	}
+	return fact.header.Get(name)
+}
+
+// Query 获取查询参数
+func (fact *RuleFact) Query(name string) string {
+	if fact.query == nil {
+		if fact.request != nil {
+			fact.query = fact.request.URL.Query()
+		} else if _, rawQuery, ok := strings.Cut(fact.URL, "?"); ok {
+			fact.query, _ = url.ParseQuery(rawQuery)
+		}
+		if fact.query == nil {
+			fact.query = url.Values{}
+		}
+	}
+	return fact.query.Get(name)
+}
+
+// Cookie 获取cookie值
+func (fact *RuleFact) Cookie(name string) string {
+	if fact.request != nil {
+		cookie, err := fact.request.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return cookie.Value
+	}
+	if fact.cookies == nil {
+		fact.cookies = map[string]string{}
+		var cookies []http.Cookie
+		if err := json.Unmarshal([]byte(fact.COOKIES), &cookies); err == nil {
+			for _, cookie := range cookies {
+				fact.cookies[cookie.Name] = cookie.Value
+			}
+		}
+	}
+	return fact.cookies[name]
+}
+
+/*
+*
+获取JSON请求体中的值 路径如 user.name items[0].id，对象和数组返回JSON文本
+*/
+func (fact *RuleFact) JSON(path string) string {
+	if !fact.jsonParsed {
+		fact.jsonParsed = true
+		if err := json.Unmarshal([]byte(fact.BODY), &fact.jsonBody); err != nil {
+			fact.jsonBody = nil
+		}
+	}
+	value := fact.jsonBody
+	for _, segment := range strings.Split(path, ".") {
+		name, indexes, _ := strings.Cut(segment, "[")
+		if name != "" {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return ""
+			}
+			value = object[name]
+		}
+		if indexes == "" {
+			continue
+		}
+		for _, indexStr := range strings.Split(strings.TrimSuffix(indexes, "]"), "][") {
+			index, err := strconv.Atoi(indexStr)
+			array, ok := value.([]interface{})
+			if err != nil || !ok || index < 0 || index >= len(array) {
+				return ""
+			}
+			value = array[index]
+		}
+	}
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		valueBytes, _ := json.Marshal(v)
+		return string(valueBytes)
+	}
+}
+
+// InCIDR 来源IP是否在列表中 多个IP或网段用逗号隔开
+func (fact *RuleFact) InCIDR(list string) bool {
+	ip := net.ParseIP(fact.SRC_IP)
+	if ip == nil {
+		return false
+	}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if strings.Contains(item, "/") {
+			if _, ipNet, err := net.ParseCIDR(item); err == nil && ipNet.Contains(ip) {
+				return true
+			}
+		} else if itemIp := net.ParseIP(item); itemIp != nil && itemIp.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// InASN 来源IP所属ASN是否在列表中 多个ASN用逗号隔开，如 AS13335,16509
+func (fact *RuleFact) InASN(list string) bool {
+	return asntool.InASNList(fact.ASN, list)
+}
+
+// MatchRegex 值是否匹配正则 正则不正确时返回false
+func (fact *RuleFact) MatchRegex(value string, pattern string) bool {
+	cached, ok := ruleRegexCache.Load(pattern)
+	if !ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false
+		}
+		cached, _ = ruleRegexCache.LoadOrStore(pattern, re)
+	}
+	return cached.(*regexp.Regexp).MatchString(value)
+}
+
+// IsBot 是否为爬虫
+func (fact *RuleFact) IsBot() bool {
+	fact.resolveBot()
+	return fact.isBot
+}
+
+// IsNormalBot 是否为正常的搜索引擎爬虫
+func (fact *RuleFact) IsNormalBot() bool {
+	fact.resolveBot()
+	return fact.isNormalBot
+}
+
+// BotName 爬虫名称
+func (fact *RuleFact) BotName() string {
+	fact.resolveBot()
+	return fact.botName
+}
+
+// IPCount 当前IP最近N秒访问本网站的次数
+func (fact *RuleFact) IPCount(seconds int64) int64 {
+	if fact.counter == nil {
+		return 0
+	}
+	return fact.counter(fact.CounterKeys()[0], seconds)
+}
+
+// IPURLCount 当前IP最近N秒访问本路径的次数
+func (fact *RuleFact) IPURLCount(seconds int64) int64 {
+	if fact.counter == nil {
+		return 0
+	}
+	return fact.counter(fact.CounterKeys()[1], seconds)
+}
+
+// 爬虫识别较耗时 只在规则用到时执行
+func (fact *RuleFact) resolveBot() {
+	if fact.botResolved {
+		return
+	}
+	fact.botResolved = true
+	if fact.botResolver != nil {
+		fact.isBot, fact.isNormalBot, fact.botName = fact.botResolver()
+	}
+}
+
+func (fact *RuleFact) path() string {
+	if fact.request != nil {
+		return fact.request.URL.Path
+	}
+	path, _, _ := strings.Cut(fact.URL, "?")
+	return path
+}
+
+/*
+*
+规则开始执行时由引擎调用 ruleName 为规则说明
+*/
+func (fact *RuleFact) BeginRule(ruleName string) {
+	fact.EndRule()
+	fact.firing = true
+	fact.firedRule = ruleName
+	fact.firedActed = false
+}
+
+/*
+*
+规则执行完毕时由引擎调用 没有调用任何动作的规则按阻止处理（兼容旧规则）
+*/
+func (fact *RuleFact) EndRule() {
+	if fact.firing && !fact.firedActed {
+		fact.decide(RuleDecision{Action: RULE_ACTION_BLOCK})
+	}
+	fact.firing = false
+}
+
+// 规则按优先级从高到低执行 先做出的处置生效
+func (fact *RuleFact) decide(decision RuleDecision) {
+	fact.firedActed = true
+	if fact.decision.Action != "" {
+		return
+	}
+	decision.RuleName = fact.firedRule
+	fact.decision = decision
+}
+
+/*
+*
+Allow 放行 规则在扫描、SQL注入、XSS、CC等检测之后执行，放行只跳过规则之后的敏感词、OWASP检测；
+需要完全跳过检测时请使用IP白名单或URL白名单
+*/
+func (fact *RuleFact) Allow() {
+	fact.decide(RuleDecision{Action: RULE_ACTION_ALLOW})
+}
+
+// Block 阻止 status 为0时返回403，msg 为空时使用默认提示
+func (fact *RuleFact) Block(status int64, msg string) {
+	fact.decide(RuleDecision{Action: RULE_ACTION_BLOCK, StatusCode: int(status), Message: msg})
+}
+
+// Challenge 进行JS挑战 通过后放行
+func (fact *RuleFact) Challenge() {
+	fact.decide(RuleDecision{Action: RULE_ACTION_CHALLENGE})
+}
+
+// Redirect 跳转到指定地址
+func (fact *RuleFact) Redirect(url string) {
+	fact.decide(RuleDecision{Action: RULE_ACTION_REDIRECT, RedirectUrl: url})
+}
+
+// LogOnly 只记录标记 不影响处置
+func (fact *RuleFact) LogOnly(tag string) {
+	fact.firedActed = true
+	fact.tags = append(fact.tags, tag)
+}
+
+// SetRisk 设置危险等级 0-4，多条规则设置时取最高
+func (fact *RuleFact) SetRisk(n int64) {
+	fact.firedActed = true
+	if n < 0 {
+		n = 0
+	} else if n > 4 {
+		n = 4
+	}
+	if int(n) > fact.risk {
+		fact.risk = int(n)
+	}
+}
+
+// AddUpstreamHeader 转发到后端时增加请求头 同名请求头以优先级高的规则为准
+func (fact *RuleFact) AddUpstreamHeader(key string, value string) {
+	fact.firedActed = true
+	if fact.upstreamHeaders == nil {
+		fact.upstreamHeaders = map[string]string{}
+	}
+	if _, ok := fact.upstreamHeaders[key]; !ok {
+		fact.upstreamHeaders[key] = value
+	}
+}
+
+// Decision 规则的处置结果
+func (fact *RuleFact) Decision() RuleDecision {
+	return fact.decision
+}
+
+// Risk 规则设置的危险等级 未设置时为-1
+func (fact *RuleFact) Risk() int {
+	return fact.risk
+}
+
+// Tags 规则记录的标记
+func (fact *RuleFact) Tags() []string {
+	return fact.tags
+}
+
+// UpstreamHeaders 需要转发到后端的请求头
+func (fact *RuleFact) UpstreamHeaders() map[string]string {
+	return fact.upstreamHeaders
+}
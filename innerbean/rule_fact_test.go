@@ -0,0 +1,88 @@
+package innerbean
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRuleFactFromWebLog(t *testing.T) {
+	fact := NewRuleFact(&WebLog{
+		URL:     "/api/login?user=admin&id=1",
+		HEADER:  "X-Token: abc\r\nUser-Agent: curl\r\n",
+		COOKIES: `[{"Name":"sid","Value":"s1"}]`,
+		BODY:    `{"user":{"name":"tom","tags":["a","b"]},"items":[{"id":7}],"ok":true}`,
+		SRC_IP:  "10.1.2.3",
+	}, nil)
+	if fact.Header("x-token") != "abc" || fact.Query("user") != "admin" || fact.Cookie("sid") != "s1" {
+		t.Errorf("header/query/cookie not parsed")
+	}
+	tests := map[string]string{
+		"user.name":    "tom",
+		"user.tags[1]": "b",
+		"items[0].id":  "7",
+		"ok":           "true",
+		"user.tags":    `["a","b"]`,
+		"items[3].id":  "",
+		"missing.name": "",
+	}
+	for path, want := range tests {
+		if got := fact.JSON(path); got != want {
+			t.Errorf("JSON(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if !fact.InCIDR("192.168.0.0/16, 10.0.0.0/8") || fact.InCIDR("10.1.2.4,172.16.0.0/12") || !fact.InCIDR("10.1.2.3") {
+		t.Errorf("InCIDR not correct")
+	}
+	if fact.InASN("13335") {
+		t.Errorf("InASN should not match without asn")
+	}
+	fact.ASN = 13335
+	if !fact.InASN("AS16509, as13335") || fact.InASN("AS16509,abc") {
+		t.Errorf("InASN not correct")
+	}
+	if !fact.MatchRegex(fact.URL, `^/api/.*user=`) || fact.MatchRegex(fact.URL, `(`) {
+		t.Errorf("MatchRegex not correct")
+	}
+	if fact.IsBot() || fact.IPCount(60) != 0 {
+		t.Errorf("bot and counter should be empty without resolver")
+	}
+}
+
+func TestRuleFactFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/list?page=2", nil)
+	r.Header.Set("X-Token", "req")
+	r.AddCookie(&http.Cookie{Name: "sid", Value: "s2"})
+	fact := NewRuleFact(&WebLog{HOST_CODE: "h1", SRC_IP: "1.1.1.1"}, r)
+	if fact.Header("X-Token") != "req" || fact.Query("page") != "2" || fact.Cookie("sid") != "s2" {
+		t.Errorf("request values not used")
+	}
+	keys := fact.CounterKeys()
+	if keys[0] != "h1|1.1.1.1" || keys[1] != "h1|1.1.1.1|/list" {
+		t.Errorf("unexpected counter keys %v", keys)
+	}
+	resolved := 0
+	fact.SetBotResolver(func() (bool, bool, string) {
+		resolved++
+		return true, true, "Baiduspider"
+	})
+	fact.SetCounter(func(key string, seconds int64) int64 {
+		return seconds
+	})
+	if !fact.IsBot() || !fact.IsNormalBot() || fact.BotName() != "Baiduspider" || resolved != 1 {
+		t.Errorf("bot resolver should run once")
+	}
+	if fact.IPCount(30) != 30 || fact.IPURLCount(10) != 10 {
+		t.Errorf("counter not used")
+	}
+}
+
+func TestRuleFactUsesWebLogCopy(t *testing.T) {
+	weblog := WebLog{URL: "/old", RULE: ""}
+	fact := NewRuleFact(&weblog, nil)
+	fact.URL = "/changed"
+	fact.RULE = "changed"
+	if weblog.URL != "/old" || weblog.RULE != "" {
+		t.Errorf("rule fact should not modify weblog, got %s %s", weblog.URL, weblog.RULE)
+	}
+}
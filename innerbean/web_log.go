@@ -13,6 +13,8 @@ type WebLog struct {
 	COUNTRY              string `json:"country"`
 	PROVINCE             string `json:"province"`
 	CITY                 string `json:"city"`
+	ASN                  int64  `json:"asn"`     //自治系统编号
+	ASN_ORG              string `json:"asn_org"` //自治系统所属网络运营者
 	CREATE_TIME          string `gorm:"index:idx_weblog_time" json:"create_time"`
 	CONTENT_LENGTH       int64  `json:"content_length"`
 	COOKIES              string `json:"cookies"`
@@ -33,6 +35,15 @@ type WebLog struct {
 	RISK_LEVEL           int    `json:"risk_level"`                        //危险等级 0:正常 1:轻微 2:有害 3:严重 4:特别严重
 	GUEST_IDENTIFICATION string `json:"guest_identification"`              //访客身份识别
 	TimeSpent            int64  `json:"time_spent"`                        //用时
+	MATCH_PARAM          string `json:"match_param"`                       //命中的参数名称
+	MATCH_FINGERPRINT    string `json:"match_fingerprint"`                 //命中的注入指纹
+	REQ_BYTES            int64  `json:"req_bytes"`                         //客户端发送字节数(协议升级连接)
+	RES_BYTES            int64  `json:"res_bytes"`                         //服务端发送字节数(协议升级连接)
+	GRPC_SERVICE         string `json:"grpc_service"`                      //gRPC服务名
+	GRPC_METHOD          string `json:"grpc_method"`                       //gRPC方法名
+	GRPC_STATUS          string `json:"grpc_status"`                       //gRPC状态码
+	OWASP_DETAIL         string `json:"owasp_detail"`                      //OWASP命中规则详情 json
+	TLS_FINGERPRINT      string `json:"tls_fingerprint"`                   //TLS客户端指纹
 }
 
 // 在 GORM 的 Model 方法中定义复合索引
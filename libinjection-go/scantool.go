@@ -2,9 +2,70 @@ package libinjection
 
 import (
 	"SamWaf/innerbean"
+	"net/http"
 	"strings"
 )
 
+// 扫描工具特征 按顺序匹配，保证同一请求命中结果固定
+type scanKeyword struct {
+	keyword  string
+	toolName string
+}
+
+// 扫描工具User-Agent特征 (小写)
+var scanUserAgentKeywords = []scanKeyword{
+	{"nikto", "Nikto"},
+	{"nmap scripting", "Nmap NSE"},
+	{"nmap nse", "Nmap NSE"},
+	{"nuclei", "Nuclei"},
+	{"dirsearch", "dirsearch"},
+	{"gobuster", "gobuster"},
+	{"fuzz faster u fool", "ffuf"},
+	{"ffuf", "ffuf"},
+	{"acunetix", "AWVS"},
+	{"sqlmap", "sqlmap"},
+	{"masscan", "masscan"},
+	{"zgrab", "zgrab"},
+	{"wpscan", "WPScan"},
+	{"appscan", "AppScan"},
+	{"netsparker", "Netsparker"},
+	{"dirbuster", "DirBuster"},
+}
+
+// 扫描工具请求头特征
+var scanHeaderKeywords = []scanKeyword{
+	{"Acunetix-Aspect", "AWVS"},
+	{"Acunetix-Aspect-Password", "AWVS"},
+	{"Acunetix-Aspect-Queries", "AWVS"},
+	{"X-Wvs-Id", "AWVS"},
+	{"X-Scan-Memo", "AWVS"},
+	{"X-Scanner", "Netsparker"},
+}
+
+// 高危敏感路径(小写) 按完整的路径段匹配，避免 /.envoy/ 之类的正常路径被误判
+var sensitivePathKeywords = []string{
+	".git", ".svn", ".hg", ".bzr",
+	".env", ".ds_store", ".htpasswd", ".idea", ".vscode",
+	"web-inf/web.xml", ".aws/credentials", ".ssh",
+}
+
+// 备份文件后缀(小写) .old、.sql、~ 等后缀常见于正常业务文件，不列入
+var backupFileSuffixes = []string{
+	".bak", ".orig", ".swp",
+}
+
+// 常见整站备份文件名(小写)
+var backupFileNames = []string{
+	"/backup.zip", "/backup.rar", "/backup.tar.gz", "/www.zip", "/www.rar", "/www.tar.gz",
+	"/wwwroot.zip", "/wwwroot.rar", "/web.zip", "/web.rar", "/site.zip", "/db.sql", "/database.sql",
+}
+
+// 常见程序管理路径(小写)，只有在网站不存在(404/403)时才视为探测
+var probePathKeywords = []string{
+	"/wp-admin", "/wp-login.php", "/wp-config.php", "/phpmyadmin", "/pma/",
+	"/administrator/", "/manager/html", "/solr/admin", "/actuator",
+}
+
 func IsScan(log *innerbean.WebLog) bool {
 	url_keywords := []string{"sqlmap", "Appscan", "nessus", "Nessus", "nessus",
 		"acunetix-wvs-test-for-some-inexistent-file", "acunetix_wvs_security_test",
@@ -17,3 +78,61 @@ func IsScan(log *innerbean.WebLog) bool {
 	}
 	return false
 }
+
+/*
+*
+通过User-Agent和请求头识别扫描工具 返回值：是否是扫描工具，工具名称
+*/
+func IsScanSignature(userAgent string, header http.Header) (bool, string) {
+	lowerUserAgent := strings.ToLower(userAgent)
+	for _, item := range scanUserAgentKeywords {
+		if strings.Contains(lowerUserAgent, item.keyword) {
+			return true, item.toolName
+		}
+	}
+	for _, item := range scanHeaderKeywords {
+		if header.Get(item.keyword) != "" {
+			return true, item.toolName
+		}
+	}
+	return false, ""
+}
+
+/*
+*
+检测是否访问敏感路径（版本库、配置文件、备份文件）返回值：是否敏感路径，命中内容
+*/
+func IsSensitivePath(path string) (bool, string) {
+	lowerPath := strings.ToLower(path)
+	segmentPath := lowerPath + "/"
+	for _, keyword := range sensitivePathKeywords {
+		if strings.Contains(segmentPath, "/"+keyword+"/") {
+			return true, "/" + keyword
+		}
+	}
+	for _, name := range backupFileNames {
+		if strings.HasSuffix(lowerPath, name) {
+			return true, name
+		}
+	}
+	for _, suffix := range backupFileSuffixes {
+		if strings.HasSuffix(lowerPath, suffix) {
+			return true, suffix
+		}
+	}
+	return false, ""
+}
+
+/*
+*
+检测是否是常见程序管理路径
+*/
+func IsProbePath(path string) (bool, string) {
+	lowerPath := strings.ToLower(path)
+	for _, keyword := range probePathKeywords {
+		if strings.HasPrefix(lowerPath, keyword) {
+			return true, keyword
+		}
+	}
+	return false, ""
+}
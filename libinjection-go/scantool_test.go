@@ -0,0 +1,40 @@
+package libinjection
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIsScanSignature(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Scanner", "1")
+	header.Set("Acunetix-Aspect", "enabled")
+	//多个特征同时命中时结果固定
+	for i := 0; i < 20; i++ {
+		if isScan, toolName := IsScanSignature("Mozilla/5.0", header); !isScan || toolName != "AWVS" {
+			t.Fatalf("unexpected result %v %s", isScan, toolName)
+		}
+		if isScan, toolName := IsScanSignature("sqlmap/1.7 nuclei", http.Header{}); !isScan || toolName != "Nuclei" {
+			t.Fatalf("unexpected result %v %s", isScan, toolName)
+		}
+	}
+	if isScan, _ := IsScanSignature("Mozilla/5.0 (Windows NT 10.0; Win64; x64)", http.Header{}); isScan {
+		t.Errorf("normal user agent detected as scanner")
+	}
+}
+
+func TestIsSensitivePath(t *testing.T) {
+	sensitive := []string{"/.git/config", "/.git", "/.env", "/app/.ENV", "/WEB-INF/web.xml", "/index.php.bak", "/www.zip", "/db.sql"}
+	for _, path := range sensitive {
+		if ok, _ := IsSensitivePath(path); !ok {
+			t.Errorf("sensitive path not detected: %s", path)
+		}
+	}
+	normal := []string{"/export/report.sql", "/docs/readme~", "/files/config.old", "/draft.save", "/index.html",
+		"/.envoy/config", "/api/.environment", "/static/my.git/a.js", "/docs/.sshkeys"}
+	for _, path := range normal {
+		if ok, name := IsSensitivePath(path); ok {
+			t.Errorf("normal path detected: %s (%s)", path, name)
+		}
+	}
+}
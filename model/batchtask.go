@@ -1,6 +1,9 @@
 package model
 
-import "SamWaf/model/baseorm"
+import (
+	"SamWaf/customtype"
+	"SamWaf/model/baseorm"
+)
 
 // BatchTask 批量任务
 type BatchTask struct {
@@ -11,5 +14,23 @@ type BatchTask struct {
 	BatchSourceType    string `json:"batch_source_type"`    //来源类型(local,url)
 	BatchSource        string `json:"batch_source"`         //来源内容 路径或者实际的url内容
 	BatchExecuteMethod string `json:"batch_execute_method"` //任务执行方式 追加,覆盖
+	BatchCron          string `json:"batch_cron"`           //定时执行的cron表达式 为空时跟随每天05:00的批量任务执行
+	BatchEnable        int    `json:"batch_enable"`         //是否启用 1:启用 0:停用
 	Remark             string `json:"remark"`               //备注
 }
+
+// BatchTaskHistory 批量任务执行记录
+type BatchTaskHistory struct {
+	baseorm.BaseOrm
+	BatchTaskId   string              `json:"batch_task_id" gorm:"index"` //任务ID
+	BatchTaskName string              `json:"batch_task_name"`            //任务名
+	BatchType     string              `json:"batch_type"`                 //任务类型
+	TriggerType   string              `json:"trigger_type"`               //触发方式 cron:定时 manual:手工
+	StartTime     customtype.JsonTime `json:"start_time"`                 //开始时间
+	EndTime       customtype.JsonTime `json:"end_time"`                   //结束时间
+	AddCount      int                 `json:"add_count"`                  //新增条数
+	UpdateCount   int                 `json:"update_count"`               //覆盖条数
+	SkipCount     int                 `json:"skip_count"`                 //已存在或重复而跳过的条数
+	InvalidCount  int                 `json:"invalid_count"`              //无法识别的条数
+	ErrorMsg      string              `json:"error_msg"`                  //错误信息 为空表示执行成功
+}
@@ -21,4 +21,16 @@ type Result struct {
 	检测内容
 	*/
 	Content string
+	/**
+	处置方式 为空时按阻止处理，可选 challenge redirect
+	*/
+	Action string
+	/**
+	阻止时返回的状态码 为0时返回403
+	*/
+	StatusCode int
+	/**
+	跳转地址
+	*/
+	RedirectUrl string
 }
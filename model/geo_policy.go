@@ -0,0 +1,56 @@
+package model
+
+import (
+	"SamWaf/model/baseorm"
+	"strings"
+)
+
+/*
+*
+地域限制 每个网站一条，全局网站的配置对所有网站生效
+地区每行（或逗号隔开）一个，格式为 国家|省份|城市，名称与IP库一致，如 中国、中国|广东省、中国|广东省|深圳市、美国
+*/
+type GeoPolicy struct {
+	baseorm.BaseOrm
+	HostCode  string `json:"host_code"`  //网站唯一码（主要键）
+	Mode      string `json:"mode"`       //限制方式 allow:只允许列表中的地区 deny:禁止列表中的地区
+	Regions   string `json:"regions"`    //地区列表
+	BotBypass int    `json:"bot_bypass"` //正常搜索引擎爬虫是否放行 1:放行 0:不放行
+	Action    string `json:"action"`     //处置方式 block:阻止 challenge:挑战
+	Status    int    `json:"status"`     //状态 1:启用 0:停用
+	Remarks   string `json:"remarks"`    //备注
+}
+
+/*
+*
+判断地区是否在列表中 返回命中的地区
+每一级都要求名称完全相同，只写国家时匹配该国家的所有省份和城市
+*/
+func (policy GeoPolicy) MatchRegion(country string, province string, city string) (string, bool) {
+	regions := strings.FieldsFunc(policy.Regions, func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ',' || r == '，'
+	})
+	actual := []string{country, province, city}
+	for _, region := range regions {
+		region = strings.TrimSpace(region)
+		if region == "" {
+			continue
+		}
+		parts := strings.Split(region, "|")
+		if len(parts) > len(actual) {
+			continue
+		}
+		matched := true
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" || actual[i] != part {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return region, true
+		}
+	}
+	return "", false
+}
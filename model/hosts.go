@@ -1,6 +1,10 @@
 package model
 
-import "SamWaf/model/baseorm"
+import (
+	"SamWaf/model/baseorm"
+	"encoding/json"
+	"errors"
+)
 
 type Hosts struct {
 	baseorm.BaseOrm
@@ -26,6 +30,16 @@ type Hosts struct {
 	UnrestrictedPort    int    `json:"unrestricted_port"`      //不限来源匹配端口 0 限制 1，不限制
 	BindSslId           string `json:"bind_ssl_id"`            //绑定SSL的ID
 	AutoJumpHTTPS       int    `json:"auto_jump_https"`        //是否自动跳转https  0 不自动 1 强制80跳转https
+	SSRF_ALLOW_DOMAINS  string `json:"ssrf_allow_domains"`     //SSRF检测允许访问的外部域名 换行隔开
+	XML_LIMIT_JSON      string `json:"xml_limit_json"`         //XML检测限制 json
+	DETECT_EXCLUDE_JSON string `json:"detect_exclude_json"`    //检测排除项 json
+	INSPECT_HEADERS     string `json:"inspect_headers"`        //需要注入检测的请求头 换行隔开 为空使用默认
+	INSPECT_COOKIES     string `json:"inspect_cookies"`        //需要注入检测的cookie 换行隔开 为空检测全部
+	GRAPHQL_JSON        string `json:"graphql_json"`           //GraphQL检测配置 json 为空时不检测
+	WEBSOCKET_JSON      string `json:"websocket_json"`         //WebSocket防护配置 json
+	UPSTREAM_PROTOCOL   string `json:"upstream_protocol"`      //后端协议 http1(默认) h2 h2c
+	GRPC_JSON           string `json:"grpc_json"`              //gRPC方法访问控制 json
+	OWASP_JSON          string `json:"owasp_json"`             //OWASP CRS配置 json 为空时使用全局配置
 }
 
 type HostsDefense struct {
@@ -35,4 +49,88 @@ type HostsDefense struct {
 	DEFENSE_SCAN      int `json:"scan"`      //防御-scan工具扫描
 	DEFENSE_RCE       int `json:"rce"`       //防御-scan工具扫描
 	DEFENSE_SENSITIVE int `json:"sensitive"` //敏感词检测
+	DEFENSE_SSRF      int `json:"ssrf"`      //防御-SSRF服务端请求伪造
+	DEFENSE_XML       int `json:"xml"`       //防御-XML/XXE及SOAP报文检测
+	DEFENSE_HEADER    int `json:"header"`    //防御-请求头及cookie注入检测
+	DEFENSE_GRAPHQL   int `json:"graphql"`   //防御-GraphQL检测
+	DEFENSE_TRAVERSAL int `json:"traversal"` //防御-目录穿越检测
+}
+
+type HostsXmlLimit struct {
+	MaxDepth      int `json:"max_depth"`       //最大嵌套深度
+	MaxEntity     int `json:"max_entity"`      //最大实体声明数量
+	MaxExpandSize int `json:"max_expand_size"` //实体展开后的最大长度
+	MaxNodes      int `json:"max_nodes"`       //最大节点数量 超出后拦截
+}
+
+type HostsDetectExclude struct {
+	Detector string `json:"detector"` //检测器 sqli xss rce traversal 为空或*表示全部
+	Path     string `json:"path"`     //路径 为空表示全部 *结尾表示前缀匹配
+	Param    string `json:"param"`    //参数名称 如 content 或 form.content 为空或*表示全部
+}
+
+/*
+*
+解析检测排除项 检测器、路径、参数都不限制的排除项会关闭主机的全部检测，视为配置错误
+返回可用的排除项，有错误的排除项时同时返回错误
+*/
+func ParseDetectExcludes(excludeJson string) ([]HostsDetectExclude, error) {
+	if excludeJson == "" {
+		return nil, nil
+	}
+	var excludes []HostsDetectExclude
+	if err := json.Unmarshal([]byte(excludeJson), &excludes); err != nil {
+		return nil, err
+	}
+	validExcludes := make([]HostsDetectExclude, 0, len(excludes))
+	var err error
+	for _, exclude := range excludes {
+		if isMatchAll(exclude.Detector) && exclude.Path == "" && isMatchAll(exclude.Param) {
+			err = errors.New("检测排除项的检测器、路径、参数不能同时为空")
+			continue
+		}
+		validExcludes = append(validExcludes, exclude)
+	}
+	return validExcludes, err
+}
+
+func isMatchAll(value string) bool {
+	return value == "" || value == "*"
+}
+
+type HostsGraphql struct {
+	Path               string `json:"path"`                //GraphQL路径 *结尾表示前缀匹配
+	MaxDepth           int    `json:"max_depth"`           //最大查询深度
+	MaxAliases         int    `json:"max_aliases"`         //最大别名数量
+	MaxFields          int    `json:"max_fields"`          //最大字段数量
+	MaxBatch           int    `json:"max_batch"`           //最大批量请求数量
+	BlockIntrospection int    `json:"block_introspection"` //阻止内省查询 1 阻止 0 不阻止
+}
+
+type HostsWebsocket struct {
+	AllowOrigins   []string `json:"allow_origins"`    //允许握手的Origin 如 https://chat.example.com 或 *.example.com 为空表示不限制
+	MaxFrameSize   int64    `json:"max_frame_size"`   //客户端单帧最大字节数 0 不限制
+	MaxMessageRate int      `json:"max_message_rate"` //客户端每秒最多消息数 0 不限制
+	InspectText    int      `json:"inspect_text"`     //文本消息注入检测 1 检测 0 不检测 检测时单条文本消息超过64KB会关闭连接
+}
+
+type HostsGrpc struct {
+	AllowMethods   []string `json:"allow_methods"`    //允许的方法 如 pkg.Service/Method pkg.Service/* 为空表示不限制
+	DenyMethods    []string `json:"deny_methods"`     //禁止的方法 格式同上
+	InspectBody    int      `json:"inspect_body"`     //消息内容注入检测 1 检测 0 不检测 流式请求只检测第一条消息
+	MaxMessageSize int      `json:"max_message_size"` //检测的最大消息字节数 超过时拦截 0 使用默认值64KB
+}
+
+type HostsOwasp struct {
+	Enable           int                 `json:"enable"`            //是否启用 1 启用 0 不启用
+	ParanoiaLevel    int                 `json:"paranoia_level"`    //偏执等级 1-4 0 使用默认值1
+	AnomalyThreshold int                 `json:"anomaly_threshold"` //入站异常分数阈值 0 使用默认值
+	Excludes         []HostsOwaspExclude `json:"excludes"`          //规则排除
+}
+
+type HostsOwaspExclude struct {
+	RuleId string `json:"rule_id"` //规则ID或范围 如 942100 或 942100-942199
+	Tag    string `json:"tag"`     //规则标签 如 attack-sqli
+	Path   string `json:"path"`    //路径 为空表示全部 *结尾表示前缀匹配
+	Param  string `json:"param"`   //参数名称 为空表示排除整条规则
 }
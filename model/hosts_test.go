@@ -0,0 +1,19 @@
+package model
+
+import "testing"
+
+func TestParseDetectExcludes(t *testing.T) {
+	excludes, err := ParseDetectExcludes(`[{"detector":"sqli","path":"","param":""},{"detector":"","path":"","param":""},{"detector":"*","path":"","param":"*"},{"path":"/cms/*"}]`)
+	if err == nil {
+		t.Errorf("match-all exclude should return error")
+	}
+	if len(excludes) != 2 || excludes[0].Detector != "sqli" || excludes[1].Path != "/cms/*" {
+		t.Errorf("valid excludes got %v", excludes)
+	}
+	if excludes, err = ParseDetectExcludes(""); err != nil || len(excludes) != 0 {
+		t.Errorf("empty json got %v %v", excludes, err)
+	}
+	if _, err = ParseDetectExcludes("{bad"); err == nil {
+		t.Errorf("bad json should return error")
+	}
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"SamWaf/model/baseorm"
+)
+
+/*
+登录防护（防暴力破解、撞库）
+*/
+type LoginProtect struct {
+	baseorm.BaseOrm
+	HostCode        string `json:"host_code"`         //网站唯一码（主要键）
+	Url             string `json:"url"`               //登录地址
+	Method          string `json:"method"`            //请求方法 为空默认POST
+	UserField       string `json:"user_field"`        //用户名字段
+	FailStatusCodes string `json:"fail_status_codes"` //失败状态码 逗号隔开
+	FailRedirect    string `json:"fail_redirect"`     //失败跳转地址(包含)
+	FailBodyPattern string `json:"fail_body_pattern"` //失败返回内容(正则)
+	WindowSeconds   int    `json:"window_seconds"`    //统计周期(秒)
+	IpMaxFail       int    `json:"ip_max_fail"`       //单IP最大失败次数
+	UserMaxFail     int    `json:"user_max_fail"`     //单用户名最大失败次数
+	IpUserMaxFail   int    `json:"ip_user_max_fail"`  //单IP+用户名最大失败次数
+	IpMaxUser       int    `json:"ip_max_user"`       //单IP最多尝试的用户名数量
+	Action          string `json:"action"`            //处置方式 throttle:限流 challenge:挑战 block:阻止
+	LockMinutes     int    `json:"lock_minutes"`      //锁定分钟
+	Remarks         string `json:"remarks"`           //备注
+}
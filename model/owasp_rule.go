@@ -0,0 +1,18 @@
+package model
+
+import (
+	"SamWaf/model/baseorm"
+)
+
+/*
+自定义SecLang规则（ModSecurity语法）
+*/
+type OwaspRule struct {
+	baseorm.BaseOrm
+	HostCode string `json:"host_code"` //网站唯一码（主要键） 全局网站代码表示全局规则
+	RuleName string `json:"rule_name"` //规则名称
+	Content  string `json:"content"`   //规则内容
+	Position int    `json:"position"`  //加载位置 0:CRS规则之后 1:CRS规则之前
+	Status   int    `json:"status"`    //状态 1:启用 0:停用
+	Remarks  string `json:"remarks"`   //备注
+}
@@ -5,5 +5,12 @@ type WafAllowUrlEditReq struct {
 	HostCode    string `json:"host_code"`                        //网站唯一码（主要键）
 	CompareType string `json:"compare_type" form:"compare_type"` //对比方式
 	Url         string `json:"url"`                              //白名单url
+	IgnoreCase  int    `json:"ignore_case"`                      //是否忽略大小写 1是
+	ReqMethod   string `json:"req_method"`                       //请求方法 多个用逗号分隔 为空不限制
+	ReqHost     string `json:"req_host"`                         //请求域名 为空不限制
+	HeaderName  string `json:"header_name"`                      //请求头名称 为空不限制
+	HeaderValue string `json:"header_value"`                     //请求头值 为空时只要求请求头存在
+	QueryName   string `json:"query_name"`                       //查询参数名称 为空不限制
+	QueryValue  string `json:"query_value"`                      //查询参数值 为空时只要求参数存在
 	Remarks     string `json:"remarks"`                          //备注
 }
@@ -9,6 +9,8 @@ type BatchTaskAddReq struct {
 	BatchSourceType    string `json:"batch_source_type"`    //来源类型(local,url)
 	BatchSource        string `json:"batch_source"`         //来源内容 路径或者实际的url内容
 	BatchExecuteMethod string `json:"batch_execute_method"` //任务执行方式 追加,覆盖
+	BatchCron          string `json:"batch_cron"`           //定时执行的cron表达式
+	BatchEnable        int    `json:"batch_enable"`         //是否启用 1:启用 0:停用 未传时默认启用
 	Remark             string `json:"remark"`               //备注
 }
 type BatchTaskEditReq struct {
@@ -19,6 +21,8 @@ type BatchTaskEditReq struct {
 	BatchSourceType    string `json:"batch_source_type"`    //来源类型(local,url)
 	BatchSource        string `json:"batch_source"`         //来源内容 路径或者实际的url内容
 	BatchExecuteMethod string `json:"batch_execute_method"` //任务执行方式 追加,覆盖
+	BatchCron          string `json:"batch_cron"`           //定时执行的cron表达式
+	BatchEnable        int    `json:"batch_enable"`         //是否启用 1:启用 0:停用
 	Remark             string `json:"remark"`               //备注
 }
 type BatchTaskDetailReq struct {
@@ -34,3 +38,7 @@ type BatchTaskSearchReq struct {
 type BatchTaskManualReq struct {
 	Id string `json:"id"   form:"id"`
 }
+type BatchTaskHistorySearchReq struct {
+	BatchTaskId string `json:"batch_task_id"` //任务ID
+	request.PageInfo
+}
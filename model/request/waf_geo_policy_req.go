@@ -0,0 +1,33 @@
+package request
+
+import "SamWaf/model/common/request"
+
+type WafGeoPolicyAddReq struct {
+	HostCode  string `json:"host_code"  form:"host_code"`  //网站唯一码（主要键）
+	Mode      string `json:"mode" form:"mode"`             //限制方式 allow:只允许列表中的地区 deny:禁止列表中的地区
+	Regions   string `json:"regions" form:"regions"`       //地区列表
+	BotBypass int    `json:"bot_bypass" form:"bot_bypass"` //正常搜索引擎爬虫是否放行
+	Action    string `json:"action" form:"action"`         //处置方式 block:阻止 challenge:挑战
+	Status    int    `json:"status" form:"status"`         //状态 1:启用 0:停用
+	Remarks   string `json:"remarks" form:"remarks"`       //备注
+}
+type WafGeoPolicySearchReq struct {
+	HostCode string `json:"host_code" ` //主机码
+	request.PageInfo
+}
+type WafGeoPolicyDelReq struct {
+	Id string `json:"id"  form:"id"` //唯一键
+}
+type WafGeoPolicyDetailReq struct {
+	Id string `json:"id"  form:"id"` //唯一键
+}
+type WafGeoPolicyEditReq struct {
+	Id        string `json:"id"`                           //唯一键
+	HostCode  string `json:"host_code"  form:"host_code"`  //网站唯一码（主要键）
+	Mode      string `json:"mode" form:"mode"`             //限制方式 allow:只允许列表中的地区 deny:禁止列表中的地区
+	Regions   string `json:"regions" form:"regions"`       //地区列表
+	BotBypass int    `json:"bot_bypass" form:"bot_bypass"` //正常搜索引擎爬虫是否放行
+	Action    string `json:"action" form:"action"`         //处置方式 block:阻止 challenge:挑战
+	Status    int    `json:"status" form:"status"`         //状态 1:启用 0:停用
+	Remarks   string `json:"remarks" form:"remarks"`       //备注
+}
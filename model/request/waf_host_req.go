@@ -23,6 +23,16 @@ type WafHostAddReq struct {
 	UnrestrictedPort    int    `json:"unrestricted_port"`      //不限来源匹配端口 0 限制 1，不限制
 	BindSslId           string `json:"bind_ssl_id"`            //绑定SSL的ID
 	AutoJumpHTTPS       int    `json:"auto_jump_https"`        //是否自动跳转https  0 不自动 1 强制80跳转https
+	SSRF_ALLOW_DOMAINS  string `json:"ssrf_allow_domains"`     //SSRF检测允许访问的外部域名 换行隔开
+	XML_LIMIT_JSON      string `json:"xml_limit_json"`         //XML检测限制 json
+	DETECT_EXCLUDE_JSON string `json:"detect_exclude_json"`    //检测排除项 json
+	INSPECT_HEADERS     string `json:"inspect_headers"`        //需要注入检测的请求头 换行隔开 为空使用默认
+	INSPECT_COOKIES     string `json:"inspect_cookies"`        //需要注入检测的cookie 换行隔开 为空检测全部
+	GRAPHQL_JSON        string `json:"graphql_json"`           //GraphQL检测配置 json 为空时不检测
+	WEBSOCKET_JSON      string `json:"websocket_json"`         //WebSocket防护配置 json
+	UPSTREAM_PROTOCOL   string `json:"upstream_protocol"`      //后端协议 http1(默认) h2 h2c
+	GRPC_JSON           string `json:"grpc_json"`              //gRPC方法访问控制 json
+	OWASP_JSON          string `json:"owasp_json"`             //OWASP CRS配置 json 为空时使用全局配置
 
 }
 type WafHostDelReq struct {
@@ -54,6 +64,16 @@ type WafHostEditReq struct {
 	UnrestrictedPort    int    `json:"unrestricted_port"`      //不限来源匹配端口 0 限制 1，不限制
 	BindSslId           string `json:"bind_ssl_id"`            //绑定SSL的ID
 	AutoJumpHTTPS       int    `json:"auto_jump_https"`        //是否自动跳转https  0 不自动 1 强制80跳转https
+	SSRF_ALLOW_DOMAINS  string `json:"ssrf_allow_domains"`     //SSRF检测允许访问的外部域名 换行隔开
+	XML_LIMIT_JSON      string `json:"xml_limit_json"`         //XML检测限制 json
+	DETECT_EXCLUDE_JSON string `json:"detect_exclude_json"`    //检测排除项 json
+	INSPECT_HEADERS     string `json:"inspect_headers"`        //需要注入检测的请求头 换行隔开 为空使用默认
+	INSPECT_COOKIES     string `json:"inspect_cookies"`        //需要注入检测的cookie 换行隔开 为空检测全部
+	GRAPHQL_JSON        string `json:"graphql_json"`           //GraphQL检测配置 json 为空时不检测
+	WEBSOCKET_JSON      string `json:"websocket_json"`         //WebSocket防护配置 json
+	UPSTREAM_PROTOCOL   string `json:"upstream_protocol"`      //后端协议 http1(默认) h2 h2c
+	GRPC_JSON           string `json:"grpc_json"`              //gRPC方法访问控制 json
+	OWASP_JSON          string `json:"owasp_json"`             //OWASP CRS配置 json 为空时使用全局配置
 
 }
 
@@ -0,0 +1,54 @@
+package request
+
+import "SamWaf/model/common/request"
+
+type WafLoginProtectAddReq struct {
+	HostCode        string `json:"host_code"  form:"host_code"`                //网站唯一码（主要键）
+	Url             string `json:"url" form:"url"`                             //登录地址
+	Method          string `json:"method" form:"method"`                       //请求方法
+	UserField       string `json:"user_field" form:"user_field"`               //用户名字段
+	FailStatusCodes string `json:"fail_status_codes" form:"fail_status_codes"` //失败状态码 逗号隔开
+	FailRedirect    string `json:"fail_redirect" form:"fail_redirect"`         //失败跳转地址(包含)
+	FailBodyPattern string `json:"fail_body_pattern" form:"fail_body_pattern"` //失败返回内容(正则)
+	WindowSeconds   int    `json:"window_seconds" form:"window_seconds"`       //统计周期(秒)
+	IpMaxFail       int    `json:"ip_max_fail" form:"ip_max_fail"`             //单IP最大失败次数
+	UserMaxFail     int    `json:"user_max_fail" form:"user_max_fail"`         //单用户名最大失败次数
+	IpUserMaxFail   int    `json:"ip_user_max_fail" form:"ip_user_max_fail"`   //单IP+用户名最大失败次数
+	IpMaxUser       int    `json:"ip_max_user" form:"ip_max_user"`             //单IP最多尝试的用户名数量
+	Action          string `json:"action" form:"action"`                       //处置方式
+	LockMinutes     int    `json:"lock_minutes" form:"lock_minutes"`           //锁定分钟
+	Remarks         string `json:"remarks" form:"remarks"`                     //备注
+}
+type WafLoginProtectSearchReq struct {
+	HostCode string `json:"host_code" ` //主机码
+	request.PageInfo
+}
+type WafLoginProtectDelReq struct {
+	Id string `json:"id"  form:"id"` //唯一键
+}
+type WafLoginProtectDetailReq struct {
+	Id string `json:"id"  form:"id"` //唯一键
+}
+type WafLoginProtectEditReq struct {
+	Id              string `json:"id"`                                         //唯一键
+	HostCode        string `json:"host_code"  form:"host_code"`                //网站唯一码（主要键）
+	Url             string `json:"url" form:"url"`                             //登录地址
+	Method          string `json:"method" form:"method"`                       //请求方法
+	UserField       string `json:"user_field" form:"user_field"`               //用户名字段
+	FailStatusCodes string `json:"fail_status_codes" form:"fail_status_codes"` //失败状态码 逗号隔开
+	FailRedirect    string `json:"fail_redirect" form:"fail_redirect"`         //失败跳转地址(包含)
+	FailBodyPattern string `json:"fail_body_pattern" form:"fail_body_pattern"` //失败返回内容(正则)
+	WindowSeconds   int    `json:"window_seconds" form:"window_seconds"`       //统计周期(秒)
+	IpMaxFail       int    `json:"ip_max_fail" form:"ip_max_fail"`             //单IP最大失败次数
+	UserMaxFail     int    `json:"user_max_fail" form:"user_max_fail"`         //单用户名最大失败次数
+	IpUserMaxFail   int    `json:"ip_user_max_fail" form:"ip_user_max_fail"`   //单IP+用户名最大失败次数
+	IpMaxUser       int    `json:"ip_max_user" form:"ip_max_user"`             //单IP最多尝试的用户名数量
+	Action          string `json:"action" form:"action"`                       //处置方式
+	LockMinutes     int    `json:"lock_minutes" form:"lock_minutes"`           //锁定分钟
+	Remarks         string `json:"remarks" form:"remarks"`                     //备注
+}
+
+// WafLoginProtectRemoveLockReq 移除登录锁定
+type WafLoginProtectRemoveLockReq struct {
+	LockKey string `json:"lock_key"  form:"lock_key"` //锁定键
+}
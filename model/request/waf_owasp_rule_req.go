@@ -0,0 +1,32 @@
+package request
+
+import "SamWaf/model/common/request"
+
+type WafOwaspRuleAddReq struct {
+	HostCode string `json:"host_code"  form:"host_code"` //网站唯一码（主要键）
+	RuleName string `json:"rule_name" form:"rule_name"`  //规则名称
+	Content  string `json:"content" form:"content"`      //规则内容
+	Position int    `json:"position" form:"position"`    //加载位置 0:CRS规则之后 1:CRS规则之前
+	Status   int    `json:"status" form:"status"`        //状态 1:启用 0:停用
+	Remarks  string `json:"remarks" form:"remarks"`      //备注
+}
+type WafOwaspRuleSearchReq struct {
+	HostCode string `json:"host_code" ` //主机码
+	RuleName string `json:"rule_name" ` //规则名称
+	request.PageInfo
+}
+type WafOwaspRuleDelReq struct {
+	Id string `json:"id"  form:"id"` //唯一键
+}
+type WafOwaspRuleDetailReq struct {
+	Id string `json:"id"  form:"id"` //唯一键
+}
+type WafOwaspRuleEditReq struct {
+	Id       string `json:"id"`                          //唯一键
+	HostCode string `json:"host_code"  form:"host_code"` //网站唯一码（主要键）
+	RuleName string `json:"rule_name" form:"rule_name"`  //规则名称
+	Content  string `json:"content" form:"content"`      //规则内容
+	Position int    `json:"position" form:"position"`    //加载位置 0:CRS规则之后 1:CRS规则之前
+	Status   int    `json:"status" form:"status"`        //状态 1:启用 0:停用
+	Remarks  string `json:"remarks" form:"remarks"`      //备注
+}
@@ -1,8 +1,9 @@
 package request
 
 type WafRuleEditReq struct {
-	CODE         string `json:"code"`
-	RuleJson     string `json:"rulejson"`
-	IsManualRule int    `json:"is_manual_rule"`
-	RuleContent  string `json:"rule_content"` //规则内容
+	CODE          string `json:"code"`
+	RuleJson      string `json:"rulejson"`
+	IsManualRule  int    `json:"is_manual_rule"`
+	RuleContent   string `json:"rule_content"`    //规则内容
+	RuleTestCases string `json:"rule_test_cases"` //规则测试用例 json数组
 }
@@ -0,0 +1,39 @@
+package request
+
+import "SamWaf/model/common/request"
+
+type WafRuleTemplateAddReq struct {
+	TemplateName string `json:"template_name" form:"template_name"` //模板名称
+	RuleContent  string `json:"rule_content" form:"rule_content"`   //规则内容 规则名使用${rule_name}
+	Params       string `json:"params" form:"params"`               //参数定义 json数组
+	Remarks      string `json:"remarks" form:"remarks"`             //备注
+}
+type WafRuleTemplateSearchReq struct {
+	TemplateName string `json:"template_name" ` //模板名称
+	request.PageInfo
+}
+type WafRuleTemplateDelReq struct {
+	Id string `json:"id"  form:"id"` //唯一键
+}
+type WafRuleTemplateDetailReq struct {
+	Id string `json:"id"  form:"id"` //唯一键
+}
+type WafRuleTemplateEditReq struct {
+	Id           string `json:"id"`                                 //唯一键
+	TemplateName string `json:"template_name" form:"template_name"` //模板名称
+	RuleContent  string `json:"rule_content" form:"rule_content"`   //规则内容 规则名使用${rule_name}
+	Params       string `json:"params" form:"params"`               //参数定义 json数组
+	Remarks      string `json:"remarks" form:"remarks"`             //备注
+}
+type WafRuleTemplateAttachReq struct {
+	Id          string   `json:"id"`           //模板ID
+	HostCodes   []string `json:"host_codes"`   //挂载的网站
+	ParamValues string   `json:"param_values"` //参数值 json对象，已挂载的网站会更新为该参数值
+}
+type WafRuleTemplateDetachReq struct {
+	Id       string `json:"id"  form:"id"`               //模板ID
+	HostCode string `json:"host_code"  form:"host_code"` //网站唯一码
+}
+type WafRuleTemplateBindListReq struct {
+	Id string `json:"id"  form:"id"` //模板ID
+}
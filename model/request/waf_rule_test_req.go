@@ -0,0 +1,6 @@
+package request
+
+type WafRuleTestReq struct {
+	CODE     string `json:"code"`      //规则编号 为空时测试网站的全部规则
+	HostCode string `json:"host_code"` //网站唯一码
+}
@@ -1,7 +1,10 @@
 package request
 
 type WafSensitiveAddReq struct {
-	Type    int    `json:"type" form:"type"`         //敏感词类型
-	Content string `json:"content" form:"content"  ` //内容
-	Remarks string `json:"remarks" form:"remarks"  ` //备注
+	HostCode       string `json:"host_code" form:"host_code"`             //网站唯一码 为空时全局生效
+	Type           int    `json:"type" form:"type"`                       //敏感词类型
+	CheckDirection int    `json:"check_direction" form:"check_direction"` //检测方向 0 请求 1 响应 2 请求和响应
+	Action         int    `json:"action" form:"action"`                   //处理方式 0 阻止 1 替换成*号 2 仅记录
+	Content        string `json:"content" form:"content"  `               //内容
+	Remarks        string `json:"remarks" form:"remarks"  `               //备注
 }
@@ -0,0 +1,45 @@
+package request
+
+import "SamWaf/model/common/request"
+
+type WafThreatFeedAddReq struct {
+	HostCode        string `json:"host_code"`        //网站唯一码（主要键）
+	FeedName        string `json:"feed_name"`        //订阅名称
+	SourceType      string `json:"source_type"`      //来源类型 local,remote
+	Source          string `json:"source"`           //本地路径或远程地址
+	Format          string `json:"format"`           //格式
+	FormatArg       string `json:"format_arg"`       //格式参数
+	RefreshInterval int    `json:"refresh_interval"` //刷新间隔(分钟)
+	ExpireMinutes   int    `json:"expire_minutes"`   //条目有效期(分钟)
+	Action          string `json:"action"`           //处置方式
+	Status          int    `json:"status"`           //状态
+	Remarks         string `json:"remarks"`          //备注
+}
+type WafThreatFeedEditReq struct {
+	Id              string `json:"id"`
+	HostCode        string `json:"host_code"`        //网站唯一码（主要键）
+	FeedName        string `json:"feed_name"`        //订阅名称
+	SourceType      string `json:"source_type"`      //来源类型 local,remote
+	Source          string `json:"source"`           //本地路径或远程地址
+	Format          string `json:"format"`           //格式
+	FormatArg       string `json:"format_arg"`       //格式参数
+	RefreshInterval int    `json:"refresh_interval"` //刷新间隔(分钟)
+	ExpireMinutes   int    `json:"expire_minutes"`   //条目有效期(分钟)
+	Action          string `json:"action"`           //处置方式
+	Status          int    `json:"status"`           //状态
+	Remarks         string `json:"remarks"`          //备注
+}
+type WafThreatFeedDetailReq struct {
+	Id string `json:"id"   form:"id"`
+}
+type WafThreatFeedDelReq struct {
+	Id string `json:"id"   form:"id"`
+}
+type WafThreatFeedRefreshReq struct {
+	Id string `json:"id"   form:"id"`
+}
+type WafThreatFeedSearchReq struct {
+	HostCode string `json:"host_code"` //网站唯一码（主要键）
+	FeedName string `json:"feed_name"` //订阅名称
+	request.PageInfo
+}
@@ -0,0 +1,6 @@
+package response
+
+type LoginLockRep struct {
+	LockKey    string `json:"lock_key"`    //锁定键 主机码_类型_锁定对象 类型为ip、user、ip_user
+	RemainTime string `json:"remain_time"` //剩余时间
+}
@@ -0,0 +1,12 @@
+package response
+
+type RuleTestRep struct {
+	RuleCode    string   `json:"rule_code"`    //规则编号
+	RuleName    string   `json:"rule_name"`    //规则名称
+	CaseName    string   `json:"case_name"`    //用例名称
+	ExpectMatch int      `json:"expect_match"` //期望结果 1:命中 0:不命中
+	Matched     bool     `json:"matched"`      //当前规则是否命中
+	MatchRules  []string `json:"match_rules"`  //样例命中的全部规则
+	Pass        bool     `json:"pass"`         //是否符合期望
+	Error       string   `json:"error"`        //执行错误
+}
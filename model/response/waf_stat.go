@@ -53,3 +53,25 @@ type WafNameValue struct {
 	Name  string `json:"name"  form:"name"`
 	Value string `json:"value"  form:"value"`
 }
+
+// 规则及黑白名单的命中统计
+type WafRuleHitStats struct {
+	HitCount      int64                 `json:"hit_count"`       //累计命中次数
+	TodayHitCount int64                 `json:"today_hit_count"` //今日命中次数
+	DayHits       []model.StatsDayCount `json:"day_hits"`        //最近7天每天的命中次数
+	LastHitTime   string                `json:"last_hit_time"`   //最后命中时间
+	LastReqUuid   string                `json:"last_req_uuid"`   //最后命中的请求
+	EvalCount     int64                 `json:"eval_count"`      //累计判断次数
+	AvgCostUs     int64                 `json:"avg_cost_us"`     //平均判断耗时 微秒
+	MaxCostUs     int64                 `json:"max_cost_us"`     //最近一天判断耗时最大值 微秒
+	P99CostUs     int64                 `json:"p99_cost_us"`     //最近一天判断耗时99分位 微秒
+}
+
+// 带命中统计的分页结果 用于规则及黑白名单列表
+type WafRuleHitPageResult struct {
+	List      interface{}                `json:"list"`
+	Total     int64                      `json:"total"`
+	PageIndex int                        `json:"pageIndex"`
+	PageSize  int                        `json:"pageSize"`
+	HitStats  map[string]WafRuleHitStats `json:"hit_stats"` //命中统计 key为规则唯一码或名单ID
+}
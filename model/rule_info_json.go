@@ -34,11 +34,77 @@ type RuleBase struct {
 type RelationDetail struct {
 	FactName  string `json:"fact_name"`
 	Attr      string `json:"attr"`
+	AttrArg   string `json:"attr_arg"` //辅助函数的参数 如 Header 的请求头名称
 	AttrType  string `json:"attr_type"`
 	AttrJudge string `json:"attr_judge"`
 	AttrVal   string `json:"attr_val"`
 	AttrVal2  string `json:"attr_val2"` //TODO 暂时这么是为了给函数返回值进行判断的
 }
+
+// 规则对象的辅助函数 值为参数类型 空表示无参数
+var RuleFactHelpers = map[string]string{
+	"Header":      "string",
+	"Query":       "string",
+	"Cookie":      "string",
+	"JSON":        "string",
+	"InCIDR":      "string",
+	"InASN":       "string",
+	"IPCount":     "int",
+	"IPURLCount":  "int",
+	"IsBot":       "",
+	"IsNormalBot": "",
+	"BotName":     "",
+}
+
+/*
+*
+条件左侧的取值 普通字段为 MF.URL，辅助函数为 MF.Header("X-Token")
+*/
+func conditionAttr(condition RelationDetail) string {
+	argType, isHelper := RuleFactHelpers[condition.Attr]
+	if !isHelper {
+		return condition.FactName + "." + condition.Attr
+	}
+	return fmt.Sprintf("%s.%s(%s)", condition.FactName, condition.Attr, helperArg(argType, condition.AttrArg))
+}
+
+/*
+*
+生成辅助函数的参数 字符串参数转义后加引号，整数参数不合法时按0处理，避免拼接出额外的规则语句
+*/
+func helperArg(argType string, arg string) string {
+	switch argType {
+	case "string":
+		return strconv.Quote(arg)
+	case "int":
+		num, err := strconv.ParseInt(strings.TrimSpace(arg), 10, 64)
+		if err != nil {
+			return "0"
+		}
+		return strconv.FormatInt(num, 10)
+	}
+	return ""
+}
+
+/*
+*
+生成单个条件 判断方式:
+system.xxx 调用值本身的函数 如 MF.URL.Contains("a") == true
+fact.xxx 调用规则对象的函数 如 MF.MatchRegex(MF.URL, "a") == true
+其他为比较符 如 MF.URL == "a"
+*/
+func conditionExpr(condition RelationDetail) string {
+	attr := conditionAttr(condition)
+	val := IfCompare(condition.AttrType == "string", "\""+condition.AttrVal+"\"", condition.AttrVal)
+	if strings.HasPrefix(condition.AttrJudge, "system.") {
+		return fmt.Sprintf("%s.%s(%s) == %s", attr, strings.Replace(condition.AttrJudge, "system.", "", 1), val, condition.AttrVal2)
+	}
+	if strings.HasPrefix(condition.AttrJudge, "fact.") {
+		return fmt.Sprintf("%s.%s(%s, %s) == %s", condition.FactName, strings.Replace(condition.AttrJudge, "fact.", "", 1), attr, val, condition.AttrVal2)
+	}
+	return fmt.Sprintf("%s %s %s", attr, condition.AttrJudge, val)
+}
+
 type RuleCondition struct {
 	RelationDetail []RelationDetail `json:"relation_detail"`
 	RelationSymbol string           `json:"relation_symbol"`
@@ -78,28 +144,10 @@ func (receiver *RuleTool) GenRuleInfo(rule RuleInfo, remark string) string {
 
 	var conditionTpl = ""
 	for _, condition := range rule.RuleCondition.RelationDetail {
-
 		if conditionTpl != "" {
-			if strings.HasPrefix(condition.AttrJudge, "system.") {
-				conditionTpl = conditionTpl + fmt.Sprintf(" %s %s.%s.%s(%s) == %s",
-					rule.RuleCondition.RelationSymbol, condition.FactName, condition.Attr, strings.Replace(condition.AttrJudge, "system.", "", 1),
-					IfCompare(condition.AttrType == "string", "\""+condition.AttrVal+"\"", condition.AttrVal), condition.AttrVal2)
-			} else {
-				conditionTpl = conditionTpl + fmt.Sprintf(" %s %s.%s %s %s",
-					rule.RuleCondition.RelationSymbol, condition.FactName, condition.Attr, condition.AttrJudge,
-					IfCompare(condition.AttrType == "string", "\""+condition.AttrVal+"\"", condition.AttrVal))
-			}
+			conditionTpl = conditionTpl + fmt.Sprintf(" %s %s", rule.RuleCondition.RelationSymbol, conditionExpr(condition))
 		} else {
-			if strings.HasPrefix(condition.AttrJudge, "system.") {
-				conditionTpl = conditionTpl + fmt.Sprintf("%s.%s.%s(%s) == %s",
-					condition.FactName, condition.Attr, strings.Replace(condition.AttrJudge, "system.", "", 1),
-					IfCompare(condition.AttrType == "string", "\""+condition.AttrVal+"\"", condition.AttrVal), condition.AttrVal2)
-			} else {
-				conditionTpl = conditionTpl + fmt.Sprintf("%s.%s %s %s",
-					condition.FactName, condition.Attr, condition.AttrJudge,
-					IfCompare(condition.AttrType == "string", "\""+condition.AttrVal+"\"", condition.AttrVal))
-			}
-
+			conditionTpl = conditionExpr(condition)
 		}
 	}
 	var doAssignmentTpl = ""
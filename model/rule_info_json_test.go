@@ -0,0 +1,23 @@
+package model
+
+import "testing"
+
+func TestConditionAttrEscapeArg(t *testing.T) {
+	tests := []struct {
+		condition RelationDetail
+		want      string
+	}{
+		{RelationDetail{FactName: "MF", Attr: "Header", AttrArg: "X-Token"}, `MF.Header("X-Token")`},
+		{RelationDetail{FactName: "MF", Attr: "Header", AttrArg: `a") == "" || MF.Retract("R1`}, `MF.Header("a\") == \"\" || MF.Retract(\"R1")`},
+		{RelationDetail{FactName: "MF", Attr: "Query", AttrArg: `a\`}, `MF.Query("a\\")`},
+		{RelationDetail{FactName: "MF", Attr: "IPCount", AttrArg: " 60 "}, `MF.IPCount(60)`},
+		{RelationDetail{FactName: "MF", Attr: "IPCount", AttrArg: "60) > 0 || MF.IPCount(1"}, `MF.IPCount(0)`},
+		{RelationDetail{FactName: "MF", Attr: "IsBot", AttrArg: "1"}, `MF.IsBot()`},
+		{RelationDetail{FactName: "MF", Attr: "URL"}, `MF.URL`},
+	}
+	for _, tt := range tests {
+		if got := conditionAttr(tt.condition); got != tt.want {
+			t.Errorf("conditionAttr(%+v) = %s, want %s", tt.condition, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"SamWaf/model/baseorm"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+*
+规则模板 内容为带参数的grule规则，挂载到网站后生成该网站的规则
+规则名使用 ${rule_name}，参数使用 ${参数名}
+参数按类型生成字面量，字符串参数会自动加上引号，如 MF.URL.Contains(${path})
+*/
+type RuleTemplate struct {
+	baseorm.BaseOrm
+	TemplateName    string `json:"template_name"`    //模板名称
+	RuleContent     string `json:"rule_content"`     //规则内容
+	Params          string `json:"params"`           //参数定义 json数组
+	TemplateVersion int    `json:"template_version"` //模板版本号
+	Remarks         string `json:"remarks"`          //备注
+}
+
+// 规则模板参数
+type RuleTemplateParam struct {
+	Name         string `json:"name"`          //参数名
+	Type         string `json:"type"`          //参数类型 string（默认）、int、float、bool
+	DefaultValue string `json:"default_value"` //默认值
+	Remarks      string `json:"remarks"`       //说明
+}
+
+// 按参数类型生成规则中的字面量 字符串转义后加引号，其他类型校验后使用规范格式
+func (param RuleTemplateParam) literal(value string) (string, error) {
+	switch param.Type {
+	case "", "string":
+		return strconv.Quote(value), nil
+	case "int":
+		num, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return "", errors.New("参数" + param.Name + "不是整数")
+		}
+		return strconv.FormatInt(num, 10), nil
+	case "float":
+		num, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return "", errors.New("参数" + param.Name + "不是数字")
+		}
+		return strconv.FormatFloat(num, 'f', -1, 64), nil
+	case "bool":
+		flag, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", errors.New("参数" + param.Name + "不是布尔值")
+		}
+		return strconv.FormatBool(flag), nil
+	}
+	return "", errors.New("参数" + param.Name + "类型不支持:" + param.Type)
+}
+
+/*
+*
+规则模板挂载的网站
+*/
+type RuleTemplateBind struct {
+	baseorm.BaseOrm
+	TemplateId  string `json:"template_id"`  //模板ID
+	HostCode    string `json:"host_code"`    //网站唯一码
+	RuleCode    string `json:"rule_code"`    //生成的规则唯一码
+	ParamValues string `json:"param_values"` //网站的参数值 json对象，未设置的使用默认值
+}
+
+// GetParams 解析参数定义
+func (template RuleTemplate) GetParams() ([]RuleTemplateParam, error) {
+	var params []RuleTemplateParam
+	if strings.TrimSpace(template.Params) == "" {
+		return params, nil
+	}
+	err := json.Unmarshal([]byte(template.Params), &params)
+	return params, err
+}
+
+/*
+*
+生成网站的规则内容 ruleCode 为生成规则的唯一码，paramValues 为json对象
+参数值按类型生成字面量，以免参数值改变规则的结构
+*/
+func (template RuleTemplate) Render(ruleCode string, paramValues string) (string, error) {
+	params, err := template.GetParams()
+	if err != nil {
+		return "", errors.New("参数定义格式不正确")
+	}
+	values := map[string]string{}
+	if strings.TrimSpace(paramValues) != "" {
+		if err := json.Unmarshal([]byte(paramValues), &values); err != nil {
+			return "", errors.New("参数值格式不正确")
+		}
+	}
+	dev := map[string]string{
+		"rule_name": "R" + strings.Replace(ruleCode, "-", "", -1),
+	}
+	for _, param := range params {
+		value, ok := values[param.Name]
+		if !ok {
+			value = param.DefaultValue
+		}
+		literal, err := param.literal(value)
+		if err != nil {
+			return "", err
+		}
+		dev[param.Name] = literal
+	}
+	var missing string
+	content := os.Expand(template.RuleContent, func(k string) string {
+		value, ok := dev[k]
+		if !ok && missing == "" {
+			missing = k
+		}
+		return value
+	})
+	if missing != "" {
+		return "", errors.New("参数未定义:" + missing)
+	}
+	return content, nil
+}
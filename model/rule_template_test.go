@@ -0,0 +1,60 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuleTemplate_Render(t *testing.T) {
+	template := RuleTemplate{
+		RuleContent: `rule ${rule_name} "禁止访问路径" salience 10 {
+    when
+        MF.URL.Contains(${path}) == True && MF.IPCount(60) > ${limit}
+    then
+        MF.Block(403, "");
+}`,
+		Params: `[{"name":"path","default_value":"/admin"},{"name":"limit","type":"int","default_value":"10"}]`,
+	}
+	content, err := template.Render("a-b-c", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "rule Rabc ") || !strings.Contains(content, `Contains("/admin")`) || !strings.Contains(content, "> 10") {
+		t.Errorf("default render got %s", content)
+	}
+	content, err = template.Render("a-b-c", `{"path":"/x\"y\\","limit":" 5 "}`)
+	if err != nil || !strings.Contains(content, `Contains("/x\"y\\")`) || !strings.Contains(content, "> 5\n") {
+		t.Errorf("param render got %s %v", content, err)
+	}
+	if _, err = template.Render("a-b-c", `{"limit":"5 || true"}`); err == nil {
+		t.Errorf("invalid int param should return error")
+	}
+	template.RuleContent = template.RuleContent + "${unknown}"
+	if _, err = template.Render("a-b-c", ""); err == nil {
+		t.Errorf("unknown param should return error")
+	}
+}
+
+func TestRuleTemplateParamLiteral(t *testing.T) {
+	tests := []struct {
+		param RuleTemplateParam
+		value string
+		want  string
+		ok    bool
+	}{
+		{RuleTemplateParam{Name: "a"}, `a"b`, `"a\"b"`, true},
+		{RuleTemplateParam{Name: "a", Type: "string"}, "", `""`, true},
+		{RuleTemplateParam{Name: "a", Type: "int"}, "-3", "-3", true},
+		{RuleTemplateParam{Name: "a", Type: "int"}, "1.5", "", false},
+		{RuleTemplateParam{Name: "a", Type: "float"}, "1.50", "1.5", true},
+		{RuleTemplateParam{Name: "a", Type: "float"}, "1)", "", false},
+		{RuleTemplateParam{Name: "a", Type: "bool"}, "TRUE", "true", true},
+		{RuleTemplateParam{Name: "a", Type: "date"}, "1", "", false},
+	}
+	for _, tt := range tests {
+		got, err := tt.param.literal(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("literal(%+v, %q) = %q, %v", tt.param, tt.value, got, err)
+		}
+	}
+}
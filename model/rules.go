@@ -16,4 +16,13 @@ type Rules struct {
 	IsPublicRule    int    `json:"is_public_rule"`    //是否为公共规则
 	IsManualRule    int    `json:"is_manual_rule"`    //是否为手工写规则  1：手工编写 0 ：UI界面形式
 	RuleStatus      int    `json:"rule_status"`       //规则是否开启 1，开启 0，关闭不生效 999 删除
+	RuleTestCases   string `json:"rule_test_cases"`   //规则测试用例 json数组
+	TemplateId      string `json:"template_id"`       //来源规则模板ID 为空表示网站自有规则
+}
+
+// 规则测试用例
+type RuleTestCase struct {
+	Name        string                 `json:"name"`         //用例名称
+	Fact        map[string]interface{} `json:"fact"`         //样例请求 字段同访问日志 如 {"url":"/admin","src_ip":"1.1.1.1"}
+	ExpectMatch int                    `json:"expect_match"` //期望结果 1:命中 0:不命中
 }
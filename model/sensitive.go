@@ -4,7 +4,10 @@ import "SamWaf/model/baseorm"
 
 type Sensitive struct {
 	baseorm.BaseOrm
-	Type    int    `json:"type"`    //敏感词类型
-	Content string `json:"content"` //内容
-	Remarks string `json:"remarks"` //备注
+	HostCode       string `json:"host_code"`       //网站唯一码 为空或全局网站码时全局生效
+	Type           int    `json:"type"`            //敏感词类型
+	CheckDirection int    `json:"check_direction"` //检测方向 0 请求 1 响应 2 请求和响应
+	Action         int    `json:"action"`          //处理方式 0 阻止 1 替换成*号 2 仅记录
+	Content        string `json:"content"`         //内容
+	Remarks        string `json:"remarks"`         //备注
 }
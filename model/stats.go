@@ -1,6 +1,7 @@
 package model
 
 import (
+	"SamWaf/customtype"
 	"SamWaf/model/baseorm"
 )
 
@@ -59,6 +60,26 @@ type StatsIPCityDay struct {
 	Count    int    `json:"count"`     //数量
 }
 
+/*
+*
+规则及黑白名单按天的命中统计
+*/
+type StatsRuleHit struct {
+	baseorm.BaseOrm
+	HostCode     string              `json:"host_code"`      //网站唯一码
+	RuleType     string              `json:"rule_type"`      //类型 rule,ip_allow,url_allow,ip_block,url_block,url_ldp
+	RuleCode     string              `json:"rule_code"`      //规则唯一码或名单ID（主要键）
+	Day          int                 `json:"day"`            //年月日（主要键）
+	HitCount     int64               `json:"hit_count"`      //命中次数
+	EvalCount    int64               `json:"eval_count"`     //判断次数
+	EvalCostSum  int64               `json:"eval_cost_sum"`  //判断总耗时 微秒
+	EvalCostMax  int64               `json:"eval_cost_max"`  //判断耗时最大值 微秒
+	EvalCostP99  int64               `json:"eval_cost_p99"`  //判断耗时99分位 微秒 按分段计数估算
+	EvalCostHist string              `json:"eval_cost_hist"` //判断耗时分段计数 逗号分隔
+	LastHitTime  customtype.JsonTime `json:"last_hit_time"`  //最后命中时间
+	LastReqUuid  string              `json:"last_req_uuid"`  //最后命中的请求
+}
+
 /*
 *
 天数对应的数量[临时]
@@ -76,5 +97,7 @@ type StatsIPCount struct {
 	IP       string  `json:"ip"`        //ip
 	IPBelong string  `json:"ip_belong"` //归属地
 	IPTag    []IPTag `json:"ip_tags"`   //IP标签
+	ASN      int64   `json:"asn"`       //自治系统编号
+	ASNOrg   string  `json:"asn_org"`   //自治系统所属网络运营者
 	Count    int64   `json:"count"`     //数量
 }
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"SamWaf/customtype"
+	"SamWaf/model/baseorm"
+)
+
+/*
+*
+威胁情报订阅 定时从本地文件或远程地址拉取IP列表
+绑定到全局网站时对所有网站生效
+*/
+type ThreatFeed struct {
+	baseorm.BaseOrm
+	HostCode        string              `json:"host_code"`         //网站唯一码（主要键）
+	FeedName        string              `json:"feed_name"`         //订阅名称
+	SourceType      string              `json:"source_type"`       //来源类型 local,remote
+	Source          string              `json:"source"`            //本地路径或远程地址
+	Format          string              `json:"format"`            //格式 plain,cidr,csv,json,spamhaus_drop,tor_exit
+	FormatArg       string              `json:"format_arg"`        //格式参数 csv为列序号(从1开始) json为取值路径
+	RefreshInterval int                 `json:"refresh_interval"`  //刷新间隔(分钟)
+	ExpireMinutes   int                 `json:"expire_minutes"`    //条目有效期(分钟) 超过时间未刷新则失效 0表示不失效
+	Action          string              `json:"action"`            //处置方式 block,challenge,tag
+	Status          int                 `json:"status"`            //状态 1:启用 0:停用
+	LastRefreshTime customtype.JsonTime `json:"last_refresh_time"` //最后刷新时间
+	LastRefreshMsg  string              `json:"last_refresh_msg"`  //最后刷新结果
+	EntryCount      int                 `json:"entry_count"`       //当前条目数
+	Remarks         string              `json:"remarks"`           //备注
+}
+
+/*
+*
+威胁情报条目 每次刷新整体替换
+*/
+type ThreatFeedEntry struct {
+	baseorm.BaseOrm
+	HostCode   string `json:"host_code" gorm:"index"` //网站唯一码（主要键）
+	FeedId     string `json:"feed_id" gorm:"index"`   //来源订阅
+	FeedName   string `json:"feed_name"`              //来源订阅名称
+	Ip         string `json:"ip"`                     //IP或网段
+	Action     string `json:"action"`                 //处置方式 block,challenge,tag
+	ExpireTime int64  `json:"expire_time"`            //失效时间 unix秒 0表示不失效
+}
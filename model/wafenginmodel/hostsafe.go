@@ -7,6 +7,7 @@ import (
 	"SamWaf/wafproxy"
 	"SamWaf/webplugin"
 	"sync"
+	"sync/atomic"
 )
 
 // 主机安全配置
@@ -17,16 +18,37 @@ type HostSafe struct {
 	RuleData            []model.Rules
 	RuleVersionSum      int //规则版本的汇总 通过这个来进行版本动态加载
 	Host                model.Hosts
-	PluginIpRateLimiter *webplugin.IPRateLimiter //ip限流
-	IPWhiteLists        []model.IPAllowList      //ip 白名单
-	UrlWhiteLists       []model.URLAllowList     //url 白名单
-	LdpUrlLists         []model.LDPUrl           //url 隐私保护
+	DefenseBean         model.HostsDefense         //防御开关 加载主机时解析
+	XmlLimitBean        model.HostsXmlLimit        //XML检测限制 加载主机时解析
+	DetectExcludes      []model.HostsDetectExclude //检测排除项 加载主机时解析
+	GraphqlConfigs      []model.HostsGraphql       //GraphQL检测配置 加载主机时解析
+	WebsocketBean       model.HostsWebsocket       //WebSocket防护配置 加载主机时解析
+	GrpcBean            model.HostsGrpc            //gRPC配置 加载主机时解析
+	GrpcConfigured      bool                       //是否有可用的gRPC配置
+	PluginIpRateLimiter *webplugin.IPRateLimiter   //ip限流
+	IPWhiteLists        []model.IPAllowList        //ip 白名单
+	IPWhiteMatcher      *utils.IPMatcher           //ip 白名单前缀树
+	UrlWhiteLists       []model.URLAllowList       //url 白名单
+	UrlWhiteMatcher     *utils.URLMatcher          //url 白名单匹配器
+	LdpUrlLists         []model.LDPUrl             //url 隐私保护
+	LdpUrlMatcher       *utils.URLMatcher          //url 隐私保护匹配器
 
-	IPBlockLists       []model.IPBlockList  //ip 黑名单
-	UrlBlockLists      []model.URLBlockList //url 黑名单
-	LoadBalanceLists   []model.LoadBalance  //负载均衡
-	LoadBalanceRuntime *LoadBalanceRuntime  //负载运行时
-	AntiCCBean         model.AntiCC         //抵御CC
+	IPBlockLists       []model.IPBlockList     //ip 黑名单
+	IPBlockMatcher     *utils.IPMatcher        //ip 黑名单前缀树
+	UrlBlockLists      []model.URLBlockList    //url 黑名单
+	UrlBlockMatcher    *utils.URLMatcher       //url 黑名单匹配器
+	LoadBalanceLists   []model.LoadBalance     //负载均衡
+	LoadBalanceRuntime *LoadBalanceRuntime     //负载运行时
+	AntiCCBean         model.AntiCC            //抵御CC
+	LoginProtectLists  []model.LoginProtect    //登录防护
+	OwaspRuleLists     []model.OwaspRule       //自定义SecLang规则
+	OwaspBean          model.HostsOwasp        //OWASP配置 加载主机时解析
+	OwaspConfigured    bool                    //是否有主机OWASP配置 没有时跟随全局开关
+	OwaspWaf           atomic.Value            //主机编译好的OWASP实例(*wafowasp.WafOWASP) 为空时使用全局实例
+	OwaspVersion       atomic.Int64            //OWASP编译版本 只保留最后一次编译的结果
+	GeoPolicyBean      model.GeoPolicy         //地域限制
+	ThreatFeedLists    []model.ThreatFeedEntry //威胁情报条目
+	ThreatFeedMatcher  *utils.IPMatcher        //威胁情报前缀树
 }
 
 // 负载处理运行对象
@@ -0,0 +1,33 @@
+package wafenginmodel
+
+import (
+	"SamWaf/model"
+	"SamWaf/utils"
+)
+
+// BuildIPAllowMatcher 编译ip白名单 匹配结果为名单下标
+func BuildIPAllowMatcher(list []model.IPAllowList) *utils.IPMatcher {
+	matcher := utils.NewIPMatcher()
+	for i := 0; i < len(list); i++ {
+		matcher.Add(list[i].Ip, i)
+	}
+	return matcher
+}
+
+// BuildIPBlockMatcher 编译ip黑名单 匹配结果为名单下标
+func BuildIPBlockMatcher(list []model.IPBlockList) *utils.IPMatcher {
+	matcher := utils.NewIPMatcher()
+	for i := 0; i < len(list); i++ {
+		matcher.Add(list[i].Ip, i)
+	}
+	return matcher
+}
+
+// BuildThreatFeedMatcher 编译威胁情报条目 匹配结果为条目下标
+func BuildThreatFeedMatcher(list []model.ThreatFeedEntry) *utils.IPMatcher {
+	matcher := utils.NewIPMatcher()
+	for i := 0; i < len(list); i++ {
+		matcher.Add(list[i].Ip, i)
+	}
+	return matcher
+}
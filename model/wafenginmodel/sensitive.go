@@ -0,0 +1,88 @@
+package wafenginmodel
+
+import (
+	"SamWaf/model"
+	goahocorasick "github.com/anknown/ahocorasick"
+	"net/url"
+	"strings"
+)
+
+// SensitiveMatcher 敏感词匹配器（按主机和检测方向划分）
+type SensitiveMatcher struct {
+	Machine *goahocorasick.Machine
+	Words   map[string][]model.Sensitive //敏感词对应的配置 同一内容可对应多条（类型、处理方式不同）
+}
+
+// NewSensitiveMatcher 构建敏感词匹配器
+func NewSensitiveMatcher(sensitiveList []model.Sensitive) (*SensitiveMatcher, error) {
+	matcher := &SensitiveMatcher{
+		Words: map[string][]model.Sensitive{},
+	}
+	var keywords [][]rune
+	for _, sensitive := range sensitiveList {
+		if sensitive.Content == "" {
+			continue
+		}
+		if _, ok := matcher.Words[sensitive.Content]; !ok {
+			keywords = append(keywords, []rune(sensitive.Content))
+		}
+		matcher.Words[sensitive.Content] = append(matcher.Words[sensitive.Content], sensitive)
+	}
+	if len(keywords) == 0 {
+		return nil, nil
+	}
+	m := new(goahocorasick.Machine)
+	err := m.Build(keywords)
+	if err != nil {
+		return nil, err
+	}
+	matcher.Machine = m
+	return matcher, nil
+}
+
+// Search 查找内容中命中的敏感词（同一内容只返回一次，包含该内容的所有配置）
+func (matcher *SensitiveMatcher) Search(content string) []model.Sensitive {
+	var result []model.Sensitive
+	if matcher == nil || matcher.Machine == nil || content == "" {
+		return result
+	}
+	terms := matcher.Machine.MultiPatternSearch([]rune(content), false)
+	found := map[string]bool{}
+	for _, term := range terms {
+		word := string(term.Word)
+		if found[word] {
+			continue
+		}
+		found[word] = true
+		result = append(result, matcher.Words[word]...)
+	}
+	return result
+}
+
+// MaskSensitiveWords 将命中的敏感词替换成*号
+func MaskSensitiveWords(content string, sensitiveList []model.Sensitive) string {
+	for _, sensitive := range sensitiveList {
+		content = strings.ReplaceAll(content, sensitive.Content, strings.Repeat("*", len([]rune(sensitive.Content))))
+	}
+	return content
+}
+
+// MaskSensitiveQuery 替换查询参数值中的敏感词 保持原有参数顺序和未命中参数的原始编码
+func MaskSensitiveQuery(rawQuery string, sensitiveList []model.Sensitive) string {
+	parts := strings.Split(rawQuery, "&")
+	for i, part := range parts {
+		key, value, found := strings.Cut(part, "=")
+		if !found || value == "" {
+			continue
+		}
+		unescapeValue, err := url.QueryUnescape(value)
+		if err != nil {
+			continue
+		}
+		maskValue := MaskSensitiveWords(unescapeValue, sensitiveList)
+		if maskValue != unescapeValue {
+			parts[i] = key + "=" + url.QueryEscape(maskValue)
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,40 @@
+package wafenginmodel
+
+import (
+	"SamWaf/enums"
+	"SamWaf/model"
+	"testing"
+)
+
+func TestSensitiveMatcherKeepsDuplicateContent(t *testing.T) {
+	matcher, err := NewSensitiveMatcher([]model.Sensitive{
+		{Type: 1, Action: enums.SENSITIVE_ACTION_LOG, Content: "foo"},
+		{Type: 2, Action: enums.SENSITIVE_ACTION_BLOCK, Content: "foo"},
+		{Type: 1, Action: enums.SENSITIVE_ACTION_REPLACE, Content: "bar"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hitList := matcher.Search("foo foo bar")
+	if len(hitList) != 3 {
+		t.Fatalf("want 3 hits, got %d", len(hitList))
+	}
+	blocked := false
+	for _, sensitive := range hitList {
+		if sensitive.Action == enums.SENSITIVE_ACTION_BLOCK {
+			blocked = true
+		}
+	}
+	if !blocked {
+		t.Errorf("block config shadowed by duplicate content")
+	}
+}
+
+func TestMaskSensitiveQuery(t *testing.T) {
+	sensitiveList := []model.Sensitive{{Content: "坏词"}}
+	rawQuery := "z=1&a=%E5%9D%8F%E8%AF%8D%E4%BD%A0&m=a%2Bb&flag"
+	want := "z=1&a=" + "%2A%2A%E4%BD%A0" + "&m=a%2Bb&flag"
+	if got := MaskSensitiveQuery(rawQuery, sensitiveList); got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+}
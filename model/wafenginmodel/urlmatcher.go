@@ -0,0 +1,39 @@
+package wafenginmodel
+
+import (
+	"SamWaf/model"
+	"SamWaf/utils"
+)
+
+// BuildURLAllowMatcher 编译url白名单 匹配结果为名单下标
+func BuildURLAllowMatcher(list []model.URLAllowList) *utils.URLMatcher {
+	rules := make([]utils.URLMatchRule, len(list))
+	for i, bean := range list {
+		rules[i] = utils.URLMatchRule{CompareType: bean.CompareType, Url: bean.Url, IgnoreCase: bean.IgnoreCase == 1,
+			Method: bean.ReqMethod, Host: bean.ReqHost, HeaderName: bean.HeaderName, HeaderValue: bean.HeaderValue,
+			QueryName: bean.QueryName, QueryValue: bean.QueryValue}
+	}
+	return utils.NewURLMatcher(rules)
+}
+
+// BuildURLBlockMatcher 编译url黑名单 匹配结果为名单下标
+func BuildURLBlockMatcher(list []model.URLBlockList) *utils.URLMatcher {
+	rules := make([]utils.URLMatchRule, len(list))
+	for i, bean := range list {
+		rules[i] = utils.URLMatchRule{CompareType: bean.CompareType, Url: bean.Url, IgnoreCase: bean.IgnoreCase == 1,
+			Method: bean.ReqMethod, Host: bean.ReqHost, HeaderName: bean.HeaderName, HeaderValue: bean.HeaderValue,
+			QueryName: bean.QueryName, QueryValue: bean.QueryValue}
+	}
+	return utils.NewURLMatcher(rules)
+}
+
+// BuildLdpUrlMatcher 编译隐私保护url 匹配结果为名单下标
+func BuildLdpUrlMatcher(list []model.LDPUrl) *utils.URLMatcher {
+	rules := make([]utils.URLMatchRule, len(list))
+	for i, bean := range list {
+		rules[i] = utils.URLMatchRule{CompareType: bean.CompareType, Url: bean.Url, IgnoreCase: bean.IgnoreCase == 1,
+			Method: bean.ReqMethod, Host: bean.ReqHost, HeaderName: bean.HeaderName, HeaderValue: bean.HeaderValue,
+			QueryName: bean.QueryName, QueryValue: bean.QueryValue}
+	}
+	return utils.NewURLMatcher(rules)
+}
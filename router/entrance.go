@@ -27,6 +27,11 @@ type ApiGroup struct {
 	LoadBalanceRouter
 	SslConfigRouter
 	BatchTaskRouter
+	LoginProtectRouter
+	OwaspRuleRouter
+	RuleTemplateRouter
+	GeoPolicyRouter
+	ThreatFeedRouter
 }
 type PublicApiGroup struct {
 	LoginRouter
@@ -0,0 +1,19 @@
+package router
+
+import (
+	"SamWaf/api"
+	"github.com/gin-gonic/gin"
+)
+
+type GeoPolicyRouter struct {
+}
+
+func (receiver *GeoPolicyRouter) InitGeoPolicyRouter(group *gin.RouterGroup) {
+	api := api.APIGroupAPP.WafGeoPolicyApi
+	router := group.Group("")
+	router.POST("/samwaf/wafhost/geopolicy/list", api.GetListApi)
+	router.GET("/samwaf/wafhost/geopolicy/detail", api.GetDetailApi)
+	router.POST("/samwaf/wafhost/geopolicy/add", api.AddApi)
+	router.GET("/samwaf/wafhost/geopolicy/del", api.DelGeoPolicyApi)
+	router.POST("/samwaf/wafhost/geopolicy/edit", api.ModifyGeoPolicyApi)
+}
@@ -0,0 +1,21 @@
+package router
+
+import (
+	"SamWaf/api"
+	"github.com/gin-gonic/gin"
+)
+
+type LoginProtectRouter struct {
+}
+
+func (receiver *LoginProtectRouter) InitLoginProtectRouter(group *gin.RouterGroup) {
+	api := api.APIGroupAPP.WafLoginProtectApi
+	router := group.Group("")
+	router.POST("/samwaf/wafhost/loginprotect/list", api.GetListApi)
+	router.GET("/samwaf/wafhost/loginprotect/detail", api.GetDetailApi)
+	router.POST("/samwaf/wafhost/loginprotect/add", api.AddApi)
+	router.GET("/samwaf/wafhost/loginprotect/del", api.DelLoginProtectApi)
+	router.POST("/samwaf/wafhost/loginprotect/edit", api.ModifyLoginProtectApi)
+	router.GET("/samwaf/wafhost/loginprotect/locklist", api.GetLockListApi)
+	router.POST("/samwaf/wafhost/loginprotect/removelock", api.RemoveLockApi)
+}
@@ -0,0 +1,19 @@
+package router
+
+import (
+	"SamWaf/api"
+	"github.com/gin-gonic/gin"
+)
+
+type OwaspRuleRouter struct {
+}
+
+func (receiver *OwaspRuleRouter) InitOwaspRuleRouter(group *gin.RouterGroup) {
+	api := api.APIGroupAPP.WafOwaspRuleApi
+	router := group.Group("")
+	router.POST("/samwaf/wafhost/owasprule/list", api.GetListApi)
+	router.GET("/samwaf/wafhost/owasprule/detail", api.GetDetailApi)
+	router.POST("/samwaf/wafhost/owasprule/add", api.AddApi)
+	router.GET("/samwaf/wafhost/owasprule/del", api.DelOwaspRuleApi)
+	router.POST("/samwaf/wafhost/owasprule/edit", api.ModifyOwaspRuleApi)
+}
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"SamWaf/api"
+	"github.com/gin-gonic/gin"
+)
+
+type RuleTemplateRouter struct {
+}
+
+func (receiver *RuleTemplateRouter) InitRuleTemplateRouter(group *gin.RouterGroup) {
+	api := api.APIGroupAPP.WafRuleTemplateApi
+	router := group.Group("")
+	router.POST("/samwaf/wafhost/ruletemplate/list", api.GetListApi)
+	router.GET("/samwaf/wafhost/ruletemplate/detail", api.GetDetailApi)
+	router.POST("/samwaf/wafhost/ruletemplate/add", api.AddApi)
+	router.GET("/samwaf/wafhost/ruletemplate/del", api.DelRuleTemplateApi)
+	router.POST("/samwaf/wafhost/ruletemplate/edit", api.ModifyRuleTemplateApi)
+	router.POST("/samwaf/wafhost/ruletemplate/attach", api.AttachApi)
+	router.GET("/samwaf/wafhost/ruletemplate/detach", api.DetachApi)
+	router.GET("/samwaf/wafhost/ruletemplate/bindlist", api.GetBindListApi)
+}
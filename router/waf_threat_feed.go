@@ -0,0 +1,20 @@
+package router
+
+import (
+	"SamWaf/api"
+	"github.com/gin-gonic/gin"
+)
+
+type ThreatFeedRouter struct {
+}
+
+func (receiver *ThreatFeedRouter) InitThreatFeedRouter(group *gin.RouterGroup) {
+	api := api.APIGroupAPP.WafThreatFeedApi
+	router := group.Group("")
+	router.POST("/samwaf/wafhost/threatfeed/list", api.GetListApi)
+	router.GET("/samwaf/wafhost/threatfeed/detail", api.GetDetailApi)
+	router.POST("/samwaf/wafhost/threatfeed/add", api.AddApi)
+	router.GET("/samwaf/wafhost/threatfeed/del", api.DelThreatFeedApi)
+	router.POST("/samwaf/wafhost/threatfeed/edit", api.ModifyThreatFeedApi)
+	router.GET("/samwaf/wafhost/threatfeed/refresh", api.RefreshThreatFeedApi)
+}
@@ -27,6 +27,13 @@ func (receiver *WafWhiteUrlService) AddApi(req request.WafAllowUrlAddReq) error
 		HostCode:    req.HostCode,
 		CompareType: req.CompareType,
 		Url:         req.Url,
+		IgnoreCase:  req.IgnoreCase,
+		ReqMethod:   req.ReqMethod,
+		ReqHost:     req.ReqHost,
+		HeaderName:  req.HeaderName,
+		HeaderValue: req.HeaderValue,
+		QueryName:   req.QueryName,
+		QueryValue:  req.QueryValue,
 		Remarks:     req.Remarks,
 	}
 	global.GWAF_LOCAL_DB.Create(bean)
@@ -48,6 +55,13 @@ func (receiver *WafWhiteUrlService) ModifyApi(req request.WafAllowUrlEditReq) er
 		"Host_Code":    req.HostCode,
 		"Compare_Type": req.CompareType,
 		"Url":          req.Url,
+		"IgnoreCase":   req.IgnoreCase,
+		"ReqMethod":    req.ReqMethod,
+		"ReqHost":      req.ReqHost,
+		"HeaderName":   req.HeaderName,
+		"HeaderValue":  req.HeaderValue,
+		"QueryName":    req.QueryName,
+		"QueryValue":   req.QueryValue,
 		"Remarks":      req.Remarks,
 		"UPDATE_TIME":  customtype.JsonTime(time.Now()),
 	}
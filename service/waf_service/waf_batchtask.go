@@ -36,6 +36,8 @@ func (receiver *WafBatchTaskService) AddApi(req request.BatchTaskAddReq) error {
 		BatchSource:        req.BatchSource,
 		BatchSourceType:    req.BatchSourceType,
 		BatchType:          req.BatchType,
+		BatchCron:          req.BatchCron,
+		BatchEnable:        req.BatchEnable,
 		Remark:             req.Remark,
 	}
 	global.GWAF_LOCAL_DB.Create(bean)
@@ -50,7 +52,7 @@ func (receiver *WafBatchTaskService) ModifyApi(req request.BatchTaskEditReq) err
 
 	var bean model.BatchTask
 	global.GWAF_LOCAL_DB.Where("batch_task_name = ?", req.BatchTaskName).Find(&bean)
-	if bean.Id != "" && bean.BatchTaskName != req.BatchTaskName {
+	if bean.Id != "" && bean.Id != req.Id {
 		return errors.New("该任务已经存在")
 	}
 
@@ -61,6 +63,8 @@ func (receiver *WafBatchTaskService) ModifyApi(req request.BatchTaskEditReq) err
 		"BatchSource":        req.BatchSource,
 		"BatchSourceType":    req.BatchSourceType,
 		"BatchType":          req.BatchType,
+		"BatchCron":          req.BatchCron,
+		"BatchEnable":        req.BatchEnable,
 		"Remark":             req.Remark,
 	}
 	err := global.GWAF_LOCAL_DB.Model(model.BatchTask{}).Where("id = ?", req.Id).Updates(beanMap).Error
@@ -114,5 +118,43 @@ func (receiver *WafBatchTaskService) DelApi(req request.BatchTaskDeleteReq) erro
 		return err
 	}
 	err = global.GWAF_LOCAL_DB.Where("id = ?", req.Id).Delete(model.BatchTask{}).Error
-	return err
+	if err != nil {
+		return err
+	}
+	//执行记录一并删除
+	return global.GWAF_LOCAL_DB.Where("batch_task_id = ?", req.Id).Delete(model.BatchTaskHistory{}).Error
+}
+
+// GetAllEnableListInner 获取所有启用的任务
+func (receiver *WafBatchTaskService) GetAllEnableListInner() []model.BatchTask {
+	var list []model.BatchTask
+	global.GWAF_LOCAL_DB.Where("batch_enable = ?", 1).Find(&list)
+	return list
+}
+
+// GetHistoryListApi 获取任务执行记录 最新的在前
+func (receiver *WafBatchTaskService) GetHistoryListApi(req request.BatchTaskHistorySearchReq) ([]model.BatchTaskHistory, int64, error) {
+	var list []model.BatchTaskHistory
+	var total int64 = 0
+	var whereField = ""
+	var whereValues []interface{}
+
+	if len(req.BatchTaskId) > 0 {
+		whereField += "batch_task_id = ?"
+		whereValues = append(whereValues, req.BatchTaskId)
+	}
+
+	global.GWAF_LOCAL_DB.Model(&model.BatchTaskHistory{}).Where(whereField, whereValues...).Order("create_time desc").Limit(req.PageSize).Offset(req.PageSize * (req.PageIndex - 1)).Find(&list)
+	global.GWAF_LOCAL_DB.Model(&model.BatchTaskHistory{}).Where(whereField, whereValues...).Count(&total)
+	return list, total, nil
+}
+
+// AddHistoryApi 记录任务执行结果
+func (receiver *WafBatchTaskService) AddHistoryApi(history *model.BatchTaskHistory) error {
+	history.Id = uuid.NewV4().String()
+	history.USER_CODE = global.GWAF_USER_CODE
+	history.Tenant_ID = global.GWAF_TENANT_ID
+	history.CREATE_TIME = customtype.JsonTime(time.Now())
+	history.UPDATE_TIME = customtype.JsonTime(time.Now())
+	return global.GWAF_LOCAL_DB.Create(history).Error
 }
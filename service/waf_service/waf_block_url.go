@@ -26,6 +26,13 @@ func (receiver *WafBlockUrlService) AddApi(req request.WafBlockUrlAddReq) error
 		},
 		HostCode:    req.HostCode,
 		Url:         req.Url,
+		IgnoreCase:  req.IgnoreCase,
+		ReqMethod:   req.ReqMethod,
+		ReqHost:     req.ReqHost,
+		HeaderName:  req.HeaderName,
+		HeaderValue: req.HeaderValue,
+		QueryName:   req.QueryName,
+		QueryValue:  req.QueryValue,
 		CompareType: req.CompareType,
 		Remarks:     req.Remarks,
 	}
@@ -47,6 +54,13 @@ func (receiver *WafBlockUrlService) ModifyApi(req request.WafBlockUrlEditReq) er
 	modfiyMap := map[string]interface{}{
 		"Host_Code":   req.HostCode,
 		"Url":         req.Url,
+		"IgnoreCase":  req.IgnoreCase,
+		"ReqMethod":   req.ReqMethod,
+		"ReqHost":     req.ReqHost,
+		"HeaderName":  req.HeaderName,
+		"HeaderValue": req.HeaderValue,
+		"QueryName":   req.QueryName,
+		"QueryValue":  req.QueryValue,
 		"Remarks":     req.Remarks,
 		"CompareType": req.CompareType,
 		"UPDATE_TIME": customtype.JsonTime(time.Now()),
@@ -0,0 +1,109 @@
+package waf_service
+
+import (
+	"SamWaf/customtype"
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/baseorm"
+	"SamWaf/model/request"
+	"errors"
+	uuid "github.com/satori/go.uuid"
+	"time"
+)
+
+type WafGeoPolicyService struct{}
+
+var WafGeoPolicyServiceApp = new(WafGeoPolicyService)
+
+func (receiver *WafGeoPolicyService) AddApi(req request.WafGeoPolicyAddReq) error {
+	var bean = &model.GeoPolicy{
+		BaseOrm: baseorm.BaseOrm{
+			Id:          uuid.NewV4().String(),
+			USER_CODE:   global.GWAF_USER_CODE,
+			Tenant_ID:   global.GWAF_TENANT_ID,
+			CREATE_TIME: customtype.JsonTime(time.Now()),
+			UPDATE_TIME: customtype.JsonTime(time.Now()),
+		},
+		HostCode:  req.HostCode,
+		Mode:      req.Mode,
+		Regions:   req.Regions,
+		BotBypass: req.BotBypass,
+		Action:    req.Action,
+		Status:    req.Status,
+		Remarks:   req.Remarks,
+	}
+	global.GWAF_LOCAL_DB.Create(bean)
+	return nil
+}
+
+func (receiver *WafGeoPolicyService) CheckIsExistApi(req request.WafGeoPolicyAddReq) error {
+	return global.GWAF_LOCAL_DB.First(&model.GeoPolicy{}, "host_code = ?", req.HostCode).Error
+}
+func (receiver *WafGeoPolicyService) ModifyApi(req request.WafGeoPolicyEditReq) error {
+	var bean model.GeoPolicy
+	global.GWAF_LOCAL_DB.Where("host_code = ? ", req.HostCode).Find(&bean)
+	if bean.Id != "" && bean.Id != req.Id {
+		return errors.New("当前网站已经存在地域限制")
+	}
+	beanMap := map[string]interface{}{
+		"Host_Code":   req.HostCode,
+		"Mode":        req.Mode,
+		"Regions":     req.Regions,
+		"BotBypass":   req.BotBypass,
+		"Action":      req.Action,
+		"Status":      req.Status,
+		"Remarks":     req.Remarks,
+		"UPDATE_TIME": customtype.JsonTime(time.Now()),
+	}
+	err := global.GWAF_LOCAL_DB.Model(model.GeoPolicy{}).Where("id = ?", req.Id).Updates(beanMap).Error
+
+	return err
+}
+func (receiver *WafGeoPolicyService) GetDetailApi(req request.WafGeoPolicyDetailReq) model.GeoPolicy {
+	var bean model.GeoPolicy
+	global.GWAF_LOCAL_DB.Where("id=?", req.Id).Find(&bean)
+	return bean
+}
+func (receiver *WafGeoPolicyService) GetDetailByIdApi(id string) model.GeoPolicy {
+	var bean model.GeoPolicy
+	global.GWAF_LOCAL_DB.Where("id=?", id).Find(&bean)
+	return bean
+}
+
+// GetDetailByHostCodeApi 获取网站的地域限制
+func (receiver *WafGeoPolicyService) GetDetailByHostCodeApi(hostCode string) model.GeoPolicy {
+	var bean model.GeoPolicy
+	global.GWAF_LOCAL_DB.Where("host_code = ? ", hostCode).Limit(1).Find(&bean)
+	return bean
+}
+func (receiver *WafGeoPolicyService) GetListApi(req request.WafGeoPolicySearchReq) ([]model.GeoPolicy, int64, error) {
+	var list []model.GeoPolicy
+	var total int64 = 0
+
+	/*where条件*/
+	var whereField = ""
+	var whereValues []interface{}
+	//where字段
+	whereField = ""
+	if len(req.HostCode) > 0 {
+		whereField = whereField + " host_code=? "
+	}
+	//where字段赋值
+	if len(req.HostCode) > 0 {
+		whereValues = append(whereValues, req.HostCode)
+	}
+
+	global.GWAF_LOCAL_DB.Model(&model.GeoPolicy{}).Where(whereField, whereValues...).Limit(req.PageSize).Offset(req.PageSize * (req.PageIndex - 1)).Find(&list)
+	global.GWAF_LOCAL_DB.Model(&model.GeoPolicy{}).Where(whereField, whereValues...).Count(&total)
+
+	return list, total, nil
+}
+func (receiver *WafGeoPolicyService) DelApi(req request.WafGeoPolicyDelReq) error {
+	var bean model.GeoPolicy
+	err := global.GWAF_LOCAL_DB.Where("id = ?", req.Id).First(&bean).Error
+	if err != nil {
+		return err
+	}
+	err = global.GWAF_LOCAL_DB.Where("id = ?", req.Id).Delete(model.GeoPolicy{}).Error
+	return err
+}
@@ -54,6 +54,16 @@ func (receiver *WafHostService) AddApi(wafHostAddReq request.WafHostAddReq) (str
 		UnrestrictedPort:    wafHostAddReq.UnrestrictedPort,
 		BindSslId:           wafHostAddReq.BindSslId,
 		AutoJumpHTTPS:       wafHostAddReq.AutoJumpHTTPS,
+		SSRF_ALLOW_DOMAINS:  wafHostAddReq.SSRF_ALLOW_DOMAINS,
+		XML_LIMIT_JSON:      wafHostAddReq.XML_LIMIT_JSON,
+		DETECT_EXCLUDE_JSON: wafHostAddReq.DETECT_EXCLUDE_JSON,
+		INSPECT_HEADERS:     wafHostAddReq.INSPECT_HEADERS,
+		INSPECT_COOKIES:     wafHostAddReq.INSPECT_COOKIES,
+		GRAPHQL_JSON:        wafHostAddReq.GRAPHQL_JSON,
+		WEBSOCKET_JSON:      wafHostAddReq.WEBSOCKET_JSON,
+		UPSTREAM_PROTOCOL:   wafHostAddReq.UPSTREAM_PROTOCOL,
+		GRPC_JSON:           wafHostAddReq.GRPC_JSON,
+		OWASP_JSON:          wafHostAddReq.OWASP_JSON,
 	}
 	global.GWAF_LOCAL_DB.Create(wafHost)
 	return wafHost.Code, nil
@@ -98,6 +108,16 @@ func (receiver *WafHostService) ModifyApi(wafHostEditReq request.WafHostEditReq)
 		"UnrestrictedPort":    wafHostEditReq.UnrestrictedPort,
 		"BindSslId":           wafHostEditReq.BindSslId,
 		"AutoJumpHTTPS":       wafHostEditReq.AutoJumpHTTPS,
+		"SSRF_ALLOW_DOMAINS":  wafHostEditReq.SSRF_ALLOW_DOMAINS,
+		"XML_LIMIT_JSON":      wafHostEditReq.XML_LIMIT_JSON,
+		"DETECT_EXCLUDE_JSON": wafHostEditReq.DETECT_EXCLUDE_JSON,
+		"INSPECT_HEADERS":     wafHostEditReq.INSPECT_HEADERS,
+		"INSPECT_COOKIES":     wafHostEditReq.INSPECT_COOKIES,
+		"GRAPHQL_JSON":        wafHostEditReq.GRAPHQL_JSON,
+		"WEBSOCKET_JSON":      wafHostEditReq.WEBSOCKET_JSON,
+		"UPSTREAM_PROTOCOL":   wafHostEditReq.UPSTREAM_PROTOCOL,
+		"GRPC_JSON":           wafHostEditReq.GRPC_JSON,
+		"OWASP_JSON":          wafHostEditReq.OWASP_JSON,
 	}
 	err := global.GWAF_LOCAL_DB.Debug().Model(model.Hosts{}).Where("CODE=?", wafHostEditReq.CODE).Updates(hostMap).Error
 
@@ -181,6 +201,19 @@ func (receiver *WafHostService) DelHostApi(req request.WafHostDelReq) (model.Hos
 	err = global.GWAF_LOCAL_DB.Where("Host_Code = ?", req.CODE).Delete(model.IPAllowList{}).Error
 	//删除白名单URL
 	err = global.GWAF_LOCAL_DB.Where("Host_Code = ?", req.CODE).Delete(model.URLAllowList{}).Error
+	//删除敏感词
+	err = global.GWAF_LOCAL_DB.Where("Host_Code = ?", req.CODE).Delete(model.Sensitive{}).Error
+	//删除登录防护
+	err = global.GWAF_LOCAL_DB.Where("Host_Code = ?", req.CODE).Delete(model.LoginProtect{}).Error
+	//删除自定义SecLang规则
+	err = global.GWAF_LOCAL_DB.Where("Host_Code = ?", req.CODE).Delete(model.OwaspRule{}).Error
+	//删除规则模板挂载
+	err = global.GWAF_LOCAL_DB.Where("Host_Code = ?", req.CODE).Delete(model.RuleTemplateBind{}).Error
+	//删除地域限制
+	err = global.GWAF_LOCAL_DB.Where("Host_Code = ?", req.CODE).Delete(model.GeoPolicy{}).Error
+	//删除威胁情报
+	err = global.GWAF_LOCAL_DB.Where("Host_Code = ?", req.CODE).Delete(model.ThreatFeed{}).Error
+	err = global.GWAF_LOCAL_DB.Where("Host_Code = ?", req.CODE).Delete(model.ThreatFeedEntry{}).Error
 	return webhost, err
 }
 func (receiver *WafHostService) ModifyGuardStatusApi(req request.WafHostGuardStatusReq) error {
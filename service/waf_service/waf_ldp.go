@@ -27,6 +27,13 @@ func (receiver *WafLdpUrlService) AddApi(req request.WafLdpUrlAddReq) error {
 		HostCode:    req.HostCode,
 		CompareType: req.CompareType,
 		Url:         req.Url,
+		IgnoreCase:  req.IgnoreCase,
+		ReqMethod:   req.ReqMethod,
+		ReqHost:     req.ReqHost,
+		HeaderName:  req.HeaderName,
+		HeaderValue: req.HeaderValue,
+		QueryName:   req.QueryName,
+		QueryValue:  req.QueryValue,
 		Remarks:     req.Remarks,
 	}
 	global.GWAF_LOCAL_DB.Create(bean)
@@ -47,6 +54,13 @@ func (receiver *WafLdpUrlService) ModifyApi(req request.WafLdpUrlEditReq) error
 	ipWhiteMap := map[string]interface{}{
 		"Host_Code":    req.HostCode,
 		"Url":          req.Url,
+		"IgnoreCase":   req.IgnoreCase,
+		"ReqMethod":    req.ReqMethod,
+		"ReqHost":      req.ReqHost,
+		"HeaderName":   req.HeaderName,
+		"HeaderValue":  req.HeaderValue,
+		"QueryName":    req.QueryName,
+		"QueryValue":   req.QueryValue,
 		"Remarks":      req.Remarks,
 		"Compare_Type": req.CompareType,
 		"UPDATE_TIME":  customtype.JsonTime(time.Now()),
@@ -0,0 +1,124 @@
+package waf_service
+
+import (
+	"SamWaf/customtype"
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/baseorm"
+	"SamWaf/model/request"
+	"errors"
+	uuid "github.com/satori/go.uuid"
+	"strings"
+	"time"
+)
+
+type WafLoginProtectService struct{}
+
+var WafLoginProtectServiceApp = new(WafLoginProtectService)
+
+func (receiver *WafLoginProtectService) AddApi(req request.WafLoginProtectAddReq) error {
+	var bean = &model.LoginProtect{
+		BaseOrm: baseorm.BaseOrm{
+			Id:          uuid.NewV4().String(),
+			USER_CODE:   global.GWAF_USER_CODE,
+			Tenant_ID:   global.GWAF_TENANT_ID,
+			CREATE_TIME: customtype.JsonTime(time.Now()),
+			UPDATE_TIME: customtype.JsonTime(time.Now()),
+		},
+		HostCode:        req.HostCode,
+		Url:             req.Url,
+		Method:          strings.ToUpper(req.Method),
+		UserField:       req.UserField,
+		FailStatusCodes: req.FailStatusCodes,
+		FailRedirect:    req.FailRedirect,
+		FailBodyPattern: req.FailBodyPattern,
+		WindowSeconds:   req.WindowSeconds,
+		IpMaxFail:       req.IpMaxFail,
+		UserMaxFail:     req.UserMaxFail,
+		IpUserMaxFail:   req.IpUserMaxFail,
+		IpMaxUser:       req.IpMaxUser,
+		Action:          req.Action,
+		LockMinutes:     req.LockMinutes,
+		Remarks:         req.Remarks,
+	}
+	global.GWAF_LOCAL_DB.Create(bean)
+	return nil
+}
+
+func (receiver *WafLoginProtectService) CheckIsExistApi(req request.WafLoginProtectAddReq) error {
+	return global.GWAF_LOCAL_DB.First(&model.LoginProtect{}, "host_code = ? and url = ? and method = ?", req.HostCode,
+		req.Url, strings.ToUpper(req.Method)).Error
+}
+func (receiver *WafLoginProtectService) ModifyApi(req request.WafLoginProtectEditReq) error {
+	var bean model.LoginProtect
+	global.GWAF_LOCAL_DB.Where("host_code = ? and url = ? and method = ?", req.HostCode,
+		req.Url, strings.ToUpper(req.Method)).Find(&bean)
+	if bean.Id != "" && bean.Id != req.Id {
+		return errors.New("当前登录地址已经存在")
+	}
+	beanMap := map[string]interface{}{
+		"Host_Code":       req.HostCode,
+		"Url":             req.Url,
+		"Method":          strings.ToUpper(req.Method),
+		"UserField":       req.UserField,
+		"FailStatusCodes": req.FailStatusCodes,
+		"FailRedirect":    req.FailRedirect,
+		"FailBodyPattern": req.FailBodyPattern,
+		"WindowSeconds":   req.WindowSeconds,
+		"IpMaxFail":       req.IpMaxFail,
+		"UserMaxFail":     req.UserMaxFail,
+		"IpUserMaxFail":   req.IpUserMaxFail,
+		"IpMaxUser":       req.IpMaxUser,
+		"Action":          req.Action,
+		"LockMinutes":     req.LockMinutes,
+		"Remarks":         req.Remarks,
+		"UPDATE_TIME":     customtype.JsonTime(time.Now()),
+	}
+	err := global.GWAF_LOCAL_DB.Model(model.LoginProtect{}).Where("id = ?", req.Id).Updates(beanMap).Error
+
+	return err
+}
+func (receiver *WafLoginProtectService) GetDetailApi(req request.WafLoginProtectDetailReq) model.LoginProtect {
+	var bean model.LoginProtect
+	global.GWAF_LOCAL_DB.Where("id=?", req.Id).Find(&bean)
+	return bean
+}
+func (receiver *WafLoginProtectService) GetDetailByIdApi(id string) model.LoginProtect {
+	var bean model.LoginProtect
+	global.GWAF_LOCAL_DB.Where("id=?", id).Find(&bean)
+	return bean
+}
+func (receiver *WafLoginProtectService) GetListApi(req request.WafLoginProtectSearchReq) ([]model.LoginProtect, int64, error) {
+	var list []model.LoginProtect
+	var total int64 = 0
+
+	/*where条件*/
+	var whereField = ""
+	var whereValues []interface{}
+	//where字段
+	whereField = ""
+	if len(req.HostCode) > 0 {
+		if len(whereField) > 0 {
+			whereField = whereField + " and "
+		}
+		whereField = whereField + " host_code=? "
+	}
+	//where字段赋值
+	if len(req.HostCode) > 0 {
+		whereValues = append(whereValues, req.HostCode)
+	}
+
+	global.GWAF_LOCAL_DB.Model(&model.LoginProtect{}).Where(whereField, whereValues...).Limit(req.PageSize).Offset(req.PageSize * (req.PageIndex - 1)).Find(&list)
+	global.GWAF_LOCAL_DB.Model(&model.LoginProtect{}).Where(whereField, whereValues...).Count(&total)
+
+	return list, total, nil
+}
+func (receiver *WafLoginProtectService) DelApi(req request.WafLoginProtectDelReq) error {
+	var bean model.LoginProtect
+	err := global.GWAF_LOCAL_DB.Where("id = ?", req.Id).First(&bean).Error
+	if err != nil {
+		return err
+	}
+	err = global.GWAF_LOCAL_DB.Where("id = ?", req.Id).Delete(model.LoginProtect{}).Error
+	return err
+}
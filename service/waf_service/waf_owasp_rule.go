@@ -0,0 +1,121 @@
+package waf_service
+
+import (
+	"SamWaf/customtype"
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/baseorm"
+	"SamWaf/model/request"
+	"errors"
+	uuid "github.com/satori/go.uuid"
+	"time"
+)
+
+type WafOwaspRuleService struct{}
+
+var WafOwaspRuleServiceApp = new(WafOwaspRuleService)
+
+func (receiver *WafOwaspRuleService) AddApi(req request.WafOwaspRuleAddReq) error {
+	var bean = &model.OwaspRule{
+		BaseOrm: baseorm.BaseOrm{
+			Id:          uuid.NewV4().String(),
+			USER_CODE:   global.GWAF_USER_CODE,
+			Tenant_ID:   global.GWAF_TENANT_ID,
+			CREATE_TIME: customtype.JsonTime(time.Now()),
+			UPDATE_TIME: customtype.JsonTime(time.Now()),
+		},
+		HostCode: req.HostCode,
+		RuleName: req.RuleName,
+		Content:  req.Content,
+		Position: req.Position,
+		Status:   req.Status,
+		Remarks:  req.Remarks,
+	}
+	global.GWAF_LOCAL_DB.Create(bean)
+	return nil
+}
+
+func (receiver *WafOwaspRuleService) CheckIsExistApi(req request.WafOwaspRuleAddReq) error {
+	return global.GWAF_LOCAL_DB.First(&model.OwaspRule{}, "host_code = ? and rule_name = ?", req.HostCode,
+		req.RuleName).Error
+}
+func (receiver *WafOwaspRuleService) ModifyApi(req request.WafOwaspRuleEditReq) error {
+	var bean model.OwaspRule
+	global.GWAF_LOCAL_DB.Where("host_code = ? and rule_name = ?", req.HostCode,
+		req.RuleName).Find(&bean)
+	if bean.Id != "" && bean.Id != req.Id {
+		return errors.New("当前规则名称已经存在")
+	}
+	beanMap := map[string]interface{}{
+		"Host_Code":   req.HostCode,
+		"RuleName":    req.RuleName,
+		"Content":     req.Content,
+		"Position":    req.Position,
+		"Status":      req.Status,
+		"Remarks":     req.Remarks,
+		"UPDATE_TIME": customtype.JsonTime(time.Now()),
+	}
+	err := global.GWAF_LOCAL_DB.Model(model.OwaspRule{}).Where("id = ?", req.Id).Updates(beanMap).Error
+
+	return err
+}
+func (receiver *WafOwaspRuleService) GetDetailApi(req request.WafOwaspRuleDetailReq) model.OwaspRule {
+	var bean model.OwaspRule
+	global.GWAF_LOCAL_DB.Where("id=?", req.Id).Find(&bean)
+	return bean
+}
+func (receiver *WafOwaspRuleService) GetDetailByIdApi(id string) model.OwaspRule {
+	var bean model.OwaspRule
+	global.GWAF_LOCAL_DB.Where("id=?", id).Find(&bean)
+	return bean
+}
+
+// GetListByHostCodeApi 获取网站的全部规则 按创建时间排序
+func (receiver *WafOwaspRuleService) GetListByHostCodeApi(hostCode string) []model.OwaspRule {
+	var list []model.OwaspRule
+	global.GWAF_LOCAL_DB.Where("host_code = ? ", hostCode).Order("create_time asc").Find(&list)
+	return list
+}
+func (receiver *WafOwaspRuleService) GetListApi(req request.WafOwaspRuleSearchReq) ([]model.OwaspRule, int64, error) {
+	var list []model.OwaspRule
+	var total int64 = 0
+
+	/*where条件*/
+	var whereField = ""
+	var whereValues []interface{}
+	//where字段
+	whereField = ""
+	if len(req.HostCode) > 0 {
+		if len(whereField) > 0 {
+			whereField = whereField + " and "
+		}
+		whereField = whereField + " host_code=? "
+	}
+	if len(req.RuleName) > 0 {
+		if len(whereField) > 0 {
+			whereField = whereField + " and "
+		}
+		whereField = whereField + " rule_name like ? "
+	}
+	//where字段赋值
+	if len(req.HostCode) > 0 {
+		whereValues = append(whereValues, req.HostCode)
+	}
+	if len(req.RuleName) > 0 {
+		whereValues = append(whereValues, "%"+req.RuleName+"%")
+	}
+
+	global.GWAF_LOCAL_DB.Model(&model.OwaspRule{}).Where(whereField, whereValues...).Order("create_time asc").Limit(req.PageSize).Offset(req.PageSize * (req.PageIndex - 1)).Find(&list)
+	global.GWAF_LOCAL_DB.Model(&model.OwaspRule{}).Where(whereField, whereValues...).Count(&total)
+
+	return list, total, nil
+}
+func (receiver *WafOwaspRuleService) DelApi(req request.WafOwaspRuleDelReq) error {
+	var bean model.OwaspRule
+	err := global.GWAF_LOCAL_DB.Where("id = ?", req.Id).First(&bean).Error
+	if err != nil {
+		return err
+	}
+	err = global.GWAF_LOCAL_DB.Where("id = ?", req.Id).Delete(model.OwaspRule{}).Error
+	return err
+}
@@ -36,6 +36,7 @@ func (receiver *WafRuleService) AddApi(wafRuleAddReq request.WafRuleAddReq, rule
 		IsPublicRule:    0,
 		IsManualRule:    wafRuleAddReq.IsManualRule,
 		RuleStatus:      1,
+		RuleTestCases:   wafRuleAddReq.RuleTestCases,
 	}
 	global.GWAF_LOCAL_DB.Create(wafRule)
 	return nil
@@ -79,6 +80,7 @@ func (receiver *WafRuleService) ModifyApi(wafRuleEditReq request.WafRuleEditReq,
 		"IsPublicRule":    0,
 		"IsManualRule":    wafRuleEditReq.IsManualRule,
 		"RuleStatus":      "1",
+		"RuleTestCases":   wafRuleEditReq.RuleTestCases,
 		"UPDATE_TIME":     customtype.JsonTime(time.Now()),
 	}
 	err := global.GWAF_LOCAL_DB.Model(model.Rules{}).Where("rule_code=?", wafRuleEditReq.CODE).Updates(ruleMap).Error
@@ -141,6 +143,13 @@ func (receiver *WafRuleService) GetListByHostCodeApi(wafRuleSearchReq request.Wa
 	return rules, total, nil
 }
 
+// GetEffectiveListByHostCodeApi 获取网站引擎中加载的全部规则
+func (receiver *WafRuleService) GetEffectiveListByHostCodeApi(hostCode string) []model.Rules {
+	var rules []model.Rules
+	global.GWAF_LOCAL_DB.Where("host_code = ? and rule_status<>999", hostCode).Find(&rules)
+	return rules
+}
+
 func (receiver *WafRuleService) DelRuleApi(req request.WafRuleDelReq) error {
 	var rule model.Rules
 	err := global.GWAF_LOCAL_DB.Where("rule_code = ?", req.CODE).First(&rule).Error
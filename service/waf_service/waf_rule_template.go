@@ -0,0 +1,230 @@
+package waf_service
+
+import (
+	"SamWaf/customtype"
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/baseorm"
+	"SamWaf/model/request"
+	"errors"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+type WafRuleTemplateService struct{}
+
+var WafRuleTemplateServiceApp = new(WafRuleTemplateService)
+
+func (receiver *WafRuleTemplateService) AddApi(req request.WafRuleTemplateAddReq) error {
+	var bean = &model.RuleTemplate{
+		BaseOrm: baseorm.BaseOrm{
+			Id:          uuid.NewV4().String(),
+			USER_CODE:   global.GWAF_USER_CODE,
+			Tenant_ID:   global.GWAF_TENANT_ID,
+			CREATE_TIME: customtype.JsonTime(time.Now()),
+			UPDATE_TIME: customtype.JsonTime(time.Now()),
+		},
+		TemplateName:    req.TemplateName,
+		RuleContent:     req.RuleContent,
+		Params:          req.Params,
+		TemplateVersion: 1,
+		Remarks:         req.Remarks,
+	}
+	global.GWAF_LOCAL_DB.Create(bean)
+	return nil
+}
+
+func (receiver *WafRuleTemplateService) CheckIsExistApi(req request.WafRuleTemplateAddReq) error {
+	return global.GWAF_LOCAL_DB.First(&model.RuleTemplate{}, "template_name = ?", req.TemplateName).Error
+}
+
+/*
+*
+编辑模板 同时更新已挂载网站生成的规则，ruleContents 与 binds 一一对应，在同一个事务中完成
+*/
+func (receiver *WafRuleTemplateService) ModifyApi(req request.WafRuleTemplateEditReq, template model.RuleTemplate, binds []model.RuleTemplateBind, ruleContents []string) error {
+	var bean model.RuleTemplate
+	global.GWAF_LOCAL_DB.Where("template_name = ?", req.TemplateName).Find(&bean)
+	if bean.Id != "" && bean.Id != req.Id {
+		return errors.New("当前模板名称已经存在")
+	}
+	beanMap := map[string]interface{}{
+		"TemplateName":    req.TemplateName,
+		"RuleContent":     req.RuleContent,
+		"Params":          req.Params,
+		"TemplateVersion": receiver.GetDetailByIdApi(req.Id).TemplateVersion + 1,
+		"Remarks":         req.Remarks,
+		"UPDATE_TIME":     customtype.JsonTime(time.Now()),
+	}
+	return global.GWAF_LOCAL_DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(model.RuleTemplate{}).Where("id = ?", req.Id).Updates(beanMap).Error
+		if err != nil {
+			return err
+		}
+		return saveBindRules(tx, template, binds, ruleContents)
+	})
+}
+func (receiver *WafRuleTemplateService) GetDetailApi(req request.WafRuleTemplateDetailReq) model.RuleTemplate {
+	var bean model.RuleTemplate
+	global.GWAF_LOCAL_DB.Where("id=?", req.Id).Find(&bean)
+	return bean
+}
+func (receiver *WafRuleTemplateService) GetDetailByIdApi(id string) model.RuleTemplate {
+	var bean model.RuleTemplate
+	global.GWAF_LOCAL_DB.Where("id=?", id).Find(&bean)
+	return bean
+}
+func (receiver *WafRuleTemplateService) GetListApi(req request.WafRuleTemplateSearchReq) ([]model.RuleTemplate, int64, error) {
+	var list []model.RuleTemplate
+	var total int64 = 0
+
+	/*where条件*/
+	var whereField = ""
+	var whereValues []interface{}
+	//where字段
+	whereField = ""
+	if len(req.TemplateName) > 0 {
+		whereField = whereField + " template_name like ? "
+	}
+	//where字段赋值
+	if len(req.TemplateName) > 0 {
+		whereValues = append(whereValues, "%"+req.TemplateName+"%")
+	}
+
+	global.GWAF_LOCAL_DB.Model(&model.RuleTemplate{}).Where(whereField, whereValues...).Order("create_time asc").Limit(req.PageSize).Offset(req.PageSize * (req.PageIndex - 1)).Find(&list)
+	global.GWAF_LOCAL_DB.Model(&model.RuleTemplate{}).Where(whereField, whereValues...).Count(&total)
+
+	return list, total, nil
+}
+
+/*
+*
+删除模板 binds 为模板已挂载的网站，取消挂载和删除模板在同一个事务中完成
+*/
+func (receiver *WafRuleTemplateService) DelApi(req request.WafRuleTemplateDelReq, binds []model.RuleTemplateBind) error {
+	var bean model.RuleTemplate
+	err := global.GWAF_LOCAL_DB.Where("id = ?", req.Id).First(&bean).Error
+	if err != nil {
+		return err
+	}
+	return global.GWAF_LOCAL_DB.Transaction(func(tx *gorm.DB) error {
+		for _, bind := range binds {
+			if err := delBind(tx, bind); err != nil {
+				return err
+			}
+		}
+		return tx.Where("id = ?", req.Id).Delete(model.RuleTemplate{}).Error
+	})
+}
+
+// GetBindListApi 获取模板挂载的网站
+func (receiver *WafRuleTemplateService) GetBindListApi(templateId string) []model.RuleTemplateBind {
+	var list []model.RuleTemplateBind
+	global.GWAF_LOCAL_DB.Where("template_id = ?", templateId).Order("create_time asc").Find(&list)
+	return list
+}
+
+// GetBindApi 获取模板在网站上的挂载信息
+func (receiver *WafRuleTemplateService) GetBindApi(templateId string, hostCode string) model.RuleTemplateBind {
+	var bean model.RuleTemplateBind
+	global.GWAF_LOCAL_DB.Where("template_id = ? and host_code = ?", templateId, hostCode).Find(&bean)
+	return bean
+}
+
+/*
+*
+保存模板生成的网站规则 ruleContents 与 binds 一一对应，在同一个事务中完成
+*/
+func (receiver *WafRuleTemplateService) SaveBindRulesApi(template model.RuleTemplate, binds []model.RuleTemplateBind, ruleContents []string) error {
+	return global.GWAF_LOCAL_DB.Transaction(func(tx *gorm.DB) error {
+		return saveBindRules(tx, template, binds, ruleContents)
+	})
+}
+
+func saveBindRules(tx *gorm.DB, template model.RuleTemplate, binds []model.RuleTemplateBind, ruleContents []string) error {
+	for i, bind := range binds {
+		if err := saveBindRule(tx, template, bind, ruleContents[i]); err != nil {
+			return errors.New("网站" + bind.HostCode + ":" + err.Error())
+		}
+	}
+	return nil
+}
+
+// 保存一个网站的规则 未挂载时新建规则和挂载信息，已挂载时更新规则内容和参数值
+func saveBindRule(tx *gorm.DB, template model.RuleTemplate, bind model.RuleTemplateBind, ruleContent string) error {
+	if bind.Id == "" {
+		bind.Id = uuid.NewV4().String()
+		bind.USER_CODE = global.GWAF_USER_CODE
+		bind.Tenant_ID = global.GWAF_TENANT_ID
+		bind.CREATE_TIME = customtype.JsonTime(time.Now())
+		bind.UPDATE_TIME = customtype.JsonTime(time.Now())
+		bind.TemplateId = template.Id
+		var rule = &model.Rules{
+			BaseOrm: baseorm.BaseOrm{
+				Id:          uuid.NewV4().String(),
+				USER_CODE:   global.GWAF_USER_CODE,
+				Tenant_ID:   global.GWAF_TENANT_ID,
+				CREATE_TIME: customtype.JsonTime(time.Now()),
+				UPDATE_TIME: customtype.JsonTime(time.Now()),
+			},
+			HostCode:        bind.HostCode,
+			RuleCode:        bind.RuleCode,
+			RuleName:        template.TemplateName,
+			RuleContent:     ruleContent,
+			RuleVersionName: "初版",
+			RuleVersion:     1,
+			IsPublicRule:    1,
+			IsManualRule:    1,
+			RuleStatus:      1,
+			TemplateId:      template.Id,
+		}
+		err := tx.Create(rule).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&bind).Error
+	}
+	var rule model.Rules
+	tx.Where("rule_code = ?", bind.RuleCode).Find(&rule)
+	ruleMap := map[string]interface{}{
+		"RuleName":    template.TemplateName,
+		"RuleContent": ruleContent,
+		"RuleVersion": rule.RuleVersion + 1,
+		"RuleStatus":  1,
+		"UPDATE_TIME": customtype.JsonTime(time.Now()),
+	}
+	err := tx.Model(model.Rules{}).Where("rule_code = ?", bind.RuleCode).Updates(ruleMap).Error
+	if err != nil {
+		return err
+	}
+	bindMap := map[string]interface{}{
+		"ParamValues": bind.ParamValues,
+		"UPDATE_TIME": customtype.JsonTime(time.Now()),
+	}
+	return tx.Model(model.RuleTemplateBind{}).Where("id = ?", bind.Id).Updates(bindMap).Error
+}
+
+// DelBindApi 取消挂载 生成的规则一并删除
+func (receiver *WafRuleTemplateService) DelBindApi(bind model.RuleTemplateBind) error {
+	return global.GWAF_LOCAL_DB.Transaction(func(tx *gorm.DB) error {
+		return delBind(tx, bind)
+	})
+}
+
+func delBind(tx *gorm.DB, bind model.RuleTemplateBind) error {
+	ruleMap := map[string]interface{}{
+		"RuleStatus":  "999",
+		"RuleVersion": 999999,
+	}
+	err := tx.Model(model.Rules{}).Where("rule_code = ?", bind.RuleCode).Updates(ruleMap).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("id = ?", bind.Id).Delete(model.RuleTemplateBind{}).Error
+}
+
+// DelBindByRuleCodeApi 删除模板生成的规则时同时删除挂载信息
+func (receiver *WafRuleTemplateService) DelBindByRuleCodeApi(ruleCode string) error {
+	return global.GWAF_LOCAL_DB.Where("rule_code = ?", ruleCode).Delete(model.RuleTemplateBind{}).Error
+}
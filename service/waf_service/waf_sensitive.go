@@ -24,30 +24,36 @@ func (receiver *WafSensitiveService) AddApi(req request.WafSensitiveAddReq) erro
 			CREATE_TIME: customtype.JsonTime(time.Now()),
 			UPDATE_TIME: customtype.JsonTime(time.Now()),
 		},
-		Type:    req.Type,
-		Content: req.Content,
-		Remarks: req.Remarks,
+		HostCode:       req.HostCode,
+		Type:           req.Type,
+		CheckDirection: req.CheckDirection,
+		Action:         req.Action,
+		Content:        req.Content,
+		Remarks:        req.Remarks,
 	}
 	global.GWAF_LOCAL_DB.Create(bean)
 	return nil
 }
 
 func (receiver *WafSensitiveService) CheckIsExistApi(req request.WafSensitiveAddReq) error {
-	return global.GWAF_LOCAL_DB.First(&model.Sensitive{}, "type = ? and content= ?", req.Type,
+	return global.GWAF_LOCAL_DB.First(&model.Sensitive{}, "host_code = ? and type = ? and content= ?", req.HostCode, req.Type,
 		req.Content).Error
 }
 func (receiver *WafSensitiveService) ModifyApi(req request.WafSensitiveEditReq) error {
 	var bean model.Sensitive
-	global.GWAF_LOCAL_DB.Where("type = ? and content= ?", req.Type,
+	global.GWAF_LOCAL_DB.Where("host_code = ? and type = ? and content= ?", req.HostCode, req.Type,
 		req.Content).Find(&bean)
-	if bean.Id != "" && bean.Type != req.Type && bean.Content != req.Content {
+	if bean.Id != "" && bean.Id != req.Id {
 		return errors.New("当前敏感词已经存在")
 	}
 	beanMap := map[string]interface{}{
-		"Type":        req.Type,
-		"Content":     req.Content,
-		"Remarks":     req.Remarks,
-		"UPDATE_TIME": customtype.JsonTime(time.Now()),
+		"HostCode":       req.HostCode,
+		"Type":           req.Type,
+		"CheckDirection": req.CheckDirection,
+		"Action":         req.Action,
+		"Content":        req.Content,
+		"Remarks":        req.Remarks,
+		"UPDATE_TIME":    customtype.JsonTime(time.Now()),
 	}
 	err := global.GWAF_LOCAL_DB.Model(model.Sensitive{}).Where("id = ?", req.Id).Updates(beanMap).Error
 
@@ -71,6 +77,12 @@ func (receiver *WafSensitiveService) GetListApi(req request.WafSensitiveSearchRe
 	var whereValues []interface{}
 	//where字段
 	whereField = ""
+	if len(req.HostCode) > 0 {
+		if len(whereField) > 0 {
+			whereField = whereField + " and "
+		}
+		whereField = whereField + " host_code =? "
+	}
 	if req.Type > 0 {
 		if len(whereField) > 0 {
 			whereField = whereField + " and "
@@ -90,6 +102,9 @@ func (receiver *WafSensitiveService) GetListApi(req request.WafSensitiveSearchRe
 		whereField = whereField + " remarks like ? "
 	}
 	//where字段赋值
+	if len(req.HostCode) > 0 {
+		whereValues = append(whereValues, req.HostCode)
+	}
 	if req.Type > 0 {
 		whereValues = append(whereValues, req.Type)
 	}
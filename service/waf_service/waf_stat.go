@@ -112,6 +112,7 @@ func (receiver *WafStatService) StatHomeSumDayTopIPRangeApi(req request.WafStats
 	for i := range AttackCountOfRange {
 		region := utils.GetCountry(AttackCountOfRange[i].IP)
 		AttackCountOfRange[i].IPBelong = region[0]
+		AttackCountOfRange[i].ASN, AttackCountOfRange[i].ASNOrg = utils.GetASN(AttackCountOfRange[i].IP)
 		//查询IP标签
 		var ipTags []model.IPTag
 		global.GWAF_LOCAL_DB.Where("tenant_id = ? and user_code = ? and ip=?",
@@ -129,6 +130,7 @@ func (receiver *WafStatService) StatHomeSumDayTopIPRangeApi(req request.WafStats
 	for i := range NormalCountOfRange {
 		region := utils.GetCountry(NormalCountOfRange[i].IP)
 		NormalCountOfRange[i].IPBelong = region[0]
+		NormalCountOfRange[i].ASN, NormalCountOfRange[i].ASNOrg = utils.GetASN(NormalCountOfRange[i].IP)
 
 		//查询IP标签
 		var ipTags []model.IPTag
@@ -236,3 +238,45 @@ func (receiver *WafStatService) StatHomeRumtimeSysinfo() []response2.WafNameValu
 
 	return data
 }
+
+/*
+*
+查询规则及黑白名单的命中统计 codes 为规则唯一码或名单ID，返回以唯一码为key
+*/
+func (receiver *WafStatService) StatRuleHitApi(ruleType string, codes []string) map[string]response2.WafRuleHitStats {
+	result := map[string]response2.WafRuleHitStats{}
+	if len(codes) == 0 || global.GWAF_LOCAL_STATS_DB == nil {
+		return result
+	}
+	var hitList []model.StatsRuleHit
+	global.GWAF_LOCAL_STATS_DB.Where("rule_type = ? and rule_code in ?", ruleType, codes).Order("day asc").Find(&hitList)
+
+	currentDay, _ := strconv.Atoi(time.Now().Format("20060102"))
+	weekDay, _ := strconv.Atoi(time.Now().AddDate(0, 0, -6).Format("20060102"))
+	costSum := map[string]int64{}
+	lastHit := map[string]time.Time{}
+	for _, hit := range hitList {
+		stats := result[hit.RuleCode]
+		stats.HitCount += hit.HitCount
+		stats.EvalCount += hit.EvalCount
+		stats.MaxCostUs = hit.EvalCostMax
+		stats.P99CostUs = hit.EvalCostP99
+		costSum[hit.RuleCode] += hit.EvalCostSum
+		if hit.Day == currentDay {
+			stats.TodayHitCount = hit.HitCount
+		}
+		if hit.Day >= weekDay {
+			stats.DayHits = append(stats.DayHits, model.StatsDayCount{Day: hit.Day, Count: hit.HitCount})
+		}
+		if hit.HitCount > 0 && time.Time(hit.LastHitTime).After(lastHit[hit.RuleCode]) {
+			lastHit[hit.RuleCode] = time.Time(hit.LastHitTime)
+			stats.LastHitTime = time.Time(hit.LastHitTime).Format("2006-01-02 15:04:05")
+			stats.LastReqUuid = hit.LastReqUuid
+		}
+		if stats.EvalCount > 0 {
+			stats.AvgCostUs = costSum[hit.RuleCode] / stats.EvalCount
+		}
+		result[hit.RuleCode] = stats
+	}
+	return result
+}
@@ -0,0 +1,209 @@
+package waf_service
+
+import (
+	"SamWaf/customtype"
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/baseorm"
+	"SamWaf/model/request"
+	"errors"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+type WafThreatFeedService struct{}
+
+var WafThreatFeedServiceApp = new(WafThreatFeedService)
+
+func (receiver *WafThreatFeedService) AddApi(req request.WafThreatFeedAddReq) (model.ThreatFeed, error) {
+	var bean = model.ThreatFeed{
+		BaseOrm: baseorm.BaseOrm{
+			Id:          uuid.NewV4().String(),
+			USER_CODE:   global.GWAF_USER_CODE,
+			Tenant_ID:   global.GWAF_TENANT_ID,
+			CREATE_TIME: customtype.JsonTime(time.Now()),
+			UPDATE_TIME: customtype.JsonTime(time.Now()),
+		},
+		HostCode:        req.HostCode,
+		FeedName:        req.FeedName,
+		SourceType:      req.SourceType,
+		Source:          req.Source,
+		Format:          req.Format,
+		FormatArg:       req.FormatArg,
+		RefreshInterval: req.RefreshInterval,
+		ExpireMinutes:   req.ExpireMinutes,
+		Action:          req.Action,
+		Status:          req.Status,
+		Remarks:         req.Remarks,
+	}
+	err := global.GWAF_LOCAL_DB.Create(&bean).Error
+	return bean, err
+}
+
+func (receiver *WafThreatFeedService) CheckIsExistApi(req request.WafThreatFeedAddReq) error {
+	return global.GWAF_LOCAL_DB.First(&model.ThreatFeed{}, "host_code = ? and feed_name = ?", req.HostCode, req.FeedName).Error
+}
+func (receiver *WafThreatFeedService) ModifyApi(req request.WafThreatFeedEditReq) error {
+	var bean model.ThreatFeed
+	global.GWAF_LOCAL_DB.Where("host_code = ? and feed_name = ?", req.HostCode, req.FeedName).Find(&bean)
+	if bean.Id != "" && bean.Id != req.Id {
+		return errors.New("当前网站已经存在同名订阅")
+	}
+	beanMap := map[string]interface{}{
+		"HostCode":        req.HostCode,
+		"FeedName":        req.FeedName,
+		"SourceType":      req.SourceType,
+		"Source":          req.Source,
+		"Format":          req.Format,
+		"FormatArg":       req.FormatArg,
+		"RefreshInterval": req.RefreshInterval,
+		"ExpireMinutes":   req.ExpireMinutes,
+		"Action":          req.Action,
+		"Status":          req.Status,
+		"Remarks":         req.Remarks,
+		"UPDATE_TIME":     customtype.JsonTime(time.Now()),
+	}
+	return global.GWAF_LOCAL_DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(model.ThreatFeed{}).Where("id = ?", req.Id).Updates(beanMap).Error
+		if err != nil {
+			return err
+		}
+		//已有条目同步来源和处置方式
+		return tx.Model(model.ThreatFeedEntry{}).Where("feed_id = ?", req.Id).Updates(map[string]interface{}{
+			"HostCode": req.HostCode,
+			"FeedName": req.FeedName,
+			"Action":   req.Action,
+		}).Error
+	})
+}
+func (receiver *WafThreatFeedService) GetDetailApi(req request.WafThreatFeedDetailReq) model.ThreatFeed {
+	var bean model.ThreatFeed
+	global.GWAF_LOCAL_DB.Where("id=?", req.Id).Find(&bean)
+	return bean
+}
+func (receiver *WafThreatFeedService) GetDetailByIdApi(id string) model.ThreatFeed {
+	var bean model.ThreatFeed
+	global.GWAF_LOCAL_DB.Where("id=?", id).Find(&bean)
+	return bean
+}
+func (receiver *WafThreatFeedService) GetListApi(req request.WafThreatFeedSearchReq) ([]model.ThreatFeed, int64, error) {
+	var list []model.ThreatFeed
+	var total int64 = 0
+
+	/*where条件*/
+	var whereField = ""
+	var whereValues []interface{}
+	//where字段
+	whereField = ""
+	if len(req.HostCode) > 0 {
+		whereField = whereField + " host_code=? "
+	}
+	if len(req.FeedName) > 0 {
+		if len(whereField) > 0 {
+			whereField = whereField + " and "
+		}
+		whereField = whereField + " feed_name like ? "
+	}
+	//where字段赋值
+	if len(req.HostCode) > 0 {
+		whereValues = append(whereValues, req.HostCode)
+	}
+	if len(req.FeedName) > 0 {
+		whereValues = append(whereValues, "%"+req.FeedName+"%")
+	}
+
+	global.GWAF_LOCAL_DB.Model(&model.ThreatFeed{}).Where(whereField, whereValues...).Order("create_time asc").Limit(req.PageSize).Offset(req.PageSize * (req.PageIndex - 1)).Find(&list)
+	global.GWAF_LOCAL_DB.Model(&model.ThreatFeed{}).Where(whereField, whereValues...).Count(&total)
+
+	return list, total, nil
+}
+
+// GetAllEnableListInner 获取所有启用的订阅
+func (receiver *WafThreatFeedService) GetAllEnableListInner() []model.ThreatFeed {
+	var list []model.ThreatFeed
+	global.GWAF_LOCAL_DB.Where("status = ?", 1).Find(&list)
+	return list
+}
+
+// DelApi 删除订阅 条目一并删除
+func (receiver *WafThreatFeedService) DelApi(req request.WafThreatFeedDelReq) error {
+	var bean model.ThreatFeed
+	err := global.GWAF_LOCAL_DB.Where("id = ?", req.Id).First(&bean).Error
+	if err != nil {
+		return err
+	}
+	return global.GWAF_LOCAL_DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("feed_id = ?", req.Id).Delete(model.ThreatFeedEntry{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id = ?", req.Id).Delete(model.ThreatFeed{}).Error
+	})
+}
+
+/*
+*
+用新拉取的IP整体替换订阅的条目 并记录刷新结果
+*/
+func (receiver *WafThreatFeedService) ReplaceEntriesApi(feed model.ThreatFeed, ips []string, refreshMsg string) error {
+	now := time.Now()
+	var expireTime int64 = 0
+	if feed.ExpireMinutes > 0 {
+		expireTime = now.Add(time.Duration(feed.ExpireMinutes) * time.Minute).Unix()
+	}
+	entries := make([]model.ThreatFeedEntry, 0, len(ips))
+	for _, ip := range ips {
+		entries = append(entries, model.ThreatFeedEntry{
+			BaseOrm: baseorm.BaseOrm{
+				Id:          uuid.NewV4().String(),
+				USER_CODE:   global.GWAF_USER_CODE,
+				Tenant_ID:   global.GWAF_TENANT_ID,
+				CREATE_TIME: customtype.JsonTime(now),
+				UPDATE_TIME: customtype.JsonTime(now),
+			},
+			HostCode:   feed.HostCode,
+			FeedId:     feed.Id,
+			FeedName:   feed.FeedName,
+			Ip:         ip,
+			Action:     feed.Action,
+			ExpireTime: expireTime,
+		})
+	}
+	return global.GWAF_LOCAL_DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("feed_id = ?", feed.Id).Delete(model.ThreatFeedEntry{}).Error
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			err = tx.CreateInBatches(entries, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(model.ThreatFeed{}).Where("id = ?", feed.Id).Updates(map[string]interface{}{
+			"LastRefreshTime": customtype.JsonTime(now),
+			"LastRefreshMsg":  refreshMsg,
+			"EntryCount":      len(entries),
+		}).Error
+	})
+}
+
+// UpdateRefreshMsgApi 刷新失败时只记录结果 保留原有条目
+func (receiver *WafThreatFeedService) UpdateRefreshMsgApi(feedId string, refreshMsg string) error {
+	return global.GWAF_LOCAL_DB.Model(model.ThreatFeed{}).Where("id = ?", feedId).Updates(map[string]interface{}{
+		"LastRefreshTime": customtype.JsonTime(time.Now()),
+		"LastRefreshMsg":  refreshMsg,
+	}).Error
+}
+
+/*
+*
+获取网站生效中的条目 只包含启用的订阅且未失效的条目
+*/
+func (receiver *WafThreatFeedService) GetEntryListByHostCodeApi(hostCode string) []model.ThreatFeedEntry {
+	var list []model.ThreatFeedEntry
+	global.GWAF_LOCAL_DB.Where("host_code = ? and (expire_time = 0 or expire_time > ?) and feed_id in (?)", hostCode, time.Now().Unix(),
+		global.GWAF_LOCAL_DB.Model(&model.ThreatFeed{}).Select("id").Where("status = ?", 1)).Find(&list)
+	return list
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"SamWaf/common/asntool"
+	"SamWaf/common/zlog"
+	"SamWaf/global"
+	"github.com/oschwald/geoip2-golang"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ASN查询器只创建一次 ASN数据库在启动时加载
+var asnSearcherOnce sync.Once
+
+/*
+*
+获取IP所属的自治系统 返回ASN编号和网络运营者名称
+未加载ASN数据库、内网IP或查询不到时返回 0 和空
+*/
+func GetASN(ip string) (int64, string) {
+	if len(global.GCACHE_IP_ASN_CBUFF) == 0 {
+		return 0, ""
+	}
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil || parsedIP.IsPrivate() || parsedIP.IsLoopback() {
+		return 0, ""
+	}
+	searcher := getASNSearcher()
+	if searcher == nil {
+		return 0, ""
+	}
+	record, err := searcher.ASN(parsedIP)
+	if err != nil {
+		zlog.Error("Failed to Search GeoLite2-ASN.mmdb:", err)
+		return 0, ""
+	}
+	return int64(record.AutonomousSystemNumber), record.AutonomousSystemOrganization
+}
+
+// 获取ASN查询器 数据库无法打开时返回nil
+func getASNSearcher() *geoip2.Reader {
+	asnSearcherOnce.Do(func() {
+		db, err := geoip2.FromBytes(global.GCACHE_IP_ASN_CBUFF)
+		if err != nil {
+			zlog.Error("Failed to open GeoLite2-ASN.mmdb:", err)
+			return
+		}
+		global.GCACHE_ASN_SEARCHER = db
+	})
+	return global.GCACHE_ASN_SEARCHER
+}
+
+/*
+*
+判断IP是否命中名单项 名单项可以是IP、网段或ASN（如 AS13335）
+*/
+func CheckIPOrASN(ip string, asn int64, ipRange string) bool {
+	if len(ipRange) > 2 && strings.EqualFold(ipRange[:2], "AS") {
+		rangeAsn, ok := asntool.ParseASN(ipRange)
+		return ok && asn > 0 && rangeAsn == asn
+	}
+	return CheckIPInCIDR(ip, ipRange)
+}
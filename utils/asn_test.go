@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"SamWaf/global"
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// 生成一个没有任何数据的ASN库 只包含一个节点和元数据
+func emptyASNDatabase() []byte {
+	var buf bytes.Buffer
+	//一个节点 左右记录都等于节点数，表示没有数据
+	buf.Write([]byte{0, 0, 1, 0, 0, 1})
+	buf.Write(make([]byte, 16))
+	buf.WriteString("\xAB\xCD\xEFMaxMind.com")
+	writeString := func(s string) {
+		buf.WriteByte(2<<5 | byte(len(s)))
+		buf.WriteString(s)
+	}
+	writeUint := func(typ byte, v byte) {
+		buf.WriteByte(typ<<5 | 1)
+		buf.WriteByte(v)
+	}
+	buf.WriteByte(7<<5 | 6)
+	writeString("node_count")
+	writeUint(6, 1)
+	writeString("record_size")
+	writeUint(5, 24)
+	writeString("ip_version")
+	writeUint(5, 4)
+	writeString("database_type")
+	writeString("GeoLite2-ASN")
+	writeString("binary_format_major_version")
+	writeUint(5, 2)
+	writeString("binary_format_minor_version")
+	writeUint(5, 0)
+	return buf.Bytes()
+}
+
+func resetASNSearcher(data []byte) {
+	global.GCACHE_IP_ASN_CBUFF = data
+	global.GCACHE_ASN_SEARCHER = nil
+	asnSearcherOnce = sync.Once{}
+}
+
+func TestGetASN(t *testing.T) {
+	defer resetASNSearcher(nil)
+	resetASNSearcher(nil)
+	if asn, org := GetASN("1.1.1.1"); asn != 0 || org != "" {
+		t.Errorf("GetASN without database = %d %s", asn, org)
+	}
+
+	resetASNSearcher(emptyASNDatabase())
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if asn, _ := GetASN("1.1.1.1"); asn != 0 {
+				t.Errorf("empty database should not have asn")
+			}
+		}()
+	}
+	wg.Wait()
+	searcher := global.GCACHE_ASN_SEARCHER
+	if searcher == nil {
+		t.Fatal("searcher not created")
+	}
+	GetASN("8.8.8.8")
+	if global.GCACHE_ASN_SEARCHER != searcher {
+		t.Errorf("searcher should be created only once")
+	}
+	if asn, _ := GetASN("192.168.1.1"); asn != 0 {
+		t.Errorf("private ip should not have asn")
+	}
+}
+
+func TestCheckIPOrASN(t *testing.T) {
+	if !CheckIPOrASN("1.1.1.1", 13335, "AS13335") || CheckIPOrASN("1.1.1.1", 0, "AS13335") || CheckIPOrASN("1.1.1.1", 13335, "ASabc") {
+		t.Errorf("asn range not correct")
+	}
+	if !CheckIPOrASN("10.1.1.1", 0, "10.0.0.0/8") || CheckIPOrASN("11.1.1.1", 0, "10.0.0.0/8") {
+		t.Errorf("cidr range not correct")
+	}
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"SamWaf/common/asntool"
+	"net"
+	"strings"
+)
+
+/*
+*
+IP名单匹配器 加载主机时把名单编译成IPv4/IPv6前缀树，ASN名单项单独建索引
+查询耗时只和IP位数有关，和名单条数无关
+*/
+type IPMatcher struct {
+	v4    *ipMatcherNode
+	v6    *ipMatcherNode
+	asn   map[int64][]int
+	count int
+}
+
+type ipMatcherNode struct {
+	children [2]*ipMatcherNode
+	indexes  []int //落在当前前缀上的名单下标 按加入顺序
+}
+
+func NewIPMatcher() *IPMatcher {
+	return &IPMatcher{
+		v4:  &ipMatcherNode{},
+		v6:  &ipMatcherNode{},
+		asn: map[int64][]int{},
+	}
+}
+
+/*
+*
+加入一条名单项 index 为名单项在原列表中的下标
+名单项可以是IP、网段或ASN（如 AS13335），无法识别时返回false
+*/
+func (m *IPMatcher) Add(ipRange string, index int) bool {
+	ipRange = strings.TrimSpace(ipRange)
+	if len(ipRange) > 2 && strings.EqualFold(ipRange[:2], "AS") {
+		asn, ok := asntool.ParseASN(ipRange)
+		if !ok {
+			return false
+		}
+		m.asn[asn] = append(m.asn[asn], index)
+		m.count++
+		return true
+	}
+	var ip net.IP
+	var ones int
+	if strings.Contains(ipRange, "/") {
+		_, ipNet, err := net.ParseCIDR(ipRange)
+		if err != nil {
+			return false
+		}
+		ip = ipNet.IP
+		var bits int
+		ones, bits = ipNet.Mask.Size()
+		//IPv4映射的IPv6网段 如 ::ffff:1.2.3.0/120
+		if bits == 128 && ip.To4() != nil {
+			ones -= 96
+			if ones < 0 {
+				ones = 0
+			}
+		}
+	} else {
+		ip = net.ParseIP(ipRange)
+		if ip == nil {
+			return false
+		}
+		ones = -1
+	}
+	node, ip := m.root(ip)
+	if ones < 0 || ones > len(ip)*8 {
+		ones = len(ip) * 8
+	}
+	for i := 0; i < ones; i++ {
+		bit := ip[i/8] >> (7 - uint(i%8)) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &ipMatcherNode{}
+		}
+		node = node.children[bit]
+	}
+	node.indexes = append(node.indexes, index)
+	m.count++
+	return true
+}
+
+/*
+*
+查找IP命中的名单项 返回名单项下标
+网段按最长前缀优先，同一前缀按加入顺序，IP都未命中时再按ASN查找
+accept 不为空时可跳过不可用的名单项（如已过期）
+*/
+func (m *IPMatcher) Match(ip string, asn int64, accept func(index int) bool) (int, bool) {
+	if m == nil || m.count == 0 {
+		return 0, false
+	}
+	if parsedIP := net.ParseIP(ip); parsedIP != nil {
+		node, parsedIP := m.root(parsedIP)
+		var path [129]*ipMatcherNode
+		depth := 0
+		path[depth] = node
+		for i := 0; i < len(parsedIP)*8; i++ {
+			node = node.children[parsedIP[i/8]>>(7-uint(i%8))&1]
+			if node == nil {
+				break
+			}
+			depth++
+			path[depth] = node
+		}
+		for ; depth >= 0; depth-- {
+			if index, ok := pickIndex(path[depth].indexes, accept); ok {
+				return index, true
+			}
+		}
+	}
+	if asn > 0 {
+		return pickIndex(m.asn[asn], accept)
+	}
+	return 0, false
+}
+
+// Len 名单项条数
+func (m *IPMatcher) Len() int {
+	if m == nil {
+		return 0
+	}
+	return m.count
+}
+
+// 根据IP类型取对应的树 IPv4统一转换为4字节
+func (m *IPMatcher) root(ip net.IP) (*ipMatcherNode, net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return m.v4, ip4
+	}
+	return m.v6, ip.To16()
+}
+
+func pickIndex(indexes []int, accept func(index int) bool) (int, bool) {
+	for _, index := range indexes {
+		if accept == nil || accept(index) {
+			return index, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,47 @@
+package utils
+
+import "testing"
+
+func TestIPMatcher(t *testing.T) {
+	matcher := NewIPMatcher()
+	ranges := []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.3", "2001:db8::/32", "AS13335", "bad", "10.1.0.0/16"}
+	for i, ipRange := range ranges {
+		if ok := matcher.Add(ipRange, i); ok != (ipRange != "bad") {
+			t.Errorf("Add(%s) = %v", ipRange, ok)
+		}
+	}
+	if matcher.Len() != 6 {
+		t.Errorf("Len() = %d, want 6", matcher.Len())
+	}
+	tests := []struct {
+		ip    string
+		asn   int64
+		want  int
+		found bool
+	}{
+		{"10.1.2.3", 0, 2, true},
+		{"10.1.9.9", 0, 1, true},
+		{"10.200.0.1", 0, 0, true},
+		{"::ffff:10.1.2.3", 0, 2, true},
+		{"2001:db8:1::1", 0, 3, true},
+		{"2001:db9::1", 0, 0, false},
+		{"1.1.1.1", 13335, 4, true},
+		{"1.1.1.1", 0, 0, false},
+		{"not-ip", 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, found := matcher.Match(tt.ip, tt.asn, nil)
+		if found != tt.found || (found && got != tt.want) {
+			t.Errorf("Match(%s, %d) = %d %v, want %d %v", tt.ip, tt.asn, got, found, tt.want, tt.found)
+		}
+	}
+	//跳过的名单项继续找同前缀和更短的前缀
+	got, found := matcher.Match("10.1.2.3", 0, func(index int) bool { return index != 2 && index != 1 })
+	if !found || got != 6 {
+		t.Errorf("Match with accept = %d %v, want 6 true", got, found)
+	}
+	var empty *IPMatcher
+	if _, found = empty.Match("10.1.2.3", 0, nil); found {
+		t.Errorf("nil matcher should not match")
+	}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 规则头 rule 名称 "描述" salience 10 {
+var ruleHeaderRegex = regexp.MustCompile(`(?m)^\s*rule\s+([A-Za-z_][A-Za-z0-9_]*)\s*(?:"((?:[^"\\]|\\.)*)")?[^{]*\{`)
+
+// 从规则文本中拆出的单条规则
+type RuleBlock struct {
+	Name        string //规则名
+	Description string //规则描述 没有描述时为规则名
+	Content     string //规则全文
+}
+
+/*
+*
+把包含多条规则的文本拆分成单条规则 按大括号配对截取，忽略字符串内的括号
+*/
+func SplitRules(content string) []RuleBlock {
+	var blocks []RuleBlock
+	offset := 0
+	for {
+		loc := ruleHeaderRegex.FindStringSubmatchIndex(content[offset:])
+		if loc == nil {
+			return blocks
+		}
+		start := offset + loc[0]
+		end := matchRuleBrace(content, offset+loc[1])
+		if end < 0 {
+			return blocks
+		}
+		block := RuleBlock{
+			Name:    content[offset+loc[2] : offset+loc[3]],
+			Content: strings.TrimSpace(content[start:end]),
+		}
+		if loc[4] >= 0 {
+			block.Description = content[offset+loc[4] : offset+loc[5]]
+		}
+		if block.Description == "" {
+			block.Description = block.Name
+		}
+		blocks = append(blocks, block)
+		offset = end
+	}
+}
+
+// 返回与已打开的大括号配对的右括号之后的位置 找不到时返回-1
+func matchRuleBrace(content string, pos int) int {
+	depth := 1
+	inString := false
+	for i := pos; i < len(content); i++ {
+		switch c := content[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case !inString && c == '{':
+			depth++
+		case !inString && c == '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+/*
+*
+修改单条规则的规则名 同时替换规则内 Retract 引用的名称
+*/
+func RenameRule(block RuleBlock, newName string) string {
+	loc := ruleHeaderRegex.FindStringSubmatchIndex(block.Content)
+	if loc == nil {
+		return block.Content
+	}
+	content := block.Content[:loc[2]] + newName + block.Content[loc[3]:]
+	return strings.ReplaceAll(content, `Retract("`+block.Name+`")`, `Retract("`+newName+`")`)
+}
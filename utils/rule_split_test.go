@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitRules(t *testing.T) {
+	content := `
+rule R1 "禁止 {admin}" salience 10 {
+    when
+        MF.URL.Contains("}") == true
+    then
+        Retract("R1");
+}
+// 注释
+rule R2 salience 5 {
+    when
+        MF.SRC_IP == "1.1.1.1"
+    then
+        MF.Block(403, "blocked");
+        Retract("R2");
+}
+rule R3 "未闭合" {
+    when MF.URL == "a"
+`
+	blocks := SplitRules(content)
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(blocks))
+	}
+	if blocks[0].Name != "R1" || blocks[0].Description != "禁止 {admin}" || !strings.HasSuffix(blocks[0].Content, "}") {
+		t.Errorf("unexpected first rule %+v", blocks[0])
+	}
+	if blocks[1].Name != "R2" || blocks[1].Description != "R2" {
+		t.Errorf("unexpected second rule %+v", blocks[1])
+	}
+	renamed := RenameRule(blocks[1], "Rabc")
+	if !strings.HasPrefix(renamed, "rule Rabc salience 5") || !strings.Contains(renamed, `Retract("Rabc")`) {
+		t.Errorf("unexpected renamed rule %s", renamed)
+	}
+	ruleHelper := &RuleHelper{}
+	if err := ruleHelper.CheckRuleAvailable(RenameRule(blocks[0], "Rxyz")); err != nil {
+		t.Errorf("renamed rule should be available: %v", err)
+	}
+}
@@ -4,11 +4,17 @@ import (
 	"SamWaf/common/zlog"
 	"SamWaf/innerbean"
 	"SamWaf/model"
+	"SamWaf/model/response"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/hyperjumptech/grule-rule-engine/ast"
 	"github.com/hyperjumptech/grule-rule-engine/builder"
 	"github.com/hyperjumptech/grule-rule-engine/engine"
 	"github.com/hyperjumptech/grule-rule-engine/pkg"
+	"strings"
+	"sync"
+	"time"
 )
 
 // 规则帮助类
@@ -17,6 +23,17 @@ type RuleHelper struct {
 	KnowledgeBase    *ast.KnowledgeBase
 	knowledgeLibrary *ast.KnowledgeLibrary
 	ruleBuilder      *builder.RuleBuilder
+	knowledgePool    *sync.Pool //执行规则会修改知识库状态 每个请求使用独立的副本
+	ruleCodes        map[string]string
+	statsRecorder    RuleStatsRecorder
+}
+
+// 规则统计回调 ruleCode 为规则唯一码，hit 为条件是否满足，cost 为条件判断耗时
+type RuleStatsRecorder func(ruleCode string, hit bool, cost time.Duration, reqUuid string)
+
+// SetStatsRecorder 设置规则统计回调
+func (rulehelper *RuleHelper) SetStatsRecorder(recorder RuleStatsRecorder) {
+	rulehelper.statsRecorder = recorder
 }
 
 func (rulehelper *RuleHelper) InitRuleEngine() {
@@ -31,7 +48,7 @@ func (rulehelper *RuleHelper) LoadRule(ruleconfig model.Rules) {
 	if err != nil {
 		zlog.Error("LoadRule", err)
 	}
-	rulehelper.KnowledgeBase = rulehelper.knowledgeLibrary.NewKnowledgeBaseInstance("Region", "0.0.1")
+	rulehelper.setKnowledgeBase(rulehelper.knowledgeLibrary.NewKnowledgeBaseInstance("Region", "0.0.1"))
 }
 
 func (rulehelper *RuleHelper) LoadRules(ruleconfig []model.Rules) string {
@@ -60,19 +77,37 @@ func (rulehelper *RuleHelper) LoadRules(ruleconfig []model.Rules) string {
 		}
 	}
 	rulestr := ""
+	ruleCodes := map[string]string{}
 	for _, v := range ruleconfig {
 		rulestr = rulestr + v.RuleContent + " \n"
+		names, err := rulehelper.RuleNames(v.RuleContent)
+		if err == nil {
+			for _, name := range names {
+				ruleCodes[name] = v.RuleCode
+			}
+		}
 	}
+	rulehelper.ruleCodes = ruleCodes
 	byteArr := pkg.NewBytesResource([]byte(rulestr))
 	err := rulehelper.ruleBuilder.BuildRuleFromResource("Region", "0.0.1", byteArr)
 	if err != nil {
 		zlog.Error("LoadRules", err)
 	}
 
-	rulehelper.KnowledgeBase = rulehelper.knowledgeLibrary.NewKnowledgeBaseInstance("Region", "0.0.1")
+	rulehelper.setKnowledgeBase(rulehelper.knowledgeLibrary.NewKnowledgeBaseInstance("Region", "0.0.1"))
 
 	return rulestr
 }
+
+// 设置知识库 同时重建执行用的副本池
+func (rulehelper *RuleHelper) setKnowledgeBase(knowledgeBase *ast.KnowledgeBase) {
+	rulehelper.KnowledgeBase = knowledgeBase
+	rulehelper.knowledgePool = &sync.Pool{
+		New: func() interface{} {
+			return knowledgeBase.Clone(pkg.NewCloneTable())
+		},
+	}
+}
 func (rulehelper *RuleHelper) Exec(key string, ruleinfo *innerbean.WAF_REQUEST_FULL) error {
 
 	//rulehelper.dataCtx = ast.NewDataContext()
@@ -87,7 +122,11 @@ func (rulehelper *RuleHelper) Exec(key string, ruleinfo *innerbean.WAF_REQUEST_F
 	return err
 }
 
-func (rulehelper *RuleHelper) Match(key string, ruleinfo *innerbean.WebLog) ([]*ast.RuleEntry, error) {
+/*
+*
+匹配规则 ruleinfo 一般为 *innerbean.RuleFact，兼容直接使用 *innerbean.WebLog
+*/
+func (rulehelper *RuleHelper) Match(key string, ruleinfo interface{}) ([]*ast.RuleEntry, error) {
 
 	defer func() {
 		e := recover()
@@ -102,6 +141,78 @@ func (rulehelper *RuleHelper) Match(key string, ruleinfo *innerbean.WebLog) ([]*
 	}
 	return rulehelper.engine.FetchMatchingRules(dataCtx, rulehelper.KnowledgeBase)
 }
+
+/*
+*
+执行规则 命中的规则按优先级从高到低依次执行（每条只执行一次），由规则调用 fact 的动作决定处置方式
+*/
+func (rulehelper *RuleHelper) ExecuteFact(key string, fact *innerbean.RuleFact) (err error) {
+	knowledgePool := rulehelper.knowledgePool
+	if knowledgePool == nil {
+		return errors.New("没有规则数据")
+	}
+	knowledgeBase := knowledgePool.Get().(*ast.KnowledgeBase)
+	defer func() {
+		e := recover()
+		if e != nil {
+			err = fmt.Errorf("规则执行异常 %v", e)
+			return
+		}
+		knowledgePool.Put(knowledgeBase)
+	}()
+	return executeFact(knowledgeBase, key, fact, rulehelper.ruleCodes, rulehelper.statsRecorder)
+}
+
+func executeFact(knowledgeBase *ast.KnowledgeBase, key string, fact *innerbean.RuleFact, ruleCodes map[string]string, recorder RuleStatsRecorder) error {
+	dataCtx := ast.NewDataContext()
+	err := dataCtx.Add(key, fact)
+	if err != nil {
+		return err
+	}
+	myEngine := engine.NewGruleEngine()
+	myEngine.Listeners = []engine.GruleEngineListener{&ruleFactListener{fact: fact, ruleCodes: ruleCodes, recorder: recorder}}
+	err = myEngine.Execute(dataCtx, knowledgeBase)
+	fact.EndRule()
+	return err
+}
+
+// 规则执行监听 记录正在执行的规则，并保证每条规则只执行一次
+type ruleFactListener struct {
+	fact      *innerbean.RuleFact
+	ruleCodes map[string]string
+	recorder  RuleStatsRecorder
+	evalStart time.Time //当前规则条件开始判断的时间
+}
+
+// 第一轮逐条判断规则条件 引擎每判断完一条就回调一次，两次回调的间隔即为该条规则的判断耗时
+func (listener *ruleFactListener) EvaluateRuleEntry(cycle uint64, entry *ast.RuleEntry, candidate bool) {
+	if cycle != 1 || listener.recorder == nil {
+		return
+	}
+	cost := time.Since(listener.evalStart)
+	ruleCode, ok := listener.ruleCodes[entry.RuleName]
+	if !ok {
+		ruleCode = entry.RuleName
+	}
+	listener.recorder(ruleCode, candidate, cost, listener.fact.REQ_UUID)
+	listener.evalStart = time.Now()
+}
+
+func (listener *ruleFactListener) ExecuteRuleEntry(cycle uint64, entry *ast.RuleEntry) {
+	entry.Retracted = true
+	ruleName := entry.RuleDescription
+	if ruleName == "" {
+		ruleName = entry.RuleName
+	}
+	listener.fact.BeginRule(ruleName)
+}
+
+func (listener *ruleFactListener) BeginCycle(cycle uint64) {
+	if cycle == 1 && listener.recorder != nil {
+		listener.evalStart = time.Now()
+	}
+}
+
 func (rulehelper *RuleHelper) CheckRuleAvailable(ruleText string) error {
 	myFact := &innerbean.WebLog{
 		SRC_IP: "127.0.0.1",
@@ -132,3 +243,148 @@ func (rulehelper *RuleHelper) CheckRuleAvailable(ruleText string) error {
 	}
 	return nil
 }
+
+// 编译规则为独立的知识库
+func buildKnowledgeBase(ruleText string) (*ast.KnowledgeBase, error) {
+	knowledgeLibrary := ast.NewKnowledgeLibrary()
+	ruleBuilder := builder.NewRuleBuilder(knowledgeLibrary)
+	byteArr := pkg.NewBytesResource([]byte(ruleText))
+	err := ruleBuilder.BuildRuleFromResource("TestRule", "0.0.1", byteArr)
+	if err != nil {
+		return nil, err
+	}
+	return knowledgeLibrary.NewKnowledgeBaseInstance("TestRule", "0.0.1"), nil
+}
+
+/*
+*
+编译规则并返回其中包含的规则名称
+*/
+func (rulehelper *RuleHelper) RuleNames(ruleText string) ([]string, error) {
+	knowledgeBase, err := buildKnowledgeBase(ruleText)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(knowledgeBase.RuleEntries))
+	for name := range knowledgeBase.RuleEntries {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+/*
+*
+用样例请求匹配规则 返回每个样例命中的规则
+*/
+func (rulehelper *RuleHelper) MatchFacts(ruleText string, facts []*innerbean.RuleFact) (matches [][]*ast.RuleEntry, err error) {
+	defer func() {
+		e := recover()
+		if e != nil {
+			matches = nil
+			err = fmt.Errorf("规则执行异常 %v", e)
+		}
+	}()
+	knowledgeBase, err := buildKnowledgeBase(ruleText)
+	if err != nil {
+		return nil, err
+	}
+	myEngine := engine.NewGruleEngine()
+	for _, fact := range facts {
+		dataCtx := ast.NewDataContext()
+		err = dataCtx.Add("MF", fact)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := myEngine.FetchMatchingRules(dataCtx, knowledgeBase)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, entries)
+	}
+	return matches, nil
+}
+
+/*
+*
+把测试用例中的样例请求转为规则使用的对象 字段名同访问日志的json名称
+请求头、参数、cookie从 header url cookies 字段中解析
+*/
+func RuleTestFact(fact map[string]interface{}) (*innerbean.RuleFact, error) {
+	weblog := &innerbean.WebLog{}
+	factBytes, err := json.Marshal(fact)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(factBytes, weblog)
+	if err != nil {
+		return nil, err
+	}
+	return innerbean.NewRuleFact(weblog, nil), nil
+}
+
+/*
+*
+执行规则的测试用例 ruleText 为参与匹配的全部规则，命中结果只看被测规则
+没有测试用例时返回空
+*/
+func RunRuleTestCases(rule model.Rules, ruleText string) ([]response.RuleTestRep, error) {
+	if strings.TrimSpace(rule.RuleTestCases) == "" {
+		return nil, nil
+	}
+	var testCases []model.RuleTestCase
+	if err := json.Unmarshal([]byte(rule.RuleTestCases), &testCases); err != nil {
+		return nil, errors.New("测试用例解析错误")
+	}
+	ruleHelper := &RuleHelper{}
+	ruleNames, err := ruleHelper.RuleNames(rule.RuleContent)
+	if err != nil {
+		return nil, err
+	}
+	facts := make([]*innerbean.RuleFact, 0, len(testCases))
+	for _, testCase := range testCases {
+		fact, err := RuleTestFact(testCase.Fact)
+		if err != nil {
+			return nil, errors.New("测试用例 " + testCase.Name + " 样例请求错误")
+		}
+		facts = append(facts, fact)
+	}
+	matches, err := ruleHelper.MatchFacts(ruleText, facts)
+	if err != nil {
+		return nil, err
+	}
+	testResults := make([]response.RuleTestRep, 0, len(testCases))
+	for i, testCase := range testCases {
+		testResult := response.RuleTestRep{
+			RuleCode:    rule.RuleCode,
+			RuleName:    rule.RuleName,
+			CaseName:    testCase.Name,
+			ExpectMatch: testCase.ExpectMatch,
+			MatchRules:  []string{},
+		}
+		for _, entry := range matches[i] {
+			matchRule := entry.RuleDescription
+			if matchRule == "" {
+				matchRule = entry.RuleName
+			}
+			testResult.MatchRules = append(testResult.MatchRules, matchRule)
+			for _, ruleName := range ruleNames {
+				if entry.RuleName == ruleName {
+					testResult.Matched = true
+				}
+			}
+		}
+		testResult.Pass = testResult.Matched == (testCase.ExpectMatch == 1)
+		testResults = append(testResults, testResult)
+	}
+	return testResults, nil
+}
+
+// 测试用例是否全部通过
+func IsRuleTestPassed(testResults []response.RuleTestRep) bool {
+	for _, testResult := range testResults {
+		if !testResult.Pass {
+			return false
+		}
+	}
+	return true
+}
@@ -5,6 +5,7 @@ import (
 	"SamWaf/model"
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestRuleHelper_Match(t *testing.T) {
@@ -54,3 +55,149 @@ rule R1ca0bf1c409e4c1b823c995afe7733b0 "禁止一些robotahrefs" salience 10 {
 	}
 
 }
+
+func TestRuleHelper_MatchFacts(t *testing.T) {
+	ruleHelper := &RuleHelper{}
+	drls := `
+rule Radmin "禁止访问后台" salience 10 {
+    when
+        MF.URL.Contains("/admin") == True
+    then
+		Retract("Radmin");
+}
+rule Rbot "禁止爬虫" salience 10 {
+    when
+        MF.USER_AGENT.Contains("bot") == True
+    then
+		Retract("Rbot");
+} `
+	names, err := ruleHelper.RuleNames(drls)
+	if err != nil || len(names) != 2 {
+		t.Fatalf("RuleNames got %v %v", names, err)
+	}
+	adminFact, err := RuleTestFact(map[string]interface{}{"url": "/admin/login", "user_agent": "Mozilla"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	botFact, _ := RuleTestFact(map[string]interface{}{"url": "/admin", "user_agent": "amazonbot", "content_length": 10})
+	normalFact, _ := RuleTestFact(map[string]interface{}{"url": "/index"})
+	matches, err := ruleHelper.MatchFacts(drls, []*innerbean.RuleFact{adminFact, botFact, normalFact})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches[0]) != 1 || matches[0][0].RuleName != "Radmin" {
+		t.Errorf("admin sample should only match Radmin, got %v", matches[0])
+	}
+	if len(matches[1]) != 2 || botFact.CONTENT_LENGTH != 10 {
+		t.Errorf("bot sample should match both rules, got %v", matches[1])
+	}
+	if len(matches[2]) != 0 {
+		t.Errorf("normal sample should not match, got %v", matches[2])
+	}
+	if _, err := ruleHelper.MatchFacts("rule R1 {", []*innerbean.RuleFact{normalFact}); err == nil {
+		t.Errorf("invalid rule should return error")
+	}
+}
+
+func TestRuleHelper_GenRuleWithFactHelpers(t *testing.T) {
+	ruleHelper := &RuleHelper{}
+	ruleTool := model.RuleTool{}
+	ruleInfo := model.RuleInfo{
+		RuleBase: model.RuleBase{Salience: 10, RuleName: "helper"},
+		RuleCondition: model.RuleCondition{
+			RelationSymbol: "&&",
+			RelationDetail: []model.RelationDetail{
+				{FactName: "MF", Attr: "Header", AttrArg: "X-Token", AttrType: "string", AttrJudge: "system.Contains", AttrVal: "bad", AttrVal2: "true"},
+				{FactName: "MF", Attr: "URL", AttrType: "string", AttrJudge: "fact.MatchRegex", AttrVal: "^/api/", AttrVal2: "true"},
+				{FactName: "MF", Attr: "IPCount", AttrArg: "60", AttrType: "int", AttrJudge: ">=", AttrVal: "0"},
+			},
+		},
+	}
+	ruleText := ruleTool.GenRuleInfo(ruleInfo, "辅助函数")
+	hitFact, _ := RuleTestFact(map[string]interface{}{"url": "/api/user", "header": "X-Token: a-bad-token\r\n"})
+	missFact, _ := RuleTestFact(map[string]interface{}{"url": "/api/user", "header": "X-Token: good\r\n"})
+	matches, err := ruleHelper.MatchFacts(ruleText, []*innerbean.RuleFact{hitFact, missFact})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, ruleText)
+	}
+	if len(matches[0]) != 1 || len(matches[1]) != 0 {
+		t.Errorf("unexpected matches %v %v\n%s", matches[0], matches[1], ruleText)
+	}
+}
+
+func TestRuleHelper_ExecuteFact(t *testing.T) {
+	ruleHelper := &RuleHelper{}
+	ruleHelper.InitRuleEngine()
+	evaluated := map[string]int{}
+	hits := map[string]int{}
+	ruleHelper.SetStatsRecorder(func(ruleCode string, hit bool, cost time.Duration, reqUuid string) {
+		if cost < 0 {
+			t.Errorf("negative cost %v", cost)
+		}
+		evaluated[ruleCode]++
+		if hit {
+			hits[ruleCode]++
+		}
+	})
+	ruleHelper.LoadRules([]model.Rules{
+		{RuleCode: "code1", RuleContent: `
+rule Rallow "放行内网" salience 100 {
+    when
+        MF.SRC_IP == "10.0.0.1"
+    then
+        MF.Allow();
+}
+rule Rtag "标记后台" salience 50 {
+    when
+        MF.URL.Contains("/admin") == True
+    then
+        MF.LogOnly("admin");
+        MF.SetRisk(2);
+        MF.AddUpstreamHeader("X-Waf-Tag", "admin");
+}
+rule Rblock "禁止后台" salience 10 {
+    when
+        MF.URL.Contains("/admin") == True
+    then
+        MF.Block(429, "稍后再试");
+}
+rule Rlegacy "旧规则" salience 1 {
+    when
+        MF.URL.Contains("/old") == True
+    then
+        Retract("Rlegacy");
+} `},
+	})
+	cases := []struct {
+		weblog innerbean.WebLog
+		action string
+		rule   string
+	}{
+		{innerbean.WebLog{SRC_IP: "10.0.0.1", URL: "/admin"}, innerbean.RULE_ACTION_ALLOW, "放行内网"},
+		{innerbean.WebLog{SRC_IP: "1.1.1.1", URL: "/admin"}, innerbean.RULE_ACTION_BLOCK, "禁止后台"},
+		{innerbean.WebLog{SRC_IP: "1.1.1.1", URL: "/old"}, innerbean.RULE_ACTION_BLOCK, "旧规则"},
+		{innerbean.WebLog{SRC_IP: "1.1.1.1", URL: "/index"}, "", ""},
+	}
+	for _, c := range cases {
+		weblog := c.weblog
+		fact := innerbean.NewRuleFact(&weblog, nil)
+		if err := ruleHelper.ExecuteFact("MF", fact); err != nil {
+			t.Fatal(err)
+		}
+		decision := fact.Decision()
+		if decision.Action != c.action || decision.RuleName != c.rule {
+			t.Errorf("%s %s got %+v", weblog.SRC_IP, weblog.URL, decision)
+		}
+		if c.rule == "禁止后台" {
+			if decision.StatusCode != 429 || decision.Message != "稍后再试" {
+				t.Errorf("block detail got %+v", decision)
+			}
+			if len(fact.Tags()) != 1 || fact.Risk() != 2 || fact.UpstreamHeaders()["X-Waf-Tag"] != "admin" {
+				t.Errorf("tag rule got %v %d %v", fact.Tags(), fact.Risk(), fact.UpstreamHeaders())
+			}
+		}
+	}
+	if evaluated["code1"] != 4*len(cases) || hits["code1"] != 6 {
+		t.Errorf("stats got evaluated %v hits %v", evaluated, hits)
+	}
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"SamWaf/enums"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/*
+*
+解析威胁情报内容 返回去重后的IP或网段，以及无法识别的行数
+注释行（# ; //开头）和空行不计入无法识别
+*/
+func ParseThreatFeed(format string, formatArg string, content string) ([]string, int, error) {
+	var candidates []string
+	invalid := 0
+	switch format {
+	case enums.THREAT_FEED_FORMAT_JSON:
+		var data interface{}
+		if err := json.Unmarshal([]byte(content), &data); err != nil {
+			return nil, 0, errors.New("JSON解析失败:" + err.Error())
+		}
+		candidates = collectJSONPath(data, formatArg)
+	case enums.THREAT_FEED_FORMAT_CSV:
+		column, err := strconv.Atoi(strings.TrimSpace(formatArg))
+		if err != nil || column < 1 {
+			return nil, 0, errors.New("CSV列序号不正确")
+		}
+		reader := csv.NewReader(strings.NewReader(content))
+		reader.FieldsPerRecord = -1
+		reader.Comment = '#'
+		reader.LazyQuotes = true
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, 0, errors.New("CSV解析失败:" + err.Error())
+		}
+		for _, record := range records {
+			if len(record) < column {
+				invalid++
+				continue
+			}
+			candidates = append(candidates, record[column-1])
+		}
+	default:
+		for _, line := range strings.Split(content, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//") {
+				continue
+			}
+			switch format {
+			case enums.THREAT_FEED_FORMAT_SPAMHAUS:
+				//新版为每行一个JSON {"cidr":"1.10.16.0/20","sblid":"SBL256894"}
+				if strings.HasPrefix(line, "{") {
+					var item struct {
+						Cidr string `json:"cidr"`
+					}
+					if json.Unmarshal([]byte(line), &item) == nil && item.Cidr != "" {
+						candidates = append(candidates, item.Cidr)
+					}
+					continue
+				}
+			case enums.THREAT_FEED_FORMAT_TOR:
+				//exit-addresses 格式只取 ExitAddress 行，其他描述行跳过
+				if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "ExitAddress" {
+					candidates = append(candidates, fields[1])
+					continue
+				} else if len(fields) > 1 {
+					continue
+				}
+			}
+			//取行内第一段 去掉 ; # 后的说明
+			fields := strings.FieldsFunc(line, func(r rune) bool {
+				return r == ';' || r == '#' || r == ' ' || r == '\t' || r == ','
+			})
+			if len(fields) == 0 {
+				continue
+			}
+			candidates = append(candidates, fields[0])
+		}
+	}
+	seen := make(map[string]bool, len(candidates))
+	ips := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if valid, _ := IsValidIPOrNetwork(candidate); !valid {
+			invalid++
+			continue
+		}
+		if !seen[candidate] {
+			seen[candidate] = true
+			ips = append(ips, candidate)
+		}
+	}
+	return ips, invalid, nil
+}
+
+// 按路径取JSON中的值 路径如 data.ip，遇到数组时对每个元素继续取值
+func collectJSONPath(data interface{}, path string) []string {
+	if array, ok := data.([]interface{}); ok {
+		var values []string
+		for _, item := range array {
+			values = append(values, collectJSONPath(item, path)...)
+		}
+		return values
+	}
+	if path == "" {
+		if value, ok := data.(string); ok {
+			return []string{value}
+		}
+		return nil
+	}
+	name, rest, _ := strings.Cut(path, ".")
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return collectJSONPath(object[name], rest)
+}
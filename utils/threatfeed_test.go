@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"SamWaf/enums"
+	"reflect"
+	"testing"
+)
+
+func TestParseThreatFeed(t *testing.T) {
+	tests := []struct {
+		format    string
+		formatArg string
+		content   string
+		want      []string
+		invalid   int
+	}{
+		{enums.THREAT_FEED_FORMAT_PLAIN, "", "# comment\n1.1.1.1\n\n2.2.2.0/24 # note\nbad\n1.1.1.1\n", []string{"1.1.1.1", "2.2.2.0/24"}, 1},
+		{enums.THREAT_FEED_FORMAT_SPAMHAUS, "", "; Spamhaus DROP\n1.10.16.0/20 ; SBL256894\n{\"cidr\":\"2.56.192.0/22\",\"sblid\":\"SBL459831\"}\n{\"type\":\"metadata\"}\n", []string{"1.10.16.0/20", "2.56.192.0/22"}, 0},
+		{enums.THREAT_FEED_FORMAT_TOR, "", "ExitNode 0011BD2485AD45D984EC4159C88FC066E5E3300E\nPublished 2024-01-01 00:00:00\nExitAddress 3.3.3.3 2024-01-01 00:10:00\n4.4.4.4\n", []string{"3.3.3.3", "4.4.4.4"}, 0},
+		{enums.THREAT_FEED_FORMAT_CSV, "2", "id,ip,score\n1,5.5.5.5,90\n2,6.6.6.0/24,80\n3\n", []string{"5.5.5.5", "6.6.6.0/24"}, 2},
+		{enums.THREAT_FEED_FORMAT_JSON, "data.ip", `{"data":[{"ip":"7.7.7.7"},{"ip":"8.8.8.0/24"},{"ip":"x"}]}`, []string{"7.7.7.7", "8.8.8.0/24"}, 1},
+		{enums.THREAT_FEED_FORMAT_JSON, "", `["9.9.9.9"]`, []string{"9.9.9.9"}, 0},
+	}
+	for _, tt := range tests {
+		got, invalid, err := ParseThreatFeed(tt.format, tt.formatArg, tt.content)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.format, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) || invalid != tt.invalid {
+			t.Errorf("%s: got %v invalid %d, want %v invalid %d", tt.format, got, invalid, tt.want, tt.invalid)
+		}
+	}
+	if _, _, err := ParseThreatFeed(enums.THREAT_FEED_FORMAT_CSV, "0", "a,b"); err == nil {
+		t.Errorf("csv column 0 should be rejected")
+	}
+}
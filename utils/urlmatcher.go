@@ -0,0 +1,377 @@
+package utils
+
+import (
+	"SamWaf/enums"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// URLMatchRule URL名单项 URL对比方式以及可选的附加条件
+type URLMatchRule struct {
+	CompareType string //对比方式
+	Url         string //URL
+	IgnoreCase  bool   //忽略大小写
+	Method      string //请求方法 多个用逗号分隔 为空不限制
+	Host        string //请求域名 为空不限制
+	HeaderName  string //请求头名称 为空不限制
+	HeaderValue string //请求头值 为空时只要求请求头存在
+	QueryName   string //查询参数名称 为空不限制
+	QueryValue  string //查询参数值 为空时只要求参数存在
+}
+
+/*
+*
+校验URL名单项
+*/
+func CheckURLMatchRule(rule URLMatchRule) error {
+	switch rule.CompareType {
+	case enums.URL_COMPARE_EQUAL, enums.URL_COMPARE_PREFIX, enums.URL_COMPARE_SUFFIX, enums.URL_COMPARE_CONTAINS:
+	case enums.URL_COMPARE_REGEX:
+		if _, err := compileURLRegex(rule); err != nil {
+			return errors.New("正则表达式不正确:" + err.Error())
+		}
+	default:
+		return errors.New("对比方式不正确")
+	}
+	if rule.Url == "" {
+		return errors.New("URL不能为空")
+	}
+	if rule.HeaderName == "" && rule.HeaderValue != "" {
+		return errors.New("请填写请求头名称")
+	}
+	if rule.QueryName == "" && rule.QueryValue != "" {
+		return errors.New("请填写查询参数名称")
+	}
+	return nil
+}
+
+/*
+*
+URL名单匹配器 加载主机时编译
+等于用哈希表，前缀、后缀用字典树，包含用AC自动机，正则预先编译
+*/
+type URLMatcher struct {
+	rules    []urlMatchItem
+	exact    [2]map[string][]int //[0]区分大小写 [1]忽略大小写
+	prefix   [2]*byteTrie
+	suffix   [2]*byteTrie
+	contains [2]*byteTrie
+	regexes  []urlRegexItem //按名单顺序
+	hasFold  bool
+	count    int
+}
+
+type urlMatchItem struct {
+	rule    URLMatchRule
+	methods []string
+}
+
+type urlRegexItem struct {
+	index int
+	re    *regexp.Regexp
+}
+
+/*
+*
+编译URL名单 匹配结果为名单下标，对比方式不正确或正则有误的名单项跳过
+*/
+func NewURLMatcher(rules []URLMatchRule) *URLMatcher {
+	m := &URLMatcher{rules: make([]urlMatchItem, len(rules))}
+	for i := 0; i < 2; i++ {
+		m.exact[i] = map[string][]int{}
+		m.prefix[i] = newByteTrie()
+		m.suffix[i] = newByteTrie()
+		m.contains[i] = newByteTrie()
+	}
+	for i, rule := range rules {
+		item := urlMatchItem{rule: rule}
+		for _, method := range strings.Split(rule.Method, ",") {
+			if method = strings.TrimSpace(method); method != "" {
+				item.methods = append(item.methods, strings.ToUpper(method))
+			}
+		}
+		m.rules[i] = item
+		fold, pattern := 0, rule.Url
+		if rule.IgnoreCase {
+			fold, pattern = 1, strings.ToLower(rule.Url)
+		}
+		switch rule.CompareType {
+		case enums.URL_COMPARE_EQUAL:
+			m.exact[fold][pattern] = append(m.exact[fold][pattern], i)
+		case enums.URL_COMPARE_PREFIX:
+			m.prefix[fold].add(pattern, i, false)
+		case enums.URL_COMPARE_SUFFIX:
+			m.suffix[fold].add(pattern, i, true)
+		case enums.URL_COMPARE_CONTAINS:
+			m.contains[fold].add(pattern, i, false)
+		case enums.URL_COMPARE_REGEX:
+			re, err := compileURLRegex(rule)
+			if err != nil {
+				continue
+			}
+			m.regexes = append(m.regexes, urlRegexItem{index: i, re: re})
+			m.count++
+			continue
+		default:
+			continue
+		}
+		if rule.IgnoreCase {
+			m.hasFold = true
+		}
+		m.count++
+	}
+	m.contains[0].buildFail()
+	m.contains[1].buildFail()
+	return m
+}
+
+/*
+*
+查找命中的名单项 返回名单项下标
+多条命中时按名单顺序取第一条满足附加条件的
+*/
+func (m *URLMatcher) Match(rawUrl string, r *http.Request) (int, bool) {
+	if m == nil || m.count == 0 {
+		return 0, false
+	}
+	var candidates []int
+	urls := [2]string{rawUrl, ""}
+	if m.hasFold {
+		urls[1] = strings.ToLower(rawUrl)
+	}
+	for fold := 0; fold < 2; fold++ {
+		if fold == 1 && !m.hasFold {
+			break
+		}
+		candidates = append(candidates, m.exact[fold][urls[fold]]...)
+		candidates = m.prefix[fold].walkPrefix(urls[fold], candidates)
+		candidates = m.suffix[fold].walkSuffix(urls[fold], candidates)
+		candidates = m.contains[fold].scan(urls[fold], candidates)
+	}
+	sort.Ints(candidates)
+	var query url.Values
+	best := len(m.rules)
+	for _, index := range candidates {
+		if m.rules[index].matchRequest(r, &query) {
+			best = index
+			break
+		}
+	}
+	//正则只需要判断排在已命中名单项之前的
+	for _, item := range m.regexes {
+		if item.index >= best {
+			break
+		}
+		if item.re.MatchString(rawUrl) && m.rules[item.index].matchRequest(r, &query) {
+			return item.index, true
+		}
+	}
+	if best < len(m.rules) {
+		return best, true
+	}
+	return 0, false
+}
+
+// Len 名单项条数
+func (m *URLMatcher) Len() int {
+	if m == nil {
+		return 0
+	}
+	return m.count
+}
+
+// 判断请求是否满足名单项的附加条件 查询参数只在需要时解析一次
+func (item *urlMatchItem) matchRequest(r *http.Request, query *url.Values) bool {
+	rule := &item.rule
+	if len(item.methods) == 0 && rule.Host == "" && rule.HeaderName == "" && rule.QueryName == "" {
+		return true
+	}
+	if r == nil {
+		return false
+	}
+	if len(item.methods) > 0 {
+		found := false
+		for _, method := range item.methods {
+			if method == r.Method {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.Host != "" {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !strings.EqualFold(host, rule.Host) {
+			return false
+		}
+	}
+	if rule.HeaderName != "" && !matchURLValues(r.Header.Values(rule.HeaderName), rule.HeaderValue, rule.IgnoreCase) {
+		return false
+	}
+	if rule.QueryName != "" {
+		if *query == nil {
+			if r.URL != nil {
+				*query = r.URL.Query()
+			} else {
+				*query = url.Values{}
+			}
+		}
+		if !matchURLValues((*query)[rule.QueryName], rule.QueryValue, rule.IgnoreCase) {
+			return false
+		}
+	}
+	return true
+}
+
+// 值为空时只要求存在
+func matchURLValues(values []string, want string, ignoreCase bool) bool {
+	if len(values) == 0 {
+		return false
+	}
+	if want == "" {
+		return true
+	}
+	for _, value := range values {
+		if value == want || (ignoreCase && strings.EqualFold(value, want)) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileURLRegex(rule URLMatchRule) (*regexp.Regexp, error) {
+	if rule.IgnoreCase {
+		return regexp.Compile("(?i)" + rule.Url)
+	}
+	return regexp.Compile(rule.Url)
+}
+
+/*
+*
+字节字典树 前缀、后缀匹配直接沿树查找，构建失败指针后作为AC自动机做包含匹配
+*/
+type byteTrie struct {
+	nodes []byteTrieNode
+}
+
+type byteTrieNode struct {
+	next map[byte]int
+	fail int
+	out  []int
+}
+
+func newByteTrie() *byteTrie {
+	return &byteTrie{nodes: []byteTrieNode{{}}}
+}
+
+// 加入模式串 reverse 为倒序加入（后缀匹配）
+func (t *byteTrie) add(pattern string, index int, reverse bool) {
+	node := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if reverse {
+			c = pattern[len(pattern)-1-i]
+		}
+		if t.nodes[node].next == nil {
+			t.nodes[node].next = map[byte]int{}
+		}
+		child, ok := t.nodes[node].next[c]
+		if !ok {
+			t.nodes = append(t.nodes, byteTrieNode{})
+			child = len(t.nodes) - 1
+			t.nodes[node].next[c] = child
+		}
+		node = child
+	}
+	t.nodes[node].out = append(t.nodes[node].out, index)
+}
+
+// 构建AC自动机的失败指针 每个节点的输出合并失败指针节点的输出
+func (t *byteTrie) buildFail() {
+	queue := make([]int, 0, len(t.nodes))
+	for _, child := range t.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for c, child := range t.nodes[node].next {
+			fail := t.nodes[node].fail
+			for {
+				if next, ok := t.nodes[fail].next[c]; ok && next != child {
+					t.nodes[child].fail = next
+					break
+				}
+				if fail == 0 {
+					break
+				}
+				fail = t.nodes[fail].fail
+			}
+			t.nodes[child].out = append(t.nodes[child].out, t.nodes[t.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+// 前缀匹配 收集路径上所有节点的输出
+func (t *byteTrie) walkPrefix(s string, result []int) []int {
+	node := 0
+	result = append(result, t.nodes[0].out...)
+	for i := 0; i < len(s); i++ {
+		next, ok := t.nodes[node].next[s[i]]
+		if !ok {
+			break
+		}
+		node = next
+		result = append(result, t.nodes[node].out...)
+	}
+	return result
+}
+
+// 后缀匹配 从尾部倒序查找
+func (t *byteTrie) walkSuffix(s string, result []int) []int {
+	node := 0
+	result = append(result, t.nodes[0].out...)
+	for i := len(s) - 1; i >= 0; i-- {
+		next, ok := t.nodes[node].next[s[i]]
+		if !ok {
+			break
+		}
+		node = next
+		result = append(result, t.nodes[node].out...)
+	}
+	return result
+}
+
+// 包含匹配 同一名单项可能重复出现
+func (t *byteTrie) scan(s string, result []int) []int {
+	if len(t.nodes) == 1 {
+		return append(result, t.nodes[0].out...)
+	}
+	node := 0
+	result = append(result, t.nodes[0].out...)
+	for i := 0; i < len(s); i++ {
+		for {
+			if next, ok := t.nodes[node].next[s[i]]; ok {
+				node = next
+				break
+			}
+			if node == 0 {
+				break
+			}
+			node = t.nodes[node].fail
+		}
+		result = append(result, t.nodes[node].out...)
+	}
+	return result
+}
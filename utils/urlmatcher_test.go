@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"SamWaf/enums"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestURLMatcher(t *testing.T) {
+	rules := []URLMatchRule{
+		{CompareType: enums.URL_COMPARE_EQUAL, Url: "/login"},
+		{CompareType: enums.URL_COMPARE_PREFIX, Url: "/Admin", IgnoreCase: true},
+		{CompareType: enums.URL_COMPARE_SUFFIX, Url: ".php"},
+		{CompareType: enums.URL_COMPARE_CONTAINS, Url: "passwd"},
+		{CompareType: enums.URL_COMPARE_CONTAINS, Url: "wd"},
+		{CompareType: enums.URL_COMPARE_REGEX, Url: `^/api/v\d+/debug`},
+		{CompareType: enums.URL_COMPARE_PREFIX, Url: "/upload", Method: "post,put", Host: "a.com"},
+		{CompareType: enums.URL_COMPARE_PREFIX, Url: "/export", HeaderName: "X-Token", HeaderValue: "abc", IgnoreCase: true},
+		{CompareType: enums.URL_COMPARE_PREFIX, Url: "/search", QueryName: "debug"},
+		{CompareType: enums.URL_COMPARE_REGEX, Url: "("},
+	}
+	matcher := NewURLMatcher(rules)
+	if matcher.Len() != 9 {
+		t.Errorf("Len() = %d, want 9", matcher.Len())
+	}
+	tests := []struct {
+		method string
+		target string
+		host   string
+		header string
+		want   int
+		found  bool
+	}{
+		{"GET", "/login", "b.com", "", 0, true},
+		{"GET", "/login?x=1", "b.com", "", 0, false},
+		{"GET", "/ADMIN/index", "b.com", "", 1, true},
+		{"GET", "/index.php", "b.com", "", 2, true},
+		{"GET", "/index.PHP", "b.com", "", 0, false},
+		{"GET", "/etc/passwd", "b.com", "", 3, true},
+		{"GET", "/pwd", "b.com", "", 4, true},
+		{"GET", "/api/v2/debug", "b.com", "", 5, true},
+		{"GET", "/API/v2/debug", "b.com", "", 0, false},
+		{"POST", "/upload/1", "a.com:8080", "", 6, true},
+		{"GET", "/upload/1", "a.com", "", 0, false},
+		{"POST", "/upload/1", "b.com", "", 0, false},
+		{"GET", "/export", "b.com", "ABC", 7, true},
+		{"GET", "/export", "b.com", "", 0, false},
+		{"GET", "/search?q=1&debug=1", "b.com", "", 8, true},
+		{"GET", "/search?q=1", "b.com", "", 0, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "http://"+tt.host+tt.target, nil)
+		r.Host = tt.host
+		if tt.header != "" {
+			r.Header.Set("X-Token", tt.header)
+		}
+		got, found := matcher.Match(tt.target, r)
+		if found != tt.found || (found && got != tt.want) {
+			t.Errorf("Match(%s %s) = %d %v, want %d %v", tt.method, tt.target, got, found, tt.want, tt.found)
+		}
+	}
+	if err := CheckURLMatchRule(rules[9]); err == nil {
+		t.Errorf("invalid regex should be rejected")
+	}
+	if err := CheckURLMatchRule(URLMatchRule{CompareType: enums.URL_COMPARE_EQUAL, Url: "/a", HeaderValue: "x"}); err == nil {
+		t.Errorf("header value without name should be rejected")
+	}
+}
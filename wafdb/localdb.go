@@ -145,6 +145,24 @@ func InitCoreDb(currentDir string) {
 
 		//自动任务
 		db.AutoMigrate(&model.BatchTask{})
+		db.AutoMigrate(&model.BatchTaskHistory{})
+
+		//登录防护
+		db.AutoMigrate(&model.LoginProtect{})
+
+		//自定义SecLang规则
+		db.AutoMigrate(&model.OwaspRule{})
+
+		//规则模板
+		db.AutoMigrate(&model.RuleTemplate{})
+		db.AutoMigrate(&model.RuleTemplateBind{})
+
+		//地域限制
+		db.AutoMigrate(&model.GeoPolicy{})
+
+		//威胁情报
+		db.AutoMigrate(&model.ThreatFeed{})
+		db.AutoMigrate(&model.ThreatFeedEntry{})
 
 		global.GWAF_LOCAL_DB.Callback().Query().Before("gorm:query").Register("tenant_plugin:before_query", before_query)
 		global.GWAF_LOCAL_DB.Callback().Query().Before("gorm:update").Register("tenant_plugin:before_update", before_update)
@@ -287,6 +305,7 @@ func InitStatsDb(currentDir string) {
 		db.AutoMigrate(&model.StatsDay{})
 		db.AutoMigrate(&model.StatsIPDay{})
 		db.AutoMigrate(&model.StatsIPCityDay{})
+		db.AutoMigrate(&model.StatsRuleHit{})
 		global.GWAF_LOCAL_STATS_DB.Callback().Query().Before("gorm:query").Register("tenant_plugin:before_query", before_query)
 		global.GWAF_LOCAL_STATS_DB.Callback().Query().Before("gorm:update").Register("tenant_plugin:before_update", before_update)
 
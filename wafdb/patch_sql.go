@@ -82,4 +82,11 @@ func pathCoreSql(db *gorm.DB) {
 	} else {
 		zlog.Info("db", "idx_iptag_ip created")
 	}
+	//批量任务默认启用
+	err = db.Exec("UPDATE batch_tasks SET batch_enable=1 WHERE batch_enable IS NULL ").Error
+	if err != nil {
+		panic("failed to batch_tasks :batch_enable " + err.Error())
+	} else {
+		zlog.Info("db", "batch_tasks :batch_enable init successfully")
+	}
 }
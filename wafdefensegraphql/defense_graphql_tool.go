@@ -0,0 +1,226 @@
+package wafdefensegraphql
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// GraphqlLimit GraphQL检测限制 值为0表示不限制
+type GraphqlLimit struct {
+	MaxDepth           int  //最大查询深度
+	MaxAliases         int  //最大别名数量
+	MaxFields          int  //最大字段数量
+	MaxBatch           int  //最大批量请求数量
+	BlockIntrospection bool //是否阻止内省查询
+}
+
+// GraphqlArg 参数值
+type GraphqlArg struct {
+	Name  string //参数路径 如 user.id $name
+	Value string //参数值
+}
+
+// GraphqlRequest 一个GraphQL请求
+type GraphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphqlResult 检测结果
+type GraphqlResult struct {
+	IsAttack      bool         //是否违规
+	Name          string       //违规名称
+	OperationName string       //操作名称
+	Args          []GraphqlArg //参数值（含变量）
+}
+
+// 遍历时最多访问的选择数量（字段、片段引用、内联片段都计数），防止片段多次引用同一片段导致指数膨胀
+const maxVisitSelections = 100000
+
+/*
+*
+从请求中提取GraphQL请求 POST的json(含批量)、application/graphql 以及GET的query参数
+*/
+func ExtractRequests(contentType string, body string, query url.Values) []GraphqlRequest {
+	var requests []GraphqlRequest
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "application/graphql") {
+		return append(requests, GraphqlRequest{Query: body, OperationName: query.Get("operationName")})
+	}
+	trimBody := strings.TrimSpace(body)
+	if trimBody != "" && strings.Contains(contentType, "json") {
+		if strings.HasPrefix(trimBody, "[") {
+			if err := json.Unmarshal([]byte(trimBody), &requests); err == nil {
+				return requests
+			}
+		} else {
+			var request GraphqlRequest
+			if err := json.Unmarshal([]byte(trimBody), &request); err == nil && request.Query != "" {
+				return append(requests, request)
+			}
+		}
+	}
+	if query.Get("query") != "" {
+		request := GraphqlRequest{Query: query.Get("query"), OperationName: query.Get("operationName")}
+		if variables := query.Get("variables"); variables != "" {
+			_ = json.Unmarshal([]byte(variables), &request.Variables)
+		}
+		requests = append(requests, request)
+	}
+	return requests
+}
+
+/*
+*
+检测GraphQL请求
+*/
+func DetermineGraphql(requests []GraphqlRequest, limit GraphqlLimit) GraphqlResult {
+	result := GraphqlResult{}
+	if limit.MaxBatch > 0 && len(requests) > limit.MaxBatch {
+		result.IsAttack = true
+		result.Name = "批量请求数量超限(" + strconv.Itoa(len(requests)) + ")"
+		if len(requests) > 0 {
+			result.OperationName = requests[0].OperationName
+		}
+		return result
+	}
+	for _, request := range requests {
+		doc, err := parseDocument(request.Query)
+		if err == errTooDeep {
+			result.IsAttack = true
+			result.Name = "查询嵌套过深"
+			result.OperationName = request.OperationName
+			return result
+		}
+		if err != nil {
+			//已配置为GraphQL的路径上无法解析的查询可能用于绕过检测
+			result.IsAttack = true
+			result.Name = "查询语句无法解析"
+			result.OperationName = request.OperationName
+			return result
+		}
+		for _, op := range doc.operations {
+			opName := op.name
+			if opName == "" {
+				opName = request.OperationName
+			}
+			result.OperationName = opName
+			stat := &queryStat{doc: doc}
+			depth := stat.visit(op.selections, 1, map[string]bool{})
+			if stat.exceeded {
+				result.IsAttack = true
+				result.Name = "查询展开数量超限"
+				return result
+			}
+			result.Args = append(result.Args, op.args...)
+			result.Args = append(result.Args, stat.args...)
+			if limit.BlockIntrospection && stat.introspection {
+				result.IsAttack = true
+				result.Name = "内省查询"
+				return result
+			}
+			if limit.MaxDepth > 0 && depth > limit.MaxDepth {
+				result.IsAttack = true
+				result.Name = "查询深度超限(" + strconv.Itoa(depth) + ")"
+				return result
+			}
+			if limit.MaxAliases > 0 && stat.aliases > limit.MaxAliases {
+				result.IsAttack = true
+				result.Name = "别名数量超限(" + strconv.Itoa(stat.aliases) + ")"
+				return result
+			}
+			if limit.MaxFields > 0 && stat.fields > limit.MaxFields {
+				result.IsAttack = true
+				result.Name = "字段数量超限(" + strconv.Itoa(stat.fields) + ")"
+				return result
+			}
+		}
+		result.Args = flattenVariables("$", request.Variables, result.Args, 0)
+	}
+	return result
+}
+
+// 查询统计
+type queryStat struct {
+	doc           *document
+	fields        int
+	aliases       int
+	introspection bool
+	args          []GraphqlArg
+	visits        int  //已访问的选择数量
+	exceeded      bool //是否超过最大访问数量
+}
+
+// 遍历选择集 返回深度
+func (s *queryStat) visit(selections []selection, depth int, visiting map[string]bool) int {
+	maxDepth := depth
+	for _, sel := range selections {
+		s.visits++
+		if s.visits > maxVisitSelections {
+			s.exceeded = true
+		}
+		if s.exceeded {
+			return maxDepth
+		}
+		s.args = append(s.args, sel.args...)
+		if sel.spread != "" {
+			//片段引用不增加深度
+			fragment, ok := s.doc.fragments[sel.spread]
+			if !ok || visiting[sel.spread] {
+				continue
+			}
+			visiting[sel.spread] = true
+			if d := s.visit(fragment, depth, visiting); d > maxDepth {
+				maxDepth = d
+			}
+			delete(visiting, sel.spread)
+			continue
+		}
+		if sel.isInline {
+			if d := s.visit(sel.children, depth, visiting); d > maxDepth {
+				maxDepth = d
+			}
+			continue
+		}
+		s.fields++
+		if sel.alias != "" {
+			s.aliases++
+		}
+		if sel.name == "__schema" || sel.name == "__type" {
+			s.introspection = true
+		}
+		if sel.hasChilds {
+			if d := s.visit(sel.children, depth+1, visiting); d > maxDepth {
+				maxDepth = d
+			}
+		}
+	}
+	return maxDepth
+}
+
+// 展开变量中的字符串值
+func flattenVariables(prefix string, data interface{}, args []GraphqlArg, depth int) []GraphqlArg {
+	if depth > 32 {
+		return args
+	}
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			name := prefix + key
+			if prefix != "$" {
+				name = prefix + "." + key
+			}
+			args = flattenVariables(name, child, args, depth+1)
+		}
+	case []interface{}:
+		for i, child := range v {
+			args = flattenVariables(prefix+"["+strconv.Itoa(i)+"]", child, args, depth+1)
+		}
+	case string:
+		args = append(args, GraphqlArg{Name: prefix, Value: v})
+	}
+	return args
+}
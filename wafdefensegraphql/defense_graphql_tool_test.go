@@ -0,0 +1,109 @@
+package wafdefensegraphql
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetermineGraphqlLimit(t *testing.T) {
+	limit := GraphqlLimit{MaxDepth: 4, MaxAliases: 2, MaxFields: 20, MaxBatch: 2, BlockIntrospection: true}
+	allowed := []string{
+		`query GetUser($id: ID!) { user(id: $id) { id name posts(first: 5) { title } } }`,
+		`{ me { ...UserFields } } fragment UserFields on User { id name }`,
+		`mutation Save { save(input: {title: "hello", tags: ["a", "b"]}) { id } }`,
+	}
+	for _, query := range allowed {
+		result := DetermineGraphql([]GraphqlRequest{{Query: query}}, limit)
+		if result.IsAttack {
+			t.Errorf("query should be allowed: %s => %s", query, result.Name)
+		}
+	}
+	blocked := map[string]string{
+		`query Deep { a { b { c { d { e } } } } }`:                                        "查询深度超限",
+		`query Alias { a1: user { id } a2: user { id } a3: user { id } }`:                 "别名数量超限",
+		`query Intro { __schema { types { name } } }`:                                     "内省查询",
+		`{ a { ...F } } fragment F on A { b { ...G } } fragment G on B { c { d { e } } }`: "查询深度超限",
+		`{ a { ...F } } fragment F on A { ...F b }`:                                       "",
+		`{ ` + strings.Repeat("f ", 30) + ` }`:                                            "字段数量超限",
+	}
+	for query, name := range blocked {
+		result := DetermineGraphql([]GraphqlRequest{{Query: query}}, limit)
+		if name == "" {
+			if result.IsAttack {
+				t.Errorf("recursive fragment should not be blocked: %s", result.Name)
+			}
+			continue
+		}
+		if !result.IsAttack || !strings.HasPrefix(result.Name, name) {
+			t.Errorf("query %s => %v %s, want %s", query, result.IsAttack, result.Name, name)
+		}
+	}
+	batch := []GraphqlRequest{{Query: "{a}"}, {Query: "{b}"}, {Query: "{c}"}}
+	if result := DetermineGraphql(batch, limit); !result.IsAttack {
+		t.Errorf("batch should be blocked")
+	}
+	deep := strings.Repeat("{a", 300) + strings.Repeat("}", 300)
+	if result := DetermineGraphql([]GraphqlRequest{{Query: deep}}, GraphqlLimit{}); !result.IsAttack {
+		t.Errorf("too deep query should be blocked")
+	}
+}
+
+func TestDetermineGraphqlArgs(t *testing.T) {
+	body := `{"query":"query Find($q: String) { search(text: \"1' or '1'='1\", q: $q) { id } }","operationName":"Find","variables":{"q":"<script>alert(1)</script>","page":{"sort":"name"}}}`
+	requests := ExtractRequests("application/json", body, url.Values{})
+	if len(requests) != 1 {
+		t.Fatalf("ExtractRequests len = %d", len(requests))
+	}
+	result := DetermineGraphql(requests, GraphqlLimit{})
+	if result.OperationName != "Find" {
+		t.Errorf("operation name = %s", result.OperationName)
+	}
+	want := map[string]string{
+		"search.text": "1' or '1'='1",
+		"$q":          "<script>alert(1)</script>",
+		"$page.sort":  "name",
+	}
+	for name, value := range want {
+		found := false
+		for _, arg := range result.Args {
+			if arg.Name == name && arg.Value == value {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("arg %s=%s not found in %v", name, value, result.Args)
+		}
+	}
+	getRequests := ExtractRequests("", "", url.Values{"query": {"{ __type(name: \"User\") { name } }"}})
+	if result := DetermineGraphql(getRequests, GraphqlLimit{BlockIntrospection: true}); !result.IsAttack {
+		t.Errorf("GET introspection should be blocked")
+	}
+}
+
+func TestDetermineGraphqlUnparseable(t *testing.T) {
+	result := DetermineGraphql([]GraphqlRequest{{Query: `query { user(id: "1) { id }`, Variables: map[string]interface{}{"q": "x"}}}, GraphqlLimit{})
+	if !result.IsAttack || result.Name != "查询语句无法解析" {
+		t.Errorf("unparseable query should be blocked: %v %s", result.IsAttack, result.Name)
+	}
+}
+
+func TestDetermineGraphqlFragmentFanOut(t *testing.T) {
+	//每个片段引用下一个片段两次 展开数量随层数指数增长
+	var builder strings.Builder
+	builder.WriteString("{ a { ...F0 } }")
+	for i := 0; i < 24; i++ {
+		builder.WriteString(" fragment F" + strconv.Itoa(i) + " on A { ...F" + strconv.Itoa(i+1) + " ...F" + strconv.Itoa(i+1) + " }")
+	}
+	builder.WriteString(" fragment F24 on A { ...Missing }")
+	start := time.Now()
+	result := DetermineGraphql([]GraphqlRequest{{Query: builder.String()}}, GraphqlLimit{})
+	if !result.IsAttack || result.Name != "查询展开数量超限" {
+		t.Errorf("fan-out query got %v %s", result.IsAttack, result.Name)
+	}
+	if cost := time.Since(start); cost > time.Second {
+		t.Errorf("fan-out query took %s", cost)
+	}
+}
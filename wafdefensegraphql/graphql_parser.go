@@ -0,0 +1,550 @@
+package wafdefensegraphql
+
+import (
+	"errors"
+	"strings"
+)
+
+// 词法单元类型
+const (
+	tokenEOF = iota
+	tokenPunct
+	tokenName
+	tokenNumber
+	tokenString
+)
+
+type token struct {
+	kind  int
+	value string
+}
+
+// 选择项（字段、片段引用、内联片段）
+type selection struct {
+	name      string       //字段名称
+	alias     string       //别名
+	args      []GraphqlArg //参数
+	children  []selection  //子选择集
+	spread    string       //引用的片段名称
+	isInline  bool         //是否内联片段
+	hasChilds bool         //是否存在子选择集
+}
+
+// 操作定义
+type operation struct {
+	opType     string
+	name       string
+	selections []selection
+	args       []GraphqlArg //变量默认值、指令参数
+}
+
+// 解析后的文档
+type document struct {
+	operations []operation
+	fragments  map[string][]selection
+}
+
+type parser struct {
+	src   string
+	pos   int
+	tok   token
+	depth int
+}
+
+// 最大语法嵌套 防止恶意构造导致栈溢出
+const maxParseDepth = 256
+
+var errTooDeep = errors.New("嵌套过深")
+
+/*
+*
+解析GraphQL查询文档
+*/
+func parseDocument(src string) (*document, error) {
+	p := &parser{src: strings.TrimPrefix(src, "\uFEFF")}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: map[string][]selection{}}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.isPunct("{"):
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, operation{opType: "query", selections: selections})
+		case p.tok.kind == tokenName && p.tok.value == "fragment":
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if p.tok.kind != tokenName || p.tok.value != "on" {
+				return nil, errors.New("fragment缺少on")
+			}
+			if err = p.next(); err != nil {
+				return nil, err
+			}
+			if _, err = p.expectName(); err != nil {
+				return nil, err
+			}
+			if _, err = p.parseDirectives(); err != nil {
+				return nil, err
+			}
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = selections
+		case p.tok.kind == tokenName && (p.tok.value == "query" || p.tok.value == "mutation" || p.tok.value == "subscription"):
+			op := operation{opType: p.tok.value}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind == tokenName {
+				op.name = p.tok.value
+				if err := p.next(); err != nil {
+					return nil, err
+				}
+			}
+			if p.isPunct("(") {
+				args, err := p.parseVariableDefinitions()
+				if err != nil {
+					return nil, err
+				}
+				op.args = append(op.args, args...)
+			}
+			args, err := p.parseDirectives()
+			if err != nil {
+				return nil, err
+			}
+			op.args = append(op.args, args...)
+			op.selections, err = p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		default:
+			return nil, errors.New("无法识别的定义:" + p.tok.value)
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, errors.New("不存在操作")
+	}
+	return doc, nil
+}
+
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	if !p.isPunct("{") {
+		return nil, errors.New("缺少{")
+	}
+	p.depth++
+	if p.depth > maxParseDepth {
+		return nil, errTooDeep
+	}
+	defer func() { p.depth-- }()
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var selections []selection
+	for !p.isPunct("}") {
+		if p.tok.kind == tokenEOF {
+			return nil, errors.New("缺少}")
+		}
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	return selections, p.next()
+}
+
+func (p *parser) parseSelection() (selection, error) {
+	var sel selection
+	if p.isPunct("...") {
+		if err := p.next(); err != nil {
+			return sel, err
+		}
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			sel.spread = p.tok.value
+			if err := p.next(); err != nil {
+				return sel, err
+			}
+			args, err := p.parseDirectives()
+			sel.args = args
+			return sel, err
+		}
+		sel.isInline = true
+		if p.tok.kind == tokenName && p.tok.value == "on" {
+			if err := p.next(); err != nil {
+				return sel, err
+			}
+			if _, err := p.expectName(); err != nil {
+				return sel, err
+			}
+		}
+		args, err := p.parseDirectives()
+		if err != nil {
+			return sel, err
+		}
+		sel.args = args
+		sel.children, err = p.parseSelectionSet()
+		sel.hasChilds = true
+		return sel, err
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return sel, err
+	}
+	if p.isPunct(":") {
+		if err = p.next(); err != nil {
+			return sel, err
+		}
+		sel.alias = name
+		if name, err = p.expectName(); err != nil {
+			return sel, err
+		}
+	}
+	sel.name = name
+	if p.isPunct("(") {
+		if sel.args, err = p.parseArguments(name); err != nil {
+			return sel, err
+		}
+	}
+	args, err := p.parseDirectives()
+	if err != nil {
+		return sel, err
+	}
+	sel.args = append(sel.args, args...)
+	if p.isPunct("{") {
+		sel.hasChilds = true
+		if sel.children, err = p.parseSelectionSet(); err != nil {
+			return sel, err
+		}
+	}
+	return sel, nil
+}
+
+// 解析参数 (name: value, ...)
+func (p *parser) parseArguments(prefix string) ([]GraphqlArg, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var args []GraphqlArg
+	for !p.isPunct(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isPunct(":") {
+			return nil, errors.New("参数缺少:")
+		}
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		values, err := p.parseValue(prefix + "." + name)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, values...)
+	}
+	return args, p.next()
+}
+
+// 解析值 返回其中的字符串值
+func (p *parser) parseValue(name string) ([]GraphqlArg, error) {
+	p.depth++
+	if p.depth > maxParseDepth {
+		return nil, errTooDeep
+	}
+	defer func() { p.depth-- }()
+	switch {
+	case p.isPunct("$"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		_, err := p.expectName()
+		return nil, err
+	case p.isPunct("["):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		var args []GraphqlArg
+		for !p.isPunct("]") {
+			if p.tok.kind == tokenEOF {
+				return nil, errors.New("缺少]")
+			}
+			values, err := p.parseValue(name)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, values...)
+		}
+		return args, p.next()
+	case p.isPunct("{"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		var args []GraphqlArg
+		for !p.isPunct("}") {
+			field, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isPunct(":") {
+				return nil, errors.New("对象缺少:")
+			}
+			if err = p.next(); err != nil {
+				return nil, err
+			}
+			values, err := p.parseValue(name + "." + field)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, values...)
+		}
+		return args, p.next()
+	case p.tok.kind == tokenString:
+		arg := GraphqlArg{Name: name, Value: p.tok.value}
+		return []GraphqlArg{arg}, p.next()
+	case p.tok.kind == tokenNumber || p.tok.kind == tokenName:
+		return nil, p.next()
+	}
+	return nil, errors.New("无法识别的值:" + p.tok.value)
+}
+
+// 解析指令 @name(args)
+func (p *parser) parseDirectives() ([]GraphqlArg, error) {
+	var args []GraphqlArg
+	for p.isPunct("@") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if p.isPunct("(") {
+			directiveArgs, err := p.parseArguments("@" + name)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, directiveArgs...)
+		}
+	}
+	return args, nil
+}
+
+// 解析变量定义 ($id: ID! = "1", ...)
+func (p *parser) parseVariableDefinitions() ([]GraphqlArg, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var args []GraphqlArg
+	for !p.isPunct(")") {
+		if !p.isPunct("$") {
+			return nil, errors.New("变量缺少$")
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isPunct(":") {
+			return nil, errors.New("变量缺少:")
+		}
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		if err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if p.isPunct("=") {
+			if err = p.next(); err != nil {
+				return nil, err
+			}
+			values, err := p.parseValue("$" + name)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, values...)
+		}
+		directiveArgs, err := p.parseDirectives()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, directiveArgs...)
+	}
+	return args, p.next()
+}
+
+func (p *parser) parseType() error {
+	p.depth++
+	if p.depth > maxParseDepth {
+		return errTooDeep
+	}
+	defer func() { p.depth-- }()
+	if p.isPunct("[") {
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.parseType(); err != nil {
+			return err
+		}
+		if !p.isPunct("]") {
+			return errors.New("类型缺少]")
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+	if p.isPunct("!") {
+		return p.next()
+	}
+	return nil
+}
+
+func (p *parser) isPunct(value string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == value
+}
+
+func (p *parser) expectName() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", errors.New("缺少名称")
+	}
+	name := p.tok.value
+	return name, p.next()
+}
+
+// 读取下一个词法单元
+func (p *parser) next() error {
+	src := p.src
+	for p.pos < len(src) {
+		c := src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+			continue
+		}
+		if c == '#' {
+			for p.pos < len(src) && src[p.pos] != '\n' && src[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		break
+	}
+	if p.pos >= len(src) {
+		p.tok = token{kind: tokenEOF}
+		return nil
+	}
+	c := src[p.pos]
+	switch {
+	case strings.HasPrefix(src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{kind: tokenPunct, value: "..."}
+	case strings.IndexByte("{}()[]:$@!=|&", c) >= 0:
+		p.pos++
+		p.tok = token{kind: tokenPunct, value: string(c)}
+	case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		start := p.pos
+		for p.pos < len(src) && isNameChar(src[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{kind: tokenName, value: src[start:p.pos]}
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(src) && (isNameChar(src[p.pos]) || src[p.pos] == '.' || src[p.pos] == '+' || src[p.pos] == '-') {
+			p.pos++
+		}
+		p.tok = token{kind: tokenNumber, value: src[start:p.pos]}
+	case c == '"':
+		return p.readString()
+	default:
+		return errors.New("非法字符:" + string(c))
+	}
+	return nil
+}
+
+// 读取字符串 支持块字符串 """..."""
+func (p *parser) readString() error {
+	src := p.src
+	if strings.HasPrefix(src[p.pos:], `"""`) {
+		end := strings.Index(src[p.pos+3:], `"""`)
+		if end < 0 {
+			return errors.New("字符串未结束")
+		}
+		p.tok = token{kind: tokenString, value: src[p.pos+3 : p.pos+3+end]}
+		p.pos += end + 6
+		return nil
+	}
+	var sb strings.Builder
+	p.pos++
+	for p.pos < len(src) {
+		c := src[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			p.tok = token{kind: tokenString, value: sb.String()}
+			return nil
+		case '\\':
+			if p.pos+1 >= len(src) {
+				return errors.New("字符串未结束")
+			}
+			escaped := src[p.pos+1]
+			switch escaped {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case 'b', 'f':
+			case 'u':
+				if p.pos+6 <= len(src) {
+					sb.WriteString(decodeUnicodeEscape(src[p.pos+2 : p.pos+6]))
+					p.pos += 4
+				}
+			default:
+				sb.WriteByte(escaped)
+			}
+			p.pos += 2
+		case '\n', '\r':
+			return errors.New("字符串未结束")
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+	return errors.New("字符串未结束")
+}
+
+func decodeUnicodeEscape(hex string) string {
+	var r rune
+	for _, c := range hex {
+		r <<= 4
+		switch {
+		case c >= '0' && c <= '9':
+			r |= c - '0'
+		case c >= 'a' && c <= 'f':
+			r |= c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			r |= c - 'A' + 10
+		default:
+			return ""
+		}
+	}
+	return string(r)
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,141 @@
+package wafdefensegrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// 提取字段的最大嵌套深度和最大数量
+const (
+	maxFieldDepth = 16
+	maxFields     = 2000
+)
+
+var ErrMessageTooLarge = errors.New("grpc message too large")
+
+// GrpcField 消息中的字符串字段
+type GrpcField struct {
+	Name  string //字段编号路径 如 1.2
+	Value string //字段值
+}
+
+/*
+*
+读取一个长度前缀的gRPC消息 格式：1字节压缩标识 + 4字节长度 + 消息
+消息长度超过 maxSize 时返回 ErrMessageTooLarge
+*/
+func ReadMessage(r io.Reader, maxSize int) ([]byte, bool, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, false, err
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if int64(length) > int64(maxSize) {
+		return nil, false, ErrMessageTooLarge
+	}
+	message := make([]byte, length)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, false, err
+	}
+	return message, prefix[0] == 1, nil
+}
+
+/*
+*
+解压gzip压缩的消息 解压后超过 maxSize 时返回 ErrMessageTooLarge
+*/
+func Gunzip(message []byte, maxSize int) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(message))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSize {
+		return nil, ErrMessageTooLarge
+	}
+	return data, nil
+}
+
+/*
+*
+按protobuf编码提取消息中的字符串字段 没有proto定义，长度分隔的字段既尝试作为字符串也尝试作为嵌套消息
+*/
+func ExtractFields(message []byte) []GrpcField {
+	fields, _ := extractFields(message, "", 0, nil)
+	return fields
+}
+
+func extractFields(data []byte, prefix string, depth int, fields []GrpcField) ([]GrpcField, bool) {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 || key>>3 == 0 {
+			return fields, false
+		}
+		data = data[n:]
+		name := strconv.FormatUint(key>>3, 10)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		switch key & 7 {
+		case 0:
+			_, n = binary.Uvarint(data)
+			if n <= 0 {
+				return fields, false
+			}
+			data = data[n:]
+		case 1:
+			if len(data) < 8 {
+				return fields, false
+			}
+			data = data[8:]
+		case 5:
+			if len(data) < 4 {
+				return fields, false
+			}
+			data = data[4:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return fields, false
+			}
+			value := data[n : n+int(length)]
+			data = data[n+int(length):]
+			if len(fields) >= maxFields {
+				return fields, true
+			}
+			if isText(value) {
+				fields = append(fields, GrpcField{Name: name, Value: string(value)})
+			}
+			if depth < maxFieldDepth && len(value) > 0 {
+				if nested, ok := extractFields(value, name, depth+1, nil); ok {
+					fields = append(fields, nested...)
+				}
+			}
+		default:
+			return fields, false
+		}
+	}
+	return fields, true
+}
+
+// 是否是可读文本 不含除制表、换行外的控制字符
+func isText(value []byte) bool {
+	if len(value) == 0 || !utf8.Valid(value) {
+		return false
+	}
+	for _, c := range value {
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' {
+			return false
+		}
+	}
+	return true
+}
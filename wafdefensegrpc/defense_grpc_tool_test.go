@@ -0,0 +1,87 @@
+package wafdefensegrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"testing"
+)
+
+// 生成长度分隔字段
+func protoBytes(fieldNum int, value []byte) []byte {
+	data := binary.AppendUvarint(nil, uint64(fieldNum<<3|2))
+	data = binary.AppendUvarint(data, uint64(len(value)))
+	return append(data, value...)
+}
+
+// 生成varint字段
+func protoVarint(fieldNum int, value uint64) []byte {
+	data := binary.AppendUvarint(nil, uint64(fieldNum<<3))
+	return binary.AppendUvarint(data, value)
+}
+
+func grpcFrame(compressed bool, message []byte) []byte {
+	frame := make([]byte, 5, 5+len(message))
+	if compressed {
+		frame[0] = 1
+	}
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+func findField(fields []GrpcField, name string) (string, bool) {
+	for _, field := range fields {
+		if field.Name == name {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+func TestExtractFields(t *testing.T) {
+	inner := append(protoVarint(1, 42), protoBytes(2, []byte("1' or '1'='1"))...)
+	message := append(protoBytes(1, []byte("hello")), protoBytes(3, inner)...)
+	message = append(message, protoBytes(4, []byte{0x00, 0xff, 0x01})...)
+	fields := ExtractFields(message)
+	if value, ok := findField(fields, "1"); !ok || value != "hello" {
+		t.Errorf("field 1 = %q %v", value, ok)
+	}
+	if value, ok := findField(fields, "3.2"); !ok || value != "1' or '1'='1" {
+		t.Errorf("nested field 3.2 = %q %v", value, ok)
+	}
+	if _, ok := findField(fields, "4"); ok {
+		t.Errorf("binary field should not be extracted")
+	}
+	//截断的消息不会panic
+	for i := range message {
+		ExtractFields(message[:i])
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	body := bytes.NewReader(append(grpcFrame(false, []byte("abc")), grpcFrame(true, []byte("defg"))...))
+	message, compressed, err := ReadMessage(body, 16)
+	if err != nil || compressed || string(message) != "abc" {
+		t.Errorf("first message = %q %v %v", message, compressed, err)
+	}
+	message, compressed, err = ReadMessage(body, 16)
+	if err != nil || !compressed || string(message) != "defg" {
+		t.Errorf("second message = %q %v %v", message, compressed, err)
+	}
+	if _, _, err = ReadMessage(bytes.NewReader(grpcFrame(false, make([]byte, 32))), 16); err != ErrMessageTooLarge {
+		t.Errorf("large message err = %v", err)
+	}
+}
+
+func TestGunzip(t *testing.T) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write(bytes.Repeat([]byte("a"), 100))
+	writer.Close()
+	if data, err := Gunzip(buf.Bytes(), 100); err != nil || len(data) != 100 {
+		t.Errorf("gunzip = %d %v", len(data), err)
+	}
+	if _, err := Gunzip(buf.Bytes(), 99); err != ErrMessageTooLarge {
+		t.Errorf("gunzip bomb err = %v", err)
+	}
+}
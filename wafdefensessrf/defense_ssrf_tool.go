@@ -0,0 +1,273 @@
+package wafdefensessrf
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 内部主机名称
+var internalHostNames = []string{
+	"localhost",
+	"localhost.localdomain",
+	"metadata",
+	"metadata.google.internal",
+	"instance-data",
+	"instance-data.ec2.internal",
+}
+
+// 内网及保留地址段
+var internalCIDRs = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+var internalNets []*net.IPNet
+
+// 云厂商元数据地址
+var metadataIP = net.ParseIP("169.254.169.254")
+
+func init() {
+	for _, cidr := range internalCIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err == nil {
+			internalNets = append(internalNets, ipNet)
+		}
+	}
+}
+
+// URL类参数名称 这些参数的值即使不是URL形式（如 10.0.0.1:6379）也会检测
+var urlParamNames = []string{
+	"url", "uri", "link", "href", "src", "source", "dest", "destination", "target",
+	"redirect", "return", "next", "callback", "webhook", "proxy", "endpoint",
+	"host", "domain", "server", "addr", "address", "site", "feed", "image",
+}
+
+/*
+*
+判断参数是否存在SSRF 返回值： 是否SSRF，SSRF名称
+allowDomains 允许访问的外部域名,为空时只检测内网地址
+name 为参数名称，只检测URL形式的值以及URL类参数的值
+*/
+func DetermineSSRF(allowDomains []string, name string, value string) (bool, string) {
+	host, isURL := extractHost(value)
+	if host == "" {
+		return false, "未知"
+	}
+	if !isURL && !IsUrlParamName(name) {
+		return false, "未知"
+	}
+	if isAllowDomain(host, allowDomains) {
+		return false, "未知"
+	}
+	isInternal, ssrfName := checkInternalHost(host, isURL)
+	if isInternal {
+		return true, ssrfName
+	}
+	//配置了允许域名的情况下，URL指向其他外部域名也视为SSRF
+	if isURL && len(allowDomains) > 0 {
+		return true, "访问非允许域名:" + host
+	}
+	return false, "未知"
+}
+
+/*
+*
+是否是URL类参数 取JSON路径的最后一段，如 data.items[0].callback_url => callback_url，image[0] => image
+*/
+func IsUrlParamName(name string) bool {
+	name = strings.ToLower(name)
+	//JSON键名本身不是URL
+	if strings.HasSuffix(name, "#key") {
+		return false
+	}
+	for strings.HasSuffix(name, "]") && strings.Contains(name, "[") {
+		name = name[:strings.LastIndex(name, "[")]
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" {
+		return false
+	}
+	for _, urlName := range urlParamNames {
+		if name == urlName {
+			return true
+		}
+	}
+	for _, suffix := range []string{"url", "uri", "host", "domain", "callback", "webhook"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+*
+提取参数中的主机 返回值：主机，是否是URL形式
+*/
+func extractHost(arg string) (string, bool) {
+	value := strings.TrimSpace(arg)
+	if value == "" || len(value) > 2048 {
+		return "", false
+	}
+	lower := strings.ToLower(value)
+	if strings.Contains(lower, "://") || strings.HasPrefix(lower, "//") {
+		if strings.HasPrefix(lower, "//") {
+			value = "http:" + value
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" {
+			return "", false
+		}
+		return normalizeHost(u.Hostname()), true
+	}
+	//非URL形式 只识别 主机[:端口] 的写法
+	if strings.ContainsAny(value, " /?#&=\"'<>") {
+		return "", false
+	}
+	host := value
+	if h, _, err := net.SplitHostPort(value); err == nil {
+		host = h
+	}
+	return normalizeHost(host), false
+}
+
+func normalizeHost(host string) string {
+	host = strings.Trim(host, "[]")
+	host = strings.TrimSuffix(host, ".")
+	return strings.ToLower(host)
+}
+
+/*
+*
+是否在允许的域名里（含子域名）
+*/
+func isAllowDomain(host string, allowDomains []string) bool {
+	for _, domain := range allowDomains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" {
+			continue
+		}
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+*
+检测主机是否指向内部资源
+*/
+func checkInternalHost(host string, isURL bool) (bool, string) {
+	if isURL {
+		for _, name := range internalHostNames {
+			if host == name {
+				return true, "内部主机:" + host
+			}
+		}
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true, "内部主机:" + host
+	}
+	var ip net.IP
+	if isURL || strings.Count(host, ".") == 3 || strings.HasPrefix(host, "0x") {
+		ip = ParseLooseIP(host)
+	} else {
+		//普通参数只识别标准IP写法，避免把数字、版本号误判成IP
+		ip = net.ParseIP(host)
+	}
+	if ip == nil {
+		return false, ""
+	}
+	if ip.Equal(metadataIP) {
+		return true, "云元数据地址:" + ip.String()
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, ipNet := range internalNets {
+		if ipNet.Contains(ip) {
+			return true, "内网地址:" + ip.String()
+		}
+	}
+	return false, ""
+}
+
+/*
+*
+宽松解析IP 支持十进制、十六进制、八进制以及省略段的写法(如 2130706433、0x7f.1、0177.0.0.1)
+*/
+func ParseLooseIP(host string) net.IP {
+	if host == "" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip
+	}
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return nil
+	}
+	values := make([]uint64, 0, len(parts))
+	for _, part := range parts {
+		v, ok := parseIPPart(part)
+		if !ok {
+			return nil
+		}
+		values = append(values, v)
+	}
+	//按照 inet_aton 规则 最后一段占用剩余的字节
+	var result uint64
+	last := len(values) - 1
+	for i := 0; i < last; i++ {
+		if values[i] > 0xff {
+			return nil
+		}
+		result |= values[i] << (8 * uint(3-i))
+	}
+	remainBits := uint(8 * (4 - last))
+	if values[last] >= 1<<remainBits {
+		return nil
+	}
+	result |= values[last]
+	return net.IPv4(byte(result>>24), byte(result>>16), byte(result>>8), byte(result))
+}
+
+func parseIPPart(part string) (uint64, bool) {
+	if part == "" {
+		return 0, false
+	}
+	lower := strings.ToLower(part)
+	base := 10
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		base = 16
+		lower = lower[2:]
+		if lower == "" {
+			return 0, true
+		}
+	case len(lower) > 1 && lower[0] == '0':
+		base = 8
+		lower = lower[1:]
+	}
+	v, err := strconv.ParseUint(lower, base, 32)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
@@ -0,0 +1,97 @@
+package wafdefensessrf
+
+import "testing"
+
+func TestParseLooseIP(t *testing.T) {
+	cases := map[string]string{
+		"127.0.0.1":  "127.0.0.1",
+		"2130706433": "127.0.0.1",
+		"0x7f000001": "127.0.0.1",
+		"0177.0.0.1": "127.0.0.1",
+		"127.1":      "127.0.0.1",
+		"0x7f.0.0.1": "127.0.0.1",
+		"0xa9fea9fe": "169.254.169.254",
+	}
+	for input, want := range cases {
+		ip := ParseLooseIP(input)
+		if ip == nil || ip.String() != want {
+			t.Errorf("ParseLooseIP(%q) = %v, want %s", input, ip, want)
+		}
+	}
+	for _, input := range []string{"abc", "1.2.3.4.5", "256.1.1.1"} {
+		if ip := ParseLooseIP(input); ip != nil {
+			t.Errorf("ParseLooseIP(%q) = %v, want nil", input, ip)
+		}
+	}
+}
+
+func TestDetermineSSRF(t *testing.T) {
+	blocked := []struct {
+		name  string
+		value string
+	}{
+		{"q", "http://127.0.0.1/admin"},
+		{"q", "http://localhost:8080/"},
+		{"q", "http://169.254.169.254/latest/meta-data/"},
+		{"q", "http://2130706433/"},
+		{"q", "http://0x7f.1/"},
+		{"q", "//10.0.0.8/internal"},
+		{"q", "http://[::1]/"},
+		{"q", "http://[::ffff:192.168.1.1]/"},
+		{"q", "gopher://metadata.google.internal/"},
+		{"host", "192.168.1.10:6379"},
+		{"callback_url", "localhost"},
+		{"data.items[0].target", "0x7f000001"},
+	}
+	for _, tt := range blocked {
+		if isSsrf, _ := DetermineSSRF(nil, tt.name, tt.value); !isSsrf {
+			t.Errorf("DetermineSSRF(%q, %q) should be blocked", tt.name, tt.value)
+		}
+	}
+	passed := []struct {
+		name  string
+		value string
+	}{
+		{"url", "https://www.samwaf.com/"},
+		{"page", "1"},
+		{"price", "0.1"},
+		{"url", "metadata"},
+		{"q", "hello world"},
+		{"q", "localhost"},
+		{"color", "0x10"},
+		{"version", "1.2.3.4"},
+		{"ip", "192.168.1.10:6379"},
+		{"url#key", "http"},
+	}
+	for _, tt := range passed {
+		if isSsrf, ssrfName := DetermineSSRF(nil, tt.name, tt.value); isSsrf {
+			t.Errorf("DetermineSSRF(%q, %q) should pass, got %s", tt.name, tt.value, ssrfName)
+		}
+	}
+	allow := []string{"samwaf.com"}
+	if isSsrf, _ := DetermineSSRF(allow, "url", "https://img.samwaf.com/a.png"); isSsrf {
+		t.Errorf("allowed domain should pass")
+	}
+	if isSsrf, _ := DetermineSSRF(allow, "url", "https://evil.com/a.png"); !isSsrf {
+		t.Errorf("domain outside allow list should be blocked")
+	}
+}
+
+func TestIsUrlParamName(t *testing.T) {
+	cases := map[string]bool{
+		"url":                    true,
+		"redirect":               true,
+		"avatarUrl":              true,
+		"data.items[0].callback": true,
+		"image[0]":               true,
+		"webhook_url#key":        false,
+		"name":                   false,
+		"data.items[0].version":  false,
+		"":                       false,
+	}
+	for name, want := range cases {
+		if got := IsUrlParamName(name); got != want {
+			t.Errorf("IsUrlParamName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
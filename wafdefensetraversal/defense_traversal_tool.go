@@ -0,0 +1,40 @@
+package wafdefensetraversal
+
+import "strings"
+
+// 常见敏感系统文件(小写)
+var sensitiveFiles = []string{
+	"/etc/passwd",
+	"/etc/shadow",
+	"/etc/hosts",
+	"/proc/self/environ",
+	"/proc/self/cmdline",
+	"c:/windows/win.ini",
+	"c:/boot.ini",
+	"/windows/system32/",
+	"/web-inf/web.xml",
+}
+
+/*
+*
+判断参数是否存在目录穿越 返回值： 是否目录穿越，名称
+参数需要先经过解码归一化
+*/
+func DetermineTraversal(args ...string) (bool, string) {
+	for _, arg := range args {
+		if arg == "" {
+			continue
+		}
+		value := strings.ToLower(strings.ReplaceAll(arg, "\\", "/"))
+		for _, file := range sensitiveFiles {
+			if strings.Contains(value, file) {
+				return true, "读取敏感文件" + file
+			}
+		}
+		//连续多级的上级目录引用
+		if strings.Count(value, "../") >= 2 || strings.HasPrefix(value, "../") || strings.Contains(value, "=../") {
+			return true, "目录穿越"
+		}
+	}
+	return false, "未知"
+}
@@ -0,0 +1,29 @@
+package wafdefensetraversal
+
+import "testing"
+
+func TestDetermineTraversal(t *testing.T) {
+	blocked := []string{
+		"../../etc/passwd",
+		"..\\..\\windows\\win.ini",
+		"lang=../config",
+		"/var/www/../../../proc/self/environ",
+		"C:\\Windows\\win.ini",
+	}
+	for _, arg := range blocked {
+		if ok, _ := DetermineTraversal(arg); !ok {
+			t.Errorf("DetermineTraversal(%q) should be blocked", arg)
+		}
+	}
+	allowed := []string{
+		"zh-CN",
+		"http://example.com/a/b/index.html",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64)",
+		"images/../logo.png",
+	}
+	for _, arg := range allowed {
+		if ok, name := DetermineTraversal(arg); ok {
+			t.Errorf("DetermineTraversal(%q) = %s, should be allowed", arg, name)
+		}
+	}
+}
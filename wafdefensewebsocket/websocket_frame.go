@@ -0,0 +1,148 @@
+package wafdefensewebsocket
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// 帧类型
+const (
+	OPCODE_CONTINUATION = 0x0
+	OPCODE_TEXT         = 0x1
+	OPCODE_BINARY       = 0x2
+	OPCODE_CLOSE        = 0x8
+	OPCODE_PING         = 0x9
+	OPCODE_PONG         = 0xA
+)
+
+// 关闭状态码
+const (
+	CLOSE_POLICY_VIOLATION = 1008 //违反策略
+	CLOSE_MESSAGE_TOO_BIG  = 1009 //消息过大
+)
+
+var errInvalidLength = errors.New("websocket frame length invalid")
+
+// FrameHeader 帧头
+type FrameHeader struct {
+	Fin     bool    //是否最后一帧
+	Rsv1    bool    //压缩标识(permessage-deflate)
+	Opcode  byte    //帧类型
+	Masked  bool    //是否掩码
+	MaskKey [4]byte //掩码
+	Length  int64   //负载长度
+	Raw     []byte  //原始帧头字节 用于原样转发
+}
+
+// IsControl 是否控制帧
+func (h FrameHeader) IsControl() bool {
+	return h.Opcode&0x8 != 0
+}
+
+/*
+*
+读取一个帧头 负载需要调用方按 Length 继续读取
+*/
+func ReadFrameHeader(r io.Reader) (FrameHeader, error) {
+	header := FrameHeader{}
+	buf := make([]byte, 2, 14)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return header, err
+	}
+	header.Fin = buf[0]&0x80 != 0
+	header.Rsv1 = buf[0]&0x40 != 0
+	header.Opcode = buf[0] & 0x0F
+	header.Masked = buf[1]&0x80 != 0
+	length := int64(buf[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return header, err
+		}
+		buf = append(buf, ext...)
+		length = int64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return header, err
+		}
+		buf = append(buf, ext...)
+		size := binary.BigEndian.Uint64(ext)
+		if size > 1<<63-1 {
+			return header, errInvalidLength
+		}
+		length = int64(size)
+	}
+	header.Length = length
+	if header.Masked {
+		if _, err := io.ReadFull(r, header.MaskKey[:]); err != nil {
+			return header, err
+		}
+		buf = append(buf, header.MaskKey[:]...)
+	}
+	header.Raw = buf
+	return header, nil
+}
+
+/*
+*
+返回去掉掩码后的负载 不修改原数据
+*/
+func UnmaskPayload(header FrameHeader, payload []byte) []byte {
+	result := make([]byte, len(payload))
+	copy(result, payload)
+	if header.Masked {
+		for i := range result {
+			result[i] ^= header.MaskKey[i%4]
+		}
+	}
+	return result
+}
+
+/*
+*
+生成服务端发给客户端的关闭帧
+*/
+func CloseFrame(code uint16, reason string) []byte {
+	//控制帧负载最多125字节
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, code)
+	payload = append(payload, reason...)
+	return append([]byte{0x80 | OPCODE_CLOSE, byte(len(payload))}, payload...)
+}
+
+/*
+*
+判断Origin是否在允许列表中 支持完整地址(https://a.com)、域名(a.com)和泛域名(*.a.com)
+*/
+func MatchOrigin(origin string, allowOrigins []string) bool {
+	origin = strings.ToLower(strings.TrimSpace(origin))
+	originHost := origin
+	if u, err := url.Parse(origin); err == nil && u.Host != "" {
+		originHost = u.Hostname()
+	}
+	for _, allowOrigin := range allowOrigins {
+		allowOrigin = strings.ToLower(strings.TrimSpace(allowOrigin))
+		if allowOrigin == "" {
+			continue
+		}
+		if allowOrigin == "*" || allowOrigin == origin {
+			return true
+		}
+		if strings.HasPrefix(allowOrigin, "*.") {
+			if strings.HasSuffix(originHost, allowOrigin[1:]) {
+				return true
+			}
+		} else if !strings.Contains(allowOrigin, "://") && allowOrigin == originHost {
+			return true
+		}
+	}
+	return false
+}
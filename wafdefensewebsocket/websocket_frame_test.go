@@ -0,0 +1,81 @@
+package wafdefensewebsocket
+
+import (
+	"bytes"
+	"testing"
+)
+
+// 生成客户端掩码帧
+func maskedFrame(opcode byte, fin bool, payload []byte) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) < 65536:
+		frame = append(frame, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	}
+	key := []byte{1, 2, 3, 4}
+	frame = append(frame, key...)
+	for i, b := range payload {
+		frame = append(frame, b^key[i%4])
+	}
+	return frame
+}
+
+func TestReadFrameHeader(t *testing.T) {
+	long := bytes.Repeat([]byte("a"), 300)
+	data := append(maskedFrame(OPCODE_TEXT, true, []byte("hello")), maskedFrame(OPCODE_BINARY, false, long)...)
+	reader := bytes.NewReader(data)
+
+	header, err := ReadFrameHeader(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !header.Fin || header.Opcode != OPCODE_TEXT || header.Length != 5 || len(header.Raw) != 6 {
+		t.Fatalf("unexpected header %+v", header)
+	}
+	payload := make([]byte, header.Length)
+	reader.Read(payload)
+	if string(UnmaskPayload(header, payload)) != "hello" {
+		t.Errorf("unmask = %q", UnmaskPayload(header, payload))
+	}
+
+	header, err = ReadFrameHeader(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Fin || header.Opcode != OPCODE_BINARY || header.Length != 300 || len(header.Raw) != 8 {
+		t.Fatalf("unexpected header %+v", header)
+	}
+}
+
+func TestCloseFrame(t *testing.T) {
+	frame := CloseFrame(CLOSE_POLICY_VIOLATION, "blocked")
+	header, err := ReadFrameHeader(bytes.NewReader(frame))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Opcode != OPCODE_CLOSE || !header.IsControl() || header.Masked || header.Length != 9 {
+		t.Errorf("unexpected header %+v", header)
+	}
+}
+
+func TestMatchOrigin(t *testing.T) {
+	allowOrigins := []string{"https://chat.example.com", "*.example.org", "app.test"}
+	allowed := []string{"https://chat.example.com", "https://a.example.org", "http://app.test:8080"}
+	for _, origin := range allowed {
+		if !MatchOrigin(origin, allowOrigins) {
+			t.Errorf("MatchOrigin(%q) should be allowed", origin)
+		}
+	}
+	denied := []string{"http://chat.example.com", "https://evil.com", "https://example.org.evil.com", "null"}
+	for _, origin := range denied {
+		if MatchOrigin(origin, allowOrigins) {
+			t.Errorf("MatchOrigin(%q) should be denied", origin)
+		}
+	}
+}
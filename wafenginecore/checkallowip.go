@@ -1,12 +1,14 @@
 package wafenginecore
 
 import (
+	"SamWaf/enums"
 	"SamWaf/global"
 	"SamWaf/innerbean"
+	"SamWaf/model"
 	"SamWaf/model/detection"
-	"SamWaf/utils"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 /*
@@ -21,22 +23,31 @@ func (waf *WafEngine) CheckAllowIP(r *http.Request, weblogbean *innerbean.WebLog
 		Content:         "",
 	}
 	//ip白名单策略（局部）
-	if waf.HostTarget[weblogbean.HOST].IPWhiteLists != nil {
-		for i := 0; i < len(waf.HostTarget[weblogbean.HOST].IPWhiteLists); i++ {
-			if utils.CheckIPInCIDR(weblogbean.SRC_IP, waf.HostTarget[weblogbean.HOST].IPWhiteLists[i].Ip) {
-				result.JumpGuardResult = true
-				break
-			}
-		}
+	if entry, ok := waf.matchAllowIP(weblogbean.HOST, weblogbean); ok {
+		weblogbean.RULE = listHitRuleName("IP白名单", entry.Id, entry.Remarks)
+		result.JumpGuardResult = true
+		return result
 	}
 	//ip白名单策略（全局）
-	if waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 && waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].IPWhiteLists != nil {
-		for i := 0; i < len(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].IPWhiteLists); i++ {
-			if utils.CheckIPInCIDR(weblogbean.SRC_IP, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].IPWhiteLists[i].Ip) {
-				result.JumpGuardResult = true
-				break
-			}
+	if waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 {
+		if entry, ok := waf.matchAllowIP(global.GWAF_GLOBAL_HOST_NAME, weblogbean); ok {
+			weblogbean.RULE = listHitRuleName("【全局】IP白名单", entry.Id, entry.Remarks)
+			result.JumpGuardResult = true
 		}
 	}
 	return result
 }
+
+// 在主机的ip白名单前缀树中查找 每次查找都计入判断统计
+func (waf *WafEngine) matchAllowIP(host string, weblogbean *innerbean.WebLog) (model.IPAllowList, bool) {
+	hostsafe := waf.HostTarget[host]
+	lists := hostsafe.IPWhiteLists
+	startTime := time.Now()
+	index, ok := hostsafe.IPWhiteMatcher.Match(weblogbean.SRC_IP, 0, nil)
+	if !ok || index >= len(lists) {
+		recordListStats(hostsafe.Host.Code, enums.RULE_STATS_TYPE_IP_ALLOW, len(lists), "", time.Since(startTime), weblogbean.REQ_UUID)
+		return model.IPAllowList{}, false
+	}
+	recordListStats(hostsafe.Host.Code, enums.RULE_STATS_TYPE_IP_ALLOW, len(lists), lists[index].Id, time.Since(startTime), weblogbean.REQ_UUID)
+	return lists[index], true
+}
@@ -1,12 +1,14 @@
 package wafenginecore
 
 import (
+	"SamWaf/enums"
 	"SamWaf/global"
 	"SamWaf/innerbean"
+	"SamWaf/model"
 	"SamWaf/model/detection"
 	"net/http"
 	"net/url"
-	"strings"
+	"time"
 )
 
 /*
@@ -14,7 +16,7 @@ import (
 检测允许的URL
 返回是否满足条件
 */
-func (waf *WafEngine) CheckAllowURL(r *http.Request, weblogbean innerbean.WebLog, formValue url.Values) detection.Result {
+func (waf *WafEngine) CheckAllowURL(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
 	result := detection.Result{
 		JumpGuardResult: false,
 		IsBlock:         false,
@@ -22,28 +24,31 @@ func (waf *WafEngine) CheckAllowURL(r *http.Request, weblogbean innerbean.WebLog
 		Content:         "",
 	}
 	//url白名单策略（局部）
-	if waf.HostTarget[weblogbean.HOST].UrlWhiteLists != nil {
-		for i := 0; i < len(waf.HostTarget[weblogbean.HOST].UrlWhiteLists); i++ {
-			if (waf.HostTarget[weblogbean.HOST].UrlWhiteLists[i].CompareType == "等于" && waf.HostTarget[weblogbean.HOST].UrlWhiteLists[i].Url == weblogbean.URL) ||
-				(waf.HostTarget[weblogbean.HOST].UrlWhiteLists[i].CompareType == "前缀匹配" && strings.HasPrefix(weblogbean.URL, waf.HostTarget[weblogbean.HOST].UrlWhiteLists[i].Url)) ||
-				(waf.HostTarget[weblogbean.HOST].UrlWhiteLists[i].CompareType == "后缀匹配" && strings.HasSuffix(weblogbean.URL, waf.HostTarget[weblogbean.HOST].UrlWhiteLists[i].Url)) ||
-				(waf.HostTarget[weblogbean.HOST].UrlWhiteLists[i].CompareType == "包含匹配" && strings.Contains(weblogbean.URL, waf.HostTarget[weblogbean.HOST].UrlWhiteLists[i].Url)) {
-				result.JumpGuardResult = true
-				break
-			}
-		}
+	if entry, ok := waf.matchAllowURL(weblogbean.HOST, r, weblogbean); ok {
+		weblogbean.RULE = listHitRuleName("URL白名单", entry.Id, entry.Remarks)
+		result.JumpGuardResult = true
+		return result
 	}
 	//url白名单策略（全局）
-	if waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 && waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlWhiteLists != nil {
-		for i := 0; i < len(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlWhiteLists); i++ {
-			if (waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlWhiteLists[i].CompareType == "等于" && waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlWhiteLists[i].Url == weblogbean.URL) ||
-				(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlWhiteLists[i].CompareType == "前缀匹配" && strings.HasPrefix(weblogbean.URL, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlWhiteLists[i].Url)) ||
-				(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlWhiteLists[i].CompareType == "后缀匹配" && strings.HasSuffix(weblogbean.URL, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlWhiteLists[i].Url)) ||
-				(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlWhiteLists[i].CompareType == "包含匹配" && strings.Contains(weblogbean.URL, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlWhiteLists[i].Url)) {
-				result.JumpGuardResult = true
-				break
-			}
+	if waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 {
+		if entry, ok := waf.matchAllowURL(global.GWAF_GLOBAL_HOST_NAME, r, weblogbean); ok {
+			weblogbean.RULE = listHitRuleName("【全局】URL白名单", entry.Id, entry.Remarks)
+			result.JumpGuardResult = true
 		}
 	}
 	return result
 }
+
+// 在主机的url白名单匹配器中查找 每次查找都计入判断统计
+func (waf *WafEngine) matchAllowURL(host string, r *http.Request, weblogbean *innerbean.WebLog) (model.URLAllowList, bool) {
+	hostsafe := waf.HostTarget[host]
+	lists := hostsafe.UrlWhiteLists
+	startTime := time.Now()
+	index, ok := hostsafe.UrlWhiteMatcher.Match(weblogbean.URL, r)
+	if !ok || index >= len(lists) {
+		recordListStats(hostsafe.Host.Code, enums.RULE_STATS_TYPE_URL_ALLOW, len(lists), "", time.Since(startTime), weblogbean.REQ_UUID)
+		return model.URLAllowList{}, false
+	}
+	recordListStats(hostsafe.Host.Code, enums.RULE_STATS_TYPE_URL_ALLOW, len(lists), lists[index].Id, time.Since(startTime), weblogbean.REQ_UUID)
+	return lists[index], true
+}
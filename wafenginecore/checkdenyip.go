@@ -1,12 +1,14 @@
 package wafenginecore
 
 import (
+	"SamWaf/enums"
 	"SamWaf/global"
 	"SamWaf/innerbean"
+	"SamWaf/model"
 	"SamWaf/model/detection"
-	"SamWaf/utils"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 /*
@@ -22,28 +24,38 @@ func (waf *WafEngine) CheckDenyIP(r *http.Request, weblogbean *innerbean.WebLog,
 		Content:         "",
 	}
 	//ip黑名单策略  （局部）
-	if waf.HostTarget[weblogbean.HOST].IPBlockLists != nil {
-		for i := 0; i < len(waf.HostTarget[weblogbean.HOST].IPBlockLists); i++ {
-			if utils.CheckIPInCIDR(weblogbean.SRC_IP, waf.HostTarget[weblogbean.HOST].IPBlockLists[i].Ip) {
-				weblogbean.RISK_LEVEL = 1
-				result.IsBlock = true
-				result.Title = "IP黑名单"
-				result.Content = "您的访问被阻止了IP限制"
-				return result
-			}
-		}
+	if entry, ok := waf.matchDenyIP(weblogbean.HOST, weblogbean); ok {
+		fillDenyIPResult(&result, entry, weblogbean, "IP黑名单")
+		return result
 	}
 	//ip黑名单策略（全局）
-	if waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 && waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].IPBlockLists != nil {
-		for i := 0; i < len(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].IPBlockLists); i++ {
-			if utils.CheckIPInCIDR(weblogbean.SRC_IP, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].IPBlockLists[i].Ip) {
-				weblogbean.RISK_LEVEL = 1
-				result.IsBlock = true
-				result.Title = "【全局】IP黑名单"
-				result.Content = "您的访问被阻止了IP限制"
-				return result
-			}
+	if waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 {
+		if entry, ok := waf.matchDenyIP(global.GWAF_GLOBAL_HOST_NAME, weblogbean); ok {
+			fillDenyIPResult(&result, entry, weblogbean, "【全局】IP黑名单")
+			return result
 		}
 	}
 	return result
 }
+
+// 在主机的ip黑名单前缀树中查找 IP和网段都未命中时按ASN查找 每次查找都计入判断统计
+func (waf *WafEngine) matchDenyIP(host string, weblogbean *innerbean.WebLog) (model.IPBlockList, bool) {
+	hostsafe := waf.HostTarget[host]
+	lists := hostsafe.IPBlockLists
+	startTime := time.Now()
+	index, ok := hostsafe.IPBlockMatcher.Match(weblogbean.SRC_IP, weblogbean.ASN, nil)
+	if !ok || index >= len(lists) {
+		recordListStats(hostsafe.Host.Code, enums.RULE_STATS_TYPE_IP_BLOCK, len(lists), "", time.Since(startTime), weblogbean.REQ_UUID)
+		return model.IPBlockList{}, false
+	}
+	recordListStats(hostsafe.Host.Code, enums.RULE_STATS_TYPE_IP_BLOCK, len(lists), lists[index].Id, time.Since(startTime), weblogbean.REQ_UUID)
+	return lists[index], true
+}
+
+// 填充黑名单拦截结果 名单项Id和备注作为规则名记录到日志
+func fillDenyIPResult(result *detection.Result, entry model.IPBlockList, weblogbean *innerbean.WebLog, title string) {
+	weblogbean.RISK_LEVEL = 1
+	result.IsBlock = true
+	result.Title = listHitRuleName(title, entry.Id, entry.Remarks)
+	result.Content = "您的访问被阻止了IP限制"
+}
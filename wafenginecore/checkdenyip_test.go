@@ -0,0 +1,92 @@
+package wafenginecore
+
+import (
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/baseorm"
+	"SamWaf/model/wafenginmodel"
+	"net/http/httptest"
+	"testing"
+)
+
+func newIPListTestWaf(allowLists []model.IPAllowList, blockLists []model.IPBlockList) *WafEngine {
+	return &WafEngine{
+		HostTarget: map[string]*wafenginmodel.HostSafe{
+			"a.com:80": {
+				Host:           model.Hosts{Code: "hostA", GUARD_STATUS: 1},
+				IPWhiteLists:   allowLists,
+				IPWhiteMatcher: wafenginmodel.BuildIPAllowMatcher(allowLists),
+				IPBlockLists:   blockLists,
+				IPBlockMatcher: wafenginmodel.BuildIPBlockMatcher(blockLists),
+			},
+			global.GWAF_GLOBAL_HOST_NAME: {
+				Host:           model.Hosts{Code: "hostGlobal", GUARD_STATUS: 1},
+				IPWhiteMatcher: wafenginmodel.BuildIPAllowMatcher(nil),
+				IPBlockMatcher: wafenginmodel.BuildIPBlockMatcher(nil),
+			},
+		},
+	}
+}
+
+func ruleStatsCount(ruleType string, ruleCode string) (int64, int64) {
+	value, ok := ruleStats.Load(ruleStatsKey{ruleType: ruleType, ruleCode: ruleCode})
+	if !ok {
+		return 0, 0
+	}
+	counter := value.(*ruleStatsCounter)
+	return counter.evalCount, counter.hitCount
+}
+
+func TestCheckIPListRecordsEntry(t *testing.T) {
+	allowLists := []model.IPAllowList{{BaseOrm: baseorm.BaseOrm{Id: "allow1"}, HostCode: "hostA", Ip: "1.1.1.1", Remarks: "办公网"}}
+	blockLists := []model.IPBlockList{{BaseOrm: baseorm.BaseOrm{Id: "block1"}, HostCode: "hostA", Ip: "2.2.2.0/24"}}
+	waf := newIPListTestWaf(allowLists, blockLists)
+	keys := []ruleStatsKey{
+		{enums.RULE_STATS_TYPE_IP_ALLOW, "allow1"}, {enums.RULE_STATS_TYPE_IP_ALLOW, "hostA"},
+		{enums.RULE_STATS_TYPE_IP_BLOCK, "block1"}, {enums.RULE_STATS_TYPE_IP_BLOCK, "hostA"},
+	}
+	for _, key := range keys {
+		ruleStats.Delete(key)
+	}
+	defer func() {
+		for _, key := range keys {
+			ruleStats.Delete(key)
+		}
+	}()
+	r := httptest.NewRequest("GET", "http://a.com/", nil)
+
+	weblogbean := &innerbean.WebLog{HOST: "a.com:80", SRC_IP: "1.1.1.1"}
+	if result := waf.CheckAllowIP(r, weblogbean, nil); !result.JumpGuardResult || weblogbean.RULE != "IP白名单(allow1):办公网" {
+		t.Errorf("allow got %v %s", result.JumpGuardResult, weblogbean.RULE)
+	}
+	weblogbean = &innerbean.WebLog{HOST: "a.com:80", SRC_IP: "2.2.2.2"}
+	if result := waf.CheckAllowIP(r, weblogbean, nil); result.JumpGuardResult || weblogbean.RULE != "" {
+		t.Errorf("allow miss got %v %s", result.JumpGuardResult, weblogbean.RULE)
+	}
+	if result := waf.CheckDenyIP(r, weblogbean, nil); !result.IsBlock || result.Title != "IP黑名单(block1)" {
+		t.Errorf("block got %v %s", result.IsBlock, result.Title)
+	}
+	weblogbean = &innerbean.WebLog{HOST: "a.com:80", SRC_IP: "3.3.3.3"}
+	if result := waf.CheckDenyIP(r, weblogbean, nil); result.IsBlock {
+		t.Errorf("block miss got %v %s", result.IsBlock, result.Title)
+	}
+
+	//命中计入名单项 未命中计入主机 空的全局名单不记录
+	if eval, hit := ruleStatsCount(enums.RULE_STATS_TYPE_IP_ALLOW, "allow1"); eval != 1 || hit != 1 {
+		t.Errorf("allow entry stats %d %d", eval, hit)
+	}
+	if eval, hit := ruleStatsCount(enums.RULE_STATS_TYPE_IP_ALLOW, "hostA"); eval != 1 || hit != 0 {
+		t.Errorf("allow miss stats %d %d", eval, hit)
+	}
+	if eval, hit := ruleStatsCount(enums.RULE_STATS_TYPE_IP_BLOCK, "block1"); eval != 1 || hit != 1 {
+		t.Errorf("block entry stats %d %d", eval, hit)
+	}
+	if eval, hit := ruleStatsCount(enums.RULE_STATS_TYPE_IP_BLOCK, "hostA"); eval != 1 || hit != 0 {
+		t.Errorf("block miss stats %d %d", eval, hit)
+	}
+	if eval, _ := ruleStatsCount(enums.RULE_STATS_TYPE_IP_BLOCK, "hostGlobal"); eval != 0 {
+		t.Errorf("empty global list should not be recorded, got %d", eval)
+	}
+}
@@ -1,12 +1,14 @@
 package wafenginecore
 
 import (
+	"SamWaf/enums"
 	"SamWaf/global"
 	"SamWaf/innerbean"
+	"SamWaf/model"
 	"SamWaf/model/detection"
 	"net/http"
 	"net/url"
-	"strings"
+	"time"
 )
 
 /*
@@ -21,35 +23,39 @@ func (waf *WafEngine) CheckDenyURL(r *http.Request, weblogbean *innerbean.WebLog
 		Title:           "",
 		Content:         "",
 	}
-	//url黑名单策略-(局部) （待优化性能）
-	if waf.HostTarget[weblogbean.HOST].UrlBlockLists != nil {
-		for i := 0; i < len(waf.HostTarget[weblogbean.HOST].UrlBlockLists); i++ {
-			if (waf.HostTarget[weblogbean.HOST].UrlBlockLists[i].CompareType == "等于" && waf.HostTarget[weblogbean.HOST].UrlBlockLists[i].Url == weblogbean.URL) ||
-				(waf.HostTarget[weblogbean.HOST].UrlBlockLists[i].CompareType == "前缀匹配" && strings.HasPrefix(weblogbean.URL, waf.HostTarget[weblogbean.HOST].UrlBlockLists[i].Url)) ||
-				(waf.HostTarget[weblogbean.HOST].UrlBlockLists[i].CompareType == "后缀匹配" && strings.HasSuffix(weblogbean.URL, waf.HostTarget[weblogbean.HOST].UrlBlockLists[i].Url)) ||
-				(waf.HostTarget[weblogbean.HOST].UrlBlockLists[i].CompareType == "包含匹配" && strings.Contains(weblogbean.URL, waf.HostTarget[weblogbean.HOST].UrlBlockLists[i].Url)) {
-				weblogbean.RISK_LEVEL = 1
-				result.IsBlock = true
-				result.Title = "URL黑名单"
-				result.Content = "您的访问被阻止了URL限制"
-				return result
-			}
-		}
+	//url黑名单策略-(局部)
+	if entry, ok := waf.matchDenyURL(weblogbean.HOST, r, weblogbean); ok {
+		fillDenyURLResult(&result, entry, weblogbean, "URL黑名单")
+		return result
 	}
-	//url黑名单策略-(全局) （待优化性能）
-	if waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 && waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlBlockLists != nil {
-		for i := 0; i < len(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlBlockLists); i++ {
-			if (waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlBlockLists[i].CompareType == "等于" && waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlBlockLists[i].Url == weblogbean.URL) ||
-				(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlBlockLists[i].CompareType == "前缀匹配" && strings.HasPrefix(weblogbean.URL, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlBlockLists[i].Url)) ||
-				(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlBlockLists[i].CompareType == "后缀匹配" && strings.HasSuffix(weblogbean.URL, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlBlockLists[i].Url)) ||
-				(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlBlockLists[i].CompareType == "包含匹配" && strings.Contains(weblogbean.URL, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].UrlBlockLists[i].Url)) {
-				weblogbean.RISK_LEVEL = 1
-				result.IsBlock = true
-				result.Title = "【全局】URL黑名单"
-				result.Content = "您的访问被阻止了URL限制"
-				return result
-			}
+	//url黑名单策略-(全局)
+	if waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 {
+		if entry, ok := waf.matchDenyURL(global.GWAF_GLOBAL_HOST_NAME, r, weblogbean); ok {
+			fillDenyURLResult(&result, entry, weblogbean, "【全局】URL黑名单")
+			return result
 		}
 	}
 	return result
 }
+
+// 在主机的url黑名单匹配器中查找 每次查找都计入判断统计
+func (waf *WafEngine) matchDenyURL(host string, r *http.Request, weblogbean *innerbean.WebLog) (model.URLBlockList, bool) {
+	hostsafe := waf.HostTarget[host]
+	lists := hostsafe.UrlBlockLists
+	startTime := time.Now()
+	index, ok := hostsafe.UrlBlockMatcher.Match(weblogbean.URL, r)
+	if !ok || index >= len(lists) {
+		recordListStats(hostsafe.Host.Code, enums.RULE_STATS_TYPE_URL_BLOCK, len(lists), "", time.Since(startTime), weblogbean.REQ_UUID)
+		return model.URLBlockList{}, false
+	}
+	recordListStats(hostsafe.Host.Code, enums.RULE_STATS_TYPE_URL_BLOCK, len(lists), lists[index].Id, time.Since(startTime), weblogbean.REQ_UUID)
+	return lists[index], true
+}
+
+// 填充黑名单拦截结果 名单项Id和备注作为规则名记录到日志
+func fillDenyURLResult(result *detection.Result, entry model.URLBlockList, weblogbean *innerbean.WebLog, title string) {
+	weblogbean.RISK_LEVEL = 1
+	result.IsBlock = true
+	result.Title = listHitRuleName(title, entry.Id, entry.Remarks)
+	result.Content = "您的访问被阻止了URL限制"
+}
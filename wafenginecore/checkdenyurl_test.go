@@ -0,0 +1,84 @@
+package wafenginecore
+
+import (
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/baseorm"
+	"SamWaf/model/wafenginmodel"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckURLListRecordsEntry(t *testing.T) {
+	allowLists := []model.URLAllowList{{BaseOrm: baseorm.BaseOrm{Id: "allow1"}, HostCode: "hostA", CompareType: enums.URL_COMPARE_PREFIX, Url: "/static"}}
+	blockLists := []model.URLBlockList{{BaseOrm: baseorm.BaseOrm{Id: "block1"}, HostCode: "hostA", CompareType: enums.URL_COMPARE_EQUAL, Url: "/admin", Remarks: "后台"}}
+	ldpLists := []model.LDPUrl{{BaseOrm: baseorm.BaseOrm{Id: "ldp1"}, HostCode: "hostA", CompareType: enums.URL_COMPARE_PREFIX, Url: "/user"}}
+	waf := &WafEngine{
+		HostTarget: map[string]*wafenginmodel.HostSafe{
+			"a.com:80": {
+				Host:            model.Hosts{Code: "hostA", GUARD_STATUS: 1},
+				UrlWhiteLists:   allowLists,
+				UrlWhiteMatcher: wafenginmodel.BuildURLAllowMatcher(allowLists),
+				UrlBlockLists:   blockLists,
+				UrlBlockMatcher: wafenginmodel.BuildURLBlockMatcher(blockLists),
+				LdpUrlLists:     ldpLists,
+				LdpUrlMatcher:   wafenginmodel.BuildLdpUrlMatcher(ldpLists),
+			},
+			global.GWAF_GLOBAL_HOST_NAME: {
+				Host:            model.Hosts{Code: "hostGlobal", GUARD_STATUS: 1},
+				UrlWhiteMatcher: wafenginmodel.BuildURLAllowMatcher(nil),
+				UrlBlockMatcher: wafenginmodel.BuildURLBlockMatcher(nil),
+				LdpUrlMatcher:   wafenginmodel.BuildLdpUrlMatcher(nil),
+			},
+		},
+	}
+	keys := []ruleStatsKey{
+		{enums.RULE_STATS_TYPE_URL_ALLOW, "allow1"}, {enums.RULE_STATS_TYPE_URL_ALLOW, "hostA"},
+		{enums.RULE_STATS_TYPE_URL_BLOCK, "block1"}, {enums.RULE_STATS_TYPE_URL_BLOCK, "hostA"},
+		{enums.RULE_STATS_TYPE_URL_LDP, "ldp1"}, {enums.RULE_STATS_TYPE_URL_LDP, "hostA"},
+	}
+	for _, key := range keys {
+		ruleStats.Delete(key)
+	}
+	defer func() {
+		for _, key := range keys {
+			ruleStats.Delete(key)
+		}
+	}()
+
+	r := httptest.NewRequest("GET", "/static/a.js", nil)
+	weblogbean := &innerbean.WebLog{HOST: "a.com:80", URL: "/static/a.js"}
+	if result := waf.CheckAllowURL(r, weblogbean, nil); !result.JumpGuardResult || weblogbean.RULE != "URL白名单(allow1)" {
+		t.Errorf("allow got %v %s", result.JumpGuardResult, weblogbean.RULE)
+	}
+	r = httptest.NewRequest("GET", "/admin", nil)
+	weblogbean = &innerbean.WebLog{HOST: "a.com:80", URL: "/admin"}
+	if result := waf.CheckAllowURL(r, weblogbean, nil); result.JumpGuardResult || weblogbean.RULE != "" {
+		t.Errorf("allow miss got %v %s", result.JumpGuardResult, weblogbean.RULE)
+	}
+	if result := waf.CheckDenyURL(r, weblogbean, nil); !result.IsBlock || result.Title != "URL黑名单(block1):后台" {
+		t.Errorf("block got %v %s", result.IsBlock, result.Title)
+	}
+	if entry, ok := waf.matchLdpUrl("a.com:80", r, weblogbean); ok {
+		t.Errorf("ldp miss got %s", entry.Id)
+	}
+	r = httptest.NewRequest("GET", "/user/1", nil)
+	if entry, ok := waf.matchLdpUrl("a.com:80", r, weblogbean); !ok || entry.Id != "ldp1" {
+		t.Errorf("ldp got %v %s", ok, entry.Id)
+	}
+
+	want := map[ruleStatsKey][2]int64{
+		{enums.RULE_STATS_TYPE_URL_ALLOW, "allow1"}: {1, 1},
+		{enums.RULE_STATS_TYPE_URL_ALLOW, "hostA"}:  {1, 0},
+		{enums.RULE_STATS_TYPE_URL_BLOCK, "block1"}: {1, 1},
+		{enums.RULE_STATS_TYPE_URL_LDP, "ldp1"}:     {1, 1},
+		{enums.RULE_STATS_TYPE_URL_LDP, "hostA"}:    {1, 0},
+	}
+	for key, counts := range want {
+		if eval, hit := ruleStatsCount(key.ruleType, key.ruleCode); eval != counts[0] || hit != counts[1] {
+			t.Errorf("%v stats %d %d, want %v", key, eval, hit, counts)
+		}
+	}
+}
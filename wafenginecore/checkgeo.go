@@ -0,0 +1,82 @@
+package wafenginecore
+
+import (
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/detection"
+	"SamWaf/wafbot"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+/*
+*
+检测访问者所在地区是否满足地域限制
+返回是否满足条件
+*/
+func (waf *WafEngine) CheckGeo(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
+	result := detection.Result{
+		JumpGuardResult: false,
+		IsBlock:         false,
+		Title:           "",
+		Content:         "",
+	}
+	//内网及本机地址不做限制
+	ip := net.ParseIP(weblogbean.SRC_IP)
+	if ip == nil || ip.IsPrivate() || ip.IsLoopback() {
+		return result
+	}
+	//地域限制-(局部)
+	if region, isBlock := waf.matchGeoPolicy(waf.HostTarget[weblogbean.HOST].GeoPolicyBean, weblogbean); isBlock {
+		waf.fillGeoResult(&result, waf.HostTarget[weblogbean.HOST].GeoPolicyBean, weblogbean, "地域限制:"+region)
+		return result
+	}
+	//地域限制-(全局)
+	if waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 {
+		if region, isBlock := waf.matchGeoPolicy(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].GeoPolicyBean, weblogbean); isBlock {
+			waf.fillGeoResult(&result, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].GeoPolicyBean, weblogbean, "【全局】地域限制:"+region)
+			return result
+		}
+	}
+	return result
+}
+
+/*
+*
+判断地域限制是否拦截 返回访问者地区和是否拦截
+*/
+func (waf *WafEngine) matchGeoPolicy(policy model.GeoPolicy, weblogbean *innerbean.WebLog) (string, bool) {
+	if policy.Status != 1 || policy.Regions == "" {
+		return "", false
+	}
+	_, matched := policy.MatchRegion(weblogbean.COUNTRY, weblogbean.PROVINCE, weblogbean.CITY)
+	if (policy.Mode == enums.GEO_POLICY_MODE_ALLOW && matched) || (policy.Mode == enums.GEO_POLICY_MODE_DENY && !matched) {
+		return "", false
+	}
+	//正常搜索引擎爬虫放行（只在要拦截时才反查，避免每个请求都做DNS查询）
+	if policy.BotBypass == 1 {
+		isBot, isNormalBot, _ := wafbot.DetermineNormalSearch(weblogbean.USER_AGENT, weblogbean.SRC_IP)
+		if isBot && isNormalBot {
+			return "", false
+		}
+	}
+	region := weblogbean.COUNTRY
+	if weblogbean.PROVINCE != "" && weblogbean.PROVINCE != "0" {
+		region = region + "|" + weblogbean.PROVINCE
+	}
+	return region, true
+}
+
+// 按地域限制的处置方式填充结果
+func (waf *WafEngine) fillGeoResult(result *detection.Result, policy model.GeoPolicy, weblogbean *innerbean.WebLog, title string) {
+	weblogbean.RISK_LEVEL = 1
+	result.IsBlock = true
+	result.Title = title
+	result.Content = "您所在的地区不允许访问"
+	if policy.Action == enums.GEO_POLICY_ACTION_CHALLENGE {
+		result.Action = innerbean.RULE_ACTION_CHALLENGE
+	}
+}
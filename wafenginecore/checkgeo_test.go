@@ -0,0 +1,91 @@
+package wafenginecore
+
+import (
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/wafenginmodel"
+	"net/http/httptest"
+	"testing"
+)
+
+func newGeoTestWaf(hostPolicy model.GeoPolicy, globalPolicy model.GeoPolicy, globalGuard int) *WafEngine {
+	return &WafEngine{
+		HostTarget: map[string]*wafenginmodel.HostSafe{
+			"a.com:80": {
+				Host:          model.Hosts{Code: "hostA", GUARD_STATUS: 1},
+				GeoPolicyBean: hostPolicy,
+			},
+			global.GWAF_GLOBAL_HOST_NAME: {
+				Host:          model.Hosts{Code: "", GUARD_STATUS: globalGuard},
+				GeoPolicyBean: globalPolicy,
+			},
+		},
+	}
+}
+
+func geoTestLog(ip string, country string, province string, city string) *innerbean.WebLog {
+	return &innerbean.WebLog{HOST: "a.com:80", HOST_CODE: "hostA", SRC_IP: ip, COUNTRY: country, PROVINCE: province, CITY: city}
+}
+
+func TestMatchRegion(t *testing.T) {
+	policy := model.GeoPolicy{Regions: "中国|广东省\n美国, 日本|东京都|东京"}
+	tests := []struct {
+		country, province, city string
+		want                    bool
+	}{
+		{"中国", "广东省", "深圳市", true},
+		{"中国", "广东", "深圳市", false},
+		{"中国", "广东省份", "", false},
+		{"中国", "北京", "北京市", false},
+		{"中国台湾", "0", "0", false},
+		{"美国", "加利福尼亚", "0", true},
+		{"日本", "东京都", "东京", true},
+		{"日本", "东京都", "东京市", false},
+	}
+	for _, tt := range tests {
+		if _, got := policy.MatchRegion(tt.country, tt.province, tt.city); got != tt.want {
+			t.Errorf("MatchRegion(%s,%s,%s) = %v, want %v", tt.country, tt.province, tt.city, got, tt.want)
+		}
+	}
+}
+
+func TestCheckGeo(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://a.com/", nil)
+	denyPolicy := model.GeoPolicy{Mode: enums.GEO_POLICY_MODE_DENY, Regions: "美国", Action: enums.GEO_POLICY_ACTION_BLOCK, Status: 1}
+	allowPolicy := model.GeoPolicy{Mode: enums.GEO_POLICY_MODE_ALLOW, Regions: "中国", Action: enums.GEO_POLICY_ACTION_CHALLENGE, Status: 1}
+
+	waf := newGeoTestWaf(denyPolicy, model.GeoPolicy{}, 1)
+	result := waf.CheckGeo(r, geoTestLog("8.8.8.8", "美国", "0", "0"), nil)
+	if !result.IsBlock || result.Action != "" || result.Title != "地域限制:美国" {
+		t.Errorf("deny region should block, got %+v", result)
+	}
+	if waf.CheckGeo(r, geoTestLog("1.2.4.8", "中国", "北京", "北京市"), nil).IsBlock {
+		t.Errorf("region not in deny list should pass")
+	}
+	if waf.CheckGeo(r, geoTestLog("192.168.1.1", "美国", "0", "0"), nil).IsBlock {
+		t.Errorf("private ip should pass")
+	}
+
+	waf = newGeoTestWaf(allowPolicy, model.GeoPolicy{}, 1)
+	result = waf.CheckGeo(r, geoTestLog("8.8.8.8", "美国", "加利福尼亚", "0"), nil)
+	if !result.IsBlock || result.Action != innerbean.RULE_ACTION_CHALLENGE || result.Title != "地域限制:美国|加利福尼亚" {
+		t.Errorf("region not in allow list should be challenged, got %+v", result)
+	}
+	if waf.CheckGeo(r, geoTestLog("1.2.4.8", "中国", "北京", "北京市"), nil).IsBlock {
+		t.Errorf("region in allow list should pass")
+	}
+
+	disabled := denyPolicy
+	disabled.Status = 0
+	waf = newGeoTestWaf(disabled, denyPolicy, 1)
+	result = waf.CheckGeo(r, geoTestLog("8.8.8.8", "美国", "0", "0"), nil)
+	if !result.IsBlock || result.Title != "【全局】地域限制:美国" {
+		t.Errorf("global policy should block, got %+v", result)
+	}
+	waf = newGeoTestWaf(disabled, denyPolicy, 0)
+	if waf.CheckGeo(r, geoTestLog("8.8.8.8", "美国", "0", "0"), nil).IsBlock {
+		t.Errorf("global policy should not apply when global guard is off")
+	}
+}
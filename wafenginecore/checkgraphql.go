@@ -0,0 +1,125 @@
+package wafenginecore
+
+import (
+	"SamWaf/common/zlog"
+	"SamWaf/innerbean"
+	"SamWaf/libinjection-go"
+	"SamWaf/model"
+	"SamWaf/model/detection"
+	"SamWaf/wafdefensegraphql"
+	"SamWaf/wafenginecore/wafhttpcore"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/*
+*
+检测GraphQL请求
+*/
+func (waf *WafEngine) CheckGraphql(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
+	result := detection.Result{
+		JumpGuardResult: false,
+		IsBlock:         false,
+		Title:           "",
+		Content:         "",
+	}
+	graphqlConfig, ok := waf.getGraphqlConfig(weblogbean.HOST, r.URL.Path)
+	if !ok {
+		return result
+	}
+	contentType := r.Header.Get("Content-Type")
+	requests := wafdefensegraphql.ExtractRequests(contentType, weblogbean.BODY, r.URL.Query())
+	if len(requests) == 0 {
+		//GraphQL路径上提交了JSON或GraphQL请求体却无法解析
+		lowerContentType := strings.ToLower(contentType)
+		if strings.TrimSpace(weblogbean.BODY) != "" && (strings.Contains(lowerContentType, "json") || strings.Contains(lowerContentType, "graphql")) {
+			weblogbean.RISK_LEVEL = 1
+			result.IsBlock = true
+			result.Title = "GraphQL:请求体无法解析"
+			result.Content = "请正确访问"
+		}
+		return result
+	}
+	graphqlResult := wafdefensegraphql.DetermineGraphql(requests, wafdefensegraphql.GraphqlLimit{
+		MaxDepth:           graphqlConfig.MaxDepth,
+		MaxAliases:         graphqlConfig.MaxAliases,
+		MaxFields:          graphqlConfig.MaxFields,
+		MaxBatch:           graphqlConfig.MaxBatch,
+		BlockIntrospection: graphqlConfig.BlockIntrospection == 1,
+	})
+	operationName := graphqlResult.OperationName
+	if operationName == "" {
+		operationName = "匿名"
+	}
+	if graphqlResult.IsAttack {
+		weblogbean.RISK_LEVEL = 2
+		result.IsBlock = true
+		result.Title = "GraphQL:" + graphqlResult.Name + "(操作:" + operationName + ")"
+		result.Content = "请正确访问"
+		return result
+	}
+	//参数值交给sql注入和xss检测
+	hostDefense := waf.getHostDefense(weblogbean.HOST)
+	excludes := waf.getDetectExcludes(weblogbean.HOST)
+	for _, arg := range graphqlResult.Args {
+		param := wafhttpcore.WafParam{Source: wafhttpcore.PARAM_SOURCE_GRAPHQL, Name: arg.Name, Value: arg.Value}
+		if hostDefense.DEFENSE_SQLI == 1 && !isDetectExclude(excludes, "sqli", r.URL.Path, param) {
+			isSqli, fingerprint := libinjection.IsSQLi(arg.Value)
+			if isSqli {
+				weblogbean.RISK_LEVEL = 2
+				weblogbean.MATCH_PARAM = param.FullName()
+				weblogbean.MATCH_FINGERPRINT = fingerprint
+				result.IsBlock = true
+				result.Title = "SQL注入(" + param.FullName() + " 操作:" + operationName + ")"
+				result.Content = "请正确访问"
+				return result
+			}
+		}
+		if hostDefense.DEFENSE_XSS == 1 && !isDetectExclude(excludes, "xss", r.URL.Path, param) && libinjection.IsXSS(arg.Value) {
+			weblogbean.RISK_LEVEL = 2
+			weblogbean.MATCH_PARAM = param.FullName()
+			result.IsBlock = true
+			result.Title = "XSS跨站注入(" + param.FullName() + " 操作:" + operationName + ")"
+			result.Content = "请正确访问"
+			return result
+		}
+	}
+	return result
+}
+
+/*
+*
+获取当前路径的GraphQL检测配置 只检测主机配置了的路径 配置在加载主机时已解析
+*/
+func (waf *WafEngine) getGraphqlConfig(host string, path string) (model.HostsGraphql, bool) {
+	hostSafe, ok := waf.HostTarget[host]
+	if !ok || hostSafe == nil {
+		return model.HostsGraphql{}, false
+	}
+	for _, graphqlConfig := range hostSafe.GraphqlConfigs {
+		if strings.HasSuffix(graphqlConfig.Path, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(graphqlConfig.Path, "*")) {
+				return graphqlConfig, true
+			}
+		} else if graphqlConfig.Path == path {
+			return graphqlConfig, true
+		}
+	}
+	return model.HostsGraphql{}, false
+}
+
+// parseHostGraphql 解析主机的GraphQL检测配置
+func parseHostGraphql(graphqlJson string) []model.HostsGraphql {
+	if graphqlJson == "" {
+		return nil
+	}
+	var graphqlConfigs []model.HostsGraphql
+	err := json.Unmarshal([]byte(graphqlJson), &graphqlConfigs)
+	if err != nil {
+		zlog.Error("解析graphql json失败")
+		return nil
+	}
+	return graphqlConfigs
+}
@@ -0,0 +1,56 @@
+package wafenginecore
+
+import (
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/wafenginmodel"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newGraphqlTestWaf(graphqlJson string) *WafEngine {
+	return &WafEngine{
+		HostTarget: map[string]*wafenginmodel.HostSafe{
+			"a.com:80": {
+				Host:           model.Hosts{Code: "hostA", GUARD_STATUS: 1, GRAPHQL_JSON: graphqlJson},
+				GraphqlConfigs: parseHostGraphql(graphqlJson),
+			},
+		},
+	}
+}
+
+func checkGraphqlBody(waf *WafEngine, path string, body string) bool {
+	r := httptest.NewRequest(http.MethodPost, "http://a.com"+path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	weblogbean := &innerbean.WebLog{HOST: "a.com:80", BODY: body}
+	return waf.CheckGraphql(r, weblogbean, nil).IsBlock
+}
+
+func TestCheckGraphqlOptIn(t *testing.T) {
+	introspection := `{"query":"query { __schema { types { name } } }"}`
+	if checkGraphqlBody(newGraphqlTestWaf(""), "/graphql", introspection) {
+		t.Errorf("host without graphql config should not be checked")
+	}
+	waf := newGraphqlTestWaf(`[{"path":"/api/graphql","block_introspection":1}]`)
+	if !checkGraphqlBody(waf, "/api/graphql", introspection) {
+		t.Errorf("configured path should be checked")
+	}
+	if checkGraphqlBody(waf, "/graphql", introspection) {
+		t.Errorf("path not configured should not be checked")
+	}
+}
+
+func TestCheckGraphqlUnparseable(t *testing.T) {
+	waf := newGraphqlTestWaf(`[{"path":"/graphql"}]`)
+	if !checkGraphqlBody(waf, "/graphql", `{"query":"{ user(id: \"1) { id }"}`) {
+		t.Errorf("unparseable query should be blocked")
+	}
+	if !checkGraphqlBody(waf, "/graphql", `{"query":`) {
+		t.Errorf("unparseable body should be blocked")
+	}
+	if checkGraphqlBody(waf, "/graphql", `{"query":"{ user(id: 1) { id } }"}`) {
+		t.Errorf("valid query should pass")
+	}
+}
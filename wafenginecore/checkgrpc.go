@@ -0,0 +1,333 @@
+package wafenginecore
+
+import (
+	"SamWaf/common/zlog"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/libinjection-go"
+	"SamWaf/model"
+	"SamWaf/model/detection"
+	"SamWaf/wafdefensegrpc"
+	"SamWaf/wafdefenserce"
+	"SamWaf/wafenginecore/wafhttpcore"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gRPC状态码
+const (
+	grpcStatusPermissionDenied = 7
+	grpcStatusUnavailable      = 14
+)
+
+/*
+*
+检测gRPC方法访问控制
+*/
+func (waf *WafEngine) CheckGrpc(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
+	result := detection.Result{
+		JumpGuardResult: false,
+		IsBlock:         false,
+		Title:           "",
+		Content:         "",
+	}
+	if !isGrpcRequest(r) {
+		return result
+	}
+	hostSafe, ok := waf.HostTarget[weblogbean.HOST]
+	if !ok || hostSafe == nil || !hostSafe.GrpcConfigured {
+		return result
+	}
+	grpcConfig := hostSafe.GrpcBean
+	fullMethod := weblogbean.GRPC_SERVICE + "/" + weblogbean.GRPC_METHOD
+	if matchGrpcMethod(grpcConfig.DenyMethods, weblogbean.GRPC_SERVICE, weblogbean.GRPC_METHOD) {
+		weblogbean.RISK_LEVEL = 1
+		result.IsBlock = true
+		result.Title = "gRPC方法禁止访问(" + fullMethod + ")"
+		result.Content = "请正确访问"
+		return result
+	}
+	if len(grpcConfig.AllowMethods) > 0 && !matchGrpcMethod(grpcConfig.AllowMethods, weblogbean.GRPC_SERVICE, weblogbean.GRPC_METHOD) {
+		weblogbean.RISK_LEVEL = 1
+		result.IsBlock = true
+		result.Title = "gRPC方法不在允许列表(" + fullMethod + ")"
+		result.Content = "请正确访问"
+		return result
+	}
+	if grpcConfig.InspectBody == 1 {
+		return waf.checkGrpcBody(r, weblogbean, grpcConfig)
+	}
+	return result
+}
+
+/*
+*
+解析主机的gRPC配置 返回值：配置，是否有可用的配置
+*/
+func parseHostGrpc(grpcJson string) (model.HostsGrpc, bool) {
+	var grpcConfig model.HostsGrpc
+	if grpcJson == "" {
+		return grpcConfig, false
+	}
+	err := json.Unmarshal([]byte(grpcJson), &grpcConfig)
+	if err != nil {
+		zlog.Error("解析grpc json失败")
+		return model.HostsGrpc{}, false
+	}
+	return grpcConfig, true
+}
+
+// 检测gRPC消息的默认最大字节数
+const defaultGrpcInspectSize = 64 * 1024
+
+/*
+*
+检测gRPC消息内容 解码protobuf中的字符串字段交给注入检测
+*/
+func (waf *WafEngine) checkGrpcBody(r *http.Request, weblogbean *innerbean.WebLog, grpcConfig model.HostsGrpc) detection.Result {
+	result := detection.Result{
+		JumpGuardResult: false,
+		IsBlock:         false,
+		Title:           "",
+		Content:         "",
+	}
+	maxSize := grpcConfig.MaxMessageSize
+	if maxSize <= 0 {
+		maxSize = defaultGrpcInspectSize
+	}
+	messages, err := readGrpcMessages(r, maxSize)
+	if err == wafdefensegrpc.ErrMessageTooLarge {
+		weblogbean.RISK_LEVEL = 1
+		result.IsBlock = true
+		result.Title = "gRPC消息超过检测长度(" + strconv.Itoa(maxSize) + ")"
+		result.Content = "请正确访问"
+		return result
+	}
+	hostDefense := waf.getHostDefense(weblogbean.HOST)
+	excludes := waf.getDetectExcludes(weblogbean.HOST)
+	fullMethod := weblogbean.GRPC_SERVICE + "/" + weblogbean.GRPC_METHOD
+	for _, message := range messages {
+		data := message.data
+		if message.compressed {
+			//只支持gzip压缩 其他压缩方式无法检测 直接拦截避免绕过
+			encoding := r.Header.Get("Grpc-Encoding")
+			if encoding != "gzip" {
+				weblogbean.RISK_LEVEL = 1
+				result.IsBlock = true
+				result.Title = "gRPC消息压缩方式无法检测(" + encoding + ")"
+				result.Content = "请正确访问"
+				return result
+			}
+			data, err = wafdefensegrpc.Gunzip(data, maxSize)
+			if err == wafdefensegrpc.ErrMessageTooLarge {
+				weblogbean.RISK_LEVEL = 1
+				result.IsBlock = true
+				result.Title = "gRPC消息超过检测长度(" + strconv.Itoa(maxSize) + ")"
+				result.Content = "请正确访问"
+				return result
+			}
+			if err != nil {
+				weblogbean.RISK_LEVEL = 1
+				result.IsBlock = true
+				result.Title = "gRPC消息解压失败"
+				result.Content = "请正确访问"
+				return result
+			}
+		}
+		for _, field := range wafdefensegrpc.ExtractFields(data) {
+			param := wafhttpcore.WafParam{Source: wafhttpcore.PARAM_SOURCE_GRPC, Name: field.Name, Value: field.Value}
+			if hostDefense.DEFENSE_SQLI == 1 && !isDetectExclude(excludes, "sqli", r.URL.Path, param) {
+				isSqli, fingerprint := libinjection.IsSQLi(param.Value)
+				if isSqli {
+					weblogbean.RISK_LEVEL = 2
+					weblogbean.MATCH_PARAM = param.FullName()
+					weblogbean.MATCH_FINGERPRINT = fingerprint
+					result.IsBlock = true
+					result.Title = "SQL注入(" + param.FullName() + " 方法:" + fullMethod + ")"
+					result.Content = "请正确访问"
+					return result
+				}
+			}
+			if hostDefense.DEFENSE_XSS == 1 && !isDetectExclude(excludes, "xss", r.URL.Path, param) && libinjection.IsXSS(param.Value) {
+				weblogbean.RISK_LEVEL = 2
+				weblogbean.MATCH_PARAM = param.FullName()
+				result.IsBlock = true
+				result.Title = "XSS跨站注入(" + param.FullName() + " 方法:" + fullMethod + ")"
+				result.Content = "请正确访问"
+				return result
+			}
+			if hostDefense.DEFENSE_RCE == 1 && !isDetectExclude(excludes, "rce", r.URL.Path, param) {
+				isRce, rceName := wafdefenserce.DetermineRCE(param.Value)
+				if isRce {
+					weblogbean.RISK_LEVEL = 3
+					weblogbean.MATCH_PARAM = param.FullName()
+					result.IsBlock = true
+					result.Title = "RCE:" + rceName + "(" + param.FullName() + " 方法:" + fullMethod + ")"
+					result.Content = "请正确访问"
+					return result
+				}
+			}
+		}
+	}
+	return result
+}
+
+// 读取到的一条gRPC消息
+type grpcMessage struct {
+	data       []byte
+	compressed bool
+}
+
+// 已读取的部分放回请求体 继续流式转发
+type grpcReplayBody struct {
+	io.Reader
+	io.Closer
+}
+
+/*
+*
+读取请求体开头的gRPC消息 已读取的字节会放回请求体
+请求体长度已知时读取不超过 maxSize 的全部消息，流式请求只读取第一条，避免等待客户端后续消息
+*/
+func readGrpcMessages(r *http.Request, maxSize int) ([]grpcMessage, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	var consumed bytes.Buffer
+	reader := io.TeeReader(r.Body, &consumed)
+	var messages []grpcMessage
+	var err error
+	for {
+		var data []byte
+		var compressed bool
+		data, compressed, err = wafdefensegrpc.ReadMessage(reader, maxSize)
+		if err != nil {
+			break
+		}
+		messages = append(messages, grpcMessage{data: data, compressed: compressed})
+		if r.ContentLength <= 0 || consumed.Len() >= maxSize {
+			break
+		}
+	}
+	r.Body = grpcReplayBody{Reader: io.MultiReader(bytes.NewReader(consumed.Bytes()), r.Body), Closer: r.Body}
+	if err == wafdefensegrpc.ErrMessageTooLarge {
+		return messages, err
+	}
+	return messages, nil
+}
+
+// 是否是gRPC请求
+func isGrpcRequest(r *http.Request) bool {
+	return strings.HasPrefix(strings.ToLower(r.Header.Get("Content-Type")), "application/grpc")
+}
+
+/*
+*
+从路径中取出服务名和方法名 /pkg.Service/Method
+*/
+func parseGrpcMethod(path string) (string, string) {
+	path = strings.TrimPrefix(path, "/")
+	index := strings.LastIndex(path, "/")
+	if index < 0 {
+		return path, ""
+	}
+	return path[:index], path[index+1:]
+}
+
+/*
+*
+匹配gRPC方法 支持 pkg.Service/Method、pkg.Service/*、pkg.Service 和 *
+*/
+func matchGrpcMethod(patterns []string, service string, method string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+		if pattern == "*" || pattern == service || pattern == service+"/*" || pattern == service+"/"+method {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+*
+以gRPC格式返回错误 gRPC客户端只识别grpc-status
+*/
+func echoGrpcError(w http.ResponseWriter, grpcStatus int, message string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", strconv.Itoa(grpcStatus))
+	w.Header().Set("Grpc-Message", grpcPercentEncode(message))
+	w.WriteHeader(http.StatusOK)
+}
+
+// grpc-message 需要对非可见ASCII字符和%做百分号编码
+func grpcPercentEncode(message string) string {
+	var builder strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c >= 0x20 && c <= 0x7E && c != '%' {
+			builder.WriteByte(c)
+		} else {
+			builder.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return builder.String()
+}
+
+// gRPC响应体 结束后从trailer中取出状态并记录日志
+type grpcLogBody struct {
+	io.ReadCloser
+	resp      *http.Response
+	weblog    innerbean.WebLog
+	closeOnce sync.Once
+}
+
+func (b *grpcLogBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.closeOnce.Do(func() {
+		weblogbean := b.weblog
+		if grpcStatus := b.resp.Trailer.Get("Grpc-Status"); grpcStatus != "" {
+			weblogbean.GRPC_STATUS = grpcStatus
+		}
+		datetimeNow := time.Now()
+		weblogbean.TimeSpent = datetimeNow.UnixNano()/1e6 - weblogbean.UNIX_ADD_TIME
+		enqueueGrpcLog(weblogbean)
+	})
+	return err
+}
+
+/*
+*
+gRPC响应记录日志 响应体为流不读取内容，状态码在trailer中，待响应结束后记录
+*/
+func (waf *WafEngine) recordGrpcResponse(resp *http.Response, weblogbean innerbean.WebLog) {
+	weblogbean.ACTION = "放行"
+	weblogbean.STATUS = resp.Status
+	weblogbean.STATUS_CODE = resp.StatusCode
+	weblogbean.TASK_FLAG = 1
+	//只有头部的响应状态码直接在header中
+	weblogbean.GRPC_STATUS = resp.Header.Get("Grpc-Status")
+	if resp.Body == nil || resp.Body == http.NoBody {
+		datetimeNow := time.Now()
+		weblogbean.TimeSpent = datetimeNow.UnixNano()/1e6 - weblogbean.UNIX_ADD_TIME
+		enqueueGrpcLog(weblogbean)
+		return
+	}
+	resp.Body = &grpcLogBody{ReadCloser: resp.Body, resp: resp, weblog: weblogbean}
+}
+
+func enqueueGrpcLog(weblogbean innerbean.WebLog) {
+	if global.GWAF_RUNTIME_RECORD_LOG_TYPE == "all" {
+		global.GQEQUE_LOG_DB.Enqueue(weblogbean)
+	}
+}
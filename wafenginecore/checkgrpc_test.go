@@ -0,0 +1,120 @@
+package wafenginecore
+
+import (
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/wafenginmodel"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func grpcTestBody(value string) []byte {
+	message := binary.AppendUvarint(nil, 1<<3|2)
+	message = binary.AppendUvarint(message, uint64(len(value)))
+	message = append(message, value...)
+	frame := make([]byte, 5)
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+func checkGrpcRequest(grpcJson string, path string, body []byte) (bool, *http.Request) {
+	return checkGrpcEncodedRequest(grpcJson, path, "", body)
+}
+
+func checkGrpcEncodedRequest(grpcJson string, path string, encoding string, body []byte) (bool, *http.Request) {
+	grpcBean, grpcConfigured := parseHostGrpc(grpcJson)
+	waf := &WafEngine{
+		HostTarget: map[string]*wafenginmodel.HostSafe{
+			"a.com:80": {
+				Host:           model.Hosts{Code: "hostA", GUARD_STATUS: 1, GRPC_JSON: grpcJson},
+				DefenseBean:    model.HostsDefense{DEFENSE_SQLI: 1, DEFENSE_XSS: 1, DEFENSE_RCE: 1},
+				GrpcBean:       grpcBean,
+				GrpcConfigured: grpcConfigured,
+			},
+		},
+	}
+	r := httptest.NewRequest(http.MethodPost, "http://a.com"+path, bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/grpc")
+	if encoding != "" {
+		r.Header.Set("Grpc-Encoding", encoding)
+	}
+	weblogbean := &innerbean.WebLog{HOST: "a.com:80"}
+	weblogbean.GRPC_SERVICE, weblogbean.GRPC_METHOD = parseGrpcMethod(r.URL.Path)
+	return waf.CheckGrpc(r, weblogbean, nil).IsBlock, r
+}
+
+func TestParseGrpcMethod(t *testing.T) {
+	service, method := parseGrpcMethod("/helloworld.Greeter/SayHello")
+	if service != "helloworld.Greeter" || method != "SayHello" {
+		t.Errorf("parse = %s %s", service, method)
+	}
+	patterns := []string{"helloworld.Greeter/SayHello", "admin.Service/*"}
+	if !matchGrpcMethod(patterns, "helloworld.Greeter", "SayHello") || !matchGrpcMethod(patterns, "admin.Service", "Drop") {
+		t.Errorf("method should match")
+	}
+	if matchGrpcMethod(patterns, "helloworld.Greeter", "SayBye") {
+		t.Errorf("method should not match")
+	}
+}
+
+func TestCheckGrpcMethodAccess(t *testing.T) {
+	grpcJson := `{"allow_methods":["helloworld.Greeter/*"],"deny_methods":["helloworld.Greeter/Admin"]}`
+	if isBlock, _ := checkGrpcRequest(grpcJson, "/helloworld.Greeter/SayHello", nil); isBlock {
+		t.Errorf("allowed method blocked")
+	}
+	if isBlock, _ := checkGrpcRequest(grpcJson, "/helloworld.Greeter/Admin", nil); !isBlock {
+		t.Errorf("denied method not blocked")
+	}
+	if isBlock, _ := checkGrpcRequest(grpcJson, "/other.Service/Get", nil); !isBlock {
+		t.Errorf("method outside allow list not blocked")
+	}
+}
+
+func TestCheckGrpcBody(t *testing.T) {
+	grpcJson := `{"inspect_body":1,"max_message_size":1024}`
+	if isBlock, _ := checkGrpcRequest(grpcJson, "/helloworld.Greeter/SayHello", grpcTestBody("1' or '1'='1")); !isBlock {
+		t.Errorf("sqli in grpc message not blocked")
+	}
+	body := grpcTestBody("world")
+	isBlock, r := checkGrpcRequest(grpcJson, "/helloworld.Greeter/SayHello", body)
+	if isBlock {
+		t.Errorf("normal grpc message blocked")
+	}
+	forwarded, _ := io.ReadAll(r.Body)
+	if !bytes.Equal(forwarded, body) {
+		t.Errorf("grpc body not restored after inspection")
+	}
+	if isBlock, _ := checkGrpcRequest(grpcJson, "/helloworld.Greeter/SayHello", grpcTestBody(string(make([]byte, 2048)))); !isBlock {
+		t.Errorf("oversize grpc message not blocked")
+	}
+	if isBlock, _ := checkGrpcRequest(`{}`, "/helloworld.Greeter/SayHello", grpcTestBody("1' or '1'='1")); isBlock {
+		t.Errorf("grpc body inspected while disabled")
+	}
+}
+
+func TestCheckGrpcCompressedBody(t *testing.T) {
+	grpcJson := `{"inspect_body":1,"max_message_size":1024}`
+	body := grpcTestBody("world")
+	body[0] = 1
+	if isBlock, _ := checkGrpcEncodedRequest(grpcJson, "/helloworld.Greeter/SayHello", "deflate", body); !isBlock {
+		t.Errorf("grpc message in unsupported encoding not blocked")
+	}
+	if isBlock, _ := checkGrpcEncodedRequest(grpcJson, "/helloworld.Greeter/SayHello", "gzip", body); !isBlock {
+		t.Errorf("invalid gzip grpc message not blocked")
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(grpcTestBody("1' or '1'='1")[5:])
+	gz.Close()
+	frame := make([]byte, 5)
+	frame[0] = 1
+	binary.BigEndian.PutUint32(frame[1:], uint32(buf.Len()))
+	if isBlock, _ := checkGrpcEncodedRequest(grpcJson, "/helloworld.Greeter/SayHello", "gzip", append(frame, buf.Bytes()...)); !isBlock {
+		t.Errorf("sqli in gzip grpc message not blocked")
+	}
+}
@@ -0,0 +1,74 @@
+package wafenginecore
+
+import (
+	"SamWaf/innerbean"
+	"SamWaf/libinjection-go"
+	"SamWaf/model/detection"
+	"SamWaf/wafdefenserce"
+	"SamWaf/wafdefensetraversal"
+	"net/http"
+	"net/url"
+)
+
+/*
+*
+检测请求头和cookie注入
+*/
+func (waf *WafEngine) CheckHeaderInject(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
+	result := detection.Result{
+		JumpGuardResult: false,
+		IsBlock:         false,
+		Title:           "",
+		Content:         "",
+	}
+	hostDefense := waf.getHostDefense(weblogbean.HOST)
+	excludes := waf.getDetectExcludes(weblogbean.HOST)
+	for _, param := range waf.getInspectHeaderParams(r, weblogbean.HOST) {
+		if param.Value == "" {
+			continue
+		}
+		if hostDefense.DEFENSE_SQLI == 1 && !isDetectExclude(excludes, "sqli", r.URL.Path, param) {
+			isSqli, fingerprint := libinjection.IsSQLi(param.Value)
+			if isSqli {
+				weblogbean.RISK_LEVEL = 2
+				weblogbean.MATCH_PARAM = param.FullName()
+				weblogbean.MATCH_FINGERPRINT = fingerprint
+				result.IsBlock = true
+				result.Title = "SQL注入(" + param.FullName() + ")"
+				result.Content = "请正确访问"
+				return result
+			}
+		}
+		if hostDefense.DEFENSE_XSS == 1 && !isDetectExclude(excludes, "xss", r.URL.Path, param) && libinjection.IsXSS(param.Value) {
+			weblogbean.RISK_LEVEL = 2
+			weblogbean.MATCH_PARAM = param.FullName()
+			result.IsBlock = true
+			result.Title = "XSS跨站注入(" + param.FullName() + ")"
+			result.Content = "请正确访问"
+			return result
+		}
+		if hostDefense.DEFENSE_RCE == 1 && !isDetectExclude(excludes, "rce", r.URL.Path, param) {
+			isRce, rceName := wafdefenserce.DetermineRCE(param.Value)
+			if isRce {
+				weblogbean.RISK_LEVEL = 3
+				weblogbean.MATCH_PARAM = param.FullName()
+				result.IsBlock = true
+				result.Title = "RCE:" + rceName + "(" + param.FullName() + ")"
+				result.Content = "请正确访问"
+				return result
+			}
+		}
+		if hostDefense.DEFENSE_TRAVERSAL == 1 && !isDetectExclude(excludes, "traversal", r.URL.Path, param) {
+			isTraversal, traversalName := wafdefensetraversal.DetermineTraversal(param.Value)
+			if isTraversal {
+				weblogbean.RISK_LEVEL = 3
+				weblogbean.MATCH_PARAM = param.FullName()
+				result.IsBlock = true
+				result.Title = "目录穿越:" + traversalName + "(" + param.FullName() + ")"
+				result.Content = "请正确访问"
+				return result
+			}
+		}
+	}
+	return result
+}
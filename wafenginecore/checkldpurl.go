@@ -0,0 +1,29 @@
+package wafenginecore
+
+import (
+	"SamWaf/enums"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"net/http"
+	"time"
+)
+
+/*
+*
+检测返回内容是否需要隐私保护 每次查找都计入判断统计
+*/
+func (waf *WafEngine) matchLdpUrl(host string, r *http.Request, weblogbean *innerbean.WebLog) (model.LDPUrl, bool) {
+	hostsafe, ok := waf.HostTarget[host]
+	if !ok || r == nil {
+		return model.LDPUrl{}, false
+	}
+	lists := hostsafe.LdpUrlLists
+	startTime := time.Now()
+	index, ok := hostsafe.LdpUrlMatcher.Match(r.RequestURI, r)
+	if !ok || index >= len(lists) {
+		recordListStats(hostsafe.Host.Code, enums.RULE_STATS_TYPE_URL_LDP, len(lists), "", time.Since(startTime), weblogbean.REQ_UUID)
+		return model.LDPUrl{}, false
+	}
+	recordListStats(hostsafe.Host.Code, enums.RULE_STATS_TYPE_URL_LDP, len(lists), lists[index].Id, time.Since(startTime), weblogbean.REQ_UUID)
+	return lists[index], true
+}
@@ -0,0 +1,195 @@
+package wafenginecore
+
+import (
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/wafenginecore/wafhttpcore"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginAttempt 一次登录请求的信息 在响应阶段判断是否登录失败
+type LoginAttempt struct {
+	Protect  model.LoginProtect //命中的登录防护配置
+	HostCode string             //主机码
+	Ip       string             //访问IP
+	UserName string             //用户名
+}
+
+// 失败内容正则缓存
+var loginFailPatternCache sync.Map
+
+// 锁定类型 各自使用独立的key，避免用户名和IP相同时互相锁定
+const (
+	loginLockTypeIp     = "ip"
+	loginLockTypeUser   = "user"
+	loginLockTypeIpUser = "ip_user"
+)
+
+/*
+*
+匹配登录防护地址 并取出用户名
+*/
+func (waf *WafEngine) matchLoginAttempt(r *http.Request, host string, weblogbean *innerbean.WebLog, formValue url.Values) *LoginAttempt {
+	for _, protect := range waf.HostTarget[host].LoginProtectLists {
+		method := protect.Method
+		if method == "" {
+			method = http.MethodPost
+		}
+		if protect.Url != r.URL.Path || !strings.EqualFold(method, r.Method) {
+			continue
+		}
+		attempt := &LoginAttempt{
+			Protect:  protect,
+			HostCode: weblogbean.HOST_CODE,
+			Ip:       weblogbean.SRC_IP,
+		}
+		if protect.UserField != "" {
+			for _, param := range waf.getInspectParams(r, weblogbean.BODY, formValue) {
+				if param.Name == protect.UserField && param.Source != wafhttpcore.PARAM_SOURCE_PATH {
+					attempt.UserName = strings.ToLower(strings.TrimSpace(param.Value))
+					break
+				}
+			}
+		}
+		return attempt
+	}
+	return nil
+}
+
+/*
+*
+检测登录是否已经被锁定 返回值：是否锁定，锁定原因
+同时统计单IP尝试的用户名数量
+*/
+func (waf *WafEngine) checkLoginLock(attempt *LoginAttempt) (bool, string) {
+	if global.GCACHE_WAFCACHE.IsKeyExist(loginLockKey(attempt.HostCode, loginLockTypeIp, attempt.Ip)) {
+		return true, "登录防护:IP失败次数过多"
+	}
+	if attempt.UserName != "" {
+		if global.GCACHE_WAFCACHE.IsKeyExist(loginLockKey(attempt.HostCode, loginLockTypeUser, attempt.UserName)) {
+			return true, "登录防护:用户名失败次数过多"
+		}
+		if global.GCACHE_WAFCACHE.IsKeyExist(loginLockKey(attempt.HostCode, loginLockTypeIpUser, attempt.Ip+"_"+attempt.UserName)) {
+			return true, "登录防护:IP+用户名失败次数过多"
+		}
+		//单IP尝试多个用户名（撞库）
+		if attempt.Protect.IpMaxUser > 0 {
+			userCount := recordLoginIpUser(attempt)
+			if userCount > attempt.Protect.IpMaxUser {
+				lockLogin(attempt, loginLockTypeIp, attempt.Ip)
+				return true, "登录防护:单IP尝试用户名过多"
+			}
+		}
+	}
+	return false, ""
+}
+
+/*
+*
+响应阶段判断是否登录失败并计数
+*/
+func (waf *WafEngine) recordLoginResult(attempt *LoginAttempt, resp *http.Response, respBody string) {
+	if !isLoginFail(attempt.Protect, resp, respBody) {
+		//登录成功后清理当前IP+用户名的失败计数
+		if attempt.UserName != "" {
+			global.GCACHE_WAFCACHE.Remove(enums.CACHE_LOGIN_FAIL_IP_USER_PRE + attempt.HostCode + "_" + attempt.Ip + "_" + attempt.UserName)
+		}
+		return
+	}
+	if incLoginFail(attempt, enums.CACHE_LOGIN_FAIL_IP_PRE+attempt.HostCode+"_"+attempt.Ip) > attempt.Protect.IpMaxFail && attempt.Protect.IpMaxFail > 0 {
+		lockLogin(attempt, loginLockTypeIp, attempt.Ip)
+	}
+	if attempt.UserName == "" {
+		return
+	}
+	if incLoginFail(attempt, enums.CACHE_LOGIN_FAIL_USER_PRE+attempt.HostCode+"_"+attempt.UserName) > attempt.Protect.UserMaxFail && attempt.Protect.UserMaxFail > 0 {
+		lockLogin(attempt, loginLockTypeUser, attempt.UserName)
+	}
+	if incLoginFail(attempt, enums.CACHE_LOGIN_FAIL_IP_USER_PRE+attempt.HostCode+"_"+attempt.Ip+"_"+attempt.UserName) > attempt.Protect.IpUserMaxFail && attempt.Protect.IpUserMaxFail > 0 {
+		lockLogin(attempt, loginLockTypeIpUser, attempt.Ip+"_"+attempt.UserName)
+	}
+}
+
+/*
+*
+判断响应是否是登录失败 状态码、跳转地址、返回内容任意一项命中即为失败
+*/
+func isLoginFail(protect model.LoginProtect, resp *http.Response, respBody string) bool {
+	if protect.FailStatusCodes != "" {
+		for _, code := range strings.Split(protect.FailStatusCodes, ",") {
+			if strings.TrimSpace(code) == strconv.Itoa(resp.StatusCode) {
+				return true
+			}
+		}
+	}
+	if protect.FailRedirect != "" {
+		location := resp.Header.Get("Location")
+		if location != "" && strings.Contains(location, protect.FailRedirect) {
+			return true
+		}
+	}
+	if protect.FailBodyPattern != "" && respBody != "" {
+		pattern, ok := loginFailPatternCache.Load(protect.FailBodyPattern)
+		if !ok {
+			compiled, err := regexp.Compile(protect.FailBodyPattern)
+			if err != nil {
+				return false
+			}
+			pattern, _ = loginFailPatternCache.LoadOrStore(protect.FailBodyPattern, compiled)
+		}
+		if pattern.(*regexp.Regexp).MatchString(respBody) {
+			return true
+		}
+	}
+	return false
+}
+
+// 失败计数加一 返回当前计数
+func incLoginFail(attempt *LoginAttempt, cacheKey string) int {
+	return global.GCACHE_WAFCACHE.IncrWithTTl(cacheKey, loginWindow(attempt.Protect))
+}
+
+// 记录IP尝试过的用户名 返回用户名数量
+func recordLoginIpUser(attempt *LoginAttempt) int {
+	cacheKey := enums.CACHE_LOGIN_IP_USERS_PRE + attempt.HostCode + "_" + attempt.Ip
+	users := map[string]bool{}
+	if oldUsers, ok := global.GCACHE_WAFCACHE.Get(cacheKey).(map[string]bool); ok {
+		if oldUsers[attempt.UserName] {
+			return len(oldUsers)
+		}
+		for user := range oldUsers {
+			users[user] = true
+		}
+	}
+	users[attempt.UserName] = true
+	global.GCACHE_WAFCACHE.SetWithTTl(cacheKey, users, loginWindow(attempt.Protect))
+	return len(users)
+}
+
+// 锁定
+func lockLogin(attempt *LoginAttempt, lockType string, lockName string) {
+	lockMinutes := attempt.Protect.LockMinutes
+	if lockMinutes <= 0 {
+		lockMinutes = 10
+	}
+	global.GCACHE_WAFCACHE.SetWithTTl(loginLockKey(attempt.HostCode, lockType, lockName), 1, time.Duration(lockMinutes)*time.Minute)
+}
+
+func loginLockKey(hostCode string, lockType string, lockName string) string {
+	return enums.CACHE_LOGIN_LOCK_PRE + hostCode + "_" + lockType + "_" + lockName
+}
+
+func loginWindow(protect model.LoginProtect) time.Duration {
+	if protect.WindowSeconds <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(protect.WindowSeconds) * time.Second
+}
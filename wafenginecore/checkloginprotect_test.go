@@ -0,0 +1,143 @@
+package wafenginecore
+
+import (
+	"SamWaf/cache"
+	"SamWaf/global"
+	"SamWaf/model"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func newLoginTestAttempt(ip string, userName string) *LoginAttempt {
+	return &LoginAttempt{
+		Protect: model.LoginProtect{
+			FailStatusCodes: "401, 403",
+			IpMaxFail:       5,
+			UserMaxFail:     3,
+			IpUserMaxFail:   2,
+			IpMaxUser:       2,
+		},
+		HostCode: "hostA",
+		Ip:       ip,
+		UserName: userName,
+	}
+}
+
+func TestIsLoginFail(t *testing.T) {
+	protect := model.LoginProtect{FailStatusCodes: "401, 403", FailRedirect: "/login?error", FailBodyPattern: `"code":\s*1001`}
+	header := http.Header{}
+	header.Set("Location", "/login?error=1")
+	tests := []struct {
+		name   string
+		resp   *http.Response
+		body   string
+		isFail bool
+	}{
+		{"status", &http.Response{StatusCode: 403, Header: http.Header{}}, "", true},
+		{"redirect", &http.Response{StatusCode: 302, Header: header}, "", true},
+		{"body", &http.Response{StatusCode: 200, Header: http.Header{}}, `{"code": 1001}`, true},
+		{"success", &http.Response{StatusCode: 200, Header: http.Header{}}, `{"code": 0}`, false},
+	}
+	for _, tt := range tests {
+		if isFail := isLoginFail(protect, tt.resp, tt.body); isFail != tt.isFail {
+			t.Errorf("%s got %v, want %v", tt.name, isFail, tt.isFail)
+		}
+	}
+	if isLoginFail(model.LoginProtect{FailBodyPattern: "("}, &http.Response{StatusCode: 200, Header: http.Header{}}, "(") {
+		t.Errorf("invalid pattern should not be treated as fail")
+	}
+}
+
+func TestRecordLoginResultLocks(t *testing.T) {
+	global.GCACHE_WAFCACHE = cache.InitWafCache()
+	waf := &WafEngine{}
+	failResp := &http.Response{StatusCode: 401, Header: http.Header{}}
+
+	//同一IP+用户名失败超过次数后锁定，其他用户名不受影响
+	attempt := newLoginTestAttempt("1.1.1.1", "admin")
+	for i := 0; i < 3; i++ {
+		waf.recordLoginResult(attempt, failResp, "")
+	}
+	if isLock, lockName := waf.checkLoginLock(attempt); !isLock || lockName != "登录防护:IP+用户名失败次数过多" {
+		t.Errorf("ip_user lock got %v %s", isLock, lockName)
+	}
+	if isLock, lockName := waf.checkLoginLock(newLoginTestAttempt("1.1.1.1", "")); isLock {
+		t.Errorf("ip should not be locked yet: %s", lockName)
+	}
+
+	//用户名锁定
+	attempt = newLoginTestAttempt("2.2.2.2", "root")
+	waf.recordLoginResult(attempt, failResp, "")
+	waf.recordLoginResult(newLoginTestAttempt("3.3.3.3", "root"), failResp, "")
+	waf.recordLoginResult(newLoginTestAttempt("4.4.4.4", "root"), failResp, "")
+	waf.recordLoginResult(newLoginTestAttempt("5.5.5.5", "root"), failResp, "")
+	if isLock, lockName := waf.checkLoginLock(newLoginTestAttempt("6.6.6.6", "root")); !isLock || lockName != "登录防护:用户名失败次数过多" {
+		t.Errorf("user lock got %v %s", isLock, lockName)
+	}
+
+	//登录成功清理IP+用户名计数
+	attempt = newLoginTestAttempt("7.7.7.7", "guest")
+	waf.recordLoginResult(attempt, failResp, "")
+	waf.recordLoginResult(attempt, &http.Response{StatusCode: 200, Header: http.Header{}}, "")
+	waf.recordLoginResult(attempt, failResp, "")
+	waf.recordLoginResult(attempt, failResp, "")
+	if isLock, _ := waf.checkLoginLock(attempt); isLock {
+		t.Errorf("success should reset ip_user counter")
+	}
+}
+
+func TestLoginLockNamespaces(t *testing.T) {
+	global.GCACHE_WAFCACHE = cache.InitWafCache()
+	waf := &WafEngine{}
+	failResp := &http.Response{StatusCode: 401, Header: http.Header{}}
+
+	//用户名与受害IP相同 锁定用户名不能锁住该IP
+	for _, ip := range []string{"8.8.8.1", "8.8.8.2", "8.8.8.3", "8.8.8.4"} {
+		waf.recordLoginResult(newLoginTestAttempt(ip, "9.9.9.9"), failResp, "")
+	}
+	if isLock, lockName := waf.checkLoginLock(newLoginTestAttempt("9.9.9.9", "")); isLock {
+		t.Errorf("victim ip locked by username: %s", lockName)
+	}
+	//用户名写成 IP_用户名 的形式也不能锁住其他人的IP+用户名
+	for _, ip := range []string{"8.8.8.5", "8.8.8.6", "8.8.8.7", "8.8.8.8"} {
+		waf.recordLoginResult(newLoginTestAttempt(ip, "9.9.9.8_alice"), failResp, "")
+	}
+	if isLock, lockName := waf.checkLoginLock(newLoginTestAttempt("9.9.9.8", "alice")); isLock {
+		t.Errorf("victim ip_user locked by username: %s", lockName)
+	}
+}
+
+func TestIncLoginFailConcurrent(t *testing.T) {
+	global.GCACHE_WAFCACHE = cache.InitWafCache()
+	attempt := newLoginTestAttempt("1.1.1.1", "admin")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			incLoginFail(attempt, "login_fail_test")
+		}()
+	}
+	wg.Wait()
+	if counter := incLoginFail(attempt, "login_fail_test"); counter != 51 {
+		t.Errorf("counter got %d, want 51", counter)
+	}
+}
+
+func TestCheckLoginLockIpMaxUser(t *testing.T) {
+	global.GCACHE_WAFCACHE = cache.InitWafCache()
+	waf := &WafEngine{}
+	for _, userName := range []string{"a", "b"} {
+		if isLock, lockName := waf.checkLoginLock(newLoginTestAttempt("1.1.1.1", userName)); isLock {
+			t.Errorf("%s should not be locked: %s", userName, lockName)
+		}
+	}
+	if isLock, lockName := waf.checkLoginLock(newLoginTestAttempt("1.1.1.1", "c")); !isLock || lockName != "登录防护:单IP尝试用户名过多" {
+		t.Errorf("ip max user got %v %s", isLock, lockName)
+	}
+	//超出后整个IP被锁定
+	if isLock, lockName := waf.checkLoginLock(newLoginTestAttempt("1.1.1.1", "")); !isLock || lockName != "登录防护:IP失败次数过多" {
+		t.Errorf("ip lock got %v %s", isLock, lockName)
+	}
+}
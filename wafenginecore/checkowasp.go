@@ -1,12 +1,20 @@
 package wafenginecore
 
 import (
+	"SamWaf/common/zlog"
 	"SamWaf/global"
 	"SamWaf/innerbean"
+	"SamWaf/model"
 	"SamWaf/model/detection"
+	"SamWaf/utils"
+	"SamWaf/wafowasp"
+	"encoding/json"
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
+
+	"github.com/corazawaf/coraza/v3/types"
 )
 
 func (waf *WafEngine) CheckOwasp(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
@@ -16,15 +24,184 @@ func (waf *WafEngine) CheckOwasp(r *http.Request, weblogbean *innerbean.WebLog,
 		Title:           "",
 		Content:         "",
 	}
-	if global.GCONFIG_RECORD_ENABLE_OWASP == 0 {
+	owasp := waf.getHostOwasp(weblogbean.HOST)
+	if owasp == nil {
+		return result
+	}
+	tx, interruption, err := owasp.BeginTransaction(r, *weblogbean)
+	if err != nil {
 		return result
 	}
-	isInteeruption, interruption, err := global.GWAF_OWASP.ProcessRequest(r, *weblogbean)
-	if err == nil && isInteeruption {
+	detail := wafowasp.GetDetail(tx)
+	if len(detail.Rules) > 0 {
+		detailBytes, _ := json.Marshal(detail)
+		weblogbean.OWASP_DETAIL = string(detailBytes)
+	}
+	if interruption != nil {
 		result.IsBlock = true
-		result.Title = "OWASP:" + strconv.Itoa(interruption.RuleID)
+		result.Title = owaspRuleTitle(interruption.RuleID, detail)
 		result.Content = "访问不合法"
 		weblogbean.RISK_LEVEL = 2
+		wafowasp.FinishTransaction(tx, global.GCONFIG_RECORD_OWASP_AUDIT_LOG == 1)
+		return result
 	}
+	//响应阶段继续使用该事务
+	if global.GCONFIG_RECORD_OWASP_RESPONSE == 1 {
+		owaspPendingTx.Store(weblogbean.REQ_UUID, tx)
+		return result
+	}
+	wafowasp.FinishTransaction(tx, global.GCONFIG_RECORD_OWASP_AUDIT_LOG == 1)
 	return result
 }
+
+/*
+*
+OWASP响应检测 返回是否阻止以及规则名称
+respBody 为解压后的响应内容
+*/
+func (waf *WafEngine) checkOwaspResponse(tx types.Transaction, resp *http.Response, respBody []byte, weblogbean *innerbean.WebLog) (bool, string) {
+	defer wafowasp.FinishTransaction(tx, global.GCONFIG_RECORD_OWASP_AUDIT_LOG == 1)
+	interruption, err := wafowasp.ProcessResponse(tx, resp, respBody)
+	if err != nil {
+		zlog.Debug("owasp response processing error", err.Error())
+	}
+	detail := wafowasp.GetDetail(tx)
+	if len(detail.Rules) > 0 {
+		detailBytes, _ := json.Marshal(detail)
+		weblogbean.OWASP_DETAIL = string(detailBytes)
+	}
+	if interruption == nil {
+		return false, ""
+	}
+	weblogbean.RISK_LEVEL = 2
+	return true, owaspRuleTitle(interruption.RuleID, detail)
+}
+
+// 等待响应阶段检测的事务 key为请求uuid
+var owaspPendingTx sync.Map
+
+// 取出等待响应阶段检测的事务
+func takeOwaspTx(reqUuid string) (types.Transaction, bool) {
+	tx, ok := owaspPendingTx.LoadAndDelete(reqUuid)
+	if !ok {
+		return nil, false
+	}
+	return tx.(types.Transaction), true
+}
+
+// 请求结束后结束未进入响应检测的事务（后端异常、协议升级、处理中panic等） 在创建事务前defer注册
+func finishPendingOwaspTx(reqUuid string) {
+	if tx, ok := takeOwaspTx(reqUuid); ok {
+		wafowasp.FinishTransaction(tx, global.GCONFIG_RECORD_OWASP_AUDIT_LOG == 1)
+	}
+}
+
+// 规则名称 带上规则说明
+func owaspRuleTitle(ruleId int, detail wafowasp.OwaspDetail) string {
+	for _, rule := range detail.Rules {
+		if rule.Id == ruleId {
+			return "OWASP:" + strconv.Itoa(ruleId) + "(" + rule.Msg + ")"
+		}
+	}
+	return "OWASP:" + strconv.Itoa(ruleId)
+}
+
+/*
+*
+获取主机使用的OWASP实例 未配置时使用全局开关和全局实例，返回nil表示不检测
+主机实例在加载主机和自定义规则变化时编译，请求中只读取
+*/
+func (waf *WafEngine) getHostOwasp(host string) *wafowasp.WafOWASP {
+	hostSafe, ok := waf.HostTarget[host]
+	if !ok || hostSafe == nil {
+		return nil
+	}
+	enable := global.GCONFIG_RECORD_ENABLE_OWASP == 1
+	if hostSafe.OwaspConfigured {
+		enable = hostSafe.OwaspBean.Enable == 1
+	}
+	if !enable {
+		return nil
+	}
+	if hostOwasp, ok := hostSafe.OwaspWaf.Load().(*wafowasp.WafOWASP); ok && hostOwasp != nil && hostOwasp.IsActive {
+		return hostOwasp
+	}
+	//未编译、编译中或编译失败时使用全局实例
+	return global.GWAF_OWASP
+}
+
+/*
+*
+解析主机的OWASP配置 返回是否有主机配置
+*/
+func parseHostOwasp(owaspJson string) (model.HostsOwasp, bool) {
+	var owaspConfig model.HostsOwasp
+	if owaspJson == "" {
+		return owaspConfig, false
+	}
+	err := json.Unmarshal([]byte(owaspJson), &owaspConfig)
+	if err != nil {
+		zlog.Error("解析owasp json失败")
+		return model.HostsOwasp{}, false
+	}
+	return owaspConfig, true
+}
+
+// 主机生效的自定义规则 全局规则在前
+func (waf *WafEngine) getHostOwaspRules(host string) []model.OwaspRule {
+	var owaspRules []model.OwaspRule
+	hostSafes := []string{host}
+	if host != global.GWAF_GLOBAL_HOST_NAME {
+		hostSafes = []string{global.GWAF_GLOBAL_HOST_NAME, host}
+	}
+	for _, hostSafe := range hostSafes {
+		if hostTarget, ok := waf.HostTarget[hostSafe]; ok {
+			for _, rule := range hostTarget.OwaspRuleLists {
+				if rule.Status == 1 {
+					owaspRules = append(owaspRules, rule)
+				}
+			}
+		}
+	}
+	return owaspRules
+}
+
+/*
+*
+编译主机的OWASP实例并保存到主机上 没有主机配置和自定义规则时使用全局实例
+编译耗时较长，在后台进行，只保留最后一次编译的结果
+*/
+func (waf *WafEngine) compileHostOwasp(host string) {
+	hostSafe, ok := waf.HostTarget[host]
+	if !ok || hostSafe == nil {
+		return
+	}
+	version := hostSafe.OwaspVersion.Add(1)
+	owaspRules := waf.getHostOwaspRules(host)
+	beforeRules, afterRules := wafowasp.BuildDirectives(hostSafe.OwaspBean)
+	if beforeRules == "" && afterRules == "" && len(owaspRules) == 0 {
+		hostSafe.OwaspWaf.Store((*wafowasp.WafOWASP)(nil))
+		return
+	}
+	owaspConfig := hostSafe.OwaspBean
+	go func() {
+		hostOwasp := wafowasp.GetHostWafOWASP(utils.GetCurrentDir(), owaspConfig, owaspRules)
+		if hostSafe.OwaspVersion.Load() == version {
+			hostSafe.OwaspWaf.Store(hostOwasp)
+		}
+	}()
+}
+
+/*
+*
+自定义规则变化后重新编译 全局规则变化时重新编译全部主机
+*/
+func (waf *WafEngine) ReloadOwaspRules(hostCode string) {
+	if hostCode != global.GWAF_GLOBAL_HOST_CODE {
+		waf.compileHostOwasp(waf.HostCode[hostCode])
+		return
+	}
+	for host := range waf.HostTarget {
+		waf.compileHostOwasp(host)
+	}
+}
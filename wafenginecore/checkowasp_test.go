@@ -0,0 +1,56 @@
+package wafenginecore
+
+import (
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/wafenginmodel"
+	"SamWaf/wafowasp"
+	"testing"
+)
+
+func TestGetHostOwasp(t *testing.T) {
+	globalOwasp := &wafowasp.WafOWASP{IsActive: true}
+	hostOwasp := &wafowasp.WafOWASP{IsActive: true}
+	oldOwasp, oldEnable := global.GWAF_OWASP, global.GCONFIG_RECORD_ENABLE_OWASP
+	defer func() {
+		global.GWAF_OWASP, global.GCONFIG_RECORD_ENABLE_OWASP = oldOwasp, oldEnable
+	}()
+	global.GWAF_OWASP = globalOwasp
+	global.GCONFIG_RECORD_ENABLE_OWASP = 1
+
+	followGlobal := &wafenginmodel.HostSafe{}
+	disabled := &wafenginmodel.HostSafe{OwaspConfigured: true, OwaspBean: model.HostsOwasp{Enable: 0}}
+	compiled := &wafenginmodel.HostSafe{OwaspConfigured: true, OwaspBean: model.HostsOwasp{Enable: 1, ParanoiaLevel: 2}}
+	compiled.OwaspWaf.Store(hostOwasp)
+	waf := &WafEngine{HostTarget: map[string]*wafenginmodel.HostSafe{
+		"a.com:80": followGlobal,
+		"b.com:80": disabled,
+		"c.com:80": compiled,
+	}}
+	if waf.getHostOwasp("a.com:80") != globalOwasp {
+		t.Errorf("host without config should use global instance")
+	}
+	if waf.getHostOwasp("b.com:80") != nil {
+		t.Errorf("disabled host should not be checked")
+	}
+	if waf.getHostOwasp("c.com:80") != hostOwasp {
+		t.Errorf("host should use its compiled instance")
+	}
+	if waf.getHostOwasp("missing:80") != nil {
+		t.Errorf("missing host should not be checked")
+	}
+	global.GCONFIG_RECORD_ENABLE_OWASP = 0
+	if waf.getHostOwasp("a.com:80") != nil {
+		t.Errorf("host without config should follow global switch")
+	}
+}
+
+func TestParseHostOwasp(t *testing.T) {
+	if _, configured := parseHostOwasp(""); configured {
+		t.Errorf("empty json should not be configured")
+	}
+	config, configured := parseHostOwasp(`{"enable":1,"paranoia_level":3}`)
+	if !configured || config.Enable != 1 || config.ParanoiaLevel != 3 {
+		t.Errorf("unexpected config %+v %v", config, configured)
+	}
+}
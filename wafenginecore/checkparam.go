@@ -0,0 +1,162 @@
+package wafenginecore
+
+import (
+	"SamWaf/common/zlog"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/detection"
+	"SamWaf/wafdefensetraversal"
+	"SamWaf/wafenginecore/wafhttpcore"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// 请求上下文中缓存具名输入的key
+const inspectParamsCtxKey = "inspect_params"
+
+type inspectParamsCache struct {
+	params    []wafhttpcore.WafParam
+	extracted bool
+}
+
+/*
+*
+在请求上下文中准备具名输入的缓存 同一请求的各个检测器共用一次提取结果
+*/
+func withInspectParamsCache(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), inspectParamsCtxKey, &inspectParamsCache{}))
+}
+
+/*
+*
+获取参数检测的具名输入 请求上下文中有缓存时只提取一次
+*/
+func (waf *WafEngine) getInspectParams(r *http.Request, body string, formValue url.Values) []wafhttpcore.WafParam {
+	cache, ok := r.Context().Value(inspectParamsCtxKey).(*inspectParamsCache)
+	if !ok {
+		return wafhttpcore.ExtractParams(r, body, formValue)
+	}
+	if !cache.extracted {
+		cache.params = wafhttpcore.ExtractParams(r, body, formValue)
+		cache.extracted = true
+	}
+	return cache.params
+}
+
+/*
+*
+检测请求参数中的目录穿越
+*/
+func (waf *WafEngine) CheckTraversal(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
+	result := detection.Result{
+		JumpGuardResult: false,
+		IsBlock:         false,
+		Title:           "",
+		Content:         "",
+	}
+	excludes := waf.getDetectExcludes(weblogbean.HOST)
+	for _, param := range waf.getInspectParams(r, weblogbean.BODY, formValue) {
+		if param.Value == "" || isDetectExclude(excludes, "traversal", r.URL.Path, param) {
+			continue
+		}
+		isTraversal, traversalName := wafdefensetraversal.DetermineTraversal(param.Value)
+		if isTraversal {
+			weblogbean.RISK_LEVEL = 3
+			weblogbean.MATCH_PARAM = param.FullName()
+			result.IsBlock = true
+			result.Title = "目录穿越:" + traversalName + "(" + param.FullName() + ")"
+			result.Content = "请正确访问"
+			return result
+		}
+	}
+	return result
+}
+
+/*
+*
+获取主机配置的需要检测的请求头和cookie
+*/
+func (waf *WafEngine) getInspectHeaderParams(r *http.Request, host string) []wafhttpcore.WafParam {
+	headerNames := wafhttpcore.DefaultInspectHeaders
+	var cookieNames []string
+	if hostSafe, ok := waf.HostTarget[host]; ok {
+		if hostSafe.Host.INSPECT_HEADERS != "" {
+			headerNames = splitLines(hostSafe.Host.INSPECT_HEADERS)
+		}
+		if hostSafe.Host.INSPECT_COOKIES != "" {
+			cookieNames = splitLines(hostSafe.Host.INSPECT_COOKIES)
+		}
+	}
+	return wafhttpcore.ExtractHeaderParams(r, headerNames, cookieNames)
+}
+
+// 按行拆分并去掉空行
+func splitLines(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r", ""), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+/*
+*
+获取主机的检测排除项（含全局主机）加载主机时已解析
+*/
+func (waf *WafEngine) getDetectExcludes(host string) []model.HostsDetectExclude {
+	var excludes []model.HostsDetectExclude
+	if hostSafe, ok := waf.HostTarget[host]; ok && hostSafe != nil {
+		excludes = hostSafe.DetectExcludes
+	}
+	if host == global.GWAF_GLOBAL_HOST_NAME {
+		return excludes
+	}
+	if globalHost, ok := waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME]; ok && globalHost != nil && globalHost.Host.GUARD_STATUS == 1 && len(globalHost.DetectExcludes) > 0 {
+		excludes = append(append([]model.HostsDetectExclude{}, excludes...), globalHost.DetectExcludes...)
+	}
+	return excludes
+}
+
+/*
+*
+解析主机的检测排除项 不限制任何条件的排除项会被丢弃
+*/
+func parseDetectExcludes(excludeJson string) []model.HostsDetectExclude {
+	excludes, err := model.ParseDetectExcludes(excludeJson)
+	if err != nil {
+		zlog.Error("解析检测排除项失败", err.Error())
+	}
+	return excludes
+}
+
+/*
+*
+判断参数是否在某个检测器的排除项里
+路径为空表示所有路径，以*结尾表示前缀匹配；参数为空或*表示所有参数，参数可写成 content 或 form.content
+*/
+func isDetectExclude(excludes []model.HostsDetectExclude, detector string, path string, param wafhttpcore.WafParam) bool {
+	for _, exclude := range excludes {
+		if exclude.Detector != "" && exclude.Detector != "*" && !strings.EqualFold(exclude.Detector, detector) {
+			continue
+		}
+		if exclude.Path != "" {
+			if strings.HasSuffix(exclude.Path, "*") {
+				if !strings.HasPrefix(path, strings.TrimSuffix(exclude.Path, "*")) {
+					continue
+				}
+			} else if exclude.Path != path {
+				continue
+			}
+		}
+		if exclude.Param == "" || exclude.Param == "*" || exclude.Param == param.Name || exclude.Param == param.FullName() {
+			return true
+		}
+	}
+	return false
+}
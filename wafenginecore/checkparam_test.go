@@ -0,0 +1,47 @@
+package wafenginecore
+
+import (
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/wafenginmodel"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestGetInspectParamsCached(t *testing.T) {
+	waf := &WafEngine{}
+	r := withInspectParamsCache(httptest.NewRequest("GET", "/item?id=1", nil))
+	params := waf.getInspectParams(r, "", url.Values{})
+	cache := r.Context().Value(inspectParamsCtxKey).(*inspectParamsCache)
+	if !cache.extracted || len(params) != len(cache.params) {
+		t.Fatalf("params not cached")
+	}
+	//缓存后不再重新提取
+	cache.params = nil
+	if params = waf.getInspectParams(r, "", url.Values{}); params != nil {
+		t.Errorf("params extracted again: %v", params)
+	}
+}
+
+func TestGetDetectExcludes(t *testing.T) {
+	hostExcludes := []model.HostsDetectExclude{{Detector: "sqli", Param: "content"}}
+	globalExcludes := []model.HostsDetectExclude{{Detector: "xss", Path: "/editor/*"}}
+	waf := &WafEngine{
+		HostTarget: map[string]*wafenginmodel.HostSafe{
+			"a.com:80":                   {Host: model.Hosts{GUARD_STATUS: 1}, DetectExcludes: hostExcludes},
+			global.GWAF_GLOBAL_HOST_NAME: {Host: model.Hosts{GUARD_STATUS: 1}, DetectExcludes: globalExcludes},
+		},
+	}
+	excludes := waf.getDetectExcludes("a.com:80")
+	if len(excludes) != 2 || excludes[0].Detector != "sqli" || excludes[1].Detector != "xss" {
+		t.Errorf("excludes got %v", excludes)
+	}
+	if excludes = waf.getDetectExcludes("a.com:80"); len(excludes) != 2 || len(waf.HostTarget["a.com:80"].DetectExcludes) != 1 {
+		t.Errorf("host excludes should not be modified, got %v", excludes)
+	}
+	waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS = 0
+	if excludes = waf.getDetectExcludes("a.com:80"); len(excludes) != 1 {
+		t.Errorf("global excludes should be skipped when global guard is off, got %v", excludes)
+	}
+}
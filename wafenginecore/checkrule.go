@@ -5,8 +5,10 @@ import (
 	"SamWaf/global"
 	"SamWaf/innerbean"
 	"SamWaf/model/detection"
+	"SamWaf/wafbot"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 /*
@@ -20,49 +22,67 @@ func (waf *WafEngine) CheckRule(r *http.Request, weblogbean *innerbean.WebLog, f
 		Title:           "",
 		Content:         "",
 	}
+	ruleFact := innerbean.NewRuleFact(weblogbean, r)
+	ruleFact.SetCounter(getRuleCounter)
+	ruleFact.SetBotResolver(func() (bool, bool, string) {
+		return wafbot.DetermineNormalSearch(weblogbean.USER_AGENT, weblogbean.SRC_IP)
+	})
 	//规则判断 （局部）
+	decision := innerbean.RuleDecision{}
 	if waf.HostTarget[weblogbean.HOST].Rule != nil {
-		if waf.HostTarget[weblogbean.HOST].Rule.KnowledgeBase != nil {
-			ruleMatchs, err := waf.HostTarget[weblogbean.HOST].Rule.Match("MF", weblogbean)
-			if err == nil {
-				if len(ruleMatchs) > 0 {
-					rulestr := ""
-					for _, v := range ruleMatchs {
-						rulestr = rulestr + v.RuleDescription + ","
-					}
-					weblogbean.RISK_LEVEL = 1
-
-					result.IsBlock = true
-					result.Title = rulestr
-					result.Content = "您的访问被阻止触发规则"
-					return result
-				}
-			} else {
-				zlog.Debug("规则 ", err)
-			}
+		err := waf.HostTarget[weblogbean.HOST].Rule.ExecuteFact("MF", ruleFact)
+		if err != nil {
+			zlog.Debug("规则 ", err)
+		}
+		decision = ruleFact.Decision()
+	}
+	//规则判断 （全局网站） 网站规则已做出处置时不再执行
+	if decision.Action == "" && waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 && waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Rule != nil {
+		err := waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Rule.ExecuteFact("MF", ruleFact)
+		if err != nil {
+			zlog.Debug("规则 ", err)
+		}
+		decision = ruleFact.Decision()
+		if decision.Action != "" {
+			decision.RuleName = "【全局】" + decision.RuleName
 		}
 	}
-	//规则判断 （全局网站）
-	if waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 && waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Rule != nil {
-		if waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Rule.KnowledgeBase != nil {
-			ruleMatchs, err := waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Rule.Match("MF", weblogbean)
-			if err == nil {
-				if len(ruleMatchs) > 0 {
-					rulestr := ""
-					for _, v := range ruleMatchs {
-						rulestr = rulestr + v.RuleDescription + ","
-					}
-					weblogbean.RISK_LEVEL = 1
+	//附加到转发请求的请求头
+	for key, value := range ruleFact.UpstreamHeaders() {
+		r.Header.Set(key, value)
+	}
+	if tags := ruleFact.Tags(); len(tags) > 0 {
+		weblogbean.RULE = "标记:" + strings.Join(tags, ",")
+	}
+	risk := ruleFact.Risk()
+	if risk < 0 && decision.Action != "" && decision.Action != innerbean.RULE_ACTION_ALLOW {
+		risk = 1
+	}
+	if risk >= 0 {
+		weblogbean.RISK_LEVEL = risk
+	}
 
-					result.IsBlock = true
-					result.Title = "【全局】" + rulestr
-					result.Content = "您的访问被阻止触发规则"
-					return result
-				}
-			} else {
-				zlog.Debug("规则 ", err)
-			}
+	switch decision.Action {
+	case innerbean.RULE_ACTION_ALLOW:
+		result.JumpGuardResult = true
+		result.Title = decision.RuleName
+	case innerbean.RULE_ACTION_BLOCK:
+		result.IsBlock = true
+		result.Title = decision.RuleName
+		result.Content = "您的访问被阻止触发规则"
+		if decision.Message != "" {
+			result.Content = decision.Message
 		}
+		result.StatusCode = decision.StatusCode
+	case innerbean.RULE_ACTION_CHALLENGE:
+		result.IsBlock = true
+		result.Title = decision.RuleName
+		result.Action = innerbean.RULE_ACTION_CHALLENGE
+	case innerbean.RULE_ACTION_REDIRECT:
+		result.IsBlock = true
+		result.Title = decision.RuleName
+		result.Action = innerbean.RULE_ACTION_REDIRECT
+		result.RedirectUrl = decision.RedirectUrl
 	}
 	return result
 }
@@ -1,16 +1,21 @@
 package wafenginecore
 
 import (
+	"SamWaf/enums"
+	"SamWaf/global"
 	"SamWaf/innerbean"
 	"SamWaf/libinjection-go"
 	"SamWaf/model/detection"
+	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 /*
 *
 检测扫描工具
+命中的请求直接拦截，周期内命中次数达到阈值后才封禁IP
 */
 func (waf *WafEngine) CheckSan(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
 	result := detection.Result{
@@ -20,16 +25,148 @@ func (waf *WafEngine) CheckSan(r *http.Request, weblogbean *innerbean.WebLog, fo
 		Content:         "",
 	}
 	var scanFlag = false
+	scanTitle := "扫描工具"
 	if libinjection.IsScan(weblogbean) {
 		scanFlag = true
 	}
+	if scanFlag == false {
+		isScanTool, toolName := libinjection.IsScanSignature(weblogbean.USER_AGENT, r.Header)
+		if isScanTool {
+			scanFlag = true
+			scanTitle = "扫描工具:" + toolName
+		}
+	}
+	if scanFlag == false {
+		isSensitivePath, pathName := libinjection.IsSensitivePath(r.URL.Path)
+		if isSensitivePath {
+			scanFlag = true
+			scanTitle = "敏感路径探测:" + pathName
+		}
+	}
 	if scanFlag == true {
 		weblogbean.RISK_LEVEL = 1
+		waf.recordScanHit(weblogbean, scanTitle)
 
 		result.IsBlock = true
-		result.Title = "扫描工具"
+		result.Title = scanTitle
 		result.Content = "请正确访问"
 		return result
 	}
 	return result
 }
+
+/*
+*
+检测IP是否已因扫描行为被当前网站封禁
+*/
+func (waf *WafEngine) CheckScanBan(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
+	result := detection.Result{
+		JumpGuardResult: false,
+		IsBlock:         false,
+		Title:           "",
+		Content:         "",
+	}
+	if waf.getHostDefense(weblogbean.HOST).DEFENSE_SCAN != 1 {
+		return result
+	}
+	if global.GCACHE_WAFCACHE.IsKeyExist(enums.CACHE_SCANBAN_PRE + weblogbean.HOST_CODE + "_" + weblogbean.SRC_IP) {
+		weblogbean.RISK_LEVEL = 1
+		result.IsBlock = true
+		result.Title = "扫描封禁"
+		result.Content = "当前IP由于扫描行为暂时无法访问"
+	}
+	return result
+}
+
+/*
+*
+记录响应状态,在时间窗口内404/403过多或探测不存在的程序路径视为扫描
+*/
+func (waf *WafEngine) recordScanStatus(r *http.Request, weblogbean innerbean.WebLog, statusCode int) {
+	if statusCode != http.StatusNotFound && statusCode != http.StatusForbidden {
+		return
+	}
+	//白名单放行的请求同样会走到这里
+	if !waf.canBanScanIP(&weblogbean) {
+		return
+	}
+	isProbePath, pathName := libinjection.IsProbePath(r.URL.Path)
+	if isProbePath {
+		waf.recordScanHit(&weblogbean, "程序路径探测:"+pathName)
+		return
+	}
+	if global.GCONFIG_RECORD_SCAN_ERROR_MAX_COUNT <= 0 {
+		return
+	}
+	cacheKey := enums.CACHE_SCAN_ERROR_PRE + weblogbean.HOST_CODE + "_" + weblogbean.SRC_IP
+	hitCounter := incrScanCounter(cacheKey)
+	if hitCounter >= int(global.GCONFIG_RECORD_SCAN_ERROR_MAX_COUNT) {
+		global.GCACHE_WAFCACHE.Remove(cacheKey)
+		waf.banScanIP(&weblogbean, fmt.Sprintf("%d秒内404/403达到%d次", global.GCONFIG_RECORD_SCAN_ERROR_WINDOW_SECONDS, hitCounter))
+	}
+}
+
+/*
+*
+记录一次扫描特征命中，周期内达到阈值后封禁
+*/
+func (waf *WafEngine) recordScanHit(weblogbean *innerbean.WebLog, reason string) {
+	if global.GCONFIG_RECORD_SCAN_BAN_HIT_COUNT <= 0 {
+		return
+	}
+	if !waf.canBanScanIP(weblogbean) {
+		return
+	}
+	cacheKey := enums.CACHE_SCAN_HIT_PRE + weblogbean.HOST_CODE + "_" + weblogbean.SRC_IP
+	hitCounter := incrScanCounter(cacheKey)
+	if hitCounter >= int(global.GCONFIG_RECORD_SCAN_BAN_HIT_COUNT) {
+		global.GCACHE_WAFCACHE.Remove(cacheKey)
+		waf.banScanIP(weblogbean, fmt.Sprintf("%d秒内命中扫描特征%d次，最近一次:%s", global.GCONFIG_RECORD_SCAN_ERROR_WINDOW_SECONDS, hitCounter, reason))
+	}
+}
+
+/*
+*
+判断是否允许封禁 网站未开启防护、未开启扫描防御或IP在白名单（网站或全局）中时不封禁
+*/
+func (waf *WafEngine) canBanScanIP(weblogbean *innerbean.WebLog) bool {
+	hostSafe, ok := waf.HostTarget[weblogbean.HOST]
+	if !ok || hostSafe == nil || hostSafe.Host.GUARD_STATUS != 1 || hostSafe.DefenseBean.DEFENSE_SCAN != 1 {
+		return false
+	}
+	if _, ok := hostSafe.IPWhiteMatcher.Match(weblogbean.SRC_IP, 0, nil); ok {
+		return false
+	}
+	globalHostSafe, ok := waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME]
+	if ok && globalHostSafe != nil && globalHostSafe.Host.GUARD_STATUS == 1 {
+		if _, ok := globalHostSafe.IPWhiteMatcher.Match(weblogbean.SRC_IP, 0, nil); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// 周期计数器加一 返回当前次数
+func incrScanCounter(cacheKey string) int {
+	return global.GCACHE_WAFCACHE.IncrWithTTl(cacheKey, time.Duration(global.GCONFIG_RECORD_SCAN_ERROR_WINDOW_SECONDS)*time.Second)
+}
+
+/*
+*
+将扫描IP加入当前网站的封禁并发送提醒
+*/
+func (waf *WafEngine) banScanIP(weblogbean *innerbean.WebLog, reason string) {
+	if global.GCONFIG_RECORD_SCAN_LOCK_MINUTES <= 0 {
+		return
+	}
+	cacheKey := enums.CACHE_SCANBAN_PRE + weblogbean.HOST_CODE + "_" + weblogbean.SRC_IP
+	if global.GCACHE_WAFCACHE.IsKeyExist(cacheKey) {
+		return
+	}
+	global.GCACHE_WAFCACHE.SetWithTTl(cacheKey, 1, time.Duration(global.GCONFIG_RECORD_SCAN_LOCK_MINUTES)*time.Minute)
+	banInfo := fmt.Sprintf("IP:%s 网站:%s 因扫描行为被封禁%d分钟，原因：%s", weblogbean.SRC_IP, weblogbean.HOST, global.GCONFIG_RECORD_SCAN_LOCK_MINUTES, reason)
+	global.GQEQUE_MESSAGE_DB.Enqueue(innerbean.OperatorMessageInfo{
+		BaseMessageInfo: innerbean.BaseMessageInfo{OperaType: "扫描封禁提醒"},
+		OperaCnt:        banInfo,
+	})
+}
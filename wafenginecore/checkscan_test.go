@@ -0,0 +1,94 @@
+package wafenginecore
+
+import (
+	"SamWaf/cache"
+	"SamWaf/common/queue"
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/wafenginmodel"
+	"SamWaf/utils"
+	"net/http/httptest"
+	"testing"
+)
+
+func newScanTestWaf(guardStatus int, whiteIp string) *WafEngine {
+	global.GCACHE_WAFCACHE = cache.InitWafCache()
+	global.GQEQUE_MESSAGE_DB = queue.NewQueue()
+	whiteMatcher := utils.NewIPMatcher()
+	if whiteIp != "" {
+		whiteMatcher.Add(whiteIp, 0)
+	}
+	return &WafEngine{
+		HostTarget: map[string]*wafenginmodel.HostSafe{
+			"a.com:80": {
+				Host:           model.Hosts{Code: "hostA", GUARD_STATUS: guardStatus},
+				DefenseBean:    model.HostsDefense{DEFENSE_SCAN: 1},
+				IPWhiteMatcher: whiteMatcher,
+			},
+			"b.com:80": {
+				Host:        model.Hosts{Code: "hostB", GUARD_STATUS: 1},
+				DefenseBean: model.HostsDefense{DEFENSE_SCAN: 1},
+			},
+			global.GWAF_GLOBAL_HOST_NAME: {
+				Host: model.Hosts{Code: "", GUARD_STATUS: 0},
+			},
+		},
+	}
+}
+
+func scanTestLog(host string, hostCode string) *innerbean.WebLog {
+	return &innerbean.WebLog{HOST: host, HOST_CODE: hostCode, SRC_IP: "10.0.0.1", USER_AGENT: "sqlmap/1.7"}
+}
+
+func isScanBanned(waf *WafEngine, weblogbean *innerbean.WebLog) bool {
+	r := httptest.NewRequest("GET", "http://"+weblogbean.HOST+"/", nil)
+	return waf.CheckScanBan(r, weblogbean, nil).IsBlock
+}
+
+func TestCheckSanBanAfterThreshold(t *testing.T) {
+	waf := newScanTestWaf(1, "")
+	weblogbean := scanTestLog("a.com:80", "hostA")
+	r := httptest.NewRequest("GET", "http://a.com/", nil)
+	for i := 1; i <= int(global.GCONFIG_RECORD_SCAN_BAN_HIT_COUNT); i++ {
+		if isScanBanned(waf, weblogbean) {
+			t.Fatalf("banned before threshold at hit %d", i)
+		}
+		if !waf.CheckSan(r, weblogbean, nil).IsBlock {
+			t.Fatalf("scan request not blocked")
+		}
+	}
+	if !isScanBanned(waf, weblogbean) {
+		t.Errorf("not banned after threshold")
+	}
+	if global.GQEQUE_MESSAGE_DB.Size() == 0 {
+		t.Errorf("ban message not sent")
+	}
+	//封禁只作用于当前网站
+	if isScanBanned(waf, scanTestLog("b.com:80", "hostB")) {
+		t.Errorf("ban leaked to other host")
+	}
+}
+
+func TestCheckSanSkipBanForWhiteIp(t *testing.T) {
+	waf := newScanTestWaf(1, "10.0.0.0/24")
+	weblogbean := scanTestLog("a.com:80", "hostA")
+	for i := 0; i < int(global.GCONFIG_RECORD_SCAN_BAN_HIT_COUNT)*2; i++ {
+		waf.recordScanHit(weblogbean, "test")
+	}
+	if global.GCACHE_WAFCACHE.IsKeyExist(enums.CACHE_SCANBAN_PRE + "hostA_10.0.0.1") {
+		t.Errorf("white ip banned")
+	}
+}
+
+func TestCheckSanSkipBanWhenGuardOff(t *testing.T) {
+	waf := newScanTestWaf(0, "")
+	weblogbean := scanTestLog("a.com:80", "hostA")
+	for i := 0; i < int(global.GCONFIG_RECORD_SCAN_BAN_HIT_COUNT)*2; i++ {
+		waf.recordScanHit(weblogbean, "test")
+	}
+	if global.GCACHE_WAFCACHE.IsKeyExist(enums.CACHE_SCANBAN_PRE + "hostA_10.0.0.1") {
+		t.Errorf("ip banned while host guard is off")
+	}
+}
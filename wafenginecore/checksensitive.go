@@ -1,10 +1,18 @@
 package wafenginecore
 
 import (
+	"SamWaf/enums"
 	"SamWaf/innerbean"
+	"SamWaf/model"
 	"SamWaf/model/detection"
+	"SamWaf/model/wafenginmodel"
+	"bytes"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
 /*
@@ -22,29 +30,140 @@ func (waf *WafEngine) CheckSensitive(r *http.Request, weblogbean *innerbean.WebL
 		return result
 	}
 	//敏感词检测
-	matchURLResult := waf.SensitiveManager.MultiPatternSearch([]rune(weblogbean.URL), true)
-	if len(matchURLResult) > 0 {
-		weblogbean.RISK_LEVEL = 1
-		result.IsBlock = true
-		result.Title = "敏感词检测：" + string(matchURLResult[0].Word)
-		result.Content = "敏感词内容"
-		return result
+	var hitList []model.Sensitive
+	for _, matcher := range waf.getSensitiveMatchers(waf.SensitiveRequestManager, weblogbean.HOST_CODE) {
+		hitList = append(hitList, matcher.Search(weblogbean.URL)...)
+		hitList = append(hitList, matcher.Search(weblogbean.BODY)...)
+		hitList = append(hitList, matcher.Search(weblogbean.POST_FORM)...)
 	}
-	matchBodyResult := waf.SensitiveManager.MultiPatternSearch([]rune(weblogbean.BODY), true)
-	if len(matchBodyResult) > 0 {
-		weblogbean.RISK_LEVEL = 1
-		result.IsBlock = true
-		result.Title = "敏感词检测：" + string(matchBodyResult[0].Word)
-		result.Content = "敏感词内容"
+	if len(hitList) == 0 {
 		return result
 	}
-	matchPostFromResult := waf.SensitiveManager.MultiPatternSearch([]rune(weblogbean.POST_FORM), true)
-	if len(matchPostFromResult) > 0 {
-		weblogbean.RISK_LEVEL = 1
-		result.IsBlock = true
-		result.Title = "敏感词检测：" + string(matchPostFromResult[0].Word)
-		result.Content = "敏感词内容"
-		return result
+	var replaceList []model.Sensitive
+	var logList []model.Sensitive
+	for _, sensitive := range hitList {
+		switch sensitive.Action {
+		case enums.SENSITIVE_ACTION_REPLACE:
+			replaceList = append(replaceList, sensitive)
+		case enums.SENSITIVE_ACTION_LOG:
+			logList = append(logList, sensitive)
+		default:
+			weblogbean.RISK_LEVEL = 1
+			result.IsBlock = true
+			result.Title = "敏感词检测：" + sensitive.Content
+			result.Content = "敏感词内容"
+			return result
+		}
+	}
+	//替换请求里面的敏感词
+	if len(replaceList) > 0 {
+		if r.URL.RawQuery != "" {
+			r.URL.RawQuery = wafenginmodel.MaskSensitiveQuery(r.URL.RawQuery, replaceList)
+		}
+		if weblogbean.BODY != "" && r.ContentLength == int64(len(weblogbean.BODY)) {
+			maskBody := wafenginmodel.MaskSensitiveWords(weblogbean.BODY, replaceList)
+			r.Body = io.NopCloser(bytes.NewBufferString(maskBody))
+			r.ContentLength = int64(len(maskBody))
+			r.Header.Set("Content-Length", strconv.Itoa(len(maskBody)))
+			weblogbean.BODY = maskBody
+		}
+		weblogbean.RULE = appendSensitiveRule(weblogbean.RULE, "敏感词替换：", replaceList)
+	}
+	if len(logList) > 0 {
+		if weblogbean.RISK_LEVEL == 0 {
+			weblogbean.RISK_LEVEL = 1
+		}
+		weblogbean.RULE = appendSensitiveRule(weblogbean.RULE, "敏感词记录：", logList)
 	}
 	return result
 }
+
+/*
+*
+检测响应内容中的敏感词 返回值：替换后的内容，是否阻止，命中的名称
+*/
+func (waf *WafEngine) CheckResponseSensitive(content string, weblogbean *innerbean.WebLog) (string, bool, string) {
+	var hitList []model.Sensitive
+	for _, matcher := range waf.getSensitiveMatchers(waf.SensitiveResponseManager, weblogbean.HOST_CODE) {
+		hitList = append(hitList, matcher.Search(content)...)
+	}
+	if len(hitList) == 0 {
+		return content, false, ""
+	}
+	var replaceList []model.Sensitive
+	var logList []model.Sensitive
+	for _, sensitive := range hitList {
+		switch sensitive.Action {
+		case enums.SENSITIVE_ACTION_REPLACE:
+			replaceList = append(replaceList, sensitive)
+		case enums.SENSITIVE_ACTION_LOG:
+			logList = append(logList, sensitive)
+		default:
+			weblogbean.RISK_LEVEL = 1
+			return content, true, "响应敏感词检测：" + sensitive.Content
+		}
+	}
+	if len(replaceList) > 0 {
+		content = wafenginmodel.MaskSensitiveWords(content, replaceList)
+		weblogbean.RULE = appendSensitiveRule(weblogbean.RULE, "响应敏感词替换：", replaceList)
+	}
+	if len(logList) > 0 {
+		if weblogbean.RISK_LEVEL == 0 {
+			weblogbean.RISK_LEVEL = 1
+		}
+		weblogbean.RULE = appendSensitiveRule(weblogbean.RULE, "响应敏感词记录：", logList)
+	}
+	return content, false, ""
+}
+
+/*
+*
+获取主机和全局的敏感词匹配器
+*/
+func (waf *WafEngine) getSensitiveMatchers(managers map[string]*wafenginmodel.SensitiveMatcher, hostCode string) []*wafenginmodel.SensitiveMatcher {
+	var matchers []*wafenginmodel.SensitiveMatcher
+	if matcher, ok := managers[hostCode]; ok && hostCode != "" {
+		matchers = append(matchers, matcher)
+	}
+	if matcher, ok := managers[""]; ok {
+		matchers = append(matchers, matcher)
+	}
+	return matchers
+}
+
+/*
+*
+响应内容是否需要做敏感词检测 文本、JSON、XML、JS类内容
+*/
+func isSensitiveContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/html", "application/xhtml+xml",
+		"application/javascript", "application/x-javascript", "application/ecmascript",
+		"application/x-www-form-urlencoded":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+func appendSensitiveRule(rule string, prefix string, sensitiveList []model.Sensitive) string {
+	words := make([]string, 0, len(sensitiveList))
+	found := map[string]bool{}
+	for _, sensitive := range sensitiveList {
+		if found[sensitive.Content] {
+			continue
+		}
+		found[sensitive.Content] = true
+		words = append(words, sensitive.Content)
+	}
+	if rule != "" {
+		rule = rule + ","
+	}
+	return rule + prefix + strings.Join(words, "|")
+}
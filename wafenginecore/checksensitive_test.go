@@ -0,0 +1,19 @@
+package wafenginecore
+
+import "testing"
+
+func TestIsSensitiveContentType(t *testing.T) {
+	textTypes := []string{"text/html; charset=gbk", "text/plain", "application/json;charset=UTF-8",
+		"application/problem+json", "application/atom+xml", "application/javascript", "TEXT/HTML"}
+	for _, contentType := range textTypes {
+		if !isSensitiveContentType(contentType) {
+			t.Errorf("text content type not checked: %s", contentType)
+		}
+	}
+	binaryTypes := []string{"", "image/png", "application/octet-stream", "application/pdf", "video/mp4"}
+	for _, contentType := range binaryTypes {
+		if isSensitiveContentType(contentType) {
+			t.Errorf("binary content type checked: %s", contentType)
+		}
+	}
+}
@@ -19,28 +19,22 @@ func (waf *WafEngine) CheckSql(r *http.Request, weblogbean *innerbean.WebLog, fo
 		Title:           "",
 		Content:         "",
 	}
-	var sqlFlag = false
-	//检测sql注入
-	if libinjection.IsSQLiNotReturnPrint(weblogbean.URL) ||
-		libinjection.IsSQLiNotReturnPrint(weblogbean.BODY) ||
-		libinjection.IsSQLiNotReturnPrint(weblogbean.POST_FORM) {
-		sqlFlag = true
-	}
-	if sqlFlag == false {
-		for _, value := range formValue {
-			for _, v := range value {
-				if libinjection.IsSQLiNotReturnPrint(v) {
-					sqlFlag = true
-				}
-			}
+	excludes := waf.getDetectExcludes(weblogbean.HOST)
+	//按参数检测sql注入
+	for _, param := range waf.getInspectParams(r, weblogbean.BODY, formValue) {
+		if isDetectExclude(excludes, "sqli", r.URL.Path, param) {
+			continue
+		}
+		isSqli, fingerprint := libinjection.IsSQLi(param.Value)
+		if isSqli {
+			weblogbean.RISK_LEVEL = 2
+			weblogbean.MATCH_PARAM = param.FullName()
+			weblogbean.MATCH_FINGERPRINT = fingerprint
+			result.IsBlock = true
+			result.Title = "SQL注入(" + param.FullName() + ")"
+			result.Content = "请正确访问"
+			return result
 		}
-	}
-	if sqlFlag == true {
-		weblogbean.RISK_LEVEL = 2
-		result.IsBlock = true
-		result.Title = "SQL注入"
-		result.Content = "请正确访问"
-		return result
 	}
 	return result
 }
@@ -0,0 +1,48 @@
+package wafenginecore
+
+import (
+	"SamWaf/innerbean"
+	"SamWaf/model/detection"
+	"SamWaf/wafdefensessrf"
+	"SamWaf/wafenginecore/wafhttpcore"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/*
+*
+检测SSRF
+*/
+func (waf *WafEngine) CheckSsrf(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
+	result := detection.Result{
+		JumpGuardResult: false,
+		IsBlock:         false,
+		Title:           "",
+		Content:         "",
+	}
+	hostSafe, ok := waf.HostTarget[weblogbean.HOST]
+	if !ok || hostSafe == nil {
+		return result
+	}
+	var allowDomains []string
+	if hostSafe.Host.SSRF_ALLOW_DOMAINS != "" {
+		allowDomains = strings.Split(strings.ReplaceAll(hostSafe.Host.SSRF_ALLOW_DOMAINS, "\r", ""), "\n")
+	}
+	//查询参数、表单以及JSON请求体展开后的参数
+	for _, param := range waf.getInspectParams(r, weblogbean.BODY, formValue) {
+		if param.Source == wafhttpcore.PARAM_SOURCE_PATH || param.Source == wafhttpcore.PARAM_SOURCE_BODY {
+			continue
+		}
+		isSsrf, ssrfName := wafdefensessrf.DetermineSSRF(allowDomains, param.Name, param.Value)
+		if isSsrf == true {
+			weblogbean.RISK_LEVEL = 3
+			weblogbean.MATCH_PARAM = param.FullName()
+			result.IsBlock = true
+			result.Title = "SSRF:" + ssrfName + "(" + param.FullName() + ")"
+			result.Content = "请正确访问"
+			return result
+		}
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package wafenginecore
+
+import (
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/detection"
+	"SamWaf/model/wafenginmodel"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+/*
+*
+检测访问IP是否在威胁情报中
+返回是否满足条件
+*/
+func (waf *WafEngine) CheckThreatFeed(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
+	result := detection.Result{
+		JumpGuardResult: false,
+		IsBlock:         false,
+		Title:           "",
+		Content:         "",
+	}
+	now := time.Now().Unix()
+	//威胁情报（局部）
+	best, ok := matchThreatFeed(waf.HostTarget[weblogbean.HOST], weblogbean.SRC_IP, now)
+	title := "威胁情报:" + best.FeedName
+	//威胁情报（全局） 局部未命中阻止时仍需检查全局，取处置更严格的
+	if threatFeedRank(best, ok) < threatFeedRankBlock && waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].Host.GUARD_STATUS == 1 {
+		entry, globalOk := matchThreatFeed(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME], weblogbean.SRC_IP, now)
+		if threatFeedRank(entry, globalOk) > threatFeedRank(best, ok) {
+			best, ok = entry, true
+			title = "【全局】威胁情报:" + entry.FeedName
+		}
+	}
+	if ok {
+		fillThreatFeedResult(&result, best, weblogbean, title)
+	}
+	return result
+}
+
+/*
+*
+在主机的威胁情报前缀树中查找命中的条目 已失效的条目跳过
+命中多个条目时取处置最严格的（阻止、挑战、标记），相同时取网段最精确的
+*/
+func matchThreatFeed(hostsafe *wafenginmodel.HostSafe, ip string, now int64) (model.ThreatFeedEntry, bool) {
+	entries := hostsafe.ThreatFeedLists
+	var best model.ThreatFeedEntry
+	found := false
+	//accept 始终返回false 以遍历所有命中的条目
+	hostsafe.ThreatFeedMatcher.Match(ip, 0, func(index int) bool {
+		if index >= len(entries) || (entries[index].ExpireTime != 0 && entries[index].ExpireTime <= now) {
+			return false
+		}
+		if threatFeedRank(entries[index], true) > threatFeedRank(best, found) {
+			best, found = entries[index], true
+		}
+		return false
+	})
+	return best, found
+}
+
+// 处置方式的优先级
+const (
+	threatFeedRankNone = iota
+	threatFeedRankTag
+	threatFeedRankChallenge
+	threatFeedRankBlock
+)
+
+func threatFeedRank(entry model.ThreatFeedEntry, ok bool) int {
+	if !ok {
+		return threatFeedRankNone
+	}
+	switch entry.Action {
+	case enums.THREAT_FEED_ACTION_BLOCK:
+		return threatFeedRankBlock
+	case enums.THREAT_FEED_ACTION_CHALLENGE:
+		return threatFeedRankChallenge
+	}
+	return threatFeedRankTag
+}
+
+// 按条目的处置方式填充结果 标记时只记录到日志不拦截
+func fillThreatFeedResult(result *detection.Result, entry model.ThreatFeedEntry, weblogbean *innerbean.WebLog, title string) {
+	if entry.Action == enums.THREAT_FEED_ACTION_TAG {
+		weblogbean.RULE = title
+		weblogbean.GUEST_IDENTIFICATION = title
+		return
+	}
+	weblogbean.RISK_LEVEL = 1
+	result.IsBlock = true
+	result.Title = title
+	result.Content = "您的访问被阻止了IP限制"
+	if entry.Action == enums.THREAT_FEED_ACTION_CHALLENGE {
+		result.Action = innerbean.RULE_ACTION_CHALLENGE
+	}
+}
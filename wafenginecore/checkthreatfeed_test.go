@@ -0,0 +1,77 @@
+package wafenginecore
+
+import (
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/wafenginmodel"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newThreatFeedTestWaf(hostEntries []model.ThreatFeedEntry, globalEntries []model.ThreatFeedEntry) *WafEngine {
+	return &WafEngine{
+		HostTarget: map[string]*wafenginmodel.HostSafe{
+			"a.com:80": {
+				Host:              model.Hosts{Code: "hostA", GUARD_STATUS: 1},
+				ThreatFeedLists:   hostEntries,
+				ThreatFeedMatcher: wafenginmodel.BuildThreatFeedMatcher(hostEntries),
+			},
+			global.GWAF_GLOBAL_HOST_NAME: {
+				Host:              model.Hosts{Code: "", GUARD_STATUS: 1},
+				ThreatFeedLists:   globalEntries,
+				ThreatFeedMatcher: wafenginmodel.BuildThreatFeedMatcher(globalEntries),
+			},
+		},
+	}
+}
+
+func checkThreatFeedTest(waf *WafEngine, ip string) (bool, string, string, string) {
+	r := httptest.NewRequest("GET", "http://a.com/", nil)
+	weblogbean := &innerbean.WebLog{HOST: "a.com:80", HOST_CODE: "hostA", SRC_IP: ip}
+	result := waf.CheckThreatFeed(r, weblogbean, nil)
+	return result.IsBlock, result.Action, result.Title, weblogbean.RULE
+}
+
+func TestCheckThreatFeedPrecedence(t *testing.T) {
+	expired := time.Now().Unix() - 10
+	hostEntries := []model.ThreatFeedEntry{
+		{FeedName: "tag24", Ip: "1.1.1.0/24", Action: enums.THREAT_FEED_ACTION_TAG},
+		{FeedName: "challenge16", Ip: "1.1.0.0/16", Action: enums.THREAT_FEED_ACTION_CHALLENGE},
+		{FeedName: "blockExpired", Ip: "1.1.1.1", Action: enums.THREAT_FEED_ACTION_BLOCK, ExpireTime: expired},
+		{FeedName: "tagOnly", Ip: "2.2.2.2", Action: enums.THREAT_FEED_ACTION_TAG},
+		{FeedName: "hostBlock", Ip: "4.4.4.4", Action: enums.THREAT_FEED_ACTION_BLOCK},
+	}
+	globalEntries := []model.ThreatFeedEntry{
+		{FeedName: "globalBlock", Ip: "2.2.2.0/24", Action: enums.THREAT_FEED_ACTION_BLOCK},
+		{FeedName: "globalTag", Ip: "3.3.3.3", Action: enums.THREAT_FEED_ACTION_TAG},
+		{FeedName: "globalChallenge", Ip: "4.4.4.4", Action: enums.THREAT_FEED_ACTION_CHALLENGE},
+	}
+	waf := newThreatFeedTestWaf(hostEntries, globalEntries)
+
+	//更精确的标记不能遮住挑战，已失效的阻止不生效
+	isBlock, action, title, _ := checkThreatFeedTest(waf, "1.1.1.1")
+	if !isBlock || action != innerbean.RULE_ACTION_CHALLENGE || title != "威胁情报:challenge16" {
+		t.Errorf("1.1.1.1 got %v %s %s", isBlock, action, title)
+	}
+	//网站只命中标记时使用全局的阻止
+	isBlock, action, title, _ = checkThreatFeedTest(waf, "2.2.2.2")
+	if !isBlock || action != "" || title != "【全局】威胁情报:globalBlock" {
+		t.Errorf("2.2.2.2 got %v %s %s", isBlock, action, title)
+	}
+	//只命中全局标记
+	isBlock, _, _, rule := checkThreatFeedTest(waf, "3.3.3.3")
+	if isBlock || rule != "【全局】威胁情报:globalTag" {
+		t.Errorf("3.3.3.3 got %v %s", isBlock, rule)
+	}
+	//网站的阻止优先于全局的挑战
+	isBlock, action, title, _ = checkThreatFeedTest(waf, "4.4.4.4")
+	if !isBlock || action != "" || title != "威胁情报:hostBlock" {
+		t.Errorf("4.4.4.4 got %v %s %s", isBlock, action, title)
+	}
+	if isBlock, _, _, rule = checkThreatFeedTest(waf, "5.5.5.5"); isBlock || rule != "" {
+		t.Errorf("5.5.5.5 should not match")
+	}
+}
@@ -0,0 +1,75 @@
+package wafenginecore
+
+import (
+	"SamWaf/common/zlog"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/detection"
+	"SamWaf/wafdefensewebsocket"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+/*
+*
+检测WebSocket握手请求
+*/
+func (waf *WafEngine) CheckWebsocket(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
+	result := detection.Result{
+		JumpGuardResult: false,
+		IsBlock:         false,
+		Title:           "",
+		Content:         "",
+	}
+	if !isWebsocketUpgrade(r) {
+		return result
+	}
+	websocketConfig := waf.getWebsocketConfig(weblogbean.HOST)
+	//浏览器发起的握手一定携带Origin，没有Origin的非浏览器客户端不做来源限制
+	origin := r.Header.Get("Origin")
+	if len(websocketConfig.AllowOrigins) > 0 && origin != "" && !wafdefensewebsocket.MatchOrigin(origin, websocketConfig.AllowOrigins) {
+		weblogbean.RISK_LEVEL = 2
+		result.IsBlock = true
+		result.Title = "WebSocket来源不允许(" + origin + ")"
+		result.Content = "请正确访问"
+		return result
+	}
+	//检测文本消息时不协商压缩，否则无法读取消息内容
+	if websocketConfig.InspectText == 1 {
+		r.Header.Del("Sec-WebSocket-Extensions")
+	}
+	return result
+}
+
+/*
+*
+获取主机的WebSocket防护配置 未配置时不限制 配置在加载主机时已解析
+*/
+func (waf *WafEngine) getWebsocketConfig(host string) model.HostsWebsocket {
+	hostSafe, ok := waf.HostTarget[host]
+	if !ok || hostSafe == nil {
+		return model.HostsWebsocket{}
+	}
+	return hostSafe.WebsocketBean
+}
+
+// parseHostWebsocket 解析主机的WebSocket防护配置
+func parseHostWebsocket(websocketJson string) model.HostsWebsocket {
+	var websocketConfig model.HostsWebsocket
+	if websocketJson != "" {
+		err := json.Unmarshal([]byte(websocketJson), &websocketConfig)
+		if err != nil {
+			zlog.Error("解析websocket json失败")
+		}
+	}
+	return websocketConfig
+}
+
+// 是否是WebSocket握手请求
+func isWebsocketUpgrade(r *http.Request) bool {
+	return httpguts.HeaderValuesContainsToken(r.Header["Connection"], "Upgrade") && strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
@@ -0,0 +1,96 @@
+package wafenginecore
+
+import (
+	"SamWaf/common/zlog"
+	"SamWaf/innerbean"
+	"SamWaf/libinjection-go"
+	"SamWaf/model"
+	"SamWaf/model/detection"
+	"SamWaf/wafdefensexml"
+	"SamWaf/wafenginecore/wafhttpcore"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+/*
+*
+检测XML/XXE及SOAP报文
+*/
+func (waf *WafEngine) CheckXml(r *http.Request, weblogbean *innerbean.WebLog, formValue url.Values) detection.Result {
+	result := detection.Result{
+		JumpGuardResult: false,
+		IsBlock:         false,
+		Title:           "",
+		Content:         "",
+	}
+	if !wafdefensexml.IsXmlRequest(r.Header.Get("Content-Type"), r.Header.Get("SOAPAction")) {
+		return result
+	}
+	isAttack, attackName, xmlValues := wafdefensexml.DetermineXML(weblogbean.BODY, waf.getXmlLimit(weblogbean.HOST))
+	if isAttack {
+		weblogbean.RISK_LEVEL = 3
+		result.IsBlock = true
+		result.Title = "XML:" + attackName
+		result.Content = "请正确访问"
+		return result
+	}
+	//提取出来的文本和属性交给sql注入和xss检测
+	hostDefense := waf.getHostDefense(weblogbean.HOST)
+	excludes := waf.getDetectExcludes(weblogbean.HOST)
+	for _, xmlValue := range xmlValues {
+		param := wafhttpcore.WafParam{Source: wafhttpcore.PARAM_SOURCE_XML, Name: xmlValue.Name, Value: xmlValue.Value}
+		if hostDefense.DEFENSE_SQLI == 1 && !isDetectExclude(excludes, "sqli", r.URL.Path, param) {
+			isSqli, fingerprint := libinjection.IsSQLi(xmlValue.Value)
+			if isSqli {
+				weblogbean.RISK_LEVEL = 2
+				weblogbean.MATCH_PARAM = param.FullName()
+				weblogbean.MATCH_FINGERPRINT = fingerprint
+				result.IsBlock = true
+				result.Title = "SQL注入(XML:" + xmlValue.Name + ")"
+				result.Content = "请正确访问"
+				return result
+			}
+		}
+		if hostDefense.DEFENSE_XSS == 1 && !isDetectExclude(excludes, "xss", r.URL.Path, param) && libinjection.IsXSS(xmlValue.Value) {
+			weblogbean.RISK_LEVEL = 2
+			weblogbean.MATCH_PARAM = param.FullName()
+			result.IsBlock = true
+			result.Title = "XSS跨站注入(XML:" + xmlValue.Name + ")"
+			result.Content = "请正确访问"
+			return result
+		}
+	}
+	return result
+}
+
+// getXmlLimit 获取主机的XML检测限制 加载主机时已解析
+func (waf *WafEngine) getXmlLimit(host string) wafdefensexml.XmlLimit {
+	xmlLimit := parseHostXmlLimit("")
+	if hostSafe, ok := waf.HostTarget[host]; ok && hostSafe != nil {
+		xmlLimit = hostSafe.XmlLimitBean
+	}
+	return wafdefensexml.XmlLimit{
+		MaxDepth:      xmlLimit.MaxDepth,
+		MaxEntity:     xmlLimit.MaxEntity,
+		MaxExpandSize: xmlLimit.MaxExpandSize,
+		MaxNodes:      xmlLimit.MaxNodes,
+	}
+}
+
+// parseHostXmlLimit 解析主机的XML检测限制 未配置的项使用默认值
+func parseHostXmlLimit(xmlLimitJson string) model.HostsXmlLimit {
+	xmlLimit := model.HostsXmlLimit{
+		MaxDepth:      64,
+		MaxEntity:     32,
+		MaxExpandSize: 64 * 1024,
+		MaxNodes:      10000,
+	}
+	if xmlLimitJson != "" {
+		err := json.Unmarshal([]byte(xmlLimitJson), &xmlLimit)
+		if err != nil {
+			zlog.Error("解析xml limit json失败")
+		}
+	}
+	return xmlLimit
+}
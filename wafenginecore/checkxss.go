@@ -19,26 +19,20 @@ func (waf *WafEngine) CheckXss(r *http.Request, weblogbean *innerbean.WebLog, fo
 		Title:           "",
 		Content:         "",
 	}
-	var xssFlag = false
-	if libinjection.IsXSS(weblogbean.URL) ||
-		libinjection.IsXSS(weblogbean.POST_FORM) {
-		xssFlag = true
-	}
-	if xssFlag == false {
-		for _, value := range formValue {
-			for _, v := range value {
-				if libinjection.IsXSS(v) {
-					//xssFlag = true
-				}
-			}
+	excludes := waf.getDetectExcludes(weblogbean.HOST)
+	//按参数检测xss
+	for _, param := range waf.getInspectParams(r, weblogbean.BODY, formValue) {
+		if isDetectExclude(excludes, "xss", r.URL.Path, param) {
+			continue
+		}
+		if libinjection.IsXSS(param.Value) {
+			weblogbean.RISK_LEVEL = 2
+			weblogbean.MATCH_PARAM = param.FullName()
+			result.IsBlock = true
+			result.Title = "XSS跨站注入(" + param.FullName() + ")"
+			result.Content = "请正确访问"
+			return result
 		}
-	}
-	if xssFlag == true {
-		weblogbean.RISK_LEVEL = 2
-		result.IsBlock = true
-		result.Title = "XSS跨站注入"
-		result.Content = "请正确访问"
-		return result
 	}
 	return result
 }
@@ -2,6 +2,7 @@ package wafenginecore
 
 import (
 	"SamWaf/common/zlog"
+	"SamWaf/enums"
 	"SamWaf/innerbean"
 	"SamWaf/model"
 	"SamWaf/wafproxy"
@@ -12,6 +13,8 @@ import (
 	"net/url"
 	"strconv"
 	"time"
+
+	"golang.org/x/net/http2"
 )
 
 func (waf *WafEngine) ProxyHTTP(w http.ResponseWriter, r *http.Request, host string, remoteUrl *url.URL, clientIp string, ctx context.Context, weblog innerbean.WebLog) {
@@ -30,6 +33,7 @@ func (waf *WafEngine) ProxyHTTP(w http.ResponseWriter, r *http.Request, host str
 				proxy.Transport = transport
 				proxy.ModifyResponse = waf.modifyResponse()
 				proxy.ErrorHandler = waf.errorResponse()
+				proxy.UpgradeHandler = waf.upgradeHandler()
 				waf.HostTarget[host].LoadBalanceRuntime.RevProxies = append(waf.HostTarget[host].LoadBalanceRuntime.RevProxies, proxy)
 
 				// 初始化策略相关信息
@@ -63,11 +67,12 @@ func (waf *WafEngine) ProxyHTTP(w http.ResponseWriter, r *http.Request, host str
 		proxy.Transport = transport
 		proxy.ModifyResponse = waf.modifyResponse()
 		proxy.ErrorHandler = waf.errorResponse()
+		proxy.UpgradeHandler = waf.upgradeHandler()
 		proxy.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
-func (waf *WafEngine) createTransport(r *http.Request, host string, isEnableLoadBalance int, loadBalance model.LoadBalance) (*http.Transport, map[string]string) {
+func (waf *WafEngine) createTransport(r *http.Request, host string, isEnableLoadBalance int, loadBalance model.LoadBalance) (http.RoundTripper, map[string]string) {
 	customHeaders := map[string]string{}
 	var transport *http.Transport
 	dialContext := func(ctx context.Context, network, addr string) (net.Conn, error) {
@@ -95,6 +100,17 @@ func (waf *WafEngine) createTransport(r *http.Request, host string, isEnableLoad
 	if r.TLS != nil {
 		// 增加https标识
 		customHeaders["X-FORWARDED-PROTO"] = "https"
+	}
+	// 明文HTTP/2(h2c) 用于gRPC等只支持HTTP/2的后端
+	if waf.HostTarget[host].Host.UPSTREAM_PROTOCOL == enums.UPSTREAM_PROTOCOL_H2C {
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return dialContext(ctx, network, addr)
+			},
+		}, customHeaders
+	}
+	if r.TLS != nil {
 		transport = &http.Transport{
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: false,
@@ -107,5 +123,9 @@ func (waf *WafEngine) createTransport(r *http.Request, host string, isEnableLoad
 			DialContext: dialContext,
 		}
 	}
+	// 基于TLS的HTTP/2
+	if waf.HostTarget[host].Host.UPSTREAM_PROTOCOL == enums.UPSTREAM_PROTOCOL_H2 {
+		transport.ForceAttemptHTTP2 = true
+	}
 	return transport, customHeaders
 }
@@ -0,0 +1,118 @@
+package wafenginecore
+
+import (
+	"SamWaf/innerbean"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ruleCounterMaxSeconds = 600    // 规则计数最多保留的秒数
+	ruleCounterMaxKeys    = 100000 // 规则计数最多保留的键数量
+)
+
+// 规则计数 key为计数键，按秒分桶
+var (
+	ruleCounters       sync.Map
+	ruleCounterKeys    int64
+	ruleCounterSweepAt int64
+)
+
+type ruleCounterBucket struct {
+	second int64
+	count  int64
+}
+
+type ruleCounterEntry struct {
+	mux     sync.Mutex
+	buckets []ruleCounterBucket
+}
+
+/*
+*
+记录一次请求 在防护检测开始前调用，避免被前面的检测拦截的请求不计数
+*/
+func countRuleRequest(r *http.Request, weblogbean *innerbean.WebLog) {
+	for _, counterKey := range innerbean.NewRuleFact(weblogbean, r).CounterKeys() {
+		addRuleCounter(counterKey)
+	}
+}
+
+// 记录一次访问 键数量达到上限时不再记录新的键
+func addRuleCounter(key string) {
+	now := time.Now().Unix()
+	value, ok := ruleCounters.Load(key)
+	if !ok {
+		if atomic.LoadInt64(&ruleCounterKeys) >= ruleCounterMaxKeys {
+			sweepRuleCounter(now, true)
+			return
+		}
+		var loaded bool
+		value, loaded = ruleCounters.LoadOrStore(key, &ruleCounterEntry{})
+		if !loaded {
+			atomic.AddInt64(&ruleCounterKeys, 1)
+		}
+	}
+	entry := value.(*ruleCounterEntry)
+	entry.mux.Lock()
+	if n := len(entry.buckets); n > 0 && entry.buckets[n-1].second == now {
+		entry.buckets[n-1].count++
+	} else {
+		entry.buckets = append(entry.buckets, ruleCounterBucket{second: now, count: 1})
+	}
+	//去掉过期的分桶
+	expired := 0
+	for expired < len(entry.buckets) && entry.buckets[expired].second <= now-ruleCounterMaxSeconds {
+		expired++
+	}
+	if expired > 0 {
+		entry.buckets = append(entry.buckets[:0], entry.buckets[expired:]...)
+	}
+	entry.mux.Unlock()
+	sweepRuleCounter(now, false)
+}
+
+// 查询最近N秒的次数
+func getRuleCounter(key string, seconds int64) int64 {
+	value, ok := ruleCounters.Load(key)
+	if !ok {
+		return 0
+	}
+	if seconds > ruleCounterMaxSeconds {
+		seconds = ruleCounterMaxSeconds
+	}
+	since := time.Now().Unix() - seconds
+	entry := value.(*ruleCounterEntry)
+	entry.mux.Lock()
+	defer entry.mux.Unlock()
+	var total int64 = 0
+	for i := len(entry.buckets) - 1; i >= 0 && entry.buckets[i].second > since; i-- {
+		total += entry.buckets[i].count
+	}
+	return total
+}
+
+// 每分钟清理一次长时间没有访问的计数 键数量达到上限时每秒最多清理一次
+func sweepRuleCounter(now int64, full bool) {
+	sweepAt := atomic.LoadInt64(&ruleCounterSweepAt)
+	interval := int64(60)
+	if full {
+		interval = 1
+	}
+	if now-sweepAt < interval || !atomic.CompareAndSwapInt64(&ruleCounterSweepAt, sweepAt, now) {
+		return
+	}
+	go ruleCounters.Range(func(key, value interface{}) bool {
+		entry := value.(*ruleCounterEntry)
+		entry.mux.Lock()
+		if n := len(entry.buckets); n == 0 || entry.buckets[n-1].second <= now-ruleCounterMaxSeconds {
+			if _, loaded := ruleCounters.LoadAndDelete(key); loaded {
+				atomic.AddInt64(&ruleCounterKeys, -1)
+			}
+		}
+		entry.mux.Unlock()
+		return true
+	})
+}
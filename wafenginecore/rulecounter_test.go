@@ -0,0 +1,50 @@
+package wafenginecore
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func resetRuleCounters() {
+	ruleCounters.Range(func(key, value interface{}) bool {
+		ruleCounters.Delete(key)
+		return true
+	})
+	atomic.StoreInt64(&ruleCounterKeys, 0)
+	atomic.StoreInt64(&ruleCounterSweepAt, time.Now().Unix())
+}
+
+func TestRuleCounter(t *testing.T) {
+	resetRuleCounters()
+	defer resetRuleCounters()
+	for i := 0; i < 3; i++ {
+		addRuleCounter("h|1.1.1.1")
+	}
+	if got := getRuleCounter("h|1.1.1.1", 60); got != 3 {
+		t.Errorf("getRuleCounter = %d, want 3", got)
+	}
+	if got := getRuleCounter("h|2.2.2.2", 60); got != 0 {
+		t.Errorf("getRuleCounter unknown key = %d, want 0", got)
+	}
+}
+
+func TestRuleCounterMaxKeys(t *testing.T) {
+	resetRuleCounters()
+	defer resetRuleCounters()
+	for i := 0; i < ruleCounterMaxKeys+10; i++ {
+		addRuleCounter("h|" + strconv.Itoa(i))
+	}
+	if got := atomic.LoadInt64(&ruleCounterKeys); got != ruleCounterMaxKeys {
+		t.Errorf("counter keys = %d, want %d", got, ruleCounterMaxKeys)
+	}
+	if got := getRuleCounter("h|"+strconv.Itoa(ruleCounterMaxKeys+1), 60); got != 0 {
+		t.Errorf("key over limit should not be counted, got %d", got)
+	}
+	//已有的键仍然计数
+	addRuleCounter("h|0")
+	if got := getRuleCounter("h|0", 60); got != 2 {
+		t.Errorf("existing key = %d, want 2", got)
+	}
+}
@@ -0,0 +1,277 @@
+package wafenginecore
+
+import (
+	"SamWaf/customtype"
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/model"
+	"SamWaf/model/baseorm"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 连续多少次写入都没有判断的计数从内存中移除
+const ruleStatsIdleFlushes = 60
+
+// 判断耗时分段上限 微秒，超过最后一段的计入溢出段，按分段计数估算99分位耗时
+var ruleStatsCostBounds = []int64{10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000, 20000, 50000, 100000}
+
+// 规则及黑白名单命中计数 key为 ruleStatsKey，请求中只做原子累加，定时写入统计库
+var (
+	ruleStats         sync.Map
+	ruleStatsFlushing int32
+)
+
+type ruleStatsKey struct {
+	ruleType string
+	ruleCode string
+}
+
+type ruleStatsCounter struct {
+	hostCode     string
+	ruleType     string
+	ruleCode     string
+	mux          sync.RWMutex //判断时加读锁，移除计数时加写锁，避免移除后还累加到旧的计数上
+	deleted      bool         //已从内存中移除
+	hitCount     int64        //未写入的命中次数
+	evalCount    int64        //未写入的判断次数
+	evalCostSum  int64        //未写入的判断耗时 微秒
+	evalCostMax  int64        //未写入的判断耗时最大值 微秒
+	evalCostHist []int64      //未写入的判断耗时分段计数
+	lastHit      atomic.Value //最后一次命中 ruleStatsLastHit
+	idleFlushes  int          //连续没有判断的写入次数 只在写入时使用
+	histDay      int          //dayCostHist 对应的日期 只在写入时使用
+	dayCostHist  []int64      //当天的判断耗时分段计数 只在写入时使用
+}
+
+type ruleStatsLastHit struct {
+	time    time.Time
+	reqUuid string
+}
+
+/*
+*
+记录一次规则判断 hit 为是否命中，cost 为判断耗时（无法单独统计耗时的传0）
+*/
+func recordRuleStats(hostCode string, ruleType string, ruleCode string, hit bool, cost time.Duration, reqUuid string) {
+	key := ruleStatsKey{ruleType: ruleType, ruleCode: ruleCode}
+	costUs := cost.Microseconds()
+	for {
+		value, ok := ruleStats.Load(key)
+		if !ok {
+			value, _ = ruleStats.LoadOrStore(key, &ruleStatsCounter{hostCode: hostCode, ruleType: ruleType, ruleCode: ruleCode,
+				evalCostHist: make([]int64, len(ruleStatsCostBounds)+1)})
+		}
+		counter := value.(*ruleStatsCounter)
+		counter.mux.RLock()
+		if counter.deleted {
+			//写入时刚好移除了该计数 重新取新的计数
+			counter.mux.RUnlock()
+			continue
+		}
+		atomic.AddInt64(&counter.evalCount, 1)
+		atomic.AddInt64(&counter.evalCostHist[ruleStatsCostBucket(costUs)], 1)
+		if costUs > 0 {
+			atomic.AddInt64(&counter.evalCostSum, costUs)
+			for {
+				costMax := atomic.LoadInt64(&counter.evalCostMax)
+				if costUs <= costMax || atomic.CompareAndSwapInt64(&counter.evalCostMax, costMax, costUs) {
+					break
+				}
+			}
+		}
+		if hit {
+			atomic.AddInt64(&counter.hitCount, 1)
+			counter.lastHit.Store(ruleStatsLastHit{time: time.Now(), reqUuid: reqUuid})
+		}
+		counter.mux.RUnlock()
+		return
+	}
+}
+
+// 耗时所在的分段
+func ruleStatsCostBucket(costUs int64) int {
+	for i, bound := range ruleStatsCostBounds {
+		if costUs <= bound {
+			return i
+		}
+	}
+	return len(ruleStatsCostBounds)
+}
+
+/*
+*
+按分段计数估算99分位耗时 取99分位所在分段的上限，不超过最大耗时；落在溢出段时为最大耗时
+*/
+func ruleStatsCostP99(hist []int64, costMax int64) int64 {
+	var total int64
+	for _, count := range hist {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+	target := (total*99 + 99) / 100
+	var cumulative int64
+	for i, count := range hist {
+		cumulative += count
+		if cumulative < target {
+			continue
+		}
+		if i < len(ruleStatsCostBounds) && ruleStatsCostBounds[i] < costMax {
+			return ruleStatsCostBounds[i]
+		}
+		return costMax
+	}
+	return costMax
+}
+
+// 解析库里保存的分段计数 分段数变化时丢弃
+func parseRuleStatsCostHist(histText string) []int64 {
+	hist := make([]int64, len(ruleStatsCostBounds)+1)
+	items := strings.Split(histText, ",")
+	if len(items) != len(hist) {
+		return hist
+	}
+	for i, item := range items {
+		hist[i], _ = strconv.ParseInt(item, 10, 64)
+	}
+	return hist
+}
+
+func formatRuleStatsCostHist(hist []int64) string {
+	items := make([]string, len(hist))
+	for i, count := range hist {
+		items[i] = strconv.FormatInt(count, 10)
+	}
+	return strings.Join(items, ",")
+}
+
+/*
+*
+记录一次黑白名单查找 命中时计入命中的名单项，未命中时计入主机下该类名单的整体判断（ruleCode为主机编码）
+名单为空时没有实际查找不记录
+*/
+func recordListStats(hostCode string, ruleType string, listLen int, entryCode string, cost time.Duration, reqUuid string) {
+	if listLen == 0 {
+		return
+	}
+	if entryCode == "" {
+		recordRuleStats(hostCode, ruleType, hostCode, false, cost, reqUuid)
+		return
+	}
+	recordRuleStats(hostCode, ruleType, entryCode, true, cost, reqUuid)
+}
+
+/*
+*
+名单命中时记录到日志的规则名 带上名单项Id便于从日志追溯到名单
+*/
+func listHitRuleName(title string, entryId string, remarks string) string {
+	ruleName := title + "(" + entryId + ")"
+	if remarks != "" {
+		ruleName = ruleName + ":" + remarks
+	}
+	return ruleName
+}
+
+/*
+*
+把内存中的命中计数写入统计库 计入写入时的日期，长时间没有判断的计数从内存中移除
+*/
+func FlushRuleStats() {
+	if global.GWAF_LOCAL_STATS_DB == nil || !atomic.CompareAndSwapInt32(&ruleStatsFlushing, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&ruleStatsFlushing, 0)
+	now := time.Now()
+	day, _ := strconv.Atoi(now.Format("20060102"))
+	ruleStats.Range(func(key, value interface{}) bool {
+		counter := value.(*ruleStatsCounter)
+		evalCount := atomic.SwapInt64(&counter.evalCount, 0)
+		if evalCount == 0 {
+			counter.idleFlushes++
+			if counter.idleFlushes >= ruleStatsIdleFlushes {
+				counter.mux.Lock()
+				//加锁后再确认一次 期间有判断的留到下次写入
+				if atomic.LoadInt64(&counter.evalCount) == 0 {
+					counter.deleted = true
+					ruleStats.Delete(key)
+				}
+				counter.mux.Unlock()
+			}
+			return true
+		}
+		counter.idleFlushes = 0
+		hitCount := atomic.SwapInt64(&counter.hitCount, 0)
+		evalCostSum := atomic.SwapInt64(&counter.evalCostSum, 0)
+		evalCostMax := atomic.SwapInt64(&counter.evalCostMax, 0)
+		lastHit, _ := counter.lastHit.Load().(ruleStatsLastHit)
+
+		var statBean model.StatsRuleHit
+		global.GWAF_LOCAL_STATS_DB.Where("rule_type = ? and rule_code = ? and day = ?",
+			counter.ruleType, counter.ruleCode, day).Limit(1).Find(&statBean)
+		//当天的分段计数保留在内存中累加，换天或重启后从库里接上
+		if counter.histDay != day {
+			counter.histDay = day
+			counter.dayCostHist = parseRuleStatsCostHist(statBean.EvalCostHist)
+		}
+		for i := range counter.evalCostHist {
+			counter.dayCostHist[i] += atomic.SwapInt64(&counter.evalCostHist[i], 0)
+		}
+		evalCostHist := formatRuleStatsCostHist(counter.dayCostHist)
+		evalCostP99 := ruleStatsCostP99(counter.dayCostHist, max(statBean.EvalCostMax, evalCostMax))
+		if statBean.Id == "" {
+			statBean = model.StatsRuleHit{
+				BaseOrm: baseorm.BaseOrm{
+					Id:          uuid.NewV4().String(),
+					USER_CODE:   global.GWAF_USER_CODE,
+					Tenant_ID:   global.GWAF_TENANT_ID,
+					CREATE_TIME: customtype.JsonTime(now),
+					UPDATE_TIME: customtype.JsonTime(now),
+				},
+				HostCode:     counter.hostCode,
+				RuleType:     counter.ruleType,
+				RuleCode:     counter.ruleCode,
+				Day:          day,
+				HitCount:     hitCount,
+				EvalCount:    evalCount,
+				EvalCostSum:  evalCostSum,
+				EvalCostMax:  evalCostMax,
+				EvalCostP99:  evalCostP99,
+				EvalCostHist: evalCostHist,
+			}
+			if hitCount > 0 {
+				statBean.LastHitTime = customtype.JsonTime(lastHit.time)
+				statBean.LastReqUuid = lastHit.reqUuid
+			}
+			global.GWAF_LOCAL_STATS_DB.Create(&statBean)
+			return true
+		}
+		statMap := map[string]interface{}{
+			"HitCount":     gorm.Expr("hit_count + ?", hitCount),
+			"EvalCount":    gorm.Expr("eval_count + ?", evalCount),
+			"EvalCostSum":  gorm.Expr("eval_cost_sum + ?", evalCostSum),
+			"EvalCostMax":  gorm.Expr("max(eval_cost_max, ?)", evalCostMax),
+			"EvalCostP99":  evalCostP99,
+			"EvalCostHist": evalCostHist,
+			"UPDATE_TIME":  customtype.JsonTime(now),
+		}
+		if hitCount > 0 {
+			statMap["LastHitTime"] = customtype.JsonTime(lastHit.time)
+			statMap["LastReqUuid"] = lastHit.reqUuid
+		}
+		global.GQEQUE_STATS_UPDATE_DB.Enqueue(innerbean.UpdateModel{
+			Model:  model.StatsRuleHit{},
+			Query:  "id = ?",
+			Args:   []interface{}{statBean.Id},
+			Update: statMap,
+		})
+		return true
+	})
+}
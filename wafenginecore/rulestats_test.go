@@ -0,0 +1,83 @@
+package wafenginecore
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecordRuleStats(t *testing.T) {
+	key := ruleStatsKey{ruleType: "test", ruleCode: "code1"}
+	defer ruleStats.Delete(key)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			recordRuleStats("h1", "test", "code1", i%2 == 0, time.Duration(i)*time.Millisecond, "uuid")
+		}(i)
+	}
+	wg.Wait()
+	value, ok := ruleStats.Load(key)
+	if !ok {
+		t.Fatal("counter not created")
+	}
+	counter := value.(*ruleStatsCounter)
+	if counter.evalCount != 10 || counter.hitCount != 5 {
+		t.Errorf("eval %d hit %d, want 10 5", counter.evalCount, counter.hitCount)
+	}
+	if counter.evalCostMax != 9000 || counter.evalCostSum != 45000 {
+		t.Errorf("cost max %d sum %d, want 9000 45000", counter.evalCostMax, counter.evalCostSum)
+	}
+	if counter.evalCostHist[0] != 1 || counter.evalCostHist[ruleStatsCostBucket(9000)] != 4 {
+		t.Errorf("cost hist %v", counter.evalCostHist)
+	}
+	if lastHit, _ := counter.lastHit.Load().(ruleStatsLastHit); lastHit.reqUuid != "uuid" {
+		t.Errorf("last hit not recorded")
+	}
+}
+
+func TestRuleStatsCostP99(t *testing.T) {
+	hist := make([]int64, len(ruleStatsCostBounds)+1)
+	for i := 0; i < 990; i++ {
+		hist[ruleStatsCostBucket(15)]++
+	}
+	for i := 0; i < 10; i++ {
+		hist[ruleStatsCostBucket(80000)]++
+	}
+	if p99 := ruleStatsCostP99(hist, 80000); p99 != 20 {
+		t.Errorf("p99 %d, want 20", p99)
+	}
+	hist[ruleStatsCostBucket(80000)] += 100
+	if p99 := ruleStatsCostP99(hist, 80000); p99 != 80000 {
+		t.Errorf("p99 %d, want 80000", p99)
+	}
+	hist[len(ruleStatsCostBounds)] += 1000
+	if p99 := ruleStatsCostP99(hist, 300000); p99 != 300000 {
+		t.Errorf("overflow p99 %d, want 300000", p99)
+	}
+	if parsed := parseRuleStatsCostHist(formatRuleStatsCostHist(hist)); formatRuleStatsCostHist(parsed) != formatRuleStatsCostHist(hist) {
+		t.Errorf("hist round trip got %v", parsed)
+	}
+}
+
+func TestRecordRuleStatsDeletedCounter(t *testing.T) {
+	key := ruleStatsKey{ruleType: "test", ruleCode: "code2"}
+	defer ruleStats.Delete(key)
+	recordRuleStats("h1", "test", "code2", false, 0, "uuid")
+	value, _ := ruleStats.Load(key)
+	counter := value.(*ruleStatsCounter)
+	//模拟写入时移除计数 移除后的判断要计入新的计数
+	counter.mux.Lock()
+	counter.deleted = true
+	ruleStats.Delete(key)
+	counter.mux.Unlock()
+	recordRuleStats("h1", "test", "code2", true, 0, "uuid")
+	if counter.evalCount != 1 {
+		t.Errorf("deleted counter eval %d, want 1", counter.evalCount)
+	}
+	value, ok := ruleStats.Load(key)
+	if !ok || value.(*ruleStatsCounter).evalCount != 1 || value.(*ruleStatsCounter).hitCount != 1 {
+		t.Errorf("new counter not recorded")
+	}
+}
@@ -0,0 +1,80 @@
+package wafenginecore
+
+import (
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 连接的TLS指纹 key为客户端地址
+var tlsFingerprints sync.Map
+
+/*
+*
+记录客户端ClientHello的指纹 返回nil表示使用默认配置
+*/
+func (waf *WafEngine) recordClientHello(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	if hello.Conn != nil {
+		tlsFingerprints.Store(hello.Conn.RemoteAddr().String(), tlsFingerprint(hello))
+	}
+	return nil, nil
+}
+
+// 连接关闭后移除指纹
+func tlsConnState(conn net.Conn, state http.ConnState) {
+	if state == http.StateClosed || state == http.StateHijacked {
+		tlsFingerprints.Delete(conn.RemoteAddr().String())
+	}
+}
+
+// 获取请求所在连接的TLS指纹
+func getTlsFingerprint(r *http.Request) string {
+	if r.TLS == nil {
+		return ""
+	}
+	if fingerprint, ok := tlsFingerprints.Load(r.RemoteAddr); ok {
+		return fingerprint.(string)
+	}
+	return ""
+}
+
+/*
+*
+计算TLS指纹 参照JA3的格式：版本,加密套件,椭圆曲线,曲线格式 的md5
+标准库拿不到扩展列表，所以不包含扩展部分
+*/
+func tlsFingerprint(hello *tls.ClientHelloInfo) string {
+	var version uint16 = 0
+	for _, v := range hello.SupportedVersions {
+		if !isGreaseValue(v) && v > version {
+			version = v
+		}
+	}
+	var ciphers, curves, points []string
+	for _, cipher := range hello.CipherSuites {
+		if !isGreaseValue(cipher) {
+			ciphers = append(ciphers, strconv.Itoa(int(cipher)))
+		}
+	}
+	for _, curve := range hello.SupportedCurves {
+		if !isGreaseValue(uint16(curve)) {
+			curves = append(curves, strconv.Itoa(int(curve)))
+		}
+	}
+	for _, point := range hello.SupportedPoints {
+		points = append(points, strconv.Itoa(int(point)))
+	}
+	raw := strconv.Itoa(int(version)) + "," + strings.Join(ciphers, "-") + "," + strings.Join(curves, "-") + "," + strings.Join(points, "-")
+	sum := md5.Sum([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// GREASE保留值（RFC 8701）每次握手随机出现，不计入指纹
+func isGreaseValue(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
@@ -14,6 +14,7 @@ import (
 	"SamWaf/utils"
 	"SamWaf/wafenginecore/loadbalance"
 	"SamWaf/wafenginecore/wafhttpcore"
+	"SamWaf/wafowasp"
 	"SamWaf/wafproxy"
 	"bufio"
 	"bytes"
@@ -24,10 +25,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	goahocorasick "github.com/anknown/ahocorasick"
 	"github.com/satori/go.uuid"
 	"go.uber.org/zap"
 	"golang.org/x/net/html/charset"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/unicode"
 	"golang.org/x/text/transform"
@@ -58,8 +60,9 @@ type WafEngine struct {
 	EngineCurrentStatus int            // 当前waf引擎状态
 
 	//敏感词管理
-	Sensitive        []model.Sensitive //敏感词
-	SensitiveManager *goahocorasick.Machine
+	Sensitive                []model.Sensitive                          //敏感词
+	SensitiveRequestManager  map[string]*wafenginmodel.SensitiveMatcher //请求敏感词匹配器（key:主机code 全局为空）
+	SensitiveResponseManager map[string]*wafenginmodel.SensitiveMatcher //响应敏感词匹配器（key:主机code 全局为空）
 }
 
 func (waf *WafEngine) Error() string {
@@ -146,8 +149,8 @@ func (waf *WafEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		contentLength := r.ContentLength
 		var bodyByte []byte
 
-		// 拷贝一份request的Body ,控制不记录大文件的情况 ，先写死的
-		if r.Body != nil && r.Body != http.NoBody && contentLength < (global.GCONFIG_RECORD_MAX_BODY_LENGTH) {
+		// 拷贝一份request的Body ,控制不记录大文件的情况 ，先写死的 gRPC请求体为流式的protobuf不读取
+		if r.Body != nil && r.Body != http.NoBody && contentLength < (global.GCONFIG_RECORD_MAX_BODY_LENGTH) && !isGrpcRequest(r) {
 			bodyByte, _ = io.ReadAll(r.Body)
 			// 把刚刚读出来的再写进去，不然后面解析表单数据就解析不到了
 			r.Body = io.NopCloser(bytes.NewBuffer(bodyByte))
@@ -161,6 +164,7 @@ func (waf *WafEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 
 		region := utils.GetCountry(clientIP)
+		asn, asnOrg := utils.GetASN(clientIP)
 
 		// 检测是否已经被CC封禁
 		ccCacheKey := enums.CACHE_CCVISITBAN_PRE + clientIP
@@ -189,6 +193,8 @@ func (waf *WafEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			COUNTRY:              region[0],
 			PROVINCE:             region[2],
 			CITY:                 region[3],
+			ASN:                  asn,
+			ASN_ORG:              asnOrg,
 			SRC_IP:               clientIP,
 			SRC_PORT:             clientPort,
 			CREATE_TIME:          datetimeNow.Format("2006-01-02 15:04:05"),
@@ -209,6 +215,10 @@ func (waf *WafEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			GUEST_IDENTIFICATION: "正常访客", //访客身份识别
 			TimeSpent:            0,
 		}
+		if isGrpcRequest(r) {
+			weblogbean.GRPC_SERVICE, weblogbean.GRPC_METHOD = parseGrpcMethod(r.URL.Path)
+		}
+		weblogbean.TLS_FINGERPRINT = getTlsFingerprint(r)
 
 		formValues := url.Values{}
 		if strings.Contains(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
@@ -237,42 +247,96 @@ func (waf *WafEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		r.Header.Add("waf_req_uuid", weblogbean.REQ_UUID)
 
+		var loginAttempt *LoginAttempt
+		websocketGuard := false
 		if waf.HostTarget[host].Host.GUARD_STATUS == 1 {
 			//一系列检测逻辑
-			handleBlock := func(checkFunc func(*http.Request, *innerbean.WebLog, url.Values) detection.Result) bool {
-				detectionResult := checkFunc(r, &weblogbean, formValues)
-				if detectionResult.IsBlock {
+			handleResult := func(detectionResult detection.Result) bool {
+				if !detectionResult.IsBlock {
+					return false
+				}
+				switch detectionResult.Action {
+				case innerbean.RULE_ACTION_CHALLENGE:
+					if isChallengePassed(r, clientIP) {
+						return false
+					}
+					decrementMonitor(waf.HostTarget[host].Host.Code)
+					EchoChallengeInfo(w, r, weblogbean, detectionResult.Title)
+				case innerbean.RULE_ACTION_REDIRECT:
 					decrementMonitor(waf.HostTarget[host].Host.Code)
-					EchoErrorInfo(w, r, weblogbean, detectionResult.Title, detectionResult.Content)
-					return true
+					EchoRedirectInfo(w, r, weblogbean, detectionResult.Title, detectionResult.RedirectUrl)
+				default:
+					decrementMonitor(waf.HostTarget[host].Host.Code)
+					EchoErrorInfoWithStatus(w, r, weblogbean, detectionResult.Title, detectionResult.Content, detectionResult.StatusCode)
 				}
-				return false
+				return true
+			}
+			handleBlock := func(checkFunc func(*http.Request, *innerbean.WebLog, url.Values) detection.Result) bool {
+				return handleResult(checkFunc(r, &weblogbean, formValues))
 			}
+			//各个检测器共用一次提取的具名输入
+			r = withInspectParamsCache(r)
+			//规则计数 在所有检测之前记录
+			countRuleRequest(r, &weblogbean)
 			detectionWhiteResult := waf.CheckAllowIP(r, &weblogbean, formValues)
 			if detectionWhiteResult.JumpGuardResult == false {
-				detectionWhiteResult = waf.CheckAllowURL(r, weblogbean, formValues)
+				detectionWhiteResult = waf.CheckAllowURL(r, &weblogbean, formValues)
 			}
 			if detectionWhiteResult.JumpGuardResult == false {
-
+				//扫描封禁
+				if handleBlock(waf.CheckScanBan) {
+					return
+				}
 				if handleBlock(waf.CheckDenyIP) {
 					return
 				}
+				//威胁情报
+				if handleBlock(waf.CheckThreatFeed) {
+					return
+				}
 				if handleBlock(waf.CheckDenyURL) {
 					return
 				}
+				//地域限制
+				if handleBlock(waf.CheckGeo) {
+					return
+				}
+				//登录防护
+				loginAttempt = waf.matchLoginAttempt(r, host, &weblogbean, formValues)
+				if loginAttempt != nil {
+					isLock, lockName := waf.checkLoginLock(loginAttempt)
+					if isLock {
+						weblogbean.RISK_LEVEL = 2
+						switch loginAttempt.Protect.Action {
+						case enums.LOGIN_PROTECT_ACTION_THROTTLE:
+							decrementMonitor(waf.HostTarget[host].Host.Code)
+							EchoThrottleInfo(w, r, weblogbean, lockName, "登录尝试过于频繁，请稍后再试", loginWindow(loginAttempt.Protect))
+							return
+						case enums.LOGIN_PROTECT_ACTION_CHALLENGE:
+							if !isChallengePassed(r, clientIP) {
+								decrementMonitor(waf.HostTarget[host].Host.Code)
+								EchoChallengeInfo(w, r, weblogbean, lockName)
+								return
+							}
+						default:
+							decrementMonitor(waf.HostTarget[host].Host.Code)
+							EchoErrorInfo(w, r, weblogbean, lockName, "登录失败次数过多，请稍后再试")
+							return
+						}
+					}
+				}
 
-				hostDefense := model.HostsDefense{
-					DEFENSE_BOT:       1,
-					DEFENSE_SQLI:      1,
-					DEFENSE_XSS:       1,
-					DEFENSE_SCAN:      1,
-					DEFENSE_RCE:       1,
-					DEFENSE_SENSITIVE: 1,
+				//WebSocket握手检测
+				if handleBlock(waf.CheckWebsocket) {
+					return
 				}
-				err := json.Unmarshal([]byte(waf.HostTarget[host].Host.DEFENSE_JSON), &hostDefense)
-				if err != nil {
-					zlog.Error("解析defense json失败")
+				websocketGuard = true
+				//gRPC方法访问控制
+				if handleBlock(waf.CheckGrpc) {
+					return
 				}
+
+				hostDefense := waf.getHostDefense(host)
 				//检测爬虫bot
 				if hostDefense.DEFENSE_BOT == 1 {
 					if handleBlock(waf.CheckBot) {
@@ -303,24 +367,58 @@ func (waf *WafEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 						return
 					}
 				}
+				//检测目录穿越
+				if hostDefense.DEFENSE_TRAVERSAL == 1 {
+					if handleBlock(waf.CheckTraversal) {
+						return
+					}
+				}
+				//检测XML
+				if hostDefense.DEFENSE_XML == 1 {
+					if handleBlock(waf.CheckXml) {
+						return
+					}
+				}
+				//检测GraphQL
+				if hostDefense.DEFENSE_GRAPHQL == 1 {
+					if handleBlock(waf.CheckGraphql) {
+						return
+					}
+				}
+				//检测请求头和cookie注入
+				if hostDefense.DEFENSE_HEADER == 1 {
+					if handleBlock(waf.CheckHeaderInject) {
+						return
+					}
+				}
+				//检测SSRF
+				if hostDefense.DEFENSE_SSRF == 1 {
+					if handleBlock(waf.CheckSsrf) {
+						return
+					}
+				}
 				//检测CC
 				if handleBlock(waf.CheckCC) {
 					return
 				}
-				//规则判断
-				if handleBlock(waf.CheckRule) {
+				//规则判断 规则放行时跳过后续的敏感词、OWASP检测
+				ruleResult := waf.CheckRule(r, &weblogbean, formValues)
+				if handleResult(ruleResult) {
 					return
 				}
-				//检测敏感词
-				if hostDefense.DEFENSE_SENSITIVE == 1 {
-					if handleBlock(waf.CheckSensitive) {
+				if ruleResult.JumpGuardResult == false {
+					//检测敏感词
+					if hostDefense.DEFENSE_SENSITIVE == 1 {
+						if handleBlock(waf.CheckSensitive) {
+							return
+						}
+					}
+					//检测OWASP 留给响应阶段的事务在请求结束时（含异常、提前返回）一定会结束
+					defer finishPendingOwaspTx(weblogbean.REQ_UUID)
+					if handleBlock(waf.CheckOwasp) {
 						return
 					}
 				}
-				//检测OWASP
-				if handleBlock(waf.CheckOwasp) {
-					return
-				}
 
 			}
 
@@ -336,6 +434,12 @@ func (waf *WafEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		// 在请求上下文中存储自定义数据
 		ctx := context.WithValue(r.Context(), "weblog", weblogbean)
+		if loginAttempt != nil {
+			ctx = context.WithValue(ctx, "login_attempt", loginAttempt)
+		}
+		if websocketGuard && isWebsocketUpgrade(r) {
+			ctx = context.WithValue(ctx, "websocket_guard", true)
+		}
 		// 代理请求
 		waf.ProxyHTTP(w, r, host, remoteUrl, clientIP, ctx, weblogbean)
 		decrementMonitor(waf.HostTarget[host].Host.Code)
@@ -368,6 +472,7 @@ func (waf *WafEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		region := utils.GetCountry(clientIP)
+		asn, asnOrg := utils.GetASN(clientIP)
 		datetimeNow := time.Now()
 
 		currentDay, _ := strconv.Atoi(datetimeNow.Format("20060102"))
@@ -381,6 +486,8 @@ func (waf *WafEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			COUNTRY:              region[0],
 			PROVINCE:             region[2],
 			CITY:                 region[3],
+			ASN:                  asn,
+			ASN_ORG:              asnOrg,
 			SRC_IP:               clientIP,
 			SRC_PORT:             clientPort,
 			CREATE_TIME:          datetimeNow.Format("2006-01-02 15:04:05"),
@@ -409,6 +516,36 @@ func (waf *WafEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		global.GQEQUE_LOG_DB.Enqueue(weblogbean)
 	}
 }
+
+// getHostDefense 获取主机的防御开关配置 加载主机时已解析
+func (waf *WafEngine) getHostDefense(host string) model.HostsDefense {
+	hostSafe, ok := waf.HostTarget[host]
+	if !ok || hostSafe == nil {
+		return model.HostsDefense{}
+	}
+	return hostSafe.DefenseBean
+}
+
+/*
+*
+解析主机的防御开关配置
+原有的防御项未配置时默认开启，后续新增的防御项未配置时默认关闭，避免升级后老主机被新的拦截逻辑影响
+*/
+func parseHostDefense(defenseJson string) model.HostsDefense {
+	hostDefense := model.HostsDefense{
+		DEFENSE_BOT:       1,
+		DEFENSE_SQLI:      1,
+		DEFENSE_XSS:       1,
+		DEFENSE_SCAN:      1,
+		DEFENSE_RCE:       1,
+		DEFENSE_SENSITIVE: 1,
+	}
+	err := json.Unmarshal([]byte(defenseJson), &hostDefense)
+	if err != nil {
+		zlog.Error("解析defense json失败")
+	}
+	return hostDefense
+}
 func (waf *WafEngine) getClientIP(r *http.Request, headers ...string) (error, string, string) {
 	for _, header := range headers {
 		ip := r.Header.Get(header)
@@ -454,6 +591,14 @@ func (waf *WafEngine) getClientIP(r *http.Request, headers ...string) (error, st
 
 // EchoErrorInfo  ruleName 对内记录  blockInfo 对外展示
 func EchoErrorInfo(w http.ResponseWriter, r *http.Request, weblogbean innerbean.WebLog, ruleName string, blockInfo string) {
+	EchoErrorInfoWithStatus(w, r, weblogbean, ruleName, blockInfo, http.StatusForbidden)
+}
+
+// EchoErrorInfoWithStatus 按指定状态码返回阻止信息 statusCode 不正确时返回403
+func EchoErrorInfoWithStatus(w http.ResponseWriter, r *http.Request, weblogbean innerbean.WebLog, ruleName string, blockInfo string, statusCode int) {
+	if statusCode < 400 || statusCode > 599 {
+		statusCode = http.StatusForbidden
+	}
 
 	go func() {
 		//发送推送消息
@@ -466,11 +611,17 @@ func EchoErrorInfo(w http.ResponseWriter, r *http.Request, weblogbean innerbean.
 	}()
 
 	resBytes := []byte("<html><head><title>您的访问被阻止</title></head><body><center><h1>" + blockInfo + "</h1> <br> 访问识别码：<h3>" + weblogbean.REQ_UUID + "</h3></center></body> </html>")
-	w.WriteHeader(403)
-	_, err := w.Write(resBytes)
-	if err != nil {
-		zlog.Debug("write fail:", zap.Any("", err))
-		return
+	if isGrpcRequest(r) {
+		resBytes = []byte(blockInfo + " 访问识别码:" + weblogbean.REQ_UUID)
+		echoGrpcError(w, grpcStatusPermissionDenied, string(resBytes))
+		weblogbean.GRPC_STATUS = strconv.Itoa(grpcStatusPermissionDenied)
+	} else {
+		w.WriteHeader(statusCode)
+		_, err := w.Write(resBytes)
+		if err != nil {
+			zlog.Debug("write fail:", zap.Any("", err))
+			return
+		}
 	}
 	datetimeNow := time.Now()
 	weblogbean.TimeSpent = datetimeNow.UnixNano()/1e6 - weblogbean.UNIX_ADD_TIME
@@ -479,7 +630,7 @@ func EchoErrorInfo(w http.ResponseWriter, r *http.Request, weblogbean innerbean.
 	weblogbean.RULE = ruleName
 	weblogbean.ACTION = "阻止"
 	weblogbean.STATUS = "阻止访问"
-	weblogbean.STATUS_CODE = 403
+	weblogbean.STATUS_CODE = statusCode
 	weblogbean.TASK_FLAG = 1
 	weblogbean.GUEST_IDENTIFICATION = "可疑用户"
 	global.GQEQUE_LOG_DB.Enqueue(weblogbean)
@@ -502,6 +653,11 @@ func (waf *WafEngine) errorResponse() func(http.ResponseWriter, *http.Request, e
 		requestInfo := fmt.Sprintf("Method: %s, URL: %s, Headers: %v", req.Method, req.URL.String(), req.Header)
 		zlog.Error("服务不可用 response:", zap.Any("err", err.Error()), zap.String("request_info", requestInfo))
 
+		if isGrpcRequest(req) {
+			echoGrpcError(w, grpcStatusUnavailable, "服务不可用")
+			return
+		}
+
 		resBytes := []byte("<html><head><title>服务不可用</title></head><body><center><h1>服务不可用</h1> <br><h3></h3></center></body> </html>")
 
 		w.WriteHeader(http.StatusServiceUnavailable)
@@ -557,30 +713,52 @@ func (waf *WafEngine) modifyResponse() func(*http.Response) error {
 				zlog.Error("主机未匹配到", host)
 				return nil
 			}
-			ldpFlag := false
-			//隐私保护（局部）
-			for i := 0; i < len(waf.HostTarget[host].LdpUrlLists); i++ {
-				if (waf.HostTarget[host].LdpUrlLists[i].CompareType == "等于" && waf.HostTarget[host].LdpUrlLists[i].Url == resp.Request.RequestURI) ||
-					(waf.HostTarget[host].LdpUrlLists[i].CompareType == "前缀匹配" && strings.HasPrefix(resp.Request.RequestURI, waf.HostTarget[host].LdpUrlLists[i].Url)) ||
-					(waf.HostTarget[host].LdpUrlLists[i].CompareType == "后缀匹配" && strings.HasSuffix(resp.Request.RequestURI, waf.HostTarget[host].LdpUrlLists[i].Url)) ||
-					(waf.HostTarget[host].LdpUrlLists[i].CompareType == "包含匹配" && strings.Contains(resp.Request.RequestURI, waf.HostTarget[host].LdpUrlLists[i].Url)) {
-
-					ldpFlag = true
-					break
+			//协议升级的连接在关闭时记录日志
+			if resp.StatusCode == http.StatusSwitchingProtocols {
+				return nil
+			}
+			//gRPC响应为流，不处理响应内容
+			if isGrpcRequest(r) {
+				waf.recordGrpcResponse(resp, weblogfrist)
+				return nil
+			}
+			//扫描行为统计
+			if waf.HostTarget[host].Host.GUARD_STATUS == 1 && waf.getHostDefense(host).DEFENSE_SCAN == 1 {
+				waf.recordScanStatus(r, weblogfrist, resp.StatusCode)
+			}
+			//登录防护失败统计
+			if loginAttempt, ok := r.Context().Value("login_attempt").(*LoginAttempt); ok {
+				respBody := ""
+				if loginAttempt.Protect.FailBodyPattern != "" && resp.Body != nil && resp.Body != http.NoBody {
+					orgContentBytes, _ := waf.getOrgContent(resp)
+					finalCompressBytes, _ := waf.compressContent(resp, orgContentBytes)
+					resp.Body = io.NopCloser(bytes.NewBuffer(finalCompressBytes))
+					resp.ContentLength = int64(len(finalCompressBytes))
+					resp.Header.Set("Content-Length", strconv.FormatInt(int64(len(finalCompressBytes)), 10))
+					respBody = string(orgContentBytes)
 				}
+				waf.recordLoginResult(loginAttempt, resp, respBody)
 			}
+			ldpFlag := false
+			ldpRuleName := ""
 			//隐私保护（局部）
-			for i := 0; i < len(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].LdpUrlLists); i++ {
-				if (waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].LdpUrlLists[i].CompareType == "等于" && waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].LdpUrlLists[i].Url == resp.Request.RequestURI) ||
-					(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].LdpUrlLists[i].CompareType == "前缀匹配" && strings.HasPrefix(resp.Request.RequestURI, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].LdpUrlLists[i].Url)) ||
-					(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].LdpUrlLists[i].CompareType == "后缀匹配" && strings.HasSuffix(resp.Request.RequestURI, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].LdpUrlLists[i].Url)) ||
-					(waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].LdpUrlLists[i].CompareType == "包含匹配" && strings.Contains(resp.Request.RequestURI, waf.HostTarget[global.GWAF_GLOBAL_HOST_NAME].LdpUrlLists[i].Url)) {
-
+			if entry, ok := waf.matchLdpUrl(host, resp.Request, &weblogfrist); ok {
+				ldpFlag = true
+				ldpRuleName = listHitRuleName("隐私保护", entry.Id, entry.Remarks)
+			}
+			//隐私保护（全局）
+			if !ldpFlag {
+				if entry, ok := waf.matchLdpUrl(global.GWAF_GLOBAL_HOST_NAME, resp.Request, &weblogfrist); ok {
 					ldpFlag = true
-					break
+					ldpRuleName = listHitRuleName("【全局】隐私保护", entry.Id, entry.Remarks)
 				}
 			}
 			if ldpFlag == true {
+				//记录命中的隐私保护名单项 保留请求阶段已记录的规则
+				if weblogfrist.RULE != "" {
+					weblogfrist.RULE = weblogfrist.RULE + ","
+				}
+				weblogfrist.RULE = weblogfrist.RULE + ldpRuleName
 				orgContentBytes, _ := waf.getOrgContent(resp)
 				newPayload := []byte("" + utils.DeSenText(string(orgContentBytes)))
 				finalCompressBytes, _ := waf.compressContent(resp, newPayload)
@@ -633,6 +811,58 @@ func (waf *WafEngine) modifyResponse() func(*http.Response) error {
 				global.GQEQUE_LOG_DB.PushBack(weblogbean)*/
 			}
 
+			//响应敏感词检测
+			isRespBlock := false
+			if isSensitiveContentType(resp.Header.Get("Content-Type")) && resp.Body != nil && resp.Body != http.NoBody && len(waf.SensitiveResponseManager) > 0 &&
+				waf.HostTarget[host].Host.GUARD_STATUS == 1 && waf.getHostDefense(host).DEFENSE_SENSITIVE == 1 {
+				orgContentBytes, _ := waf.getOrgContent(resp)
+				newContent, isBlock, ruleName := waf.CheckResponseSensitive(string(orgContentBytes), &weblogfrist)
+				var newPayload []byte
+				if isBlock {
+					isRespBlock = true
+					newPayload = []byte("<html><head><title>您的访问被阻止</title></head><body><center><h1>敏感词内容</h1> <br> 访问识别码：<h3>" + weblogfrist.REQ_UUID + "</h3></center></body> </html>")
+					resp.StatusCode = 403
+					resp.Status = "403 Forbidden"
+					weblogfrist.RULE = ruleName
+					weblogfrist.GUEST_IDENTIFICATION = "可疑用户"
+				} else {
+					newPayload = []byte(newContent)
+				}
+				finalCompressBytes, _ := waf.compressContent(resp, newPayload)
+				resp.Body = io.NopCloser(bytes.NewBuffer(finalCompressBytes))
+				resp.ContentLength = int64(len(finalCompressBytes))
+				resp.Header.Set("Content-Length", strconv.FormatInt(int64(len(finalCompressBytes)), 10))
+			}
+
+			//OWASP响应检测（SQL错误、堆栈、目录列表等泄露）
+			if owaspTx, ok := takeOwaspTx(weblogfrist.REQ_UUID); ok {
+				if isRespBlock {
+					wafowasp.FinishTransaction(owaspTx, global.GCONFIG_RECORD_OWASP_AUDIT_LOG == 1)
+				} else {
+					var owaspRespBody []byte
+					if (isText || strings.HasPrefix(respContentType, "text/")) && resp.Body != nil && resp.Body != http.NoBody {
+						owaspRespBody, _ = waf.getOrgContent(resp)
+						finalCompressBytes, _ := waf.compressContent(resp, owaspRespBody)
+						resp.Body = io.NopCloser(bytes.NewBuffer(finalCompressBytes))
+						resp.ContentLength = int64(len(finalCompressBytes))
+						resp.Header.Set("Content-Length", strconv.FormatInt(int64(len(finalCompressBytes)), 10))
+					}
+					isBlock, ruleName := waf.checkOwaspResponse(owaspTx, resp, owaspRespBody, &weblogfrist)
+					if isBlock {
+						isRespBlock = true
+						newPayload := []byte("<html><head><title>您的访问被阻止</title></head><body><center><h1>访问不合法</h1> <br> 访问识别码：<h3>" + weblogfrist.REQ_UUID + "</h3></center></body> </html>")
+						resp.StatusCode = 403
+						resp.Status = "403 Forbidden"
+						weblogfrist.RULE = ruleName
+						weblogfrist.GUEST_IDENTIFICATION = "可疑用户"
+						finalCompressBytes, _ := waf.compressContent(resp, newPayload)
+						resp.Body = io.NopCloser(bytes.NewBuffer(finalCompressBytes))
+						resp.ContentLength = int64(len(finalCompressBytes))
+						resp.Header.Set("Content-Length", strconv.FormatInt(int64(len(finalCompressBytes)), 10))
+					}
+				}
+			}
+
 			//记录响应body
 			if isText && resp.Body != nil && resp.Body != http.NoBody && global.GCONFIG_RECORD_RESP == 1 {
 
@@ -653,6 +883,9 @@ func (waf *WafEngine) modifyResponse() func(*http.Response) error {
 				datetimeNow := time.Now()
 				weblogfrist.TimeSpent = datetimeNow.UnixNano()/1e6 - weblogfrist.UNIX_ADD_TIME
 				weblogfrist.ACTION = "放行"
+				if isRespBlock {
+					weblogfrist.ACTION = "阻止"
+				}
 				weblogfrist.STATUS = resp.Status
 				weblogfrist.STATUS_CODE = resp.StatusCode
 				weblogfrist.TASK_FLAG = 1
@@ -878,8 +1111,10 @@ func (waf *WafEngine) StartProxyServer(innruntime innerbean.ServerRunTime) {
 				Addr:    ":" + strconv.Itoa(innruntime.Port),
 				Handler: waf,
 				TLSConfig: &tls.Config{
-					GetCertificate: waf.GetCertificateFunc,
+					GetCertificate:     waf.GetCertificateFunc,
+					GetConfigForClient: waf.recordClientHello,
 				},
+				ConnState: tlsConnState,
 			}
 			serclone := waf.ServerOnline[innruntime.Port]
 			serclone.Svr = svr
@@ -915,8 +1150,9 @@ func (waf *WafEngine) StartProxyServer(innruntime innerbean.ServerRunTime) {
 				}
 			}()
 			svr := &http.Server{
-				Addr:    ":" + strconv.Itoa(innruntime.Port),
-				Handler: waf,
+				Addr: ":" + strconv.Itoa(innruntime.Port),
+				// 支持明文HTTP/2(h2c)接入，如gRPC客户端
+				Handler: h2c.NewHandler(waf, &http2.Server{}),
 			}
 			serclone := waf.ServerOnline[innruntime.Port]
 			serclone.Svr = svr
@@ -0,0 +1,200 @@
+package wafhttpcore
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 参数来源
+const (
+	PARAM_SOURCE_QUERY   = "query"
+	PARAM_SOURCE_FORM    = "form"
+	PARAM_SOURCE_JSON    = "json"
+	PARAM_SOURCE_COOKIE  = "cookie"
+	PARAM_SOURCE_HEADER  = "header"
+	PARAM_SOURCE_PATH    = "path"
+	PARAM_SOURCE_BODY    = "body"
+	PARAM_SOURCE_XML     = "xml"
+	PARAM_SOURCE_GRAPHQL = "graphql"
+	PARAM_SOURCE_WS      = "ws"
+	PARAM_SOURCE_GRPC    = "grpc"
+)
+
+// JSON提取的最大深度和最大数量
+const (
+	maxJsonDepth  = 32
+	maxJsonParams = 2000
+)
+
+// 默认检测的请求头 X-Forwarded-For 由代理追加且用于识别来源IP，不默认检测
+var DefaultInspectHeaders = []string{"User-Agent", "Referer"}
+
+// WafParam 请求中的一个具名输入
+type WafParam struct {
+	Source string //来源 query form json cookie header path body
+	Name   string //参数名称 json为路径形式 如 user.name items[0]
+	Value  string //参数值
+}
+
+// FullName 带来源的名称 如 query.id
+func (p WafParam) FullName() string {
+	if p.Name == "" {
+		return p.Source
+	}
+	return p.Source + "." + p.Name
+}
+
+/*
+*
+提取请求中的具名输入（路径、查询参数、表单、JSON路径）
+body 为已读取的请求体，formValue 为已解析的表单
+查询参数、表单和JSON的值只做过一次解码，统一经过多层解码归一化，防止多重编码绕过检测
+*/
+func ExtractParams(r *http.Request, body string, formValue url.Values) []WafParam {
+	var params []WafParam
+	params = append(params, WafParam{Source: PARAM_SOURCE_PATH, Value: WafHttpCoreUrlEncode(r.URL.EscapedPath(), 100)})
+
+	for name, values := range r.URL.Query() {
+		for _, v := range values {
+			params = append(params, WafParam{Source: PARAM_SOURCE_QUERY, Name: name, Value: NormalizeValue(v)})
+		}
+	}
+
+	bodyParsed := false
+	if len(formValue) > 0 {
+		bodyParsed = true
+		for name, values := range formValue {
+			for _, v := range values {
+				params = append(params, WafParam{Source: PARAM_SOURCE_FORM, Name: name, Value: NormalizeValue(v)})
+			}
+		}
+	}
+	if !bodyParsed && body != "" && strings.Contains(strings.ToLower(r.Header.Get("Content-Type")), "json") {
+		var data interface{}
+		if err := json.Unmarshal([]byte(body), &data); err == nil {
+			var truncated bool
+			jsonStart := len(params)
+			params, truncated = FlattenJson("", data, params)
+			for i := jsonStart; i < len(params); i++ {
+				params[i].Value = NormalizeValue(params[i].Value)
+			}
+			//超过深度或数量限制时未展开的部分无法检测，退回整体检测
+			bodyParsed = !truncated
+		}
+	}
+	//无法解析或未完整展开的请求体整体作为一个输入
+	if !bodyParsed && body != "" {
+		params = append(params, WafParam{Source: PARAM_SOURCE_BODY, Value: body})
+	}
+
+	return params
+}
+
+/*
+*
+提取请求头和cookie中的输入，值会经过解码归一化
+headerNames 需要检测的请求头，cookieNames 需要检测的cookie 为空时检测全部cookie
+*/
+func ExtractHeaderParams(r *http.Request, headerNames []string, cookieNames []string) []WafParam {
+	var params []WafParam
+	for _, headerName := range headerNames {
+		for _, v := range r.Header.Values(headerName) {
+			params = append(params, WafParam{Source: PARAM_SOURCE_HEADER, Name: http.CanonicalHeaderKey(headerName), Value: NormalizeValue(v)})
+		}
+	}
+	for _, cookie := range r.Cookies() {
+		if len(cookieNames) > 0 && !containsName(cookieNames, cookie.Name) {
+			continue
+		}
+		params = append(params, WafParam{Source: PARAM_SOURCE_COOKIE, Name: cookie.Name, Value: NormalizeValue(cookie.Value)})
+	}
+	return params
+}
+
+/*
+*
+归一化输入值：多层URL解码、HTML实体解码、去除空字节
+*/
+func NormalizeValue(value string) string {
+	value = WafHttpCoreUrlEncode(value, 10)
+	if strings.Contains(value, "&") {
+		value = html.UnescapeString(value)
+	}
+	return strings.ReplaceAll(value, "\x00", "")
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == "*" || strings.EqualFold(strings.TrimSpace(n), name) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+*
+提取WebSocket文本消息中的输入 JSON消息按路径展开，其他消息整体作为一个输入
+*/
+func ExtractMessageParams(message string) []WafParam {
+	trimMessage := strings.TrimSpace(message)
+	if strings.HasPrefix(trimMessage, "{") || strings.HasPrefix(trimMessage, "[") {
+		var data interface{}
+		if err := json.Unmarshal([]byte(trimMessage), &data); err == nil {
+			params, truncated := FlattenJson("", data, nil)
+			for i := range params {
+				params[i].Source = PARAM_SOURCE_WS
+			}
+			if truncated {
+				params = append(params, WafParam{Source: PARAM_SOURCE_WS, Value: message})
+			}
+			return params
+		}
+	}
+	return []WafParam{{Source: PARAM_SOURCE_WS, Value: message}}
+}
+
+/*
+*
+把JSON展开成路径和值 如 {"user":{"name":"a"},"ids":[1]} => user.name=a ids[0]=1
+超过最大深度或最大数量时停止展开，返回值 truncated 为 true，调用方需要整体检测
+*/
+func FlattenJson(prefix string, data interface{}, params []WafParam) ([]WafParam, bool) {
+	truncated := false
+	params = flattenJson(prefix, data, 0, params, len(params)+maxJsonParams, &truncated)
+	return params, truncated
+}
+
+func flattenJson(prefix string, data interface{}, depth int, params []WafParam, limit int, truncated *bool) []WafParam {
+	if depth > maxJsonDepth || len(params) >= limit {
+		*truncated = true
+		return params
+	}
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if len(params) >= limit {
+				*truncated = true
+				return params
+			}
+			name := key
+			if prefix != "" {
+				name = prefix + "." + key
+			}
+			//键名本身也可能携带攻击内容
+			params = append(params, WafParam{Source: PARAM_SOURCE_JSON, Name: name + "#key", Value: key})
+			params = flattenJson(name, child, depth+1, params, limit, truncated)
+		}
+	case []interface{}:
+		for i, child := range v {
+			params = flattenJson(prefix+"["+strconv.Itoa(i)+"]", child, depth+1, params, limit, truncated)
+		}
+	case string:
+		params = append(params, WafParam{Source: PARAM_SOURCE_JSON, Name: prefix, Value: v})
+	}
+	return params
+}
@@ -0,0 +1,193 @@
+package wafhttpcore
+
+import (
+	"SamWaf/libinjection-go"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func findParam(params []WafParam, fullName string) (WafParam, bool) {
+	for _, p := range params {
+		if p.FullName() == fullName {
+			return p, true
+		}
+	}
+	return WafParam{}, false
+}
+
+func TestExtractParams(t *testing.T) {
+	body := `{"user":{"name":"1' or '1'='1"},"ids":["a","b"]}`
+	r := httptest.NewRequest(http.MethodPost, "/api/save?id=1&q=hello", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	params := ExtractParams(r, body, url.Values{})
+	cases := map[string]string{
+		"path":           "/api/save",
+		"query.id":       "1",
+		"query.q":        "hello",
+		"json.user.name": "1' or '1'='1",
+		"json.ids[1]":    "b",
+	}
+	for name, value := range cases {
+		p, ok := findParam(params, name)
+		if !ok {
+			t.Errorf("param %s not found", name)
+			continue
+		}
+		if p.Value != value {
+			t.Errorf("param %s value = %s, want %s", name, p.Value, value)
+		}
+	}
+	if _, ok := findParam(params, "body"); ok {
+		t.Errorf("parsed json body should not be inspected as a whole")
+	}
+}
+
+func TestExtractParamsForm(t *testing.T) {
+	body := "content=<script>alert(1)</script>&title=hi"
+	r := httptest.NewRequest(http.MethodPost, "/cms/save", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	formValue, _ := url.ParseQuery(body)
+
+	params := ExtractParams(r, body, formValue)
+	p, ok := findParam(params, "form.content")
+	if !ok || !libinjection.IsXSS(p.Value) {
+		t.Errorf("form.content should be extracted and detected as xss")
+	}
+	p, ok = findParam(params, "form.title")
+	if !ok || libinjection.IsXSS(p.Value) {
+		t.Errorf("form.title should be extracted and not detected")
+	}
+}
+
+func TestExtractHeaderParams(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Referer", "http://example.com/")
+	r.Header.Set("X-Custom", "%2527%2520or%25201%253D1")
+	r.AddCookie(&http.Cookie{Name: "sid", Value: "abc"})
+	r.AddCookie(&http.Cookie{Name: "lang", Value: "%27%20union%20select%201--"})
+
+	params := ExtractHeaderParams(r, []string{"Referer", "x-custom"}, nil)
+	cases := map[string]string{
+		"header.Referer":  "http://example.com/",
+		"header.X-Custom": "' or 1=1",
+		"cookie.sid":      "abc",
+		"cookie.lang":     "' union select 1--",
+	}
+	for name, value := range cases {
+		p, ok := findParam(params, name)
+		if !ok {
+			t.Errorf("param %s not found", name)
+			continue
+		}
+		if p.Value != value {
+			t.Errorf("param %s value = %s, want %s", name, p.Value, value)
+		}
+	}
+	params = ExtractHeaderParams(r, nil, []string{"lang"})
+	if _, ok := findParam(params, "cookie.sid"); ok {
+		t.Errorf("cookie.sid should not be inspected")
+	}
+	if _, ok := findParam(params, "cookie.lang"); !ok {
+		t.Errorf("cookie.lang should be inspected")
+	}
+}
+
+func TestDefaultHeadersNotSqli(t *testing.T) {
+	userAgents := []string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+		"203.0.113.5, 198.51.100.7",
+		"https://www.example.com/search?q=hello&page=2",
+	}
+	for _, ua := range userAgents {
+		if libinjection.IsSQLiNotReturnPrint(ua) || libinjection.IsXSS(ua) {
+			t.Errorf("normal header detected: %s", ua)
+		}
+	}
+}
+
+func TestExtractMessageParams(t *testing.T) {
+	params := ExtractMessageParams(`{"type":"chat","data":{"text":"1' or '1'='1"}}`)
+	p, ok := findParam(params, "ws.data.text")
+	if !ok || p.Value != "1' or '1'='1" {
+		t.Errorf("ws.data.text not extracted: %+v", params)
+	}
+	params = ExtractMessageParams("hello world")
+	if len(params) != 1 || params[0].FullName() != "ws" || params[0].Value != "hello world" {
+		t.Errorf("plain message not extracted: %+v", params)
+	}
+}
+
+func TestExtractParamsJsonOverLimit(t *testing.T) {
+	attack := "1' union select password from users--"
+	deepBody := strings.Repeat(`{"a":`, maxJsonDepth+8) + `"` + attack + `"` + strings.Repeat("}", maxJsonDepth+8)
+	items := make([]string, 0, maxJsonParams+10)
+	for i := 0; i < maxJsonParams+10; i++ {
+		items = append(items, `"x"`)
+	}
+	items = append(items, `"`+attack+`"`)
+	wideBody := `{"list":[` + strings.Join(items, ",") + `]}`
+
+	for _, body := range []string{deepBody, wideBody} {
+		r := httptest.NewRequest(http.MethodPost, "/api/save", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		params := ExtractParams(r, body, url.Values{})
+		p, ok := findParam(params, "body")
+		if !ok || p.Value != body {
+			t.Errorf("truncated json body should be inspected as a whole")
+			continue
+		}
+		if !libinjection.IsSQLiNotReturnPrint(p.Value) {
+			t.Errorf("attack beyond json limit not detected")
+		}
+	}
+
+	params := ExtractMessageParams(deepBody)
+	if _, ok := findParam(params, "ws"); !ok {
+		t.Errorf("truncated ws message should be inspected as a whole")
+	}
+}
+
+func TestDefaultHeadersSkipForwardedFor(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.5, 198.51.100.7")
+	r.Header.Set("User-Agent", "curl/8.0")
+	params := ExtractHeaderParams(r, DefaultInspectHeaders, nil)
+	if _, ok := findParam(params, "header.X-Forwarded-For"); ok {
+		t.Errorf("X-Forwarded-For should not be inspected by default")
+	}
+	if _, ok := findParam(params, "header.User-Agent"); !ok {
+		t.Errorf("User-Agent should be inspected by default")
+	}
+}
+
+func TestExtractParamsMultiEncoded(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/item?id=1%2527%2520or%25201%253D1--%2520&q=%253Cscript%253Ealert(1)%253C%252Fscript%253E", nil)
+	params := ExtractParams(r, "", url.Values{})
+	if p, ok := findParam(params, "query.id"); !ok || !libinjection.IsSQLiNotReturnPrint(p.Value) {
+		t.Errorf("double encoded sqli not detected: %s", p.Value)
+	}
+	if p, ok := findParam(params, "query.q"); !ok || !libinjection.IsXSS(p.Value) {
+		t.Errorf("double encoded xss not detected: %s", p.Value)
+	}
+
+	body := "content=%253Cscript%253Ealert(1)%253C%252Fscript%253E"
+	r = httptest.NewRequest(http.MethodPost, "/cms/save", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	formValue, _ := url.ParseQuery(body)
+	if p, ok := findParam(ExtractParams(r, body, formValue), "form.content"); !ok || !libinjection.IsXSS(p.Value) {
+		t.Errorf("double encoded form xss not detected: %s", p.Value)
+	}
+
+	body = `{"name":"1%2527%2520or%25201%253D1--%2520"}`
+	r = httptest.NewRequest(http.MethodPost, "/api/save", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if p, ok := findParam(ExtractParams(r, body, url.Values{}), "json.name"); !ok || !libinjection.IsSQLiNotReturnPrint(p.Value) {
+		t.Errorf("encoded json sqli not detected: %s", p.Value)
+	}
+}
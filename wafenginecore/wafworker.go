@@ -2,6 +2,7 @@ package wafenginecore
 
 import (
 	"SamWaf/common/zlog"
+	"SamWaf/enums"
 	"SamWaf/global"
 	"SamWaf/innerbean"
 	"SamWaf/model"
@@ -12,7 +13,6 @@ import (
 	"SamWaf/wafproxy"
 	"SamWaf/webplugin"
 	"context"
-	goahocorasick "github.com/anknown/ahocorasick"
 	"golang.org/x/time/rate"
 	"strconv"
 	"time"
@@ -108,6 +108,10 @@ func (waf *WafEngine) LoadHost(inHost model.Hosts) innerbean.ServerRunTime {
 	//加载主机对于的规则
 	ruleHelper := &utils.RuleHelper{}
 	ruleHelper.InitRuleEngine()
+	hostCode := inHost.Code
+	ruleHelper.SetStatsRecorder(func(ruleCode string, hit bool, cost time.Duration, reqUuid string) {
+		recordRuleStats(hostCode, enums.RULE_STATS_TYPE_RULE, ruleCode, hit, cost, reqUuid)
+	})
 	//查询规则
 	var vcnt int
 	global.GWAF_LOCAL_DB.Model(&model.Rules{}).Where("host_code = ? and rule_status<>999",
@@ -149,9 +153,28 @@ func (waf *WafEngine) LoadHost(inHost model.Hosts) innerbean.ServerRunTime {
 	var ldpurls []model.LDPUrl
 	global.GWAF_LOCAL_DB.Where("host_code=? ", inHost.Code).Find(&ldpurls)
 
+	//查询登录防护
+	var loginProtectList []model.LoginProtect
+	global.GWAF_LOCAL_DB.Where("host_code=? ", inHost.Code).Find(&loginProtectList)
+
+	//查询自定义SecLang规则
+	var owaspRuleList []model.OwaspRule
+	global.GWAF_LOCAL_DB.Where("host_code=? ", inHost.Code).Order("create_time asc").Find(&owaspRuleList)
+	owaspBean, owaspConfigured := parseHostOwasp(inHost.OWASP_JSON)
+
+	//查询地域限制
+	var geoPolicyBean model.GeoPolicy
+	global.GWAF_LOCAL_DB.Where("host_code=? ", inHost.Code).Limit(1).Find(&geoPolicyBean)
+
+	//查询威胁情报条目
+	threatFeedList := waf_service.WafThreatFeedServiceApp.GetEntryListByHostCodeApi(inHost.Code)
+
 	//查询负载均衡
 	var loadBalanceList []model.LoadBalance
 	global.GWAF_LOCAL_DB.Where("host_code=? ", inHost.Code).Find(&loadBalanceList)
+
+	//解析gRPC配置
+	grpcBean, grpcConfigured := parseHostGrpc(inHost.GRPC_JSON)
 	//初始化主机host
 	hostsafe := &wafenginmodel.HostSafe{
 		LoadBalanceRuntime: &wafenginmodel.LoadBalanceRuntime{
@@ -166,13 +189,32 @@ func (waf *WafEngine) LoadHost(inHost model.Hosts) innerbean.ServerRunTime {
 		RuleData:            ruleconfigs,
 		RuleVersionSum:      vcnt,
 		Host:                inHost,
+		DefenseBean:         parseHostDefense(inHost.DEFENSE_JSON),
+		XmlLimitBean:        parseHostXmlLimit(inHost.XML_LIMIT_JSON),
+		DetectExcludes:      parseDetectExcludes(inHost.DETECT_EXCLUDE_JSON),
+		GraphqlConfigs:      parseHostGraphql(inHost.GRAPHQL_JSON),
+		WebsocketBean:       parseHostWebsocket(inHost.WEBSOCKET_JSON),
+		GrpcBean:            grpcBean,
+		GrpcConfigured:      grpcConfigured,
 		PluginIpRateLimiter: pluginIpRateLimiter,
 		IPWhiteLists:        ipwhitelist,
+		IPWhiteMatcher:      wafenginmodel.BuildIPAllowMatcher(ipwhitelist),
 		UrlWhiteLists:       urlwhitelist,
+		UrlWhiteMatcher:     wafenginmodel.BuildURLAllowMatcher(urlwhitelist),
 		LdpUrlLists:         ldpurls,
+		LdpUrlMatcher:       wafenginmodel.BuildLdpUrlMatcher(ldpurls),
 		IPBlockLists:        ipblocklist,
+		IPBlockMatcher:      wafenginmodel.BuildIPBlockMatcher(ipblocklist),
 		UrlBlockLists:       urlblocklist,
+		UrlBlockMatcher:     wafenginmodel.BuildURLBlockMatcher(urlblocklist),
 		AntiCCBean:          anticcBean,
+		LoginProtectLists:   loginProtectList,
+		OwaspRuleLists:      owaspRuleList,
+		OwaspBean:           owaspBean,
+		OwaspConfigured:     owaspConfigured,
+		GeoPolicyBean:       geoPolicyBean,
+		ThreatFeedLists:     threatFeedList,
+		ThreatFeedMatcher:   wafenginmodel.BuildThreatFeedMatcher(threatFeedList),
 	}
 	hostsafe.Mux.Lock()
 	defer hostsafe.Mux.Unlock()
@@ -181,6 +223,13 @@ func (waf *WafEngine) LoadHost(inHost model.Hosts) innerbean.ServerRunTime {
 	//赋值到对照表里面
 	waf.HostCode[inHost.Code] = inHost.Host + ":" + strconv.Itoa(inHost.Port)
 
+	//全局主机的自定义规则对所有主机生效 先于全局主机加载的主机需要重新编译
+	if inHost.Code == global.GWAF_GLOBAL_HOST_CODE {
+		waf.ReloadOwaspRules(inHost.Code)
+	} else {
+		waf.compileHostOwasp(inHost.Host + ":" + strconv.Itoa(inHost.Port))
+	}
+
 	//如果存在强制跳转
 	if inHost.AutoJumpHTTPS == 1 {
 		waf.HostTarget[inHost.Host+":80"] = hostsafe
@@ -244,22 +293,38 @@ func (waf *WafEngine) ReLoadSensitive() {
 	global.GWAF_LOCAL_DB.Find(&sensitiveList)
 	//敏感词
 	waf.Sensitive = sensitiveList
-	if len(sensitiveList) == 0 {
-		return
-	}
-	// 提取 content 字段并转换为 [][]rune
-	var keywords [][]rune
-	for _, sensitive := range waf.Sensitive {
-		// 将 content 转换为 []rune 并追加到 keywords
-		keywords = append(keywords, []rune(sensitive.Content))
-	}
 
-	m := new(goahocorasick.Machine)
-	err := m.Build(keywords)
-	if err != nil {
-		zlog.Error("load sensitive error", err)
-		return
+	// 按照主机和检测方向分组
+	requestGroups := map[string][]model.Sensitive{}
+	responseGroups := map[string][]model.Sensitive{}
+	for _, sensitive := range sensitiveList {
+		hostCode := sensitive.HostCode
+		if hostCode == global.GWAF_GLOBAL_HOST_CODE {
+			hostCode = ""
+		}
+		if sensitive.CheckDirection == enums.SENSITIVE_DIRECTION_REQUEST || sensitive.CheckDirection == enums.SENSITIVE_DIRECTION_BOTH {
+			requestGroups[hostCode] = append(requestGroups[hostCode], sensitive)
+		}
+		if sensitive.CheckDirection == enums.SENSITIVE_DIRECTION_RESPONSE || sensitive.CheckDirection == enums.SENSITIVE_DIRECTION_BOTH {
+			responseGroups[hostCode] = append(responseGroups[hostCode], sensitive)
+		}
 	}
-	waf.SensitiveManager = m
+	waf.SensitiveRequestManager = buildSensitiveMatchers(requestGroups)
+	waf.SensitiveResponseManager = buildSensitiveMatchers(responseGroups)
+}
 
+// 构建各个主机的敏感词匹配器
+func buildSensitiveMatchers(groups map[string][]model.Sensitive) map[string]*wafenginmodel.SensitiveMatcher {
+	matchers := map[string]*wafenginmodel.SensitiveMatcher{}
+	for hostCode, list := range groups {
+		matcher, err := wafenginmodel.NewSensitiveMatcher(list)
+		if err != nil {
+			zlog.Error("load sensitive error", err)
+			continue
+		}
+		if matcher != nil {
+			matchers[hostCode] = matcher
+		}
+	}
+	return matchers
 }
@@ -0,0 +1,270 @@
+package wafenginecore
+
+import (
+	"SamWaf/global"
+	"SamWaf/innerbean"
+	"SamWaf/libinjection-go"
+	"SamWaf/model"
+	"SamWaf/utils"
+	"SamWaf/wafdefenserce"
+	"SamWaf/wafdefensewebsocket"
+	"SamWaf/wafenginecore/wafhttpcore"
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 单条文本消息最多检测的长度，开启文本检测时超出即关闭连接
+const maxWebsocketInspectSize = 64 * 1024
+
+// websocket会话
+type websocketSession struct {
+	weblog      innerbean.WebLog
+	config      model.HostsWebsocket
+	guard       bool //是否进行防护
+	hostDefense model.HostsDefense
+	excludes    []model.HostsDetectExclude
+	path        string
+	inBytes     int64 //客户端发送字节数
+	outBytes    int64 //后端发送字节数
+	userMux     sync.Mutex
+}
+
+// websocket拦截原因
+type websocketBlockError struct {
+	rule             string
+	riskLevel        int
+	matchParam       string
+	matchFingerprint string
+}
+
+func (e websocketBlockError) Error() string {
+	return e.rule
+}
+
+// 统计字节数的写入
+type websocketCountWriter struct {
+	session *websocketSession
+	writer  io.Writer
+	counter *int64
+}
+
+func (w websocketCountWriter) Write(p []byte) (int, error) {
+	w.session.userMux.Lock()
+	defer w.session.userMux.Unlock()
+	n, err := w.writer.Write(p)
+	atomic.AddInt64(w.counter, int64(n))
+	return n, err
+}
+
+/*
+*
+协议升级后的数据转发 websocket按帧检测，连接结束后记录一条连接日志
+*/
+func (waf *WafEngine) upgradeHandler() func(*http.Request, io.ReadWriter, io.ReadWriter) {
+	return func(req *http.Request, user io.ReadWriter, backend io.ReadWriter) {
+		weblogfrist, ok := req.Context().Value("weblog").(innerbean.WebLog)
+		if !ok || waf.HostTarget[weblogfrist.HOST] == nil {
+			errc := make(chan error, 2)
+			go func() {
+				_, err := io.Copy(user, backend)
+				errc <- err
+			}()
+			go func() {
+				_, err := io.Copy(backend, user)
+				errc <- err
+			}()
+			<-errc
+			return
+		}
+		session := &websocketSession{
+			weblog: weblogfrist,
+			path:   req.URL.Path,
+		}
+		session.guard, _ = req.Context().Value("websocket_guard").(bool)
+		if session.guard {
+			session.config = waf.getWebsocketConfig(weblogfrist.HOST)
+			session.hostDefense = waf.getHostDefense(weblogfrist.HOST)
+			session.excludes = waf.getDetectExcludes(weblogfrist.HOST)
+		}
+		userWriter := websocketCountWriter{session: session, writer: user, counter: &session.outBytes}
+
+		errc := make(chan error, 2)
+		go func() {
+			_, err := io.Copy(userWriter, backend)
+			errc <- err
+		}()
+		go func() {
+			if isWebsocketUpgrade(req) {
+				errc <- session.relayClient(user, backend, userWriter)
+				return
+			}
+			_, err := io.Copy(backend, websocketCountReader{reader: user, counter: &session.inBytes})
+			errc <- err
+		}()
+		err := <-errc
+		waf.recordWebsocketLog(session, err)
+	}
+}
+
+// 统计字节数的读取
+type websocketCountReader struct {
+	reader  io.Reader
+	counter *int64
+}
+
+func (r websocketCountReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	atomic.AddInt64(r.counter, int64(n))
+	return n, err
+}
+
+/*
+*
+转发客户端发往后端的帧 检测帧大小、消息频率和文本消息内容
+*/
+func (s *websocketSession) relayClient(user io.Reader, backend io.Writer, userWriter io.Writer) error {
+	reader := bufio.NewReader(websocketCountReader{reader: user, counter: &s.inBytes})
+	var message []byte
+	var pending []byte //等待检测的原始帧
+	inspecting := false
+	rateSecond := int64(0)
+	rateCount := 0
+	for {
+		header, err := wafdefensewebsocket.ReadFrameHeader(reader)
+		if err != nil {
+			return err
+		}
+		isNewMessage := !header.IsControl() && header.Opcode != wafdefensewebsocket.OPCODE_CONTINUATION
+		if s.guard {
+			if s.config.MaxFrameSize > 0 && header.Length > s.config.MaxFrameSize {
+				return s.block(userWriter, wafdefensewebsocket.CLOSE_MESSAGE_TOO_BIG, websocketBlockError{rule: "WebSocket帧过大(" + strconv.FormatInt(header.Length, 10) + ")", riskLevel: 2})
+			}
+			if isNewMessage && s.config.MaxMessageRate > 0 {
+				nowSecond := time.Now().Unix()
+				if nowSecond != rateSecond {
+					rateSecond = nowSecond
+					rateCount = 0
+				}
+				rateCount++
+				if rateCount > s.config.MaxMessageRate {
+					return s.block(userWriter, wafdefensewebsocket.CLOSE_POLICY_VIOLATION, websocketBlockError{rule: "WebSocket消息频率超限(" + strconv.Itoa(s.config.MaxMessageRate) + "/秒)", riskLevel: 2})
+				}
+			}
+		}
+		if isNewMessage {
+			message = message[:0]
+			pending = pending[:0]
+			inspecting = s.guard && s.config.InspectText == 1 && header.Opcode == wafdefensewebsocket.OPCODE_TEXT && !header.Rsv1
+		}
+		if inspecting && !header.IsControl() {
+			//超过检测长度的文本消息无法完整检测，直接关闭连接
+			if int64(len(message))+header.Length > maxWebsocketInspectSize {
+				return s.block(userWriter, wafdefensewebsocket.CLOSE_MESSAGE_TOO_BIG, websocketBlockError{rule: "WebSocket文本消息超过检测长度(" + strconv.Itoa(maxWebsocketInspectSize) + ")", riskLevel: 2})
+			}
+			payload := make([]byte, header.Length)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return err
+			}
+			message = append(message, wafdefensewebsocket.UnmaskPayload(header, payload)...)
+			pending = append(pending, header.Raw...)
+			pending = append(pending, payload...)
+			//分片先缓存，最后一帧检测通过后再一起转发
+			if header.Fin {
+				inspecting = false
+				if blockErr, isBlock := s.inspectMessage(string(message)); isBlock {
+					return s.block(userWriter, wafdefensewebsocket.CLOSE_POLICY_VIOLATION, blockErr)
+				}
+				if _, err := backend.Write(pending); err != nil {
+					return err
+				}
+				pending = pending[:0]
+			}
+			continue
+		}
+		if _, err := backend.Write(header.Raw); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(backend, reader, header.Length); err != nil {
+			return err
+		}
+	}
+}
+
+/*
+*
+检测文本消息 返回拦截原因和是否拦截
+*/
+func (s *websocketSession) inspectMessage(message string) (websocketBlockError, bool) {
+	if message == "" {
+		return websocketBlockError{}, false
+	}
+	for _, param := range wafhttpcore.ExtractMessageParams(message) {
+		if param.Value == "" {
+			continue
+		}
+		if s.hostDefense.DEFENSE_SQLI == 1 && !isDetectExclude(s.excludes, "sqli", s.path, param) {
+			isSqli, fingerprint := libinjection.IsSQLi(param.Value)
+			if isSqli {
+				return websocketBlockError{rule: "SQL注入(" + param.FullName() + ")", riskLevel: 2, matchParam: param.FullName(), matchFingerprint: fingerprint}, true
+			}
+		}
+		if s.hostDefense.DEFENSE_XSS == 1 && !isDetectExclude(s.excludes, "xss", s.path, param) && libinjection.IsXSS(param.Value) {
+			return websocketBlockError{rule: "XSS跨站注入(" + param.FullName() + ")", riskLevel: 2, matchParam: param.FullName()}, true
+		}
+		if s.hostDefense.DEFENSE_RCE == 1 && !isDetectExclude(s.excludes, "rce", s.path, param) {
+			isRce, rceName := wafdefenserce.DetermineRCE(param.Value)
+			if isRce {
+				return websocketBlockError{rule: "RCE:" + rceName + "(" + param.FullName() + ")", riskLevel: 3, matchParam: param.FullName()}, true
+			}
+		}
+	}
+	return websocketBlockError{}, false
+}
+
+// 向客户端发送关闭帧并中断连接
+func (s *websocketSession) block(userWriter io.Writer, code uint16, blockErr websocketBlockError) error {
+	_, _ = userWriter.Write(wafdefensewebsocket.CloseFrame(code, "blocked"))
+	return blockErr
+}
+
+/*
+*
+记录连接日志 包含连接时长和双向字节数
+*/
+func (waf *WafEngine) recordWebsocketLog(session *websocketSession, err error) {
+	weblogbean := session.weblog
+	datetimeNow := time.Now()
+	weblogbean.TimeSpent = datetimeNow.UnixNano()/1e6 - weblogbean.UNIX_ADD_TIME
+	weblogbean.REQ_BYTES = atomic.LoadInt64(&session.inBytes)
+	weblogbean.RES_BYTES = atomic.LoadInt64(&session.outBytes)
+	weblogbean.STATUS = "101 Switching Protocols"
+	weblogbean.STATUS_CODE = http.StatusSwitchingProtocols
+	weblogbean.TASK_FLAG = 1
+	weblogbean.ACTION = "放行"
+	if blockErr, ok := err.(websocketBlockError); ok {
+		weblogbean.ACTION = "阻止"
+		weblogbean.RULE = blockErr.rule
+		weblogbean.RISK_LEVEL = blockErr.riskLevel
+		weblogbean.MATCH_PARAM = blockErr.matchParam
+		weblogbean.MATCH_FINGERPRINT = blockErr.matchFingerprint
+		weblogbean.GUEST_IDENTIFICATION = "可疑用户"
+		go func() {
+			//发送推送消息
+			global.GQEQUE_MESSAGE_DB.Enqueue(innerbean.RuleMessageInfo{
+				BaseMessageInfo: innerbean.BaseMessageInfo{OperaType: "命中保护规则", Server: global.GWAF_CUSTOM_SERVER_NAME},
+				Domain:          weblogbean.HOST,
+				RuleInfo:        blockErr.rule,
+				Ip:              fmt.Sprintf("%s (%s)", weblogbean.SRC_IP, utils.GetCountry(weblogbean.SRC_IP)),
+			})
+		}()
+	}
+	if global.GWAF_RUNTIME_RECORD_LOG_TYPE == "all" || weblogbean.ACTION != "放行" {
+		global.GQEQUE_LOG_DB.Enqueue(weblogbean)
+	}
+}
@@ -0,0 +1,82 @@
+package wafenginecore
+
+import (
+	"SamWaf/model"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// 生成客户端掩码帧
+func maskedWebsocketFrame(opcode byte, fin bool, payload []byte) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) < 65536:
+		frame = append(frame, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	default:
+		frame = append(frame, 0x80|127, 0, 0, 0, 0, byte(len(payload)>>24), byte(len(payload)>>16), byte(len(payload)>>8), byte(len(payload)))
+	}
+	key := []byte{1, 2, 3, 4}
+	frame = append(frame, key...)
+	for i, b := range payload {
+		frame = append(frame, b^key[i%4])
+	}
+	return frame
+}
+
+func newWebsocketTestSession() *websocketSession {
+	return &websocketSession{
+		guard:       true,
+		config:      model.HostsWebsocket{InspectText: 1},
+		hostDefense: model.HostsDefense{DEFENSE_SQLI: 1, DEFENSE_XSS: 1},
+		path:        "/ws",
+	}
+}
+
+func relayWebsocketFrames(frames ...[]byte) (string, []byte, error) {
+	var backend, user bytes.Buffer
+	err := newWebsocketTestSession().relayClient(bytes.NewReader(bytes.Join(frames, nil)), &backend, &user)
+	return backend.String(), user.Bytes(), err
+}
+
+func TestRelayClientHoldsFragmentsUntilChecked(t *testing.T) {
+	backend, user, err := relayWebsocketFrames(
+		maskedWebsocketFrame(0x1, false, []byte(`{"text":"<script>`)),
+		maskedWebsocketFrame(0x0, true, []byte(`alert(1)</script>"}`)),
+	)
+	if _, ok := err.(websocketBlockError); !ok {
+		t.Fatalf("fragmented xss not blocked: %v", err)
+	}
+	if backend != "" {
+		t.Errorf("fragments forwarded before check: %q", backend)
+	}
+	if len(user) == 0 {
+		t.Errorf("close frame not sent")
+	}
+}
+
+func TestRelayClientBlocksOversizeMessage(t *testing.T) {
+	payload := []byte(strings.Repeat("a", maxWebsocketInspectSize+1))
+	backend, _, err := relayWebsocketFrames(maskedWebsocketFrame(0x1, true, payload))
+	if _, ok := err.(websocketBlockError); !ok {
+		t.Fatalf("oversize message not blocked: %v", err)
+	}
+	if backend != "" {
+		t.Errorf("oversize message forwarded")
+	}
+}
+
+func TestRelayClientForwardsCleanMessage(t *testing.T) {
+	first := maskedWebsocketFrame(0x1, false, []byte("hello "))
+	last := maskedWebsocketFrame(0x0, true, []byte("world"))
+	backend, _, _ := relayWebsocketFrames(first, last)
+	if backend != string(first)+string(last) {
+		t.Errorf("clean message not forwarded as is")
+	}
+}
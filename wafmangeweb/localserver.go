@@ -57,6 +57,11 @@ func (web *WafWebManager) initRouter(r *gin.Engine) {
 		router.ApiGroupApp.InitLoadBalanceRouter(RouterGroup)
 		router.ApiGroupApp.InitSslConfigRouter(RouterGroup)
 		router.ApiGroupApp.InitBatchTaskRouter(RouterGroup)
+		router.ApiGroupApp.InitLoginProtectRouter(RouterGroup)
+		router.ApiGroupApp.InitOwaspRuleRouter(RouterGroup)
+		router.ApiGroupApp.InitRuleTemplateRouter(RouterGroup)
+		router.ApiGroupApp.InitGeoPolicyRouter(RouterGroup)
+		router.ApiGroupApp.InitThreatFeedRouter(RouterGroup)
 	}
 	//r.Use(middleware.GinGlobalExceptionMiddleWare())
 	if global.GWAF_RELEASE == "true" {
@@ -6,6 +6,7 @@ import (
 	"github.com/corazawaf/coraza/v3"
 	"github.com/corazawaf/coraza/v3/types"
 	"net/http"
+	"strconv"
 	"testing"
 )
 
@@ -30,59 +31,83 @@ func InitOwasp(currentDir string) coraza.WAF {
 	return wafOwasp
 }
 func NewWafOWASP(isActive bool, currentDir string) *WafOWASP {
-	// 初始化一个新的 WAF 实例
-	cfg := coraza.NewWAFConfig().
-		WithDirectivesFromFile(currentDir + "/data/owasp/coraza.conf").
-		WithDirectivesFromFile(currentDir + "/data/owasp/coreruleset/crs-setup.conf").
-		WithDirectivesFromFile(currentDir + "/data/owasp/coreruleset/rules/*.conf")
+	return newWafOWASPWithDirectives(isActive, currentDir, "", "")
+}
 
-	// First we initialize our waf and our seclang parser
-	waf, err := coraza.NewWAF(cfg)
-	// Now we parse our rules
+/*
+*
+初始化WAF实例 beforeRules 在CRS初始化之后、规则之前加载，afterRules 在规则之后加载
+规则加载失败时不激活
+*/
+func newWafOWASPWithDirectives(isActive bool, currentDir string, beforeRules string, afterRules string) *WafOWASP {
+	waf, err := compileWAF(currentDir, beforeRules, afterRules)
 	if err != nil {
 		fmt.Println(err)
+		isActive = false
 	}
 	return &WafOWASP{
 		IsActive: isActive,
 		WAF:      waf,
 	}
 }
-func (w *WafOWASP) ProcessRequest(r *http.Request, weblog innerbean.WebLog) (bool, *types.Interruption, error) {
+
+// 按加载顺序编译规则
+func compileWAF(currentDir string, beforeRules string, afterRules string) (coraza.WAF, error) {
+	cfg := coraza.NewWAFConfig().
+		WithDirectivesFromFile(currentDir + "/data/owasp/coraza.conf").
+		WithDirectivesFromFile(currentDir + "/data/owasp/coreruleset/crs-setup.conf")
+	if beforeRules != "" {
+		cfg = cfg.WithDirectives(beforeRules)
+	}
+	cfg = cfg.WithDirectivesFromFile(currentDir + "/data/owasp/coreruleset/rules/*.conf")
+	if afterRules != "" {
+		cfg = cfg.WithDirectives(afterRules)
+	}
+	cfg = cfg.WithDirectives(responseAndAuditDirectives(currentDir))
+	return coraza.NewWAF(cfg)
+}
+
+/*
+*
+处理请求阶段 未出错时返回的事务保持打开，需调用 FinishTransaction 结束
+*/
+func (w *WafOWASP) BeginTransaction(r *http.Request, weblog innerbean.WebLog) (types.Transaction, *types.Interruption, error) {
 	// 只有在 WAF 激活时才处理请求
-	if w.IsActive {
-		tx := w.WAF.NewTransaction()
-		defer tx.Close()
+	if !w.IsActive {
+		return nil, nil, fmt.Errorf("owasp not active")
+	}
+	tx := w.WAF.NewTransaction()
+	srcPort, _ := strconv.Atoi(weblog.SRC_PORT)
+	tx.ProcessConnection(weblog.SRC_IP, srcPort, "", 0)
 
-		// 添加请求头
-		for key, values := range r.Header {
-			for _, value := range values {
-				tx.AddRequestHeader(key, value)
-			}
+	// 添加请求头
+	for key, values := range r.Header {
+		for _, value := range values {
+			tx.AddRequestHeader(key, value)
 		}
-		// 添加请求行信息
-		tx.ProcessURI(r.URL.RequestURI(), r.Method, r.Proto)
+	}
+	// 添加请求行信息
+	tx.ProcessURI(r.URL.RequestURI(), r.Method, r.Proto)
 
-		// 如果有请求体，则读取并写入事务
-		if weblog.BODY != "" {
-			if _, _, err := tx.WriteRequestBody([]byte(weblog.BODY)); err != nil {
-				return false, nil, fmt.Errorf("error writing request body: %v", err)
-			}
-		}
-		// 处理请求头和请求体
-		if it := tx.ProcessRequestHeaders(); it != nil {
-			return false, nil, fmt.Errorf("error ProcessRequestHeaders: %v", it)
-		}
-		if _, err := tx.ProcessRequestBody(); err != nil {
-			return false, nil, fmt.Errorf("request body processing error: %v", err)
-		}
-		interrupted := tx.IsInterrupted()
-		if interrupted {
-			return interrupted, tx.Interruption(), nil
-		} else {
-			return interrupted, nil, fmt.Errorf("request body processing error: %v")
+	// 处理请求头
+	if it := tx.ProcessRequestHeaders(); it != nil {
+		return tx, it, nil
+	}
+	// 如果有请求体，则读取并写入事务
+	if weblog.BODY != "" {
+		if it, _, err := tx.WriteRequestBody([]byte(weblog.BODY)); err != nil {
+			tx.Close()
+			return nil, nil, fmt.Errorf("error writing request body: %v", err)
+		} else if it != nil {
+			return tx, it, nil
 		}
 	}
-	return false, nil, fmt.Errorf("error")
+	it, err := tx.ProcessRequestBody()
+	if err != nil {
+		tx.Close()
+		return nil, nil, fmt.Errorf("request body processing error: %v", err)
+	}
+	return tx, it, nil
 }
 
 type testLogOutput struct {
@@ -0,0 +1,69 @@
+package wafowasp
+
+import (
+	"sync"
+
+	"github.com/corazawaf/coraza/v3/experimental/plugins"
+	"github.com/corazawaf/coraza/v3/experimental/plugins/plugintypes"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// 审计日志写入器名称
+const auditLogWriterName = "samwaf_rotate"
+
+// 所有实例共用同一个轮转文件 key为文件路径
+var (
+	auditLoggers   = map[string]*lumberjack.Logger{}
+	auditLoggerMux sync.Mutex
+)
+
+func init() {
+	plugins.RegisterAuditLogWriter(auditLogWriterName, func() plugintypes.AuditLogWriter {
+		return &rotateAuditWriter{}
+	})
+}
+
+// rotateAuditWriter 按大小轮转的审计日志
+type rotateAuditWriter struct {
+	logger    *lumberjack.Logger
+	formatter plugintypes.AuditLogFormatter
+}
+
+func (w *rotateAuditWriter) Init(c plugintypes.AuditLogConfig) error {
+	if c.Target == "" {
+		return nil
+	}
+	auditLoggerMux.Lock()
+	defer auditLoggerMux.Unlock()
+	logger, ok := auditLoggers[c.Target]
+	if !ok {
+		logger = &lumberjack.Logger{
+			Filename:   c.Target,
+			MaxSize:    100,
+			MaxBackups: 10,
+			MaxAge:     7,
+			Compress:   false,
+		}
+		auditLoggers[c.Target] = logger
+	}
+	w.logger = logger
+	w.formatter = c.Formatter
+	return nil
+}
+
+func (w *rotateAuditWriter) Write(al plugintypes.AuditLog) error {
+	if w.logger == nil || w.formatter == nil {
+		return nil
+	}
+	bts, err := w.formatter.Format(al)
+	if err != nil || len(bts) == 0 {
+		return err
+	}
+	_, err = w.logger.Write(append(bts, '\n'))
+	return err
+}
+
+// Close 文件由所有实例共用，不关闭
+func (w *rotateAuditWriter) Close() error {
+	return nil
+}
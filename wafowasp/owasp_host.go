@@ -0,0 +1,177 @@
+package wafowasp
+
+import (
+	"SamWaf/model"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 主机配置生成规则保留的ID范围 避开CRS保留的900000-999999，自定义规则不能使用
+const (
+	ReservedRuleIdMin = 9000000
+	ReservedRuleIdMax = 9000999
+	setupRuleId       = 9000001
+	excludeRuleId     = 9000100
+)
+
+var (
+	ruleIdPattern = regexp.MustCompile(`^\d+(-\d+)?$`)
+	tagPattern    = regexp.MustCompile(`^[A-Za-z0-9_\-./]+$`)
+	paramPattern  = regexp.MustCompile(`^[A-Za-z0-9_\-.\[\]]+$`)
+	pathPattern   = regexp.MustCompile(`^/[^\s"'\\]*$`)
+)
+
+// 最多保留的已编译实例数量 规则修改后旧实例会逐步淘汰
+const maxHostWafCache = 32
+
+// 已编译的实例 key为生成的规则内容
+var (
+	hostWafCache = map[string]*hostWafEntry{}
+	hostWafOrder []string
+	hostWafMux   sync.Mutex
+)
+
+type hostWafEntry struct {
+	once sync.Once
+	waf  *WafOWASP
+}
+
+/*
+*
+获取配置和自定义规则对应的OWASP实例 相同配置的主机共用一个已编译的实例
+只在加载主机和自定义规则变化时调用，编译结果保存在主机上，请求中不再调用
+*/
+func GetHostWafOWASP(currentDir string, config model.HostsOwasp, rules []model.OwaspRule) *WafOWASP {
+	beforeRules, afterRules := BuildDirectives(config)
+	customBefore, customAfter := BuildRuleDirectives(rules)
+	if customBefore != "" {
+		beforeRules = strings.TrimPrefix(beforeRules+"\n"+customBefore, "\n")
+	}
+	if customAfter != "" {
+		afterRules = strings.TrimPrefix(afterRules+"\n"+customAfter, "\n")
+	}
+	cacheKey := beforeRules + "\n" + afterRules
+	hostWafMux.Lock()
+	entry, ok := hostWafCache[cacheKey]
+	if !ok {
+		entry = &hostWafEntry{}
+		hostWafCache[cacheKey] = entry
+		hostWafOrder = append(hostWafOrder, cacheKey)
+		if len(hostWafOrder) > maxHostWafCache {
+			delete(hostWafCache, hostWafOrder[0])
+			hostWafOrder = hostWafOrder[1:]
+		}
+	}
+	hostWafMux.Unlock()
+	//编译耗时较长 不占用全局锁
+	entry.once.Do(func() {
+		entry.waf = newWafOWASPWithDirectives(true, currentDir, beforeRules, afterRules)
+	})
+	return entry.waf
+}
+
+/*
+*
+校验排除项 返回是否有效
+*/
+func IsValidExclude(exclude model.HostsOwaspExclude) bool {
+	if exclude.RuleId == "" && exclude.Tag == "" {
+		return false
+	}
+	if exclude.RuleId != "" && !ruleIdPattern.MatchString(exclude.RuleId) {
+		return false
+	}
+	if exclude.Tag != "" && !tagPattern.MatchString(exclude.Tag) {
+		return false
+	}
+	if exclude.Param != "" && !paramPattern.MatchString(exclude.Param) {
+		return false
+	}
+	if exclude.Path != "" && !pathPattern.MatchString(exclude.Path) {
+		return false
+	}
+	return true
+}
+
+/*
+*
+根据主机配置生成规则 beforeRules 设置偏执等级、阈值以及按路径排除的运行时规则，afterRules 为全主机范围的排除
+无效的排除项会被忽略
+*/
+func BuildDirectives(config model.HostsOwasp) (string, string) {
+	var before, after []string
+	var setvars []string
+	if config.ParanoiaLevel > 0 {
+		level := config.ParanoiaLevel
+		if level > 4 {
+			level = 4
+		}
+		levelStr := strconv.Itoa(level)
+		//兼容CRS v3和v4的变量名
+		setvars = append(setvars,
+			"setvar:tx.paranoia_level="+levelStr,
+			"setvar:tx.blocking_paranoia_level="+levelStr,
+			"setvar:tx.detection_paranoia_level="+levelStr,
+			"setvar:tx.executing_paranoia_level="+levelStr)
+	}
+	if config.AnomalyThreshold > 0 {
+		setvars = append(setvars, "setvar:tx.inbound_anomaly_score_threshold="+strconv.Itoa(config.AnomalyThreshold))
+	}
+	if len(setvars) > 0 {
+		before = append(before, `SecAction "id:`+strconv.Itoa(setupRuleId)+`,phase:1,pass,t:none,nolog,`+strings.Join(setvars, ",")+`"`)
+	}
+
+	ruleId := excludeRuleId
+	for _, exclude := range config.Excludes {
+		if !IsValidExclude(exclude) {
+			continue
+		}
+		if exclude.Path == "" {
+			//全主机范围 在规则加载后直接移除
+			if exclude.RuleId != "" {
+				if exclude.Param == "" {
+					after = append(after, "SecRuleRemoveById "+exclude.RuleId)
+				} else {
+					after = append(after, "SecRuleUpdateTargetById "+exclude.RuleId+` "!ARGS:`+exclude.Param+`"`)
+				}
+			}
+			if exclude.Tag != "" {
+				if exclude.Param == "" {
+					after = append(after, `SecRuleRemoveByTag "`+exclude.Tag+`"`)
+				} else {
+					after = append(after, `SecRuleUpdateTargetByTag "`+exclude.Tag+`" "!ARGS:`+exclude.Param+`"`)
+				}
+			}
+			continue
+		}
+		//按路径排除 在规则执行前通过ctl移除
+		var ctls []string
+		if exclude.RuleId != "" {
+			if exclude.Param == "" {
+				ctls = append(ctls, "ctl:ruleRemoveById="+exclude.RuleId)
+			} else {
+				ctls = append(ctls, "ctl:ruleRemoveTargetById="+exclude.RuleId+";ARGS:"+exclude.Param)
+			}
+		}
+		if exclude.Tag != "" {
+			if exclude.Param == "" {
+				ctls = append(ctls, "ctl:ruleRemoveByTag="+exclude.Tag)
+			} else {
+				ctls = append(ctls, "ctl:ruleRemoveTargetByTag="+exclude.Tag+";ARGS:"+exclude.Param)
+			}
+		}
+		if ruleId > ReservedRuleIdMax {
+			//超出保留范围的排除项忽略
+			continue
+		}
+		operator := "@streq " + exclude.Path
+		if strings.HasSuffix(exclude.Path, "*") {
+			operator = "@beginsWith " + strings.TrimSuffix(exclude.Path, "*")
+		}
+		before = append(before, `SecRule REQUEST_FILENAME "`+operator+`" "id:`+strconv.Itoa(ruleId)+`,phase:1,pass,t:none,nolog,`+strings.Join(ctls, ",")+`"`)
+		ruleId++
+	}
+	return strings.Join(before, "\n"), strings.Join(after, "\n")
+}
@@ -0,0 +1,108 @@
+package wafowasp
+
+import (
+	"SamWaf/model"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/corazawaf/coraza/v3"
+)
+
+// 用一条模拟规则代替CRS验证生成的规则
+const testRule = `SecRule ARGS "@contains attack" "id:942100,phase:2,deny,status:403,tag:'attack-sqli'"`
+
+func isBlocked(t *testing.T, config model.HostsOwasp, uri string) bool {
+	beforeRules, afterRules := BuildDirectives(config)
+	cfg := coraza.NewWAFConfig().WithDirectives("SecRuleEngine On")
+	if beforeRules != "" {
+		cfg = cfg.WithDirectives(beforeRules)
+	}
+	cfg = cfg.WithDirectives(testRule)
+	if afterRules != "" {
+		cfg = cfg.WithDirectives(afterRules)
+	}
+	waf, err := coraza.NewWAF(cfg)
+	if err != nil {
+		t.Fatalf("directives error: %v\n%s\n%s", err, beforeRules, afterRules)
+	}
+	tx := waf.NewTransaction()
+	defer tx.Close()
+	tx.ProcessURI(uri, "GET", "HTTP/1.1")
+	tx.ProcessRequestHeaders()
+	if _, err := tx.ProcessRequestBody(); err != nil {
+		t.Fatal(err)
+	}
+	return tx.IsInterrupted()
+}
+
+func TestBuildDirectivesExcludes(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  model.HostsOwasp
+		uri     string
+		blocked bool
+	}{
+		{"无排除", model.HostsOwasp{}, "/a?q=attack", true},
+		{"偏执等级", model.HostsOwasp{ParanoiaLevel: 2, AnomalyThreshold: 10}, "/a?q=attack", true},
+		{"按ID排除", model.HostsOwasp{Excludes: []model.HostsOwaspExclude{{RuleId: "942100"}}}, "/a?q=attack", false},
+		{"按ID范围排除", model.HostsOwasp{Excludes: []model.HostsOwaspExclude{{RuleId: "942000-942999"}}}, "/a?q=attack", false},
+		{"按标签排除", model.HostsOwasp{Excludes: []model.HostsOwaspExclude{{Tag: "attack-sqli"}}}, "/a?q=attack", false},
+		{"排除参数", model.HostsOwasp{Excludes: []model.HostsOwaspExclude{{RuleId: "942100", Param: "q"}}}, "/a?q=attack", false},
+		{"排除参数-其他参数", model.HostsOwasp{Excludes: []model.HostsOwaspExclude{{RuleId: "942100", Param: "q"}}}, "/a?p=attack", true},
+		{"按路径排除", model.HostsOwasp{Excludes: []model.HostsOwaspExclude{{RuleId: "942100", Path: "/api/*"}}}, "/api/x?q=attack", false},
+		{"按路径排除-其他路径", model.HostsOwasp{Excludes: []model.HostsOwaspExclude{{RuleId: "942100", Path: "/api/*"}}}, "/web?q=attack", true},
+		{"按路径和参数排除", model.HostsOwasp{Excludes: []model.HostsOwaspExclude{{Tag: "attack-sqli", Path: "/post", Param: "content"}}}, "/post?content=attack", false},
+		{"按路径和参数排除-其他参数", model.HostsOwasp{Excludes: []model.HostsOwaspExclude{{Tag: "attack-sqli", Path: "/post", Param: "content"}}}, "/post?title=attack", true},
+		{"无效排除项", model.HostsOwasp{Excludes: []model.HostsOwaspExclude{{RuleId: "942100\" \"id:1"}}}, "/a?q=attack", true},
+	}
+	for _, c := range cases {
+		if blocked := isBlocked(t, c.config, c.uri); blocked != c.blocked {
+			t.Errorf("%s: blocked = %v, want %v", c.name, blocked, c.blocked)
+		}
+	}
+}
+
+func TestBuildDirectivesCacheKey(t *testing.T) {
+	config := model.HostsOwasp{Enable: 1, ParanoiaLevel: 2}
+	before1, after1 := BuildDirectives(config)
+	config.Enable = 0
+	before2, after2 := BuildDirectives(config)
+	if before1 != before2 || after1 != after2 {
+		t.Errorf("same rule config should build same directives")
+	}
+}
+
+func TestResponsePhaseAndAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	cfg := coraza.NewWAFConfig().
+		WithDirectives("SecRuleEngine On").
+		WithDirectives(`SecRule RESPONSE_BODY "@contains SQL syntax" "id:951100,phase:4,deny,status:403,log,auditlog,msg:'SQL Error Leakage'"`).
+		WithDirectives(responseAndAuditDirectives(dir))
+	waf, err := coraza.NewWAF(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := waf.NewTransaction()
+	tx.ProcessURI("/list", "GET", "HTTP/1.1")
+	tx.ProcessRequestHeaders()
+	if _, err := tx.ProcessRequestBody(); err != nil {
+		t.Fatal(err)
+	}
+	resp := &http.Response{StatusCode: 200, Proto: "HTTP/1.1", Header: http.Header{"Content-Type": []string{"text/html"}}}
+	it, err := ProcessResponse(tx, resp, []byte("You have an error in your SQL syntax near '1'"))
+	if err != nil || it == nil || it.RuleID != 951100 {
+		t.Fatalf("response should be interrupted by 951100, got %v %v", it, err)
+	}
+	detail := GetDetail(tx)
+	if len(detail.Rules) != 1 || detail.Rules[0].Msg != "SQL Error Leakage" || detail.Rules[0].Phase != 4 {
+		t.Errorf("unexpected detail %+v", detail)
+	}
+	FinishTransaction(tx, true)
+	content, err := os.ReadFile(filepath.Join(dir, "logs", "owasp_audit.log"))
+	if err != nil || !strings.Contains(string(content), "951100") {
+		t.Errorf("audit log not written: %v %s", err, content)
+	}
+}
@@ -0,0 +1,108 @@
+package wafowasp
+
+import (
+	"SamWaf/enums"
+	"SamWaf/model"
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/corazawaf/coraza/v3"
+)
+
+// 自定义规则中禁止使用的指令 避免读写任意文件
+var forbiddenDirectives = []string{
+	"include",
+	"secauditlog",
+	"secauditlogdir",
+	"secauditlogstoragedir",
+	"secdebuglog",
+	"secdatadir",
+	"secuploaddir",
+	"sectmpdir",
+}
+
+/*
+*
+把自定义规则按加载位置拼接 before 在CRS规则之前加载，after 在CRS规则之后加载
+*/
+func BuildRuleDirectives(rules []model.OwaspRule) (string, string) {
+	var before, after []string
+	for _, rule := range rules {
+		content := strings.TrimSpace(rule.Content)
+		if content == "" {
+			continue
+		}
+		if rule.Position == enums.OWASP_RULE_POSITION_BEFORE {
+			before = append(before, content)
+		} else {
+			after = append(after, content)
+		}
+	}
+	return strings.Join(before, "\n"), strings.Join(after, "\n")
+}
+
+/*
+*
+检测规则中是否含有禁止的指令 返回禁止的指令名称
+*/
+func FindForbiddenDirective(content string) string {
+	continued := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		isContinued := continued
+		continued = strings.HasSuffix(line, "\\")
+		if isContinued || line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		directive := strings.ToLower(strings.Fields(line)[0])
+		for _, forbidden := range forbiddenDirectives {
+			if directive == forbidden {
+				return strings.Fields(line)[0]
+			}
+		}
+	}
+	return ""
+}
+
+// 规则中的id动作
+var ruleIdActionPattern = regexp.MustCompile(`(?i)(?:^|[\s,"'])id\s*:\s*'?(\d+)`)
+
+/*
+*
+检测规则是否使用了系统保留的规则ID 返回使用的保留ID 未使用返回0
+*/
+func FindReservedRuleId(content string) int {
+	for _, match := range ruleIdActionPattern.FindAllStringSubmatch(content, -1) {
+		ruleId, err := strconv.Atoi(match[1])
+		if err == nil && ruleId >= ReservedRuleIdMin && ruleId <= ReservedRuleIdMax {
+			return ruleId
+		}
+	}
+	return 0
+}
+
+/*
+*
+保存前校验规则 rules 为同一作用域内全部启用的规则（含待保存的规则）
+存在CRS规则文件时和CRS一起编译，以便检查规则ID冲突和对CRS规则的引用
+*/
+func ValidateRules(currentDir string, rules []model.OwaspRule) error {
+	for _, rule := range rules {
+		if directive := FindForbiddenDirective(rule.Content); directive != "" {
+			return errors.New("不允许使用指令 " + directive)
+		}
+		if ruleId := FindReservedRuleId(rule.Content); ruleId != 0 {
+			return errors.New("规则ID " + strconv.Itoa(ruleId) + " 为系统保留（" + strconv.Itoa(ReservedRuleIdMin) + "-" + strconv.Itoa(ReservedRuleIdMax) + "）")
+		}
+	}
+	beforeRules, afterRules := BuildRuleDirectives(rules)
+	if _, err := os.Stat(currentDir + "/data/owasp/coraza.conf"); err != nil {
+		_, err = coraza.NewWAF(coraza.NewWAFConfig().WithDirectives(beforeRules + "\n" + afterRules))
+		return err
+	}
+	_, err := compileWAF(currentDir, beforeRules, afterRules)
+	return err
+}
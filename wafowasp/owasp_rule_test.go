@@ -0,0 +1,81 @@
+package wafowasp
+
+import (
+	"SamWaf/enums"
+	"SamWaf/model"
+	"strings"
+	"testing"
+)
+
+func TestBuildRuleDirectives(t *testing.T) {
+	rules := []model.OwaspRule{
+		{Content: `SecRule ARGS "@contains a" "id:10001,phase:2,deny"`, Position: enums.OWASP_RULE_POSITION_AFTER},
+		{Content: `SecRule ARGS "@contains b" "id:10002,phase:1,pass,ctl:ruleRemoveById=942100"`, Position: enums.OWASP_RULE_POSITION_BEFORE},
+		{Content: "  ", Position: enums.OWASP_RULE_POSITION_AFTER},
+	}
+	before, after := BuildRuleDirectives(rules)
+	if !strings.Contains(before, "id:10002") || strings.Contains(before, "id:10001") {
+		t.Errorf("unexpected before rules %s", before)
+	}
+	if !strings.Contains(after, "id:10001") || strings.Contains(after, "\n") {
+		t.Errorf("unexpected after rules %s", after)
+	}
+}
+
+func TestFindForbiddenDirective(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`SecRule ARGS "@contains a" "id:10001,phase:2,deny"`, ""},
+		{"include /etc/passwd", "include"},
+		{"# SecAuditLog /tmp/a.log\nSecRuleEngine On", ""},
+		{"SecRule ARGS \"@rx a\" \\\n    \"id:10003,phase:2,deny\"\nSecDebugLog /tmp/debug.log", "SecDebugLog"},
+	}
+	for _, tt := range tests {
+		if got := FindForbiddenDirective(tt.content); got != tt.want {
+			t.Errorf("FindForbiddenDirective(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	dir := t.TempDir()
+	valid := model.OwaspRule{Content: `SecRule ARGS "@contains attack" "id:10001,phase:2,deny,status:403"`}
+	if err := ValidateRules(dir, []model.OwaspRule{valid}); err != nil {
+		t.Errorf("valid rule rejected: %v", err)
+	}
+	invalid := model.OwaspRule{Content: `SecRule ARGS "@unknownop attack" "id:10002,phase:2,deny"`}
+	if err := ValidateRules(dir, []model.OwaspRule{invalid}); err == nil {
+		t.Errorf("invalid operator should be rejected")
+	}
+	//规则ID冲突
+	if err := ValidateRules(dir, []model.OwaspRule{valid, valid}); err == nil {
+		t.Errorf("duplicated rule id should be rejected")
+	}
+	forbidden := model.OwaspRule{Content: "Include /etc/passwd"}
+	if err := ValidateRules(dir, []model.OwaspRule{forbidden}); err == nil {
+		t.Errorf("forbidden directive should be rejected")
+	}
+}
+
+func TestFindReservedRuleId(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{`SecRule ARGS "@contains a" "id:10001,phase:2,deny"`, 0},
+		{`SecRule ARGS "@contains a" "id:9000001,phase:2,deny"`, 9000001},
+		{`SecAction "phase:1,pass,nolog,id:'9000100'"`, 9000100},
+		{`SecRule ARGS "@contains a" "id:9001000,phase:2,deny,msg:'uid:9000001'"`, 0},
+	}
+	for _, tt := range tests {
+		if got := FindReservedRuleId(tt.content); got != tt.want {
+			t.Errorf("FindReservedRuleId(%q) = %d, want %d", tt.content, got, tt.want)
+		}
+	}
+	reserved := model.OwaspRule{Content: `SecRule ARGS "@contains attack" "id:9000001,phase:2,deny"`}
+	if err := ValidateRules(t.TempDir(), []model.OwaspRule{reserved}); err == nil {
+		t.Errorf("reserved rule id should be rejected")
+	}
+}
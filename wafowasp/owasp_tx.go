@@ -0,0 +1,115 @@
+package wafowasp
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/corazawaf/coraza/v3/experimental/plugins/plugintypes"
+	"github.com/corazawaf/coraza/v3/types"
+)
+
+// OwaspMatchedRule 命中的规则
+type OwaspMatchedRule struct {
+	Id       int    `json:"id"`       //规则ID
+	Msg      string `json:"msg"`      //规则说明
+	Data     string `json:"data"`     //命中的数据
+	Severity string `json:"severity"` //严重程度
+	Phase    int    `json:"phase"`    //阶段 1,2请求 3,4响应
+}
+
+// OwaspDetail 检测详情 随日志保存
+type OwaspDetail struct {
+	InboundScore  int                `json:"inbound_score"`  //请求异常分数
+	OutboundScore int                `json:"outbound_score"` //响应异常分数
+	Rules         []OwaspMatchedRule `json:"rules"`          //命中的规则
+}
+
+/*
+*
+生成响应检测和审计日志相关规则 审计日志写入程序目录下logs/owasp_audit.log并按大小轮转
+*/
+func responseAndAuditDirectives(currentDir string) string {
+	return "SecResponseBodyAccess On\n" +
+		"SecResponseBodyMimeType text/plain text/html text/xml application/json\n" +
+		"SecAuditEngine RelevantOnly\n" +
+		"SecAuditLogParts ABIJDEFHKZ\n" +
+		"SecAuditLogFormat JSON\n" +
+		"SecAuditLogType " + auditLogWriterName + "\n" +
+		"SecAuditLog " + currentDir + "/logs/owasp_audit.log"
+}
+
+/*
+*
+处理响应阶段 body为解压后的响应内容
+*/
+func ProcessResponse(tx types.Transaction, resp *http.Response, body []byte) (*types.Interruption, error) {
+	for key, values := range resp.Header {
+		for _, value := range values {
+			tx.AddResponseHeader(key, value)
+		}
+	}
+	if it := tx.ProcessResponseHeaders(resp.StatusCode, resp.Proto); it != nil {
+		return it, nil
+	}
+	if len(body) > 0 && tx.IsResponseBodyAccessible() && tx.IsResponseBodyProcessable() {
+		it, _, err := tx.WriteResponseBody(body)
+		if err != nil {
+			return nil, err
+		}
+		if it != nil {
+			return it, nil
+		}
+	}
+	return tx.ProcessResponseBody()
+}
+
+/*
+*
+取出命中的规则和异常分数
+*/
+func GetDetail(tx types.Transaction) OwaspDetail {
+	detail := OwaspDetail{}
+	for _, matchedRule := range tx.MatchedRules() {
+		//初始化等没有说明的规则不记录
+		if matchedRule.Message() == "" {
+			continue
+		}
+		detail.Rules = append(detail.Rules, OwaspMatchedRule{
+			Id:       matchedRule.Rule().ID(),
+			Msg:      matchedRule.Message(),
+			Data:     matchedRule.Data(),
+			Severity: matchedRule.Rule().Severity().String(),
+			Phase:    int(matchedRule.Rule().Phase()),
+		})
+	}
+	if state, ok := tx.(plugintypes.TransactionState); ok {
+		//兼容CRS v4和v3的变量名
+		detail.InboundScore = getTxInt(state, "blocking_inbound_anomaly_score", "anomaly_score")
+		detail.OutboundScore = getTxInt(state, "blocking_outbound_anomaly_score", "outbound_anomaly_score")
+	}
+	return detail
+}
+
+// 按顺序取第一个存在的事务变量
+func getTxInt(state plugintypes.TransactionState, keys ...string) int {
+	for _, key := range keys {
+		if values := state.Variables().TX().Get(key); len(values) > 0 {
+			value, err := strconv.Atoi(values[0])
+			if err == nil {
+				return value
+			}
+		}
+	}
+	return 0
+}
+
+/*
+*
+结束事务 auditLog 为是否写入审计日志
+*/
+func FinishTransaction(tx types.Transaction, auditLog bool) {
+	if auditLog {
+		tx.ProcessLogging()
+	}
+	_ = tx.Close()
+}
@@ -92,6 +92,10 @@ type ReverseProxy struct {
 	// If nil, the default is to log the provided error and return
 	// a 502 Status Bad Gateway response.
 	ErrorHandler func(http.ResponseWriter, *http.Request, error)
+
+	// UpgradeHandler 可选 协议升级(101)成功后接管客户端和后端之间的数据转发
+	// 为空时直接双向拷贝，函数返回即表示连接结束
+	UpgradeHandler func(req *http.Request, user io.ReadWriter, backend io.ReadWriter)
 }
 
 // A BufferPool is an interface for getting and returning temporary
@@ -655,6 +659,10 @@ func (p *ReverseProxy) handleUpgradeResponse(rw http.ResponseWriter, req *http.R
 		p.getErrorHandler()(rw, req, fmt.Errorf("response flush: %v", err))
 		return
 	}
+	if p.UpgradeHandler != nil {
+		p.UpgradeHandler(req, conn, backConn)
+		return
+	}
 	errc := make(chan error, 1)
 	spc := switchProtocolCopier{user: conn, backend: backConn}
 	go spc.copyToBackend(errc)
@@ -1,69 +1,78 @@
 package waftask
 
 import (
+	"SamWaf/common/asntool"
 	"SamWaf/common/zlog"
+	"SamWaf/customtype"
 	"SamWaf/enums"
 	"SamWaf/global"
+	"SamWaf/innerbean"
 	"SamWaf/model"
-	"SamWaf/model/request"
+	"SamWaf/model/baseorm"
 	"SamWaf/model/spec"
 	"SamWaf/service/waf_service"
 	"SamWaf/utils"
 	"errors"
 	"fmt"
+	uuid "github.com/satori/go.uuid"
 	"gorm.io/gorm"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
 	wafBatchTaskService = waf_service.WafBatchServiceApp
-	wafIPAllowService   = waf_service.WafWhiteIpServiceApp
+	// 正在执行的任务 避免定时和手工同时执行
+	batchTaskRunning sync.Map
+	// 获取远程数据使用的客户端
+	remoteSourceClient = &http.Client{Timeout: remoteSourceTimeout}
+)
+
+const (
+	batchTaskMaxErrors   = 20               // 执行记录中最多保留的错误条数
+	batchInsertSize      = 500              // 新增数据每批写入的条数
+	remoteSourceTimeout  = 60 * time.Second // 获取远程数据的超时时间
+	remoteSourceMaxBytes = 32 << 20         // 远程数据的最大长度
 )
 
 /*
 *
-批量任务
+批量任务 执行启用且没有单独设置cron表达式的任务
 */
 func BatchTask() {
 	innerLogName := "BatchTask"
 	zlog.Info(innerLogName, "准备进行自动执行批量任务")
 
-	batchTaskList, size, err := wafBatchTaskService.GetAllListInner()
-	if err != nil {
-		zlog.Error(innerLogName, "批量任务:", err)
-		return
-	}
-	if size <= 0 {
+	batchTaskList := wafBatchTaskService.GetAllEnableListInner()
+	if len(batchTaskList) <= 0 {
 		zlog.Info(innerLogName, "没有需要批量执行的任务")
 		return
 	}
-	for _, batchTask := range batchTaskList {
-
-		switch batchTask.BatchType {
-		case enums.BATCHTASK_IPALLOW:
-			IPAllowBatch(batchTask)
-			break
-
-		}
+	for _, batchTask := range filterBatchTasks(batchTaskList, false) {
+		ExecuteBatchTask(batchTask, enums.BATCHTASK_TRIGGER_CRON)
 		zlog.Info(innerLogName, "批量已处理完")
 
 	}
 }
 
 // handleLocalSource 从本地路径读取数据
-func handleLocalSource(task model.BatchTask) (string, error) {
-	data, err := ioutil.ReadFile(task.BatchSource)
+func handleLocalSource(source string) (string, error) {
+	data, err := ioutil.ReadFile(source)
 	if err != nil {
 		return "", fmt.Errorf("failed to read local file: %v", err)
 	}
 	return string(data), nil
 }
 
-// handleRemoteSource  从远程 URL 获取数据
-func handleRemoteSource(task model.BatchTask) (string, error) {
-	resp, err := http.Get(task.BatchSource)
+// handleRemoteSource  从远程 URL 获取数据 超过最大长度时返回错误
+func handleRemoteSource(source string) (string, error) {
+	resp, err := remoteSourceClient.Get(source)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch remote data: %v", err)
 	}
@@ -73,80 +82,520 @@ func handleRemoteSource(task model.BatchTask) (string, error) {
 		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	data, err := ioutil.ReadAll(resp.Body)
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, remoteSourceMaxBytes+1))
 	if err != nil {
 		return "", fmt.Errorf("failed to read remote response body: %v", err)
 	}
+	if len(data) > remoteSourceMaxBytes {
+		return "", fmt.Errorf("remote response body exceeds %d bytes", remoteSourceMaxBytes)
+	}
 	return string(data), nil
 }
 
-// IPAllowBatch 白名单IP批量处理
-func IPAllowBatch(task model.BatchTask) {
-	innerLogName := "BatchTask-IPAllowBatch"
+// 批量导入的一条数据
+type batchRow struct {
+	key    string                                      //判断是否已存在的键
+	bean   interface{}                                 //新增时写入的数据
+	update func(existId string) map[string]interface{} //覆盖时更新的字段
+	check  func(existId string) error                  //覆盖前的检查 为空时不检查
+}
+
+/*
+*
+各任务类型的导入方式
+覆盖执行时按键找到已存在的数据进行更新：IP、URL名单的键就是名单项本身（URL含匹配条件），只更新备注；
+敏感词的键为内容、检测方向和处理方式，只更新备注；规则更新内容，并且需要通过原规则的测试用例
+*/
+type batchImporter struct {
+	model    interface{}                                               //更新使用的模型
+	idColumn string                                                    //更新使用的条件字段
+	split    func(content string) []string                             //拆分数据 为空时按行拆分
+	existing func(hostCode string) map[string]string                   //已存在的数据 键->条件字段值
+	parse    func(task model.BatchTask, item string) (batchRow, error) //解析单条数据
+	notify   func(hostCode string)                                     //通知引擎重新加载
+}
+
+/*
+*
+执行批量任务 并记录执行结果，执行失败时发送通知
+任务正在执行时返回错误
+*/
+func ExecuteBatchTask(task model.BatchTask, triggerType string) (model.BatchTaskHistory, error) {
+	innerLogName := "BatchTask-" + task.BatchType
+	if _, loaded := batchTaskRunning.LoadOrStore(task.Id, true); loaded {
+		return model.BatchTaskHistory{}, errors.New("任务正在执行")
+	}
+	defer batchTaskRunning.Delete(task.Id)
+
+	history := model.BatchTaskHistory{
+		BatchTaskId:   task.Id,
+		BatchTaskName: task.BatchTaskName,
+		BatchType:     task.BatchType,
+		TriggerType:   triggerType,
+		StartTime:     customtype.JsonTime(time.Now()),
+	}
+	err := runBatchTask(task, &history)
+	if err != nil {
+		history.ErrorMsg = strings.TrimSpace(err.Error() + "\n" + history.ErrorMsg)
+		zlog.Error(innerLogName, task.BatchTaskName+" "+err.Error())
+		global.GQEQUE_MESSAGE_DB.Enqueue(innerbean.OperatorMessageInfo{
+			BaseMessageInfo: innerbean.BaseMessageInfo{OperaType: "批量任务执行失败"},
+			OperaCnt:        fmt.Sprintf("任务:%s 错误:%s", task.BatchTaskName, err.Error()),
+		})
+	}
+	history.EndTime = customtype.JsonTime(time.Now())
+	wafBatchTaskService.AddHistoryApi(&history)
+	return history, nil
+}
+
+func runBatchTask(task model.BatchTask, history *model.BatchTaskHistory) error {
+	importer, ok := getBatchImporter(task.BatchType)
+	if !ok {
+		return errors.New("不支持的任务类型:" + task.BatchType)
+	}
 	//1.取数据
-	//2.处理数据
 	content := ""
+	var err error
 	if task.BatchSourceType == "local" {
-		cnt, err := handleLocalSource(task)
-		if err != nil {
-			zlog.Error(innerLogName, err.Error())
-			return
-		}
-		content = cnt
+		content, err = handleLocalSource(task.BatchSource)
 	} else if task.BatchSourceType == "remote" {
-		cnt, err := handleRemoteSource(task)
-		if err != nil {
-			zlog.Error(innerLogName, err.Error())
-			return
-		}
-		content = cnt
+		content, err = handleRemoteSource(task.BatchSource)
+	} else {
+		err = errors.New("不支持的来源类型:" + task.BatchSourceType)
+	}
+	if err != nil {
+		return err
 	}
 	if content == "" {
-		zlog.Error(innerLogName, task.BatchTaskName+"没有数据需要处理")
-		return
+		return errors.New("没有数据需要处理")
+	}
+	//2.处理数据
+	var items []string
+	if importer.split != nil {
+		items = importer.split(content)
+	} else {
+		items = strings.Split(content, "\n")
 	}
-	hasAffectInfo := false
-	lines := strings.Split(content, "\n")
-	// 遍历每一行数据进行处理
-	for _, line := range lines {
-		if line == "" {
-			continue // 跳过空行
+	var errorMsgs []string
+	addError := func(msg string) {
+		if len(errorMsgs) < batchTaskMaxErrors {
+			errorMsgs = append(errorMsgs, msg)
 		}
-		line = strings.TrimSpace(line)
-		validRet, _ := utils.IsValidIPOrNetwork(line)
-		if !validRet {
+	}
+	existing := importer.existing(task.BatchHostCode)
+	seen := map[string]bool{}
+	var addRows, updateRows []batchRow
+	var updateIds []string
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || strings.HasPrefix(item, "#") {
+			continue // 跳过空行和注释
+		}
+		row, err := importer.parse(task, item)
+		if err != nil {
+			history.InvalidCount++
+			addError(err.Error())
 			continue
 		}
-		if task.BatchExecuteMethod == enums.BATCHTASK_EXECUTEMETHODAPPEND {
-			//添加
-			err := wafIPAllowService.CheckIsExistApi(request.WafAllowIpAddReq{task.BatchHostCode, line, ""})
-			if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
-				wafIPAllowService.AddApi(request.WafAllowIpAddReq{task.BatchHostCode, line, "20241120批量导入 任务ID:" + task.Id})
-				hasAffectInfo = true
-			}
+		if seen[row.key] {
+			history.SkipCount++
+			continue
+		}
+		seen[row.key] = true
+		existId, isExist := existing[row.key]
+		if !isExist {
+			addRows = append(addRows, row)
 		} else if task.BatchExecuteMethod == enums.BATCHTASK_EXECUTEMETHODOVERWRITE {
-			//覆写
-			bean := wafIPAllowService.GetDetailByIPApi(line, task.BatchHostCode)
-			if bean.HostCode == "" {
-				wafIPAllowService.AddApi(request.WafAllowIpAddReq{task.BatchHostCode, line, "20241120批量导入 任务ID:" + task.Id})
-				hasAffectInfo = true
-			} else {
-				wafIPAllowService.ModifyApi(request.WafAllowIpEditReq{bean.Id, task.BatchHostCode, line, "20241120批量导入编辑 任务ID:" + task.Id})
-				hasAffectInfo = true
+			if row.check != nil {
+				if err := row.check(existId); err != nil {
+					history.InvalidCount++
+					addError(err.Error())
+					continue
+				}
+			}
+			updateRows = append(updateRows, row)
+			updateIds = append(updateIds, existId)
+		} else {
+			history.SkipCount++
+		}
+	}
+	history.ErrorMsg = strings.Join(errorMsgs, "\n")
+	if len(addRows) == 0 && len(updateRows) == 0 {
+		return nil
+	}
+	//3.在一个事务中写入 失败时全部回滚
+	err = global.GWAF_LOCAL_DB.Transaction(func(tx *gorm.DB) error {
+		if len(addRows) > 0 {
+			beans := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(addRows[0].bean)), 0, len(addRows))
+			for _, row := range addRows {
+				beans = reflect.Append(beans, reflect.ValueOf(row.bean))
+			}
+			if err := tx.CreateInBatches(beans.Interface(), batchInsertSize).Error; err != nil {
+				return err
 			}
+		}
+		for i, row := range updateRows {
+			if err := tx.Model(importer.model).Where(importer.idColumn+" = ?", updateIds[i]).Updates(row.update(updateIds[i])).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("写入失败 已回滚:%v", err)
+	}
+	history.AddCount = len(addRows)
+	history.UpdateCount = len(updateRows)
+	//发送通知到引擎进行实时生效
+	importer.notify(task.BatchHostCode)
+	return nil
+}
 
+// 新增数据使用的基础信息
+func newBatchBaseOrm() baseorm.BaseOrm {
+	return baseorm.BaseOrm{
+		Id:          uuid.NewV4().String(),
+		USER_CODE:   global.GWAF_USER_CODE,
+		Tenant_ID:   global.GWAF_TENANT_ID,
+		CREATE_TIME: customtype.JsonTime(time.Now()),
+		UPDATE_TIME: customtype.JsonTime(time.Now()),
+	}
+}
+
+// 批量导入的备注
+func batchRemarks(task model.BatchTask) string {
+	return "批量导入 任务ID:" + task.Id
+}
+
+// 查询网站已存在的数据 键由 keyColumns 拼接
+func batchExisting(bean interface{}, hostCode string, idColumn string, keyColumns ...string) map[string]string {
+	var rows []map[string]interface{}
+	global.GWAF_LOCAL_DB.Model(bean).Where("host_code = ?", hostCode).
+		Select(append([]string{idColumn}, keyColumns...)).Find(&rows)
+	existing := make(map[string]string, len(rows))
+	for _, row := range rows {
+		values := make([]interface{}, len(keyColumns))
+		for i, column := range keyColumns {
+			values[i] = row[column]
 		}
+		existing[batchKey(values...)] = fmt.Sprint(row[idColumn])
 	}
-	if hasAffectInfo {
-		//发送通知到引擎进行实时生效
-		var ipWhites []model.IPAllowList
-		global.GWAF_LOCAL_DB.Where("host_code = ? ", task.BatchHostCode).Find(&ipWhites)
-		var chanInfo = spec.ChanCommonHost{
-			HostCode: task.BatchHostCode,
-			Type:     enums.ChanTypeAllowIP,
-			Content:  ipWhites,
+	return existing
+}
+
+// 拼接判断是否已存在的键 空值按空字符串处理
+func batchKey(values ...interface{}) string {
+	keys := make([]string, len(values))
+	for i, value := range values {
+		if value != nil {
+			keys[i] = fmt.Sprint(value)
 		}
-		global.GWAF_CHAN_MSG <- chanInfo
 	}
+	return strings.Join(keys, "|")
+}
+
+// 发送通知到引擎
+func sendBatchNotify(hostCode string, chanType int, content interface{}) {
+	var chanInfo = spec.ChanCommonHost{
+		HostCode: hostCode,
+		Type:     chanType,
+		Content:  content,
+	}
+	global.GWAF_CHAN_MSG <- chanInfo
+}
 
+// 解析IP 支持IP和网段，黑名单额外支持ASN
+func parseBatchIP(item string, allowASN bool) (string, error) {
+	ip := strings.TrimSpace(strings.Split(item, ",")[0])
+	if validRet, _ := utils.IsValidIPOrNetwork(ip); validRet {
+		return ip, nil
+	}
+	if allowASN {
+		if asn, ok := asntool.ParseASN(ip); ok {
+			return "AS" + strconv.FormatInt(asn, 10), nil
+		}
+	}
+	return "", errors.New("IP格式不正确:" + item)
+}
+
+// URL名单判断是否已存在的字段 对比方式、地址和匹配条件都相同才是同一项，新增时按相同顺序拼接键
+var batchURLKeyColumns = []string{"compare_type", "url", "ignore_case", "req_method", "req_host",
+	"header_name", "header_value", "query_name", "query_value"}
+
+/*
+*
+解析URL 格式为 对比方式,URL 或直接为URL（按等于处理）
+*/
+func parseBatchURL(item string) (string, string, error) {
+	compareType := enums.URL_COMPARE_EQUAL
+	url := item
+	if before, after, found := strings.Cut(item, ","); found {
+		switch strings.TrimSpace(before) {
+		case enums.URL_COMPARE_EQUAL, enums.URL_COMPARE_PREFIX, enums.URL_COMPARE_SUFFIX, enums.URL_COMPARE_CONTAINS, enums.URL_COMPARE_REGEX:
+			compareType = strings.TrimSpace(before)
+			url = strings.TrimSpace(after)
+		}
+	}
+	if err := utils.CheckURLMatchRule(utils.URLMatchRule{CompareType: compareType, Url: url}); err != nil {
+		return "", "", errors.New(err.Error() + ":" + item)
+	}
+	return compareType, url, nil
+}
+
+/*
+*
+解析敏感词 格式为 内容[,检测方向[,处理方式]]，检测方向和处理方式默认为请求、阻止
+*/
+func parseBatchSensitive(item string) (string, int, int, error) {
+	parts := strings.Split(item, ",")
+	content := strings.TrimSpace(parts[0])
+	checkDirection, action := enums.SENSITIVE_DIRECTION_REQUEST, enums.SENSITIVE_ACTION_BLOCK
+	var err error
+	if len(parts) > 1 {
+		checkDirection, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || checkDirection < enums.SENSITIVE_DIRECTION_REQUEST || checkDirection > enums.SENSITIVE_DIRECTION_BOTH {
+			return "", 0, 0, errors.New("检测方向不正确:" + item)
+		}
+	}
+	if len(parts) > 2 {
+		action, err = strconv.Atoi(strings.TrimSpace(parts[2]))
+		if err != nil || action < enums.SENSITIVE_ACTION_BLOCK || action > enums.SENSITIVE_ACTION_LOG {
+			return "", 0, 0, errors.New("处理方式不正确:" + item)
+		}
+	}
+	if content == "" || len(parts) > 3 {
+		return "", 0, 0, errors.New("敏感词格式不正确:" + item)
+	}
+	return content, checkDirection, action, nil
+}
+
+// 规则文本拆分成单条规则
+func splitBatchRules(content string) []string {
+	blocks := utils.SplitRules(content)
+	items := make([]string, len(blocks))
+	for i, block := range blocks {
+		items[i] = block.Content
+	}
+	return items
+}
+
+/*
+*
+解析单条规则 规则名改为 R+规则唯一码，避免与网站已有规则重名
+已存在同名（描述）规则时覆盖其内容，覆盖前执行原规则的测试用例，未通过时不覆盖
+*/
+func parseBatchRule(task model.BatchTask, item string) (batchRow, error) {
+	blocks := utils.SplitRules(item)
+	if len(blocks) != 1 {
+		return batchRow{}, errors.New("规则格式不正确")
+	}
+	block := blocks[0]
+	ruleHelper := &utils.RuleHelper{}
+	ruleCode := uuid.NewV4().String()
+	ruleContent := utils.RenameRule(block, "R"+strings.Replace(ruleCode, "-", "", -1))
+	if err := ruleHelper.CheckRuleAvailable(ruleContent); err != nil {
+		return batchRow{}, fmt.Errorf("规则%s校验失败:%v", block.Description, err)
+	}
+	bean := &model.Rules{
+		BaseOrm:         newBatchBaseOrm(),
+		HostCode:        task.BatchHostCode,
+		RuleCode:        ruleCode,
+		RuleName:        block.Description,
+		RuleContent:     ruleContent,
+		RuleVersionName: "初版",
+		RuleVersion:     1,
+		IsPublicRule:    0,
+		IsManualRule:    1,
+		RuleStatus:      1,
+	}
+	return batchRow{key: block.Description, bean: bean, update: func(existId string) map[string]interface{} {
+		return map[string]interface{}{
+			"RuleContent": utils.RenameRule(block, "R"+strings.Replace(existId, "-", "", -1)),
+			"RuleVersion": gorm.Expr("rule_version + 1"),
+			"UPDATE_TIME": customtype.JsonTime(time.Now()),
+		}
+	}, check: func(existId string) error {
+		var rule model.Rules
+		global.GWAF_LOCAL_DB.Where("rule_code = ?", existId).Find(&rule)
+		return checkBatchRuleTestCases(rule, utils.RenameRule(block, "R"+strings.Replace(existId, "-", "", -1)))
+	}}, nil
+}
+
+// 用原规则的测试用例检查新的规则内容
+func checkBatchRuleTestCases(rule model.Rules, ruleContent string) error {
+	rule.RuleContent = ruleContent
+	testResults, err := utils.RunRuleTestCases(rule, ruleContent)
+	if err != nil {
+		return fmt.Errorf("规则%s测试用例执行失败:%v", rule.RuleName, err)
+	}
+	if !utils.IsRuleTestPassed(testResults) {
+		return fmt.Errorf("规则%s测试用例未通过", rule.RuleName)
+	}
+	return nil
+}
+
+func getBatchImporter(batchType string) (batchImporter, bool) {
+	switch batchType {
+	case enums.BATCHTASK_IPALLOW:
+		return batchImporter{
+			model:    model.IPAllowList{},
+			idColumn: "id",
+			existing: func(hostCode string) map[string]string {
+				return batchExisting(&model.IPAllowList{}, hostCode, "id", "ip")
+			},
+			parse: func(task model.BatchTask, item string) (batchRow, error) {
+				ip, err := parseBatchIP(item, false)
+				if err != nil {
+					return batchRow{}, err
+				}
+				bean := &model.IPAllowList{BaseOrm: newBatchBaseOrm(), HostCode: task.BatchHostCode, Ip: ip, Remarks: batchRemarks(task)}
+				return batchRow{key: ip, bean: bean, update: func(string) map[string]interface{} {
+					return map[string]interface{}{"Remarks": batchRemarks(task), "UPDATE_TIME": customtype.JsonTime(time.Now())}
+				}}, nil
+			},
+			notify: func(hostCode string) {
+				var list []model.IPAllowList
+				global.GWAF_LOCAL_DB.Where("host_code = ? ", hostCode).Find(&list)
+				sendBatchNotify(hostCode, enums.ChanTypeAllowIP, list)
+			},
+		}, true
+	case enums.BATCHTASK_IPBLOCK:
+		return batchImporter{
+			model:    model.IPBlockList{},
+			idColumn: "id",
+			existing: func(hostCode string) map[string]string {
+				return batchExisting(&model.IPBlockList{}, hostCode, "id", "ip")
+			},
+			parse: func(task model.BatchTask, item string) (batchRow, error) {
+				ip, err := parseBatchIP(item, true)
+				if err != nil {
+					return batchRow{}, err
+				}
+				bean := &model.IPBlockList{BaseOrm: newBatchBaseOrm(), HostCode: task.BatchHostCode, Ip: ip, Remarks: batchRemarks(task)}
+				return batchRow{key: ip, bean: bean, update: func(string) map[string]interface{} {
+					return map[string]interface{}{"Remarks": batchRemarks(task), "UPDATE_TIME": customtype.JsonTime(time.Now())}
+				}}, nil
+			},
+			notify: func(hostCode string) {
+				var list []model.IPBlockList
+				global.GWAF_LOCAL_DB.Where("host_code = ? ", hostCode).Find(&list)
+				sendBatchNotify(hostCode, enums.ChanTypeBlockIP, list)
+			},
+		}, true
+	case enums.BATCHTASK_URLALLOW:
+		return batchImporter{
+			model:    model.URLAllowList{},
+			idColumn: "id",
+			existing: func(hostCode string) map[string]string {
+				return batchExisting(&model.URLAllowList{}, hostCode, "id", batchURLKeyColumns...)
+			},
+			parse: func(task model.BatchTask, item string) (batchRow, error) {
+				compareType, url, err := parseBatchURL(item)
+				if err != nil {
+					return batchRow{}, err
+				}
+				bean := &model.URLAllowList{BaseOrm: newBatchBaseOrm(), HostCode: task.BatchHostCode, CompareType: compareType, Url: url, Remarks: batchRemarks(task)}
+				return batchRow{key: batchKey(bean.CompareType, bean.Url, bean.IgnoreCase, bean.ReqMethod, bean.ReqHost,
+					bean.HeaderName, bean.HeaderValue, bean.QueryName, bean.QueryValue), bean: bean, update: func(string) map[string]interface{} {
+					return map[string]interface{}{"Remarks": batchRemarks(task), "UPDATE_TIME": customtype.JsonTime(time.Now())}
+				}}, nil
+			},
+			notify: func(hostCode string) {
+				var list []model.URLAllowList
+				global.GWAF_LOCAL_DB.Where("host_code = ? ", hostCode).Find(&list)
+				sendBatchNotify(hostCode, enums.ChanTypeAllowURL, list)
+			},
+		}, true
+	case enums.BATCHTASK_URLBLOCK:
+		return batchImporter{
+			model:    model.URLBlockList{},
+			idColumn: "id",
+			existing: func(hostCode string) map[string]string {
+				return batchExisting(&model.URLBlockList{}, hostCode, "id", batchURLKeyColumns...)
+			},
+			parse: func(task model.BatchTask, item string) (batchRow, error) {
+				compareType, url, err := parseBatchURL(item)
+				if err != nil {
+					return batchRow{}, err
+				}
+				bean := &model.URLBlockList{BaseOrm: newBatchBaseOrm(), HostCode: task.BatchHostCode, CompareType: compareType, Url: url, Remarks: batchRemarks(task)}
+				return batchRow{key: batchKey(bean.CompareType, bean.Url, bean.IgnoreCase, bean.ReqMethod, bean.ReqHost,
+					bean.HeaderName, bean.HeaderValue, bean.QueryName, bean.QueryValue), bean: bean, update: func(string) map[string]interface{} {
+					return map[string]interface{}{"Remarks": batchRemarks(task), "UPDATE_TIME": customtype.JsonTime(time.Now())}
+				}}, nil
+			},
+			notify: func(hostCode string) {
+				var list []model.URLBlockList
+				global.GWAF_LOCAL_DB.Where("host_code = ? ", hostCode).Find(&list)
+				sendBatchNotify(hostCode, enums.ChanTypeBlockURL, list)
+			},
+		}, true
+	case enums.BATCHTASK_LDPURL:
+		return batchImporter{
+			model:    model.LDPUrl{},
+			idColumn: "id",
+			existing: func(hostCode string) map[string]string {
+				return batchExisting(&model.LDPUrl{}, hostCode, "id", batchURLKeyColumns...)
+			},
+			parse: func(task model.BatchTask, item string) (batchRow, error) {
+				compareType, url, err := parseBatchURL(item)
+				if err != nil {
+					return batchRow{}, err
+				}
+				bean := &model.LDPUrl{BaseOrm: newBatchBaseOrm(), HostCode: task.BatchHostCode, CompareType: compareType, Url: url, Remarks: batchRemarks(task)}
+				return batchRow{key: batchKey(bean.CompareType, bean.Url, bean.IgnoreCase, bean.ReqMethod, bean.ReqHost,
+					bean.HeaderName, bean.HeaderValue, bean.QueryName, bean.QueryValue), bean: bean, update: func(string) map[string]interface{} {
+					return map[string]interface{}{"Remarks": batchRemarks(task), "UPDATE_TIME": customtype.JsonTime(time.Now())}
+				}}, nil
+			},
+			notify: func(hostCode string) {
+				var list []model.LDPUrl
+				global.GWAF_LOCAL_DB.Where("host_code = ? ", hostCode).Find(&list)
+				sendBatchNotify(hostCode, enums.ChanTypeLdp, list)
+			},
+		}, true
+	case enums.BATCHTASK_SENSITIVE:
+		return batchImporter{
+			model:    model.Sensitive{},
+			idColumn: "id",
+			existing: func(hostCode string) map[string]string {
+				return batchExisting(&model.Sensitive{}, hostCode, "id", "content", "check_direction", "action")
+			},
+			parse: func(task model.BatchTask, item string) (batchRow, error) {
+				content, checkDirection, action, err := parseBatchSensitive(item)
+				if err != nil {
+					return batchRow{}, err
+				}
+				bean := &model.Sensitive{BaseOrm: newBatchBaseOrm(), HostCode: task.BatchHostCode, CheckDirection: checkDirection, Action: action, Content: content, Remarks: batchRemarks(task)}
+				return batchRow{key: batchKey(content, checkDirection, action), bean: bean, update: func(string) map[string]interface{} {
+					return map[string]interface{}{"Remarks": batchRemarks(task), "UPDATE_TIME": customtype.JsonTime(time.Now())}
+				}}, nil
+			},
+			notify: func(hostCode string) {
+				global.GWAF_CHAN_SENSITIVE <- 1
+			},
+		}, true
+	case enums.BATCHTASK_RULE:
+		return batchImporter{
+			model:    model.Rules{},
+			idColumn: "rule_code",
+			split:    splitBatchRules,
+			existing: func(hostCode string) map[string]string {
+				var rules []model.Rules
+				global.GWAF_LOCAL_DB.Where("host_code = ? and rule_status <> 999 and (template_id = '' or template_id is null)", hostCode).Find(&rules)
+				existing := make(map[string]string, len(rules))
+				for _, rule := range rules {
+					existing[rule.RuleName] = rule.RuleCode
+				}
+				return existing
+			},
+			parse: parseBatchRule,
+			notify: func(hostCode string) {
+				var ruleconfig []model.Rules
+				global.GWAF_LOCAL_DB.Where("host_code = ?  and rule_status<>999", hostCode).Find(&ruleconfig)
+				sendBatchNotify(hostCode, enums.ChanTypeRule, ruleconfig)
+			},
+		}, true
+	}
+	return batchImporter{}, false
 }
@@ -0,0 +1,67 @@
+package waftask
+
+import (
+	"SamWaf/common/zlog"
+	"SamWaf/enums"
+	"SamWaf/model"
+	"github.com/go-co-op/gocron"
+	"strings"
+	"time"
+)
+
+// 批量任务定时器的标签
+const batchTaskCronTag = "batch_task"
+
+/*
+*
+校验cron表达式 格式为 分 时 日 月 周
+*/
+func CheckBatchTaskCron(cronExpr string) error {
+	scheduler := gocron.NewScheduler(time.Local)
+	_, err := scheduler.Cron(cronExpr).Do(func() {})
+	return err
+}
+
+/*
+*
+筛选启用的任务 withCron 为true时返回设置了cron表达式的任务（单独定时），
+为false时返回没有设置的任务（跟随每天的批量任务执行）
+*/
+func filterBatchTasks(tasks []model.BatchTask, withCron bool) []model.BatchTask {
+	var result []model.BatchTask
+	for _, task := range tasks {
+		if task.BatchEnable == 1 && (strings.TrimSpace(task.BatchCron) != "") == withCron {
+			result = append(result, task)
+		}
+	}
+	return result
+}
+
+/*
+*
+重新注册所有设置了cron表达式且启用的批量任务
+任务添加、编辑、删除后调用
+*/
+func ScheduleBatchTasks(scheduler *gocron.Scheduler) {
+	innerLogName := "BatchTask-Schedule"
+	if scheduler == nil {
+		return
+	}
+	_ = scheduler.RemoveByTag(batchTaskCronTag)
+	for _, task := range filterBatchTasks(wafBatchTaskService.GetAllEnableListInner(), true) {
+		taskId := task.Id
+		_, err := scheduler.Cron(task.BatchCron).Tag(batchTaskCronTag, batchTaskCronTag+"_"+taskId).Do(func() {
+			//执行时重新查询 使用最新的任务配置
+			bean := wafBatchTaskService.GetDetailByIdApi(taskId)
+			if bean.Id == "" || bean.BatchEnable != 1 {
+				return
+			}
+			if _, err := ExecuteBatchTask(bean, enums.BATCHTASK_TRIGGER_CRON); err != nil {
+				zlog.Info(innerLogName, bean.BatchTaskName+" "+err.Error())
+			}
+		})
+		if err != nil {
+			zlog.Error(innerLogName, task.BatchTaskName+" cron表达式不正确:"+err.Error())
+		}
+	}
+}
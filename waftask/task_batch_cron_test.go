@@ -0,0 +1,74 @@
+package waftask
+
+import (
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/baseorm"
+	"github.com/go-co-op/gocron"
+	"testing"
+	"time"
+)
+
+func TestCheckBatchTaskCron(t *testing.T) {
+	tests := []struct {
+		expr  string
+		valid bool
+	}{
+		{"0 5 * * *", true},
+		{"*/10 * * * *", true},
+		{"", false},
+		{"bad", false},
+		{"61 * * * *", false},
+	}
+	for _, tt := range tests {
+		if err := CheckBatchTaskCron(tt.expr); (err == nil) != tt.valid {
+			t.Errorf("CheckBatchTaskCron(%q) err=%v want valid=%v", tt.expr, err, tt.valid)
+		}
+	}
+}
+
+func TestFilterBatchTasks(t *testing.T) {
+	tasks := []model.BatchTask{
+		{BaseOrm: baseorm.BaseOrm{Id: "daily"}, BatchEnable: 1},
+		{BaseOrm: baseorm.BaseOrm{Id: "cron"}, BatchEnable: 1, BatchCron: "0 5 * * *"},
+		{BaseOrm: baseorm.BaseOrm{Id: "blank"}, BatchEnable: 1, BatchCron: "  "},
+		{BaseOrm: baseorm.BaseOrm{Id: "disabled"}, BatchEnable: 0, BatchCron: "0 5 * * *"},
+	}
+	daily := filterBatchTasks(tasks, false)
+	if len(daily) != 2 || daily[0].Id != "daily" || daily[1].Id != "blank" {
+		t.Errorf("daily tasks got %v", daily)
+	}
+	cron := filterBatchTasks(tasks, true)
+	if len(cron) != 1 || cron[0].Id != "cron" {
+		t.Errorf("cron tasks got %v", cron)
+	}
+}
+
+func TestScheduleBatchTasks(t *testing.T) {
+	initBatchTestDb(t)
+	if err := global.GWAF_LOCAL_DB.AutoMigrate(&model.BatchTask{}); err != nil {
+		t.Fatal(err)
+	}
+	tasks := []model.BatchTask{
+		{BaseOrm: baseorm.BaseOrm{Id: "daily"}, BatchEnable: 1},
+		{BaseOrm: baseorm.BaseOrm{Id: "cron"}, BatchEnable: 1, BatchCron: "0 5 * * *"},
+		{BaseOrm: baseorm.BaseOrm{Id: "disabled"}, BatchEnable: 0, BatchCron: "0 6 * * *"},
+	}
+	for _, task := range tasks {
+		if err := global.GWAF_LOCAL_DB.Create(&task).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scheduler := gocron.NewScheduler(time.Local)
+	ScheduleBatchTasks(scheduler)
+	//重复注册时应先移除旧任务
+	ScheduleBatchTasks(scheduler)
+	jobs, err := scheduler.FindJobsByTag(batchTaskCronTag)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("scheduled jobs got %d %v", len(jobs), err)
+	}
+	if _, err = scheduler.FindJobsByTag(batchTaskCronTag + "_cron"); err != nil {
+		t.Errorf("cron task not scheduled: %v", err)
+	}
+}
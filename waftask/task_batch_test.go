@@ -0,0 +1,194 @@
+package waftask
+
+import (
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/baseorm"
+	"bytes"
+	"fmt"
+	"github.com/pengge/sqlitedriver"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandleRemoteSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte("1.1.1.1\n2.2.2.2"))
+		case "/large":
+			w.Write(bytes.Repeat([]byte("a"), remoteSourceMaxBytes+1))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	if content, err := handleRemoteSource(server.URL + "/ok"); err != nil || content != "1.1.1.1\n2.2.2.2" {
+		t.Errorf("ok got %q %v", content, err)
+	}
+	if _, err := handleRemoteSource(server.URL + "/large"); err == nil {
+		t.Errorf("large body should return error")
+	}
+	if _, err := handleRemoteSource(server.URL + "/missing"); err == nil {
+		t.Errorf("404 should return error")
+	}
+}
+
+func initBatchTestDb(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&model.IPAllowList{}, &model.URLBlockList{}, &model.Sensitive{}, &model.Rules{}); err != nil {
+		t.Fatal(err)
+	}
+	oldDb := global.GWAF_LOCAL_DB
+	global.GWAF_LOCAL_DB = db
+	t.Cleanup(func() {
+		global.GWAF_LOCAL_DB = oldDb
+		for len(global.GWAF_CHAN_MSG) > 0 {
+			<-global.GWAF_CHAN_MSG
+		}
+	})
+}
+
+func runBatchTestTask(t *testing.T, batchType string, method string, content string) model.BatchTaskHistory {
+	source := filepath.Join(t.TempDir(), "batch.txt")
+	if err := os.WriteFile(source, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	task := model.BatchTask{BaseOrm: baseorm.BaseOrm{Id: "task1"}, BatchType: batchType, BatchHostCode: "hostA",
+		BatchSourceType: "local", BatchSource: source, BatchExecuteMethod: method}
+	history := model.BatchTaskHistory{}
+	if err := runBatchTask(task, &history); err != nil {
+		t.Fatal(err)
+	}
+	for len(global.GWAF_CHAN_MSG) > 0 {
+		<-global.GWAF_CHAN_MSG
+	}
+	return history
+}
+
+func TestRunBatchTaskIPAllow(t *testing.T) {
+	initBatchTestDb(t)
+	history := runBatchTestTask(t, enums.BATCHTASK_IPALLOW, enums.BATCHTASK_EXECUTEMETHODAPPEND, "1.1.1.1\nbad\n1.1.1.1\n# 注释\n10.0.0.0/8,备注")
+	if history.AddCount != 2 || history.InvalidCount != 1 || history.SkipCount != 1 || history.UpdateCount != 0 {
+		t.Errorf("append got %+v", history)
+	}
+	history = runBatchTestTask(t, enums.BATCHTASK_IPALLOW, enums.BATCHTASK_EXECUTEMETHODAPPEND, "1.1.1.1\n2.2.2.2")
+	if history.AddCount != 1 || history.SkipCount != 1 {
+		t.Errorf("append existing got %+v", history)
+	}
+	global.GWAF_LOCAL_DB.Model(&model.IPAllowList{}).Where("ip = ?", "1.1.1.1").Update("remarks", "手工")
+	history = runBatchTestTask(t, enums.BATCHTASK_IPALLOW, enums.BATCHTASK_EXECUTEMETHODOVERWRITE, "1.1.1.1\n3.3.3.3")
+	if history.AddCount != 1 || history.UpdateCount != 1 {
+		t.Errorf("overwrite got %+v", history)
+	}
+	var bean model.IPAllowList
+	global.GWAF_LOCAL_DB.Where("ip = ?", "1.1.1.1").Find(&bean)
+	if bean.Remarks != batchRemarks(model.BatchTask{BaseOrm: baseorm.BaseOrm{Id: "task1"}}) {
+		t.Errorf("overwrite should update remarks, got %s", bean.Remarks)
+	}
+	var total int64
+	global.GWAF_LOCAL_DB.Model(&model.IPAllowList{}).Count(&total)
+	if total != 4 {
+		t.Errorf("expected 4 entries, got %d", total)
+	}
+}
+
+func TestRunBatchTaskSensitiveOverwrite(t *testing.T) {
+	initBatchTestDb(t)
+	runBatchTestTask(t, enums.BATCHTASK_SENSITIVE, enums.BATCHTASK_EXECUTEMETHODAPPEND, "赌博\n代开发票,2")
+	history := runBatchTestTask(t, enums.BATCHTASK_SENSITIVE, enums.BATCHTASK_EXECUTEMETHODOVERWRITE, "赌博,2,1\n代开发票,2\n代开发票,9")
+	if history.AddCount != 1 || history.UpdateCount != 1 || history.InvalidCount != 1 {
+		t.Errorf("overwrite got %+v", history)
+	}
+	var beans []model.Sensitive
+	global.GWAF_LOCAL_DB.Where("content = ?", "赌博").Order("check_direction asc").Find(&beans)
+	if len(beans) != 2 || beans[0].CheckDirection != enums.SENSITIVE_DIRECTION_REQUEST ||
+		beans[1].CheckDirection != enums.SENSITIVE_DIRECTION_BOTH || beans[1].Action != enums.SENSITIVE_ACTION_REPLACE {
+		t.Errorf("different direction and action should be added, got %+v", beans)
+	}
+}
+
+func TestRunBatchTaskURLMatchAttributes(t *testing.T) {
+	initBatchTestDb(t)
+	global.GWAF_LOCAL_DB.Create(&model.URLBlockList{BaseOrm: newBatchBaseOrm(), HostCode: "hostA",
+		CompareType: enums.URL_COMPARE_EQUAL, Url: "/admin", ReqMethod: "POST"})
+	history := runBatchTestTask(t, enums.BATCHTASK_URLBLOCK, enums.BATCHTASK_EXECUTEMETHODOVERWRITE, "/admin")
+	if history.AddCount != 1 || history.UpdateCount != 0 {
+		t.Errorf("entry with other match attributes should not be overwritten, got %+v", history)
+	}
+	history = runBatchTestTask(t, enums.BATCHTASK_URLBLOCK, enums.BATCHTASK_EXECUTEMETHODAPPEND, "/admin")
+	if history.AddCount != 0 || history.SkipCount != 1 {
+		t.Errorf("append existing got %+v", history)
+	}
+	var post model.URLBlockList
+	global.GWAF_LOCAL_DB.Where("req_method = ?", "POST").Find(&post)
+	if post.Remarks != "" {
+		t.Errorf("entry with other match attributes changed, got %+v", post)
+	}
+}
+
+func TestRunBatchTaskInsertInBatches(t *testing.T) {
+	initBatchTestDb(t)
+	var lines []string
+	for i := 0; i < batchInsertSize+100; i++ {
+		lines = append(lines, fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+	}
+	history := runBatchTestTask(t, enums.BATCHTASK_IPALLOW, enums.BATCHTASK_EXECUTEMETHODAPPEND, strings.Join(lines, "\n"))
+	var total int64
+	global.GWAF_LOCAL_DB.Model(&model.IPAllowList{}).Count(&total)
+	if history.AddCount != len(lines) || total != int64(len(lines)) {
+		t.Errorf("add %d total %d, want %d", history.AddCount, total, len(lines))
+	}
+}
+
+func TestRunBatchTaskRuleTestCases(t *testing.T) {
+	initBatchTestDb(t)
+	history := runBatchTestTask(t, enums.BATCHTASK_RULE, enums.BATCHTASK_EXECUTEMETHODAPPEND, `
+rule R1 "禁止后台" salience 10 {
+    when
+        MF.URL.Contains("/admin") == True
+    then
+        Retract("R1");
+}`)
+	if history.AddCount != 1 {
+		t.Fatalf("append rule got %+v", history)
+	}
+	global.GWAF_LOCAL_DB.Model(&model.Rules{}).Where("rule_name = ?", "禁止后台").
+		Update("rule_test_cases", `[{"name":"后台","fact":{"url":"/admin/login"},"expect_match":1}]`)
+
+	history = runBatchTestTask(t, enums.BATCHTASK_RULE, enums.BATCHTASK_EXECUTEMETHODOVERWRITE, `
+rule R1 "禁止后台" salience 10 {
+    when
+        MF.URL.Contains("/manage") == True
+    then
+        Retract("R1");
+}`)
+	if history.UpdateCount != 0 || history.InvalidCount != 1 || !strings.Contains(history.ErrorMsg, "测试用例未通过") {
+		t.Errorf("rule failing test cases should not be updated, got %+v", history)
+	}
+	history = runBatchTestTask(t, enums.BATCHTASK_RULE, enums.BATCHTASK_EXECUTEMETHODOVERWRITE, `
+rule R1 "禁止后台" salience 10 {
+    when
+        MF.URL.Contains("/admin") == True || MF.URL.Contains("/manage") == True
+    then
+        Retract("R1");
+}`)
+	if history.UpdateCount != 1 || history.InvalidCount != 0 {
+		t.Errorf("rule passing test cases should be updated, got %+v", history)
+	}
+	var rule model.Rules
+	global.GWAF_LOCAL_DB.Where("rule_name = ?", "禁止后台").Find(&rule)
+	if !strings.Contains(rule.RuleContent, "/manage") || rule.RuleVersion != 2 {
+		t.Errorf("rule not updated, got %+v", rule)
+	}
+}
@@ -39,6 +39,24 @@ func setConfigIntValue(name string, value int64, change int) {
 	case "enable_owasp":
 		global.GCONFIG_RECORD_ENABLE_OWASP = value
 		break
+	case "owasp_response":
+		global.GCONFIG_RECORD_OWASP_RESPONSE = value
+		break
+	case "owasp_audit_log":
+		global.GCONFIG_RECORD_OWASP_AUDIT_LOG = value
+		break
+	case "scan_error_window_seconds":
+		global.GCONFIG_RECORD_SCAN_ERROR_WINDOW_SECONDS = value
+		break
+	case "scan_error_max_count":
+		global.GCONFIG_RECORD_SCAN_ERROR_MAX_COUNT = value
+		break
+	case "scan_lock_minutes":
+		global.GCONFIG_RECORD_SCAN_LOCK_MINUTES = value
+		break
+	case "scan_ban_hit_count":
+		global.GCONFIG_RECORD_SCAN_BAN_HIT_COUNT = value
+		break
 	default:
 		zlog.Warn("Unknown config item:", name)
 	}
@@ -132,5 +150,11 @@ func TaskLoadSetting(initLoad bool) {
 	updateConfigIntItem(initLoad, "system", "login_max_error_time", global.GCONFIG_RECORD_LOGIN_MAX_ERROR_TIME, "登录周期里错误最大次数 请大于0 ", "int", "")
 	updateConfigIntItem(initLoad, "system", "login_limit_mintutes", global.GCONFIG_RECORD_LOGIN_LIMIT_MINTUTES, "登录错误记录周期 单位分钟数，默认1分钟", "int", "")
 	updateConfigIntItem(initLoad, "system", "enable_owasp", global.GCONFIG_RECORD_ENABLE_OWASP, "启动OWASP数据检测（1启动 0关闭）", "int", "")
+	updateConfigIntItem(initLoad, "system", "owasp_response", global.GCONFIG_RECORD_OWASP_RESPONSE, "启动OWASP响应检测，识别SQL错误、堆栈、目录列表等泄露（1启动 0关闭）", "int", "")
+	updateConfigIntItem(initLoad, "system", "owasp_audit_log", global.GCONFIG_RECORD_OWASP_AUDIT_LOG, "记录OWASP审计日志到logs/owasp_audit.log（1记录 0不记录）", "int", "")
+	updateConfigIntItem(initLoad, "system", "scan_error_window_seconds", global.GCONFIG_RECORD_SCAN_ERROR_WINDOW_SECONDS, "扫描检测404/403统计周期(单位:秒)", "int", "")
+	updateConfigIntItem(initLoad, "system", "scan_error_max_count", global.GCONFIG_RECORD_SCAN_ERROR_MAX_COUNT, "扫描检测周期内404/403最大次数（0不检测）", "int", "")
+	updateConfigIntItem(initLoad, "system", "scan_lock_minutes", global.GCONFIG_RECORD_SCAN_LOCK_MINUTES, "扫描IP自动封禁分钟数（0不封禁）", "int", "")
+	updateConfigIntItem(initLoad, "system", "scan_ban_hit_count", global.GCONFIG_RECORD_SCAN_BAN_HIT_COUNT, "扫描检测周期内命中扫描特征次数达到后封禁（0不封禁）", "int", "")
 
 }
@@ -0,0 +1,91 @@
+package waftask
+
+import (
+	"SamWaf/common/zlog"
+	"SamWaf/enums"
+	"SamWaf/global"
+	"SamWaf/model"
+	"SamWaf/model/spec"
+	"SamWaf/service/waf_service"
+	"SamWaf/utils"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	wafThreatFeedService = waf_service.WafThreatFeedServiceApp
+	// 正在刷新的订阅 避免定时任务和手工刷新同时执行
+	threatFeedRefreshing sync.Map
+)
+
+/*
+*
+威胁情报定时刷新 每分钟检查一次，到达刷新间隔的订阅进行刷新
+*/
+func ThreatFeedTask() {
+	innerLogName := "ThreatFeedTask"
+	now := time.Now()
+	for _, feed := range wafThreatFeedService.GetAllEnableListInner() {
+		interval := feed.RefreshInterval
+		if interval <= 0 {
+			continue
+		}
+		if now.Sub(time.Time(feed.LastRefreshTime)) < time.Duration(interval)*time.Minute {
+			continue
+		}
+		if err := RefreshThreatFeed(feed); err != nil {
+			zlog.Error(innerLogName, feed.FeedName+" 刷新失败:"+err.Error())
+		}
+	}
+}
+
+/*
+*
+刷新单个威胁情报订阅 拉取成功后整体替换条目并通知引擎
+拉取或解析失败时保留原有条目，条目到达有效期后自动失效
+*/
+func RefreshThreatFeed(feed model.ThreatFeed) error {
+	if _, loaded := threatFeedRefreshing.LoadOrStore(feed.Id, true); loaded {
+		return errors.New("订阅正在刷新")
+	}
+	defer threatFeedRefreshing.Delete(feed.Id)
+
+	content := ""
+	var err error
+	if feed.SourceType == enums.THREAT_FEED_SOURCE_LOCAL {
+		content, err = handleLocalSource(feed.Source)
+	} else {
+		content, err = handleRemoteSource(feed.Source)
+	}
+	if err == nil && content == "" {
+		err = errors.New("没有数据需要处理")
+	}
+	var ips []string
+	invalid := 0
+	if err == nil {
+		ips, invalid, err = utils.ParseThreatFeed(feed.Format, feed.FormatArg, content)
+	}
+	if err != nil {
+		wafThreatFeedService.UpdateRefreshMsgApi(feed.Id, "刷新失败:"+err.Error())
+		return err
+	}
+	err = wafThreatFeedService.ReplaceEntriesApi(feed, ips, fmt.Sprintf("刷新成功 条目%d 无法识别%d", len(ips), invalid))
+	if err != nil {
+		wafThreatFeedService.UpdateRefreshMsgApi(feed.Id, "保存失败:"+err.Error())
+		return err
+	}
+	NotifyThreatFeed(feed.HostCode)
+	return nil
+}
+
+// NotifyThreatFeed 通知引擎重新加载网站的威胁情报条目
+func NotifyThreatFeed(hostCode string) {
+	var chanInfo = spec.ChanCommonHost{
+		HostCode: hostCode,
+		Type:     enums.ChanTypeThreatFeed,
+		Content:  wafThreatFeedService.GetEntryListByHostCodeApi(hostCode),
+	}
+	global.GWAF_CHAN_MSG <- chanInfo
+}